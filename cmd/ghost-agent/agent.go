@@ -0,0 +1,847 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/application/usecase"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/apiclient"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/audit"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/auth"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/backup"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/config"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/console"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/libvirt"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/network"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/observability"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/peer"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/storage"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/tailscale"
+	"github.com/iammahbubalam/ghost-agent/internal/presentation/grpc/health"
+	"github.com/iammahbubalam/ghost-agent/internal/presentation/grpc/interceptor"
+	"github.com/iammahbubalam/ghost-agent/internal/presentation/grpc/server"
+	httpserver "github.com/iammahbubalam/ghost-agent/internal/presentation/http"
+)
+
+// dataDir holds the persisted state of the agent, so it survives PC restarts
+const dataDir = "/var/lib/ghost/data"
+
+// agent holds the components of the running agent
+// main wires them up in phases and shuts them down in reverse
+type agent struct {
+	cfg     *config.Config
+	logger  *zap.Logger
+	metrics *observability.Metrics
+
+	tailnet       *tailscale.Monitor
+	tailnetCancel context.CancelFunc
+	tailscaleIP   string
+	hypervisor    *libvirt.Adapter
+	serverTLS     *tls.Config
+	peerTLS       *tls.Config
+	auditLog      *audit.FileLog
+	apiClient     *apiclient.Client
+
+	repos           repositories
+	net             networking
+	storageAdapter  *storage.Adapter
+	resourceRepo    repository.ResourceRepository
+	backupScheduler *backup.Scheduler
+	consoleTokens   *console.TokenStore
+
+	// Use cases run in the background
+	syncPortForwardsUC *usecase.SyncPortForwardsUseCase
+	restartVMsUC       *usecase.RestartVMsUseCase
+	expireVMsUC        *usecase.ExpireVMsUseCase
+	getQuotasUC        *usecase.GetQuotasUseCase
+
+	// Interceptors run in order for gRPC and gateway calls alike
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+
+	grpcSrv        *grpc.Server
+	healthReporter *health.Reporter
+	healthCancel   context.CancelFunc
+	httpServers    []*http.Server
+
+	heartbeatCancel context.CancelFunc
+	loopsCancel     context.CancelFunc
+	loops           sync.WaitGroup
+}
+
+// repositories hold the state of the agent
+type repositories struct {
+	vm                repository.VMRepository
+	image             repository.ImageRepository
+	backup            repository.BackupRepository
+	securityGroup     repository.SecurityGroupRepository
+	network           repository.NetworkRepository
+	portForward       repository.PortForwardRepository
+	flavor            repository.FlavorRepository
+	incomingMigration repository.IncomingMigrationRepository
+}
+
+// networking connects VMs: their networks, addresses, filters and forwards
+// ipam and firewall are nil when disabled
+type networking struct {
+	modes           service.NetworkModes
+	ipv6Prefix      netip.Prefix
+	ipam            service.IPAMService
+	router          service.NetworkService
+	firewall        service.FirewallService
+	privateNetworks *network.PrivateNetworks
+	forwarder       service.PortForwarder
+}
+
+// connect connects to the tailnet, libvirt and Ghost Core, and loads the TLS
+// configuration and the audit log every later phase may use
+func (a *agent) connect() {
+	cfg, logger := a.cfg, a.logger
+	var err error
+
+	// Watch the tailnet through tailscaled's local API
+	var tailnetCtx context.Context
+	tailnetCtx, a.tailnetCancel = context.WithCancel(context.Background())
+	a.tailnet = tailscale.NewMonitor(cfg.Tailscale.Socket, cfg.Tailscale.PollInterval, logger)
+	go a.tailnet.Run(tailnetCtx)
+
+	a.tailscaleIP = waitForTailnet(tailnetCtx, a.tailnet, cfg.Tailscale.StartupTimeout, logger)
+
+	logger.Info("Connecting to Libvirt", zap.String("uri", cfg.Libvirt.URI))
+	a.hypervisor, err = libvirt.NewAdapter(cfg.Libvirt.URI, libvirt.NetworkOptions{
+		Network: cfg.Libvirt.Network,
+		Bridge:  cfg.Libvirt.Bridge,
+	}, logger)
+	if err != nil {
+		logger.Fatal("Failed to create Libvirt adapter", zap.Error(err))
+	}
+
+	// TLS secures the TCP listener and calls to other agents
+	if cfg.GRPC.TLSEnabled {
+		a.serverTLS, err = auth.ServerTLSConfig(cfg.GRPC.TLSCert, cfg.GRPC.TLSKey, cfg.GRPC.TLSCA)
+		if err != nil {
+			logger.Fatal("Failed to load TLS configuration", zap.Error(err))
+		}
+		if cfg.GRPC.TLSCA != "" {
+			a.peerTLS, err = auth.PeerTLSConfig(cfg.GRPC.TLSCert, cfg.GRPC.TLSKey, cfg.GRPC.TLSCA)
+			if err != nil {
+				logger.Fatal("Failed to load TLS configuration", zap.Error(err))
+			}
+		}
+	}
+
+	a.auditLog, err = audit.NewFileLog(cfg.Audit.Path, cfg.Audit.MaxSizeMB, cfg.Audit.MaxFiles, logger)
+	if err != nil {
+		logger.Fatal("Failed to open audit log", zap.Error(err))
+	}
+
+	// Don't fail startup if API is unavailable; registration happens once the rest is up
+	logger.Info("Connecting to Ghost Core API", zap.String("url", cfg.Agent.APIURL))
+	a.apiClient, err = apiclient.NewClient(cfg.Agent.APIURL, cfg.Agent.Name, a.tailscaleIP, a.auditLog, logger, a.metrics)
+	if err != nil {
+		logger.Warn("Failed to connect to Ghost Core API, will retry in heartbeat",
+			zap.Error(err),
+		)
+	}
+}
+
+// openRepositories opens the repositories of the agent's state
+func (a *agent) openRepositories() {
+	var err error
+	if a.repos.vm, err = storage.NewPersistentVMRepository(dataDir); err != nil {
+		a.logger.Fatal("Failed to create VM repository", zap.Error(err))
+	}
+	if a.repos.image, err = storage.NewPersistentImageRepository(dataDir); err != nil {
+		a.logger.Fatal("Failed to create image repository", zap.Error(err))
+	}
+	if a.repos.backup, err = storage.NewPersistentBackupRepository(dataDir); err != nil {
+		a.logger.Fatal("Failed to create backup repository", zap.Error(err))
+	}
+	if a.repos.securityGroup, err = storage.NewPersistentSecurityGroupRepository(dataDir); err != nil {
+		a.logger.Fatal("Failed to create security group repository", zap.Error(err))
+	}
+	if a.repos.network, err = storage.NewPersistentNetworkRepository(dataDir); err != nil {
+		a.logger.Fatal("Failed to create network repository", zap.Error(err))
+	}
+	if a.repos.portForward, err = storage.NewPersistentPortForwardRepository(dataDir); err != nil {
+		a.logger.Fatal("Failed to create port forward repository", zap.Error(err))
+	}
+	// Named VM sizes, from the configuration and pushed by Ghost Core
+	if a.repos.flavor, err = storage.NewPersistentFlavorRepository(dataDir, flavors(a.cfg.Flavors)); err != nil {
+		a.logger.Fatal("Failed to create flavor repository", zap.Error(err))
+	}
+	a.repos.incomingMigration = storage.NewInMemoryIncomingMigrationRepository()
+}
+
+// setUpNetworking creates the networking of VMs and brings what the
+// repositories hold back into libvirt and the host
+func (a *agent) setUpNetworking() {
+	cfg, logger := a.cfg, a.logger
+
+	conn, err := libvirt.NewConnect(cfg.Libvirt.URI)
+	if err != nil {
+		logger.Fatal("Failed to connect to Libvirt for network", zap.Error(err))
+	}
+	a.net.modes = service.NetworkModes{
+		Default:   entity.NetworkMode(cfg.Libvirt.NetworkMode),
+		Available: []entity.NetworkMode{entity.NetworkModeNAT},
+	}
+	var bridgeAdapter service.NetworkService
+	if cfg.Libvirt.Bridge != "" {
+		if _, err := net.InterfaceByName(cfg.Libvirt.Bridge); err != nil {
+			logger.Fatal("Bridge interface not found", zap.String("bridge", cfg.Libvirt.Bridge), zap.Error(err))
+		}
+		bridgeAdapter = network.NewBridgeAdapter(conn, cfg.Libvirt.Bridge, logger)
+		a.net.modes.Available = append(a.net.modes.Available, entity.NetworkModeBridge)
+	}
+	logger.Info("Network configured",
+		zap.String("default_mode", cfg.Libvirt.NetworkMode),
+		zap.String("nat_network", cfg.Libvirt.Network),
+		zap.String("bridge", cfg.Libvirt.Bridge),
+	)
+
+	// Enable IPv6 on the NAT network; it gets the first /64 of the agent's prefix
+	var natSubnetV6 netip.Prefix
+	if cfg.IPv6.Enabled {
+		a.net.ipv6Prefix, err = network.IPv6Prefix(cfg.IPv6.Prefix, cfg.Agent.Name)
+		if err != nil {
+			logger.Fatal("Invalid IPv6 prefix", zap.Error(err))
+		}
+		subnet, _ := entity.IPv6Subnet(a.net.ipv6Prefix, 0)
+		natSubnetV6, err = network.EnableIPv6(conn, cfg.Libvirt.Network, subnet, logger)
+		if err != nil {
+			logger.Fatal("Failed to enable IPv6", zap.Error(err))
+		}
+		if natSubnetV6.Bits() != 64 {
+			logger.Warn("IPv6 subnet of the NAT network is not a /64, IPAM will not predict SLAAC addresses",
+				zap.String("subnet", natSubnetV6.String()),
+			)
+			natSubnetV6 = netip.Prefix{}
+		}
+		logger.Info("IPv6 configured",
+			zap.String("prefix", a.net.ipv6Prefix.String()),
+			zap.Bool("ula", network.IsULA(a.net.ipv6Prefix)),
+		)
+	}
+
+	// Offer the NAT network's subnets to the tailnet
+	if cfg.Tailscale.AdvertiseRoutes {
+		var routes []string
+		if cfg.IPAM.Subnet != "" {
+			routes = append(routes, cfg.IPAM.Subnet)
+		}
+		if natSubnetV6.IsValid() {
+			routes = append(routes, natSubnetV6.String())
+		}
+		if err := a.tailnet.AdvertiseRoutes(context.Background(), routes); err != nil {
+			logger.Warn("Failed to advertise VM subnets on the tailnet", zap.Error(err))
+		}
+	}
+
+	// Create IPAM for static addresses on the NAT network
+	if cfg.IPAM.Enabled {
+		ipRepo, err := storage.NewPersistentIPAllocationRepository(dataDir)
+		if err != nil {
+			logger.Fatal("Failed to create IP allocation repository", zap.Error(err))
+		}
+		natIPAM, err := network.NewIPAM(
+			conn, cfg.Libvirt.Network, cfg.IPAM.Subnet,
+			cfg.IPAM.RangeStart, cfg.IPAM.RangeEnd, natSubnetV6, ipRepo, logger,
+		)
+		if err != nil {
+			logger.Fatal("Failed to create IPAM", zap.Error(err))
+		}
+		if err := natIPAM.Reconcile(context.Background()); err != nil {
+			logger.Warn("IP allocations need attention", zap.Error(err))
+		}
+		a.net.ipam = natIPAM
+	}
+	a.net.router = network.NewRouter(conn, network.NewNATAdapter(conn, a.net.ipam, logger), bridgeAdapter, logger)
+
+	// Create firewall and re-apply security groups of existing VMs
+	if cfg.Firewall.Enabled {
+		nwfilter, err := network.NewNWFilterFirewall(conn, cfg.Firewall.BlockedCIDRs, cfg.Libvirt.Network, logger)
+		if err != nil {
+			logger.Fatal("Failed to create firewall", zap.Error(err))
+		}
+		if groups, err := a.repos.securityGroup.FindAll(context.Background()); err == nil {
+			for _, sg := range groups {
+				if err := nwfilter.DefineGroup(context.Background(), sg); err != nil {
+					logger.Warn("Failed to define security group", zap.String("id", sg.ID), zap.Error(err))
+				}
+			}
+		}
+		if vms, err := a.repos.vm.FindAll(context.Background()); err == nil {
+			for _, vm := range vms {
+				if err := nwfilter.ApplyVM(context.Background(), vm.ID, vm.SecurityGroups); err != nil {
+					logger.Warn("Failed to apply VM filter", zap.String("vm_id", vm.ID), zap.Error(err))
+				}
+			}
+		}
+		a.net.firewall = nwfilter
+	}
+
+	// Create private network manager and make sure existing networks are
+	// running and open to the guests on them
+	a.net.privateNetworks = network.NewPrivateNetworks(conn, logger)
+	if networks, err := a.repos.network.FindAll(context.Background()); err == nil {
+		for _, n := range networks {
+			if err := a.net.privateNetworks.Define(context.Background(), n); err != nil {
+				logger.Warn("Failed to define private network", zap.String("id", n.ID), zap.Error(err))
+			}
+		}
+		if a.net.firewall != nil {
+			if err := a.net.firewall.SetNetworks(context.Background(), networks); err != nil {
+				logger.Warn("Failed to open VM filters to private networks", zap.Error(err))
+			}
+		}
+	}
+
+	// Create port forwarder
+	a.net.forwarder = newPortForwarder(cfg.PortForward, logger)
+	logger.Info("Port forwarding configured",
+		zap.String("backend", a.net.forwarder.Name()),
+		zap.Int("port_range_start", cfg.PortForward.PortRangeStart),
+		zap.Int("port_range_end", cfg.PortForward.PortRangeEnd),
+	)
+
+	// Open VM filters to their forwards, which the default policy drops
+	if a.net.firewall != nil {
+		if forwards, err := a.repos.portForward.FindAll(context.Background()); err == nil {
+			byVM := make(map[string][]*entity.PortForward)
+			for _, fwd := range forwards {
+				byVM[fwd.VMID] = append(byVM[fwd.VMID], fwd)
+			}
+			for vmID, vmForwards := range byVM {
+				if err := a.net.firewall.AllowForwards(context.Background(), vmID, vmForwards); err != nil {
+					logger.Warn("Failed to allow port forwards", zap.String("vm_id", vmID), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// setUpStorage creates the storage of disks and images and the accounting of
+// host resources
+func (a *agent) setUpStorage() {
+	cfg := a.cfg
+	a.storageAdapter = storage.NewAdapter(cfg.Libvirt.ImageCache, a.repos.image, a.logger)
+
+	// Thin provisioned disks are counted by the space they use
+	var thinDisks *storage.Adapter
+	if cfg.Resources.ThinProvisioning {
+		thinDisks = a.storageAdapter
+	}
+	a.resourceRepo = storage.NewInMemoryResourceRepository(
+		cfg.Resources.ReservedCPU,
+		cfg.Resources.ReservedRAMGB,
+		cfg.Resources.ReservedDiskGB,
+		entity.Overcommit{
+			CPU:  cfg.Resources.Overcommit.CPU,
+			RAM:  cfg.Resources.Overcommit.RAM,
+			Disk: cfg.Resources.Overcommit.Disk,
+		},
+		thinDisks,
+	)
+}
+
+// newAgentServer creates the use cases and the AgentService implementation
+// calling them; the backup scheduler is created but not started
+func (a *agent) newAgentServer() *server.Server {
+	cfg, logger := a.cfg, a.logger
+	repos, nw := &a.repos, &a.net
+
+	backupTarget, err := newBackupTarget(cfg.Backup, logger)
+	if err != nil {
+		logger.Fatal("Failed to create backup target", zap.Error(err))
+	}
+
+	// Default limits of VMs that do not set their own
+	limitDefaults := vmLimits(cfg.Limits)
+
+	// Caps on what the VMs of each tenant may allocate
+	quotas := tenantQuotas(cfg.Quotas)
+	quotaReserver := usecase.NewQuotaReserver(repos.vm, quotas)
+
+	// Rules for the leases of ephemeral VMs
+	expiryPolicy := entity.ExpiryPolicy{
+		DefaultAction: entity.ExpiryAction(cfg.Expiry.DefaultAction),
+		MaxTTL:        cfg.Expiry.MaxTTL,
+		WarnBefore:    cfg.Expiry.WarnBefore,
+	}
+
+	// Reports go to Ghost Core when it is reachable
+	var migrationReporter service.MigrationReporter
+	var expiryReporter service.ExpiryReporter
+	if a.apiClient != nil {
+		migrationReporter = a.apiClient
+		expiryReporter = a.apiClient
+	}
+
+	createVMUC := usecase.NewCreateVMUseCase(
+		a.hypervisor, nw.router, a.storageAdapter,
+		repos.vm, a.resourceRepo, nw.modes, nw.ipam, nw.firewall, limitDefaults,
+		repos.network, nw.privateNetworks, quotaReserver, repos.flavor, expiryPolicy,
+		entity.RestartPolicy(cfg.Restart.DefaultPolicy), logger,
+	)
+	startVMUC := usecase.NewStartVMUseCase(a.hypervisor, repos.vm, logger)
+	stopVMUC := usecase.NewStopVMUseCase(a.hypervisor, repos.vm, logger)
+	getVMStatusUC := usecase.NewGetVMStatusUseCase(
+		a.hypervisor, nw.router, repos.vm, logger,
+	)
+	listVMsUC := usecase.NewListVMsUseCase(a.hypervisor, nw.router, logger)
+	exportVMUC := usecase.NewExportVMUseCase(
+		a.hypervisor, a.storageAdapter, repos.vm, logger,
+	)
+	importVMUC := usecase.NewImportVMUseCase(
+		a.hypervisor, nw.router, a.storageAdapter,
+		repos.vm, a.resourceRepo, nw.modes, nw.ipam, nw.firewall, limitDefaults, quotaReserver, logger,
+	)
+	updateVMLimitsUC := usecase.NewUpdateVMLimitsUseCase(a.hypervisor, repos.vm, repos.flavor, limitDefaults, logger)
+	uploadImageUC := usecase.NewUploadImageUseCase(a.storageAdapter, logger)
+	a.getQuotasUC = usecase.NewGetQuotasUseCase(repos.vm, a.resourceRepo, quotas, logger)
+	listFlavorsUC := usecase.NewListFlavorsUseCase(repos.flavor, a.resourceRepo, logger)
+	setFlavorsUC := usecase.NewSetFlavorsUseCase(repos.flavor, logger)
+	renewVMUC := usecase.NewRenewVMUseCase(repos.vm, expiryPolicy, logger)
+	setRestartPolicyUC := usecase.NewSetRestartPolicyUseCase(a.hypervisor, repos.vm, logger)
+	createBackupUC := usecase.NewCreateBackupUseCase(
+		a.hypervisor, a.storageAdapter, backupTarget,
+		repos.vm, repos.backup,
+		cfg.Backup.KeepDaily, cfg.Backup.KeepWeekly, logger,
+	)
+	listBackupsUC := usecase.NewListBackupsUseCase(repos.backup, logger)
+	restoreBackupUC := usecase.NewRestoreBackupUseCase(
+		a.hypervisor, a.storageAdapter, backupTarget,
+		repos.vm, repos.backup, logger,
+	)
+
+	a.backupScheduler = backup.NewScheduler(
+		func(ctx context.Context, vmID string) error {
+			_, err := createBackupUC.Execute(ctx, &dto.CreateBackupRequest{VMID: vmID})
+			return err
+		},
+		cfg.Backup.Timeout,
+		logger,
+	)
+	setBackupPolicyUC := usecase.NewSetBackupPolicyUseCase(repos.vm, a.backupScheduler, logger)
+	deleteVMUC := usecase.NewDeleteVMUseCase(
+		a.hypervisor, a.storageAdapter,
+		repos.vm, a.resourceRepo, nw.ipam, repos.portForward, nw.forwarder, nw.firewall,
+		a.backupScheduler, repos.backup, backupTarget, logger,
+	)
+
+	attachConsoleUC := usecase.NewAttachConsoleUseCase(a.hypervisor, repos.vm, logger)
+
+	// VNC proxy tokens; nil keeps the proxy disabled
+	var vncProxyURL string
+	if cfg.Console.VNCEnabled {
+		a.consoleTokens = console.NewTokenStore(cfg.Console.TokenTTL)
+		vncProxyURL = cfg.Console.PublicURL
+		if vncProxyURL == "" {
+			_, port, _ := net.SplitHostPort(cfg.Console.ListenAddr)
+			vncProxyURL = fmt.Sprintf("ws://%s/vnc", net.JoinHostPort(a.tailscaleIP, port))
+		}
+	}
+	createVNCTokenUC := usecase.NewCreateVNCTokenUseCase(
+		a.hypervisor, repos.vm, consoleTokenService(a.consoleTokens),
+		vncProxyURL, logger,
+	)
+	guestExecUC := usecase.NewGuestExecUseCase(a.hypervisor, repos.vm, logger)
+
+	addPortForwardUC := usecase.NewAddPortForwardUseCase(
+		repos.vm, nw.router, repos.portForward, nw.forwarder, nw.firewall,
+		cfg.PortForward.PortRangeStart, cfg.PortForward.PortRangeEnd, logger,
+	)
+	removePortForwardUC := usecase.NewRemovePortForwardUseCase(repos.portForward, nw.forwarder, nw.firewall, logger)
+	listPortForwardsUC := usecase.NewListPortForwardsUseCase(repos.portForward, logger)
+	createSecurityGroupUC := usecase.NewCreateSecurityGroupUseCase(repos.securityGroup, nw.firewall, logger)
+	deleteSecurityGroupUC := usecase.NewDeleteSecurityGroupUseCase(repos.securityGroup, repos.vm, nw.firewall, logger)
+	listSecurityGroupsUC := usecase.NewListSecurityGroupsUseCase(repos.securityGroup, repos.vm, logger)
+	updateSecurityGroupRulesUC := usecase.NewUpdateSecurityGroupRulesUseCase(repos.securityGroup, repos.vm, nw.firewall, logger)
+	attachSecurityGroupUC := usecase.NewAttachSecurityGroupUseCase(repos.securityGroup, repos.vm, nw.firewall, logger)
+	createNetworkUC := usecase.NewCreateNetworkUseCase(repos.network, nw.privateNetworks, nw.firewall, nw.ipv6Prefix, logger)
+	deleteNetworkUC := usecase.NewDeleteNetworkUseCase(repos.network, repos.vm, nw.privateNetworks, nw.firewall, logger)
+	listNetworksUC := usecase.NewListNetworksUseCase(repos.network, repos.vm, logger)
+
+	a.syncPortForwardsUC = usecase.NewSyncPortForwardsUseCase(
+		repos.vm, nw.router, repos.portForward, nw.forwarder, logger,
+	)
+	a.restartVMsUC = usecase.NewRestartVMsUseCase(
+		a.hypervisor, repos.vm, a.auditLog,
+		entity.RestartBackoff{Initial: cfg.Restart.Backoff, Max: cfg.Restart.MaxBackoff},
+		logger,
+	)
+	a.expireVMsUC = usecase.NewExpireVMsUseCase(
+		a.hypervisor, repos.vm, deleteVMUC, expiryReporter, a.auditLog, expiryPolicy, logger,
+	)
+
+	migrationURI := cfg.Libvirt.MigrationURI
+	if migrationURI == "" {
+		migrationURI = fmt.Sprintf("qemu+tcp://%s/system", a.tailscaleIP)
+	}
+	prepareMigrationUC := usecase.NewPrepareMigrationUseCase(
+		a.storageAdapter, repos.vm, a.resourceRepo, migrationURI, nw.modes, repos.network, nw.firewall, repos.securityGroup,
+		repos.incomingMigration, quotaReserver, logger,
+	)
+	finishMigrationUC := usecase.NewFinishMigrationUseCase(
+		a.hypervisor, nw.router, a.storageAdapter,
+		repos.vm, a.resourceRepo, nw.firewall, repos.securityGroup, a.backupScheduler, repos.incomingMigration, quotaReserver, logger,
+	)
+	_, grpcPort, _ := net.SplitHostPort(cfg.GRPC.ListenAddr)
+	migrateVMUC := usecase.NewMigrateVMUseCase(
+		a.hypervisor, a.storageAdapter, peer.NewClient(grpcPort, a.peerTLS, logger),
+		a.backupScheduler, repos.vm, a.resourceRepo,
+		migrationReporter, nw.ipam, repos.portForward, nw.forwarder, nw.firewall, repos.securityGroup, a.auditLog, logger,
+	)
+
+	return server.NewServer(
+		createVMUC, deleteVMUC, startVMUC, stopVMUC,
+		getVMStatusUC, listVMsUC,
+		exportVMUC, importVMUC, migrateVMUC, updateVMLimitsUC,
+		attachConsoleUC, createVNCTokenUC, guestExecUC,
+		addPortForwardUC, removePortForwardUC, listPortForwardsUC,
+		createSecurityGroupUC, deleteSecurityGroupUC, listSecurityGroupsUC,
+		updateSecurityGroupRulesUC, attachSecurityGroupUC,
+		createNetworkUC, deleteNetworkUC, listNetworksUC,
+		uploadImageUC,
+		setBackupPolicyUC, createBackupUC, listBackupsUC, restoreBackupUC,
+		prepareMigrationUC, finishMigrationUC,
+		usecase.NewQueryAuditLogUseCase(a.auditLog, logger),
+		usecase.NewGetAgentInfoUseCase(a.resourceRepo, cfg.Agent.Name, Version, logger),
+		a.getQuotasUC,
+		listFlavorsUC, setFlavorsUC,
+		renewVMUC, setRestartPolicyUC,
+		a.metrics, logger,
+	)
+}
+
+// restore re-applies persisted port forwards and backup schedules, then
+// brings VMs back up under their restart policy now that their networks,
+// filters and forwards are in place
+func (a *agent) restore() {
+	if err := a.syncPortForwardsUC.Execute(context.Background()); err != nil {
+		a.logger.Warn("Failed to apply port forwards", zap.Error(err))
+	}
+
+	if vms, err := a.repos.vm.FindAll(context.Background()); err == nil {
+		for _, vm := range vms {
+			if vm.Backup == nil {
+				continue
+			}
+			if err := a.backupScheduler.Schedule(vm.ID, vm.Backup.Schedule); err != nil {
+				a.logger.Warn("Failed to schedule backups",
+					zap.String("vm_id", vm.ID),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+	a.backupScheduler.Start()
+
+	if err := a.restartVMsUC.Restore(context.Background()); err != nil {
+		a.logger.Warn("Failed to restore VMs", zap.Error(err))
+	}
+}
+
+// startLoops starts following VM address changes, VMs that go down and the
+// leases of ephemeral VMs
+func (a *agent) startLoops() {
+	var ctx context.Context
+	ctx, a.loopsCancel = context.WithCancel(context.Background())
+
+	a.runEvery(ctx, a.cfg.PortForward.SyncInterval, a.syncPortForwardsUC.Execute, "Failed to sync port forwards")
+	a.runEvery(ctx, a.cfg.Restart.CheckInterval, a.restartVMsUC.Execute, "Failed to restart VMs")
+	a.runEvery(ctx, a.cfg.Expiry.CheckInterval, a.expireVMsUC.Execute, "Failed to expire VMs")
+}
+
+// runEvery calls fn every interval until ctx is done; shutdown waits for it
+func (a *agent) runEvery(ctx context.Context, interval time.Duration, fn func(context.Context) error, failure string) {
+	a.loops.Add(1)
+	go func() {
+		defer a.loops.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := fn(ctx); err != nil {
+					a.logger.Warn(failure, zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// register registers the agent with Ghost Core once the tailnet is up, and
+// again when its tailnet address changes; heartbeats start after the first
+// registration
+func (a *agent) register() {
+	if a.apiClient == nil {
+		return
+	}
+
+	heartbeatCtx, cancel := context.WithCancel(context.Background())
+	a.heartbeatCancel = cancel
+
+	getResources := func() *entity.Resource {
+		res, _ := a.resourceRepo.GetAvailable(context.Background())
+		return res
+	}
+	go a.apiClient.KeepRegistered(
+		heartbeatCtx, a.tailnet, a.cfg.Agent.HeartbeatInterval, Version, getResources,
+		func() {
+			a.logger.Info("Agent registered with Ghost Core", zap.String("agent_id", a.apiClient.GetAgentID()))
+
+			// Start heartbeat in background
+			go a.apiClient.StartHeartbeat(
+				heartbeatCtx,
+				a.cfg.Agent.HeartbeatInterval,
+				getResources,
+				func() []*entity.VM {
+					vms, _ := a.repos.vm.FindAll(context.Background())
+					return vms
+				},
+				func() []*entity.PortForward {
+					forwards, _ := a.repos.portForward.FindAll(context.Background())
+					return forwards
+				},
+				func() []*entity.TenantQuota {
+					usage, _ := a.getQuotasUC.Usage(context.Background())
+					return usage
+				},
+			)
+		},
+	)
+}
+
+// setUpInterceptors creates the interceptors of the API
+// Audited calls include the time spent waiting for a slot
+func (a *agent) setUpInterceptors() {
+	cfg, logger := a.cfg, a.logger
+
+	if cfg.Auth.Enabled {
+		authorizer, err := newAuthorizer(
+			cfg.Auth, a.repos.vm, a.repos.network, a.repos.portForward, a.repos.backup, a.auditLog, logger,
+		)
+		if err != nil {
+			logger.Fatal("Failed to set up API authentication", zap.Error(err))
+		}
+		a.unaryInterceptors = append(a.unaryInterceptors, authorizer.UnaryInterceptor())
+		a.streamInterceptors = append(a.streamInterceptors, authorizer.StreamInterceptor())
+	} else {
+		logger.Warn("API authentication is disabled, every caller has full access")
+	}
+	if cfg.GRPC.RateLimit.Enabled {
+		rateLimiter, err := newRateLimiter(cfg.GRPC.RateLimit, a.metrics, logger)
+		if err != nil {
+			logger.Fatal("Invalid rate limits", zap.Error(err))
+		}
+		a.unaryInterceptors = append(a.unaryInterceptors, rateLimiter.UnaryInterceptor())
+		a.streamInterceptors = append(a.streamInterceptors, rateLimiter.StreamInterceptor())
+	}
+	auditor := interceptor.NewAuditor(a.auditLog, logger)
+	concurrencyLimiter, err := interceptor.NewConcurrencyLimiter(
+		cfg.GRPC.Concurrency.MaxConcurrent, cfg.GRPC.Concurrency.QueueSize, cfg.GRPC.Concurrency.QueueTimeout,
+		a.metrics, logger,
+	)
+	if err != nil {
+		logger.Fatal("Invalid concurrency limits", zap.Error(err))
+	}
+	a.unaryInterceptors = append(a.unaryInterceptors, auditor.UnaryInterceptor(), concurrencyLimiter.UnaryInterceptor())
+	a.streamInterceptors = append(a.streamInterceptors, auditor.StreamInterceptor(), concurrencyLimiter.StreamInterceptor())
+}
+
+// serveGRPC serves srv and the health of the agent over gRPC, on one
+// goroutine per listener
+func (a *agent) serveGRPC(srv *server.Server) {
+	cfg, logger := a.cfg, a.logger
+
+	logger.Info("Starting gRPC server",
+		zap.String("addr", cfg.GRPC.ListenAddr),
+		zap.String("unix_socket", cfg.GRPC.UnixSocket),
+	)
+	listeners, err := grpcListeners(cfg.GRPC, a.tailnet, logger)
+	if err != nil {
+		logger.Fatal("Failed to listen", zap.Error(err))
+	}
+
+	a.grpcSrv = grpc.NewServer(
+		grpc.Creds(auth.NewServerCredentials(a.serverTLS)),
+		grpc.ChainUnaryInterceptor(a.unaryInterceptors...),
+		grpc.ChainStreamInterceptor(a.streamInterceptors...),
+	)
+	server.RegisterAgentService(a.grpcSrv, srv)
+
+	// Health of the agent and its subsystems; losing Ghost Core leaves VMs and
+	// the local API working, so it does not make the whole agent unhealthy
+	a.healthReporter = health.NewReporter(cfg.Health.CheckInterval, logger)
+	a.healthReporter.AddCheck(health.ServiceLibvirt, a.hypervisor.Ping, true)
+	a.healthReporter.AddCheck(health.ServiceStorage, a.storageAdapter.Ping, true)
+	a.healthReporter.AddCheck(health.ServiceCore, func(ctx context.Context) error {
+		if a.apiClient == nil {
+			return fmt.Errorf("not connected to Ghost Core API")
+		}
+		_, err := a.apiClient.Ping(ctx)
+		return err
+	}, false)
+	healthpb.RegisterHealthServer(a.grpcSrv, a.healthReporter.Server())
+	var healthCtx context.Context
+	healthCtx, a.healthCancel = context.WithCancel(context.Background())
+	go a.healthReporter.Run(healthCtx)
+
+	if cfg.GRPC.Reflection {
+		reflection.Register(a.grpcSrv)
+	}
+
+	for _, lis := range listeners {
+		go func() {
+			if err := a.grpcSrv.Serve(lis); err != nil {
+				logger.Error("gRPC server failed", zap.String("addr", lis.Addr().String()), zap.Error(err))
+			}
+		}()
+	}
+}
+
+// serveHTTP starts the metrics, health check and VNC proxy servers; the JSON
+// gateway to srv shares the health listener
+func (a *agent) serveHTTP(srv *server.Server) {
+	cfg, logger := a.cfg, a.logger
+
+	if cfg.Metrics.Enabled {
+		logger.Info("Starting metrics server", zap.String("addr", cfg.Metrics.ListenAddr))
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle(cfg.Metrics.Path, promhttp.Handler())
+		a.serve(&http.Server{Addr: cfg.Metrics.ListenAddr, Handler: metricsMux}, nil, "Metrics server failed")
+	}
+
+	healthServer := httpserver.NewHealthServer(a.hypervisor, a.tailnet, Version, logger)
+	healthMux := http.NewServeMux()
+	healthMux.HandleFunc(cfg.Health.Path, healthServer.HealthCheck)
+	healthMux.HandleFunc("/ready", healthServer.ReadinessCheck)
+	healthMux.HandleFunc("/live", healthServer.LivenessCheck)
+
+	// The JSON gateway shares the health listener, which then needs TLS unless
+	// it is on the tailnet; the config requires auth and either of them
+	var healthTLS *tls.Config
+	if cfg.Health.Gateway {
+		gateway, err := httpserver.NewGateway(srv, a.unaryInterceptors, a.streamInterceptors, logger)
+		if err != nil {
+			logger.Fatal("Failed to set up the JSON gateway", zap.Error(err))
+		}
+		healthMux.Handle("/v1/", gateway)
+		healthTLS = a.serverTLS
+	}
+	healthLis, err := httpListener(cfg.Health.ListenAddr, healthTLS, a.tailnet, logger)
+	if err != nil {
+		logger.Fatal("Failed to listen for health checks", zap.Error(err))
+	}
+	logger.Info("Starting health check server",
+		zap.String("addr", cfg.Health.ListenAddr),
+		zap.Bool("gateway", cfg.Health.Gateway),
+	)
+	a.serve(&http.Server{Handler: healthMux}, healthLis, "Health server failed")
+
+	if a.consoleTokens != nil {
+		logger.Info("Starting VNC proxy", zap.String("addr", cfg.Console.ListenAddr))
+		vncMux := http.NewServeMux()
+		vncMux.Handle("/vnc", httpserver.NewVNCProxy(a.hypervisor, a.consoleTokens, logger))
+		a.serve(&http.Server{Addr: cfg.Console.ListenAddr, Handler: vncMux}, nil, "VNC proxy failed")
+	}
+}
+
+// serve serves srv on lis, or on its address when lis is nil, until shutdown
+func (a *agent) serve(srv *http.Server, lis net.Listener, failure string) {
+	a.httpServers = append(a.httpServers, srv)
+	go func() {
+		var err error
+		if lis != nil {
+			err = srv.Serve(lis)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			a.logger.Error(failure, zap.Error(err))
+		}
+	}()
+}
+
+// shutdown stops taking calls, then stops background work, and closes the
+// audit log once nothing records to it anymore
+func (a *agent) shutdown(ctx context.Context) {
+	logger := a.logger
+
+	// Stop heartbeat
+	if a.heartbeatCancel != nil {
+		a.heartbeatCancel()
+	}
+
+	// Unregister from Ghost Core
+	if a.apiClient != nil && a.apiClient.GetAgentID() != "" {
+		logger.Info("Unregistering from Ghost Core")
+		if err := a.apiClient.UnregisterAgent(ctx); err != nil {
+			logger.Warn("Failed to unregister agent", zap.Error(err))
+		}
+	}
+
+	// Stop taking calls; open console sessions are cut off
+	logger.Info("Stopping HTTP servers")
+	for _, srv := range a.httpServers {
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Warn("Failed to stop HTTP server gracefully", zap.Error(err))
+			srv.Close()
+		}
+	}
+
+	logger.Info("Stopping gRPC server")
+	a.healthCancel()
+	a.healthReporter.Shutdown()
+	a.grpcSrv.GracefulStop()
+
+	logger.Info("Stopping VM restarts, VM expiry and port forward sync")
+	a.loopsCancel()
+	a.loops.Wait()
+
+	logger.Info("Stopping backup scheduler")
+	a.backupScheduler.Stop(ctx)
+
+	logger.Info("Stopping port forwarding")
+	if err := a.net.forwarder.Close(); err != nil {
+		logger.Error("Failed to stop port forwarding", zap.Error(err))
+	}
+
+	logger.Info("Closing Libvirt connection")
+	if err := a.hypervisor.Close(); err != nil {
+		logger.Error("Failed to close Libvirt connection", zap.Error(err))
+	}
+
+	if a.apiClient != nil {
+		logger.Info("Closing API client connection")
+		if err := a.apiClient.Close(); err != nil {
+			logger.Error("Failed to close API client", zap.Error(err))
+		}
+	}
+	a.tailnetCancel()
+
+	// Everything that records audit events has stopped
+	if err := a.auditLog.Close(); err != nil {
+		logger.Error("Failed to close audit log", zap.Error(err))
+	}
+}
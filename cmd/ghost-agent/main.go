@@ -2,30 +2,29 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
-	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
-	"google.golang.org/grpc"
 
-	"github.com/iammahbubalam/ghost-agent/internal/application/usecase"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
-	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/apiclient"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/auth"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/backup"
 	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/config"
-	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/libvirt"
-	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/network"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/console"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/listener"
 	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/observability"
-	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/storage"
-	"github.com/iammahbubalam/ghost-agent/internal/presentation/grpc/server"
-	httpserver "github.com/iammahbubalam/ghost-agent/internal/presentation/http"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/portforward"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/tailscale"
+	"github.com/iammahbubalam/ghost-agent/internal/presentation/grpc/interceptor"
 )
 
 var (
@@ -65,200 +64,242 @@ func main() {
 		zap.String("build_time", BuildTime),
 	)
 
-	// Setup metrics
-	metrics := observability.NewMetrics()
+	a := &agent{cfg: cfg, logger: logger, metrics: observability.NewMetrics()}
+	a.connect()
+	a.openRepositories()
+	a.setUpNetworking()
+	a.setUpStorage()
+	srv := a.newAgentServer()
+	a.restore()
+	a.startLoops()
+	a.register()
+	a.setUpInterceptors()
+	a.serveGRPC(srv)
+	a.serveHTTP(srv)
 
-	// Get Tailscale IP
-	tailscaleIP := getTailscaleIP(logger)
-	logger.Info("Tailscale IP detected", zap.String("ip", tailscaleIP))
-
-	// Create infrastructure adapters
-	logger.Info("Connecting to Libvirt", zap.String("uri", cfg.Libvirt.URI))
-	hypervisor, err := libvirt.NewAdapter(cfg.Libvirt.URI, logger)
-	if err != nil {
-		logger.Fatal("Failed to create Libvirt adapter", zap.Error(err))
-	}
-	defer hypervisor.Close()
-
-	// Create repositories
-	// Use persistent VM repository so state survives PC restarts
-	vmRepo, err := storage.NewPersistentVMRepository("/var/lib/ghost/data")
-	if err != nil {
-		logger.Fatal("Failed to create VM repository", zap.Error(err))
-	}
-	resourceRepo := storage.NewInMemoryResourceRepository(
-		cfg.Resources.ReservedCPU,
-		cfg.Resources.ReservedRAMGB,
-		cfg.Resources.ReservedDiskGB,
-	)
-	imageRepo := storage.NewInMemoryImageRepository()
+	logger.Info("Ghost Agent started successfully")
 
-	// Create network adapter
-	conn, err := libvirt.NewConnect(cfg.Libvirt.URI)
-	if err != nil {
-		logger.Fatal("Failed to connect to Libvirt for network", zap.Error(err))
-	}
-	networkAdapter := network.NewNATAdapter(conn, logger)
+	// Wait for shutdown signal
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-sigChan
 
-	// Create storage adapter
-	storageAdapter := storage.NewAdapter(cfg.Libvirt.ImageCache, imageRepo, logger)
+	logger.Info("Received shutdown signal", zap.String("signal", sig.String()))
 
-	// Create use cases
-	createVMUC := usecase.NewCreateVMUseCase(
-		hypervisor, networkAdapter, storageAdapter,
-		vmRepo, resourceRepo, logger,
-	)
-	deleteVMUC := usecase.NewDeleteVMUseCase(
-		hypervisor, storageAdapter,
-		vmRepo, resourceRepo, logger,
-	)
-	startVMUC := usecase.NewStartVMUseCase(hypervisor, logger)
-	stopVMUC := usecase.NewStopVMUseCase(hypervisor, logger)
-	getVMStatusUC := usecase.NewGetVMStatusUseCase(
-		hypervisor, networkAdapter, vmRepo, logger,
-	)
-	listVMsUC := usecase.NewListVMsUseCase(hypervisor, networkAdapter, logger)
+	// Graceful shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	a.shutdown(shutdownCtx)
 
-	// Create Ghost Core API client
-	var apiClient *apiclient.Client
-	var heartbeatCancel context.CancelFunc
+	logger.Info("Ghost Agent shutdown complete")
+}
 
-	logger.Info("Connecting to Ghost Core API", zap.String("url", cfg.Agent.APIURL))
-	apiClient, err = apiclient.NewClient(cfg.Agent.APIURL, cfg.Agent.Name, tailscaleIP, logger, metrics)
-	if err != nil {
-		logger.Warn("Failed to connect to Ghost Core API, will retry in heartbeat",
-			zap.Error(err),
-		)
-		// Don't fail startup if API is unavailable
+// grpcListeners opens the listeners the gRPC server is served on: the TCP
+// address, which may follow the tailnet addresses, and the unix socket
+func grpcListeners(cfg config.GRPCConfig, tailnet service.TailnetService, logger *zap.Logger) ([]net.Listener, error) {
+	var listeners []net.Listener
+	if cfg.ListenAddr != "" {
+		host, port, err := net.SplitHostPort(cfg.ListenAddr)
+		if err != nil {
+			return nil, err
+		}
+		if host == listener.TailnetHost {
+			listeners = append(listeners, listener.NewTailnet(tailnet, port, logger))
+		} else {
+			lis, err := net.Listen("tcp", cfg.ListenAddr)
+			if err != nil {
+				return nil, err
+			}
+			listeners = append(listeners, lis)
+		}
 	}
 
-	// Register agent with Ghost Core
-	if apiClient != nil {
-		resources, _ := resourceRepo.GetAvailable(context.Background())
-		agentID, err := apiClient.RegisterAgent(context.Background(), resources, Version)
+	if cfg.UnixSocket != "" {
+		mode, _ := cfg.SocketMode() // Validated when the config was loaded
+		lis, err := listener.Unix(cfg.UnixSocket, mode, cfg.UnixSocketGroup)
 		if err != nil {
-			logger.Warn("Failed to register agent", zap.Error(err))
-		} else {
-			logger.Info("Agent registered with Ghost Core", zap.String("agent_id", agentID))
-
-			// Start heartbeat in background
-			heartbeatCtx, cancel := context.WithCancel(context.Background())
-			heartbeatCancel = cancel
-
-			go apiClient.StartHeartbeat(
-				heartbeatCtx,
-				cfg.Agent.HeartbeatInterval,
-				func() *entity.Resource {
-					res, _ := resourceRepo.GetAvailable(context.Background())
-					return res
-				},
-				func() []*entity.VM {
-					vms, _ := vmRepo.FindAll(context.Background())
-					return vms
-				},
-			)
+			for _, lis := range listeners {
+				lis.Close()
+			}
+			return nil, err
 		}
+		listeners = append(listeners, lis)
 	}
 
-	// Create gRPC server
-	logger.Info("Starting gRPC server", zap.String("addr", cfg.GRPC.ListenAddr))
-	grpcServer := server.NewServer(
-		createVMUC, deleteVMUC, startVMUC, stopVMUC,
-		getVMStatusUC, listVMsUC,
-		metrics, logger,
-	)
+	return listeners, nil
+}
 
-	lis, err := net.Listen("tcp", cfg.GRPC.ListenAddr)
+// httpListener opens the listener of an HTTP server, which follows the tailnet
+// addresses when the host of addr is "tailnet"; other listeners serve TLS
+// unless tlsConfig is nil
+func httpListener(addr string, tlsConfig *tls.Config, tailnet service.TailnetService, logger *zap.Logger) (net.Listener, error) {
+	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
-		logger.Fatal("Failed to listen", zap.Error(err))
+		return nil, err
 	}
-
-	grpcSrv := grpc.NewServer()
-	server.RegisterAgentService(grpcSrv, grpcServer)
-
-	// Start gRPC server in goroutine
-	go func() {
-		if err := grpcSrv.Serve(lis); err != nil {
-			logger.Error("gRPC server failed", zap.Error(err))
-		}
-	}()
-
-	// Start metrics server
-	if cfg.Metrics.Enabled {
-		logger.Info("Starting metrics server", zap.String("addr", cfg.Metrics.ListenAddr))
-		go func() {
-			http.Handle(cfg.Metrics.Path, promhttp.Handler())
-			if err := http.ListenAndServe(cfg.Metrics.ListenAddr, nil); err != nil {
-				logger.Error("Metrics server failed", zap.Error(err))
-			}
-		}()
+	if host == listener.TailnetHost {
+		return listener.NewTailnet(tailnet, port, logger), nil
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
 	}
+	if tlsConfig == nil {
+		return lis, nil
+	}
+	return tls.NewListener(lis, tlsConfig), nil
+}
 
-	// Start health check server
-	logger.Info("Starting health check server", zap.String("addr", cfg.Health.ListenAddr))
-	healthServer := httpserver.NewHealthServer(hypervisor, Version, logger)
-	go func() {
-		http.HandleFunc(cfg.Health.Path, healthServer.HealthCheck)
-		http.HandleFunc("/ready", healthServer.ReadinessCheck)
-		http.HandleFunc("/live", healthServer.LivenessCheck)
-		if err := http.ListenAndServe(cfg.Health.ListenAddr, nil); err != nil {
-			logger.Error("Health server failed", zap.Error(err))
+// newAuthorizer creates the authorizer of the gRPC API from the configuration
+func newAuthorizer(
+	cfg config.AuthConfig,
+	vmRepo repository.VMRepository,
+	networkRepo repository.NetworkRepository,
+	forwardRepo repository.PortForwardRepository,
+	backupRepo repository.BackupRepository,
+	auditLog service.AuditLog,
+	logger *zap.Logger,
+) (*interceptor.Authorizer, error) {
+	var tokens service.TokenVerifier
+	if cfg.JWT.PublicKey != "" {
+		verifier, err := auth.NewJWTVerifier(cfg.JWT.PublicKey, cfg.JWT.Issuer, cfg.JWT.Audience)
+		if err != nil {
+			return nil, err
 		}
-	}()
+		tokens = verifier
+	}
 
-	logger.Info("Ghost Agent started successfully")
+	roles := interceptor.RoleMap{
+		Certs:        make(map[string]entity.Role),
+		LocalUsers:   make(map[string]entity.Role),
+		LocalDefault: entity.Role(cfg.LocalDefaultRole),
+	}
+	for name, role := range cfg.CertRoles {
+		roles.Certs[strings.ToLower(name)] = entity.Role(role)
+	}
+	for name, role := range cfg.LocalRoles {
+		roles.LocalUsers[strings.ToLower(name)] = entity.Role(role)
+	}
 
-	// Wait for shutdown signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
-	sig := <-sigChan
+	return interceptor.NewAuthorizer(tokens, roles, vmRepo, networkRepo, forwardRepo, backupRepo, auditLog, logger), nil
+}
 
-	logger.Info("Received shutdown signal", zap.String("signal", sig.String()))
+// newRateLimiter creates the rate limiter of the gRPC API from the configuration
+func newRateLimiter(cfg config.RateLimitConfig, metrics *observability.Metrics, logger *zap.Logger) (*interceptor.RateLimiter, error) {
+	perMethod := make(map[string]interceptor.Rate, len(cfg.PerMethod))
+	for name, r := range cfg.PerMethod {
+		perMethod[name] = interceptor.Rate{Limit: r.Rate, Burst: r.Burst}
+	}
+	return interceptor.NewRateLimiter(
+		interceptor.Rate{Limit: cfg.PerClient.Rate, Burst: cfg.PerClient.Burst},
+		perMethod, cfg.ClientIdleTimeout, metrics, logger,
+	)
+}
 
-	// Graceful shutdown
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// newPortForwarder creates the port forwarder selected in the configuration
+// The nftables backend falls back to the userspace proxy when nft is unavailable
+func newPortForwarder(cfg config.PortForwardConfig, logger *zap.Logger) service.PortForwarder {
+	if cfg.Backend == "nftables" {
+		forwarder, err := portforward.NewNFTablesForwarder(logger)
+		if err == nil {
+			return forwarder
+		}
+		logger.Warn("nftables unavailable, falling back to userspace proxy", zap.Error(err))
+	}
+	return portforward.NewProxyForwarder(cfg.ListenAddr, logger)
+}
 
-	// Stop heartbeat
-	if heartbeatCancel != nil {
-		heartbeatCancel()
+// vmLimits converts the configured default limits to their domain form
+func vmLimits(cfg config.LimitsConfig) entity.VMLimits {
+	return entity.VMLimits{
+		Inbound: entity.BandwidthLimit{
+			Average: cfg.Inbound.AverageKBps,
+			Peak:    cfg.Inbound.PeakKBps,
+			Burst:   cfg.Inbound.BurstKB,
+		},
+		Outbound: entity.BandwidthLimit{
+			Average: cfg.Outbound.AverageKBps,
+			Peak:    cfg.Outbound.PeakKBps,
+			Burst:   cfg.Outbound.BurstKB,
+		},
+		DiskIOPS:        cfg.DiskIOPS,
+		DiskBytesPerSec: cfg.DiskBytesPerSec,
 	}
+}
 
-	// Unregister from Ghost Core
-	if apiClient != nil {
-		logger.Info("Unregistering from Ghost Core")
-		if err := apiClient.UnregisterAgent(shutdownCtx); err != nil {
-			logger.Warn("Failed to unregister agent", zap.Error(err))
-		}
+// tenantQuotas converts the quota configuration
+func tenantQuotas(cfg config.QuotasConfig) entity.Quotas {
+	toQuota := func(q config.QuotaConfig) entity.Quota {
+		return entity.Quota{MaxVMs: q.MaxVMs, VCPU: q.VCPU, RAMGB: q.RAMGB, DiskGB: q.DiskGB}
 	}
 
-	logger.Info("Stopping gRPC server")
-	grpcSrv.GracefulStop()
+	quotas := entity.Quotas{
+		Default: toQuota(cfg.Default),
+		Tenants: make(map[string]entity.Quota, len(cfg.Tenants)),
+	}
+	for tenant, q := range cfg.Tenants {
+		quotas.Tenants[tenant] = toQuota(q)
+	}
+	return quotas
+}
 
-	logger.Info("Closing Libvirt connection")
-	if err := hypervisor.Close(); err != nil {
-		logger.Error("Failed to close Libvirt connection", zap.Error(err))
+// flavors converts the configured flavors
+func flavors(cfg map[string]config.FlavorConfig) []*entity.Flavor {
+	flavors := make([]*entity.Flavor, 0, len(cfg))
+	for name, f := range cfg {
+		flavors = append(flavors, &entity.Flavor{
+			Name:      name,
+			VCPU:      f.VCPU,
+			RAMGB:     f.RAMGB,
+			DiskGB:    f.DiskGB,
+			CPUMode:   entity.CPUMode(f.CPUMode),
+			Limits:    vmLimits(f.Limits),
+			Templates: f.Templates,
+			Source:    entity.FlavorSourceConfig,
+		})
 	}
+	return flavors
+}
 
-	if apiClient != nil {
-		logger.Info("Closing API client connection")
-		if err := apiClient.Close(); err != nil {
-			logger.Error("Failed to close API client", zap.Error(err))
-		}
+// newBackupTarget creates the backup target selected in the configuration
+func newBackupTarget(cfg config.BackupConfig, logger *zap.Logger) (service.BackupTarget, error) {
+	if cfg.Target == "s3" {
+		return backup.NewS3Target(
+			cfg.S3.Endpoint, cfg.S3.Region, cfg.S3.Bucket, cfg.S3.Prefix,
+			cfg.S3.AccessKey, cfg.S3.SecretKey, cfg.S3.UseSSL, logger,
+		)
 	}
+	return backup.NewLocalTarget(cfg.LocalDir, logger)
+}
 
-	logger.Info("Ghost Agent shutdown complete")
+// consoleTokenService avoids passing a typed nil when the VNC proxy is disabled
+func consoleTokenService(tokens *console.TokenStore) service.ConsoleTokenService {
+	if tokens == nil {
+		return nil
+	}
+	return tokens
 }
 
-// getTailscaleIP retrieves the Tailscale IP address
-func getTailscaleIP(logger *zap.Logger) string {
-	cmd := exec.Command("tailscale", "ip", "-4")
-	output, err := cmd.Output()
-	if err != nil {
-		logger.Warn("Failed to get Tailscale IP, using fallback", zap.Error(err))
-		return "unknown"
+// waitForTailnet waits up to timeout for the tailnet and returns the host's tailnet
+// address, which the default migration URI and VNC proxy URL are built from
+// When the tailnet is not up in time, the host name stands in for it
+func waitForTailnet(ctx context.Context, tailnet *tailscale.Monitor, timeout time.Duration, logger *zap.Logger) string {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	status, err := tailnet.WaitUp(ctx)
+	if err == nil {
+		logger.Info("Tailscale IP detected",
+			zap.String("ip", status.IP()),
+			zap.String("dns_name", status.DNSName),
+		)
+		return status.IP()
 	}
 
-	ip := strings.TrimSpace(string(output))
-	return ip
+	hostname, _ := os.Hostname()
+	logger.Warn("Tailnet is not up, registration waits for it; using the host name for migration and console URLs",
+		zap.String("hostname", hostname),
+		zap.Error(err),
+	)
+	return hostname
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// formatError renders err for humans, spelling out the details the agent
+// attached to a gRPC status: why the call failed, which fields or quotas were
+// at fault, and when to retry
+func formatError(err error) string {
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return "Error: " + err.Error()
+	}
+	st := grpcErr.GRPCStatus()
+
+	// Replace "rpc error: code = ... desc = ..." with the plain message
+	var b strings.Builder
+	fmt.Fprintf(&b, "Error: %s\n", strings.Replace(err.Error(), st.Err().Error(), st.Message(), 1))
+	fmt.Fprintf(&b, "  Code:        %s", st.Code())
+
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			fmt.Fprintf(&b, " (%s)", d.Reason)
+			keys := make([]string, 0, len(d.Metadata))
+			for key := range d.Metadata {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if len(keys) > 0 {
+				fmt.Fprintf(&b, "\n  Context:")
+			}
+			for _, key := range keys {
+				fmt.Fprintf(&b, "\n    %s = %s", key, d.Metadata[key])
+			}
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				fmt.Fprintf(&b, "\n  Invalid:     %s: %s", v.Field, v.Description)
+			}
+		case *errdetails.QuotaFailure:
+			for _, v := range d.Violations {
+				if v.Subject != "" {
+					fmt.Fprintf(&b, "\n  Exceeded:    %s: %s", v.Subject, v.Description)
+				} else {
+					fmt.Fprintf(&b, "\n  Exceeded:    %s", v.Description)
+				}
+			}
+		case *errdetails.RetryInfo:
+			fmt.Fprintf(&b, "\n  Retry after: %s", d.RetryDelay.AsDuration())
+		}
+	}
+	return b.String()
+}
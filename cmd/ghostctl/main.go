@@ -1,14 +1,26 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 
 	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
 )
@@ -16,6 +28,10 @@ import (
 var (
 	agentAddr string
 	timeout   time.Duration
+	token     string
+	tlsCA     string
+	tlsCert   string
+	tlsKey    string
 )
 
 func main() {
@@ -26,16 +42,31 @@ func main() {
 	}
 
 	// Global flags
-	rootCmd.PersistentFlags().StringVar(&agentAddr, "agent", "localhost:9090", "Ghost Agent gRPC address")
+	rootCmd.PersistentFlags().StringVar(&agentAddr, "agent", "unix:///run/ghost/agent.sock", "Ghost Agent gRPC address (host:port or unix:///path)")
 	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Second, "Request timeout")
+	rootCmd.PersistentFlags().StringVar(&token, "token", os.Getenv("GHOST_TOKEN"), "Bearer token issued by Ghost Core (defaults to $GHOST_TOKEN)")
+	rootCmd.PersistentFlags().StringVar(&tlsCA, "tls-ca", "", "CA certificate of the agent; enables TLS")
+	rootCmd.PersistentFlags().StringVar(&tlsCert, "tls-cert", "", "Client certificate to authenticate with over TLS")
+	rootCmd.PersistentFlags().StringVar(&tlsKey, "tls-key", "", "Key of the client certificate")
 
 	// Add commands
 	rootCmd.AddCommand(vmCmd())
+	rootCmd.AddCommand(imageCmd())
+	rootCmd.AddCommand(backupCmd())
+	rootCmd.AddCommand(portForwardCmd())
+	rootCmd.AddCommand(securityGroupCmd())
+	rootCmd.AddCommand(networkCmd())
+	rootCmd.AddCommand(auditCmd())
+	rootCmd.AddCommand(quotaCmd())
+	rootCmd.AddCommand(flavorCmd())
 	rootCmd.AddCommand(statusCmd())
 	rootCmd.AddCommand(versionCmd())
 
+	// Errors are printed below, with any details the agent attached
+	rootCmd.SilenceErrors = true
+
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, formatError(err))
 		os.Exit(1)
 	}
 }
@@ -53,6 +84,15 @@ func vmCmd() *cobra.Command {
 	cmd.AddCommand(vmStartCmd())
 	cmd.AddCommand(vmStopCmd())
 	cmd.AddCommand(vmStatusCmd())
+	cmd.AddCommand(vmExportCmd())
+	cmd.AddCommand(vmImportCmd())
+	cmd.AddCommand(vmMigrateCmd())
+	cmd.AddCommand(vmConsoleCmd())
+	cmd.AddCommand(vmVNCCmd())
+	cmd.AddCommand(vmExecCmd())
+	cmd.AddCommand(vmLimitsCmd())
+	cmd.AddCommand(vmRenewCmd())
+	cmd.AddCommand(vmRestartPolicyCmd())
 
 	return cmd
 }
@@ -98,10 +138,17 @@ func vmListCmd() *cobra.Command {
 func vmCreateCmd() *cobra.Command {
 	var (
 		name     string
+		flavor   string
 		vcpu     int32
 		ramGB    int32
 		diskGB   int32
 		template string
+		network  string
+		nics     []string
+		limits   limitFlags
+		expiry   expiryFlags
+		restart  string
+		retries  int32
 	)
 
 	cmd := &cobra.Command{
@@ -118,12 +165,28 @@ func vmCreateCmd() *cobra.Command {
 			defer cancel()
 
 			req := &agentpb.CreateVMRequest{
-				Name:     name,
-				Vcpu:     vcpu,
-				RamGb:    ramGB,
-				DiskGb:   diskGB,
-				Template: template,
+				Name:        name,
+				Vcpu:        vcpu,
+				RamGb:       ramGB,
+				DiskGb:      diskGB,
+				Template:    template,
+				NetworkMode: network,
+				Networks:    nics,
+			}
+			req.RestartPolicy, req.RestartMaxRetries = restart, retries
+			if flavor != "" {
+				// The flavor sizes the VM, so the size defaults are not sent
+				req.Flavor = flavor
+				req.Vcpu, req.RamGb, req.DiskGb = 0, 0, 0
+			}
+			vmLimits := &agentpb.VMLimits{}
+			if limits.apply(cmd, vmLimits) {
+				req.Limits = vmLimits
+			}
+			if req.TtlSeconds, req.ExpiresAt, err = expiry.values(); err != nil {
+				return err
 			}
+			req.ExpiryAction = expiry.action
 
 			fmt.Printf("Creating VM '%s'...\n", name)
 			resp, err := client.CreateVM(ctx, req)
@@ -134,6 +197,7 @@ func vmCreateCmd() *cobra.Command {
 			fmt.Printf("✅ VM created successfully!\n")
 			fmt.Printf("  VM ID: %s\n", resp.VmId)
 			fmt.Printf("  IP Address: %s\n", resp.IpAddress)
+			printInterfaces(resp.Interfaces)
 			fmt.Printf("  Status: %s\n", resp.Status)
 
 			return nil
@@ -141,11 +205,21 @@ func vmCreateCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&name, "name", "", "VM name (required)")
+	cmd.Flags().StringVar(&flavor, "flavor", "", "Size the VM by a flavor instead of --vcpu, --ram and --disk")
 	cmd.Flags().Int32Var(&vcpu, "vcpu", 2, "Number of vCPUs")
 	cmd.Flags().Int32Var(&ramGB, "ram", 4, "RAM in GB")
 	cmd.Flags().Int32Var(&diskGB, "disk", 50, "Disk size in GB")
 	cmd.Flags().StringVar(&template, "template", "ubuntu-22.04", "OS template (ubuntu-22.04, ubuntu-20.04, debian-12, debian-11)")
+	cmd.Flags().StringVar(&network, "network", "", "Network mode: nat or bridge (defaults to the agent's mode)")
+	cmd.Flags().StringArrayVar(&nics, "nic", nil, "Add a NIC on a private network (ID or name); repeatable")
+	limits.register(cmd)
+	expiry.register(cmd)
+	cmd.Flags().StringVar(&restart, "restart", "", "Restart policy: never, always, unless-stopped or on-failure (defaults to the agent's)")
+	cmd.Flags().Int32Var(&retries, "restart-max-retries", 0, "With --restart on-failure, restarts before giving up (0 for no cap)")
 	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagsMutuallyExclusive("flavor", "vcpu")
+	cmd.MarkFlagsMutuallyExclusive("flavor", "ram")
+	cmd.MarkFlagsMutuallyExclusive("flavor", "disk")
 
 	return cmd
 }
@@ -279,64 +353,1964 @@ func vmStatusCmd() *cobra.Command {
 			fmt.Printf("  ID: %s\n", resp.VmId)
 			fmt.Printf("  Name: %s\n", resp.Name)
 			fmt.Printf("  Status: %s\n", resp.Status)
+			if resp.Flavor != "" {
+				fmt.Printf("  Flavor: %s\n", resp.Flavor)
+			}
 			fmt.Printf("  vCPU: %d\n", resp.Vcpu)
 			fmt.Printf("  RAM: %d GB\n", resp.RamGb)
 			fmt.Printf("  Disk: %d GB\n", resp.DiskGb)
 			fmt.Printf("  IP Address: %s\n", resp.IpAddress)
+			fmt.Printf("  Network: %s\n", resp.NetworkMode)
+			if resp.ExpiresAt != 0 {
+				fmt.Printf("  Expires: %s (then %s)\n", time.Unix(resp.ExpiresAt, 0).Format(time.RFC3339), resp.ExpiryAction)
+			}
+			if resp.Restarts > 0 {
+				fmt.Printf("  Restart Policy: %s (%d restarts)\n", resp.RestartPolicy, resp.Restarts)
+			} else {
+				fmt.Printf("  Restart Policy: %s\n", resp.RestartPolicy)
+			}
+			printInterfaces(resp.Interfaces)
+			if len(resp.SecurityGroups) > 0 {
+				fmt.Printf("  Security Groups: %s\n", strings.Join(resp.SecurityGroups, ", "))
+			}
+			if len(resp.Networks) > 0 {
+				fmt.Printf("  Private Networks: %s\n", strings.Join(resp.Networks, ", "))
+			}
+			printLimits(resp.Limits)
 			fmt.Printf("  Uptime: %d seconds\n", resp.UptimeSeconds)
 			fmt.Printf("  CPU Usage: %.2f%%\n", resp.CpuUsagePercent)
 			fmt.Printf("  RAM Usage: %.2f%%\n", resp.RamUsagePercent)
 
+			if g := resp.Guest; g != nil {
+				fmt.Printf("Guest:\n")
+				fmt.Printf("  OS: %s %s\n", g.OsName, g.OsVersion)
+				fmt.Printf("  Kernel: %s\n", g.KernelRelease)
+				fmt.Printf("  Hostname: %s\n", g.Hostname)
+				for _, fs := range g.Filesystems {
+					fmt.Printf("  Filesystem %s (%s): %.1f / %.1f GB used\n",
+						fs.MountPoint, fs.FsType,
+						float64(fs.UsedBytes)/(1<<30), float64(fs.TotalBytes)/(1<<30))
+				}
+				for _, iface := range g.Interfaces {
+					fmt.Printf("  Interface %s (%s): %s\n",
+						iface.Name, iface.HardwareAddr, strings.Join(iface.Addresses, ", "))
+				}
+			}
+
 			return nil
 		},
 	}
 }
 
-// statusCmd shows agent status
-func statusCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "status",
-		Short: "Show Ghost Agent status",
+// vmLimitsCmd shows or changes the network and disk limits of a VM
+func vmLimitsCmd() *cobra.Command {
+	var (
+		limits limitFlags
+		reset  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "limits <vm-id>",
+		Short: "Show or change the network and disk limits of a VM",
+		Long: `Show or change the network and disk limits of a VM.
+
+Only the given limits change; 0 removes a limit. Running VMs are
+updated live. --reset returns all limits to the agent defaults.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Try to connect
-			_, conn, err := connectToAgent()
+			client, conn, err := connectToAgent()
 			if err != nil {
-				fmt.Printf("❌ Ghost Agent is not running or not reachable\n")
-				fmt.Printf("   Error: %v\n", err)
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			vmID := args[0]
+			current, err := client.GetVMStatus(ctx, &agentpb.GetVMStatusRequest{VmId: vmID})
+			if err != nil {
+				return fmt.Errorf("failed to get VM status: %w", err)
+			}
+
+			vmLimits := current.Limits
+			if reset || vmLimits == nil {
+				vmLimits = &agentpb.VMLimits{}
+			}
+			if !limits.apply(cmd, vmLimits) && !reset {
+				fmt.Printf("Limits of %s:\n", vmID)
+				printLimits(vmLimits)
 				return nil
 			}
+
+			resp, err := client.UpdateVMLimits(ctx, &agentpb.UpdateVMLimitsRequest{
+				VmId:   vmID,
+				Limits: vmLimits,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to update VM limits: %w", err)
+			}
+
+			fmt.Printf("✅ Limits of %s updated:\n", resp.VmId)
+			printLimits(resp.Limits)
+			return nil
+		},
+	}
+
+	limits.register(cmd)
+	cmd.Flags().BoolVar(&reset, "reset", false, "Return all limits to the agent defaults before applying the given ones")
+	return cmd
+}
+
+// vmRenewCmd extends or removes the lease of a VM
+func vmRenewCmd() *cobra.Command {
+	var (
+		expiry     expiryFlags
+		clearLease bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "renew <vm-id>",
+		Short: "Extend or remove the lease of an ephemeral VM",
+		Long: `Extend or remove the lease of an ephemeral VM.
+
+--ttl counts from now. A VM stopped because its lease ran out can be
+started again once renewed; --clear makes it permanent.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
 			defer conn.Close()
 
-			fmt.Printf("✅ Ghost Agent is running\n")
-			fmt.Printf("   Address: %s\n", agentAddr)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			req := &agentpb.RenewVMRequest{
+				VmId:   args[0],
+				Action: expiry.action,
+				Clear:  clearLease,
+			}
+			if req.TtlSeconds, req.ExpiresAt, err = expiry.values(); err != nil {
+				return err
+			}
+
+			resp, err := client.RenewVM(ctx, req)
+			if err != nil {
+				return fmt.Errorf("failed to renew VM: %w", err)
+			}
 
+			if resp.ExpiresAt == 0 {
+				fmt.Printf("✅ %s no longer expires\n", resp.VmId)
+				return nil
+			}
+			fmt.Printf("✅ %s expires at %s, then %s\n",
+				resp.VmId, time.Unix(resp.ExpiresAt, 0).Format(time.RFC3339), resp.ExpiryAction)
 			return nil
 		},
 	}
+
+	expiry.register(cmd)
+	cmd.Flags().BoolVar(&clearLease, "clear", false, "Remove the lease so the VM no longer expires")
+	cmd.MarkFlagsMutuallyExclusive("clear", "ttl")
+	cmd.MarkFlagsMutuallyExclusive("clear", "expires-at")
+	cmd.MarkFlagsOneRequired("clear", "ttl", "expires-at")
+	return cmd
 }
 
-// versionCmd shows version
-func versionCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "version",
-		Short: "Show ghostctl version",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("ghostctl version 1.0.0")
+// vmRestartPolicyCmd changes when the agent brings a VM back up
+func vmRestartPolicyCmd() *cobra.Command {
+	var maxRetries int32
+
+	cmd := &cobra.Command{
+		Use:   "restart-policy <vm-id> <never|always|unless-stopped|on-failure>",
+		Short: "Set when the agent brings a VM back up",
+		Long: `Set when the agent brings a VM back up.
+
+always and unless-stopped VMs are started when the agent starts and
+whenever they go down; unless-stopped ones stay down once stopped with
+"ghostctl vm stop". on-failure VMs are only restarted after a crash.
+Restarts back off from VMs that keep going down.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.SetRestartPolicy(ctx, &agentpb.SetRestartPolicyRequest{
+				VmId:       args[0],
+				Policy:     args[1],
+				MaxRetries: maxRetries,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to set restart policy: %w", err)
+			}
+
+			fmt.Printf("✅ %s restart policy: %s\n", resp.VmId, resp.Policy)
+			if resp.MaxRetries > 0 {
+				fmt.Printf("  Max Retries: %d\n", resp.MaxRetries)
+			}
+			fmt.Printf("  Autostart: %t\n", resp.Autostart)
+			return nil
 		},
 	}
+
+	cmd.Flags().Int32Var(&maxRetries, "max-retries", 0, "With on-failure, restarts before giving up (0 for no cap)")
+	return cmd
 }
 
-// connectToAgent creates a gRPC connection to Ghost Agent
-func connectToAgent() (agentpb.AgentServiceClient, *grpc.ClientConn, error) {
-	conn, err := grpc.Dial(agentAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-		grpc.WithTimeout(5*time.Second),
-	)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to connect to Ghost Agent at %s: %w", agentAddr, err)
+// expiryFlags holds the VM lease flags shared by vm create and vm renew
+type expiryFlags struct {
+	ttl       time.Duration
+	expiresAt string
+	action    string
+}
+
+func (f *expiryFlags) register(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&f.ttl, "ttl", 0, "Lease length from now, e.g. 8h; the VM expires afterwards")
+	cmd.Flags().StringVar(&f.expiresAt, "expires-at", "", "RFC 3339 time the VM expires at")
+	cmd.Flags().StringVar(&f.action, "expiry-action", "", "What happens when the lease runs out: stop or delete (defaults to the agent's)")
+	cmd.MarkFlagsMutuallyExclusive("ttl", "expires-at")
+}
+
+// values returns the lease given on the command line, zero when none was
+func (f *expiryFlags) values() (ttlSeconds, expiresAt int64, err error) {
+	if f.expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, f.expiresAt)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid --expires-at: want an RFC 3339 time")
+		}
+		expiresAt = t.Unix()
 	}
+	return int64(f.ttl / time.Second), expiresAt, nil
+}
 
-	client := agentpb.NewAgentServiceClient(conn)
-	return client, conn, nil
+// limitFlags holds the VM limit flags shared by vm create and vm limits
+type limitFlags struct {
+	inAverage, inPeak, inBurst    int32
+	outAverage, outPeak, outBurst int32
+	diskIOPS, diskBytesSec        int64
+}
+
+func (f *limitFlags) register(cmd *cobra.Command) {
+	cmd.Flags().Int32Var(&f.inAverage, "in-average", 0, "Inbound average rate in KiB/s")
+	cmd.Flags().Int32Var(&f.inPeak, "in-peak", 0, "Inbound peak rate in KiB/s")
+	cmd.Flags().Int32Var(&f.inBurst, "in-burst", 0, "Inbound burst size in KiB")
+	cmd.Flags().Int32Var(&f.outAverage, "out-average", 0, "Outbound average rate in KiB/s")
+	cmd.Flags().Int32Var(&f.outPeak, "out-peak", 0, "Outbound peak rate in KiB/s")
+	cmd.Flags().Int32Var(&f.outBurst, "out-burst", 0, "Outbound burst size in KiB")
+	cmd.Flags().Int64Var(&f.diskIOPS, "disk-iops", 0, "Disk read and write operations per second")
+	cmd.Flags().Int64Var(&f.diskBytesSec, "disk-bps", 0, "Disk read and write throughput in bytes per second")
+}
+
+// apply copies the limits given on the command line to l
+// It returns false when no limit flag was given
+func (f *limitFlags) apply(cmd *cobra.Command, l *agentpb.VMLimits) bool {
+	if l.Inbound == nil {
+		l.Inbound = &agentpb.BandwidthLimit{}
+	}
+	if l.Outbound == nil {
+		l.Outbound = &agentpb.BandwidthLimit{}
+	}
+
+	changed := false
+	set32 := func(flag string, dst *int32, v int32) {
+		if cmd.Flags().Changed(flag) {
+			*dst = v
+			changed = true
+		}
+	}
+	set64 := func(flag string, dst *int64, v int64) {
+		if cmd.Flags().Changed(flag) {
+			*dst = v
+			changed = true
+		}
+	}
+
+	set32("in-average", &l.Inbound.AverageKbytesSec, f.inAverage)
+	set32("in-peak", &l.Inbound.PeakKbytesSec, f.inPeak)
+	set32("in-burst", &l.Inbound.BurstKbytes, f.inBurst)
+	set32("out-average", &l.Outbound.AverageKbytesSec, f.outAverage)
+	set32("out-peak", &l.Outbound.PeakKbytesSec, f.outPeak)
+	set32("out-burst", &l.Outbound.BurstKbytes, f.outBurst)
+	set64("disk-iops", &l.DiskIops, f.diskIOPS)
+	set64("disk-bps", &l.DiskBytesSec, f.diskBytesSec)
+
+	return changed
+}
+
+// printLimits prints the network and disk limits of a VM
+func printLimits(l *agentpb.VMLimits) {
+	fmt.Printf("  Inbound: %s\n", formatBandwidth(l.GetInbound()))
+	fmt.Printf("  Outbound: %s\n", formatBandwidth(l.GetOutbound()))
+	fmt.Printf("  Disk IOPS: %s\n", formatLimit(l.GetDiskIops(), ""))
+	fmt.Printf("  Disk Throughput: %s\n", formatLimit(l.GetDiskBytesSec(), " bytes/s"))
+}
+
+// printInterfaces prints the addresses of each guest interface
+func printInterfaces(ifaces []*agentpb.VMInterface) {
+	for _, iface := range ifaces {
+		fmt.Printf("  Interface %s (%s):\n", iface.Name, iface.MacAddress)
+		for _, addr := range iface.Addresses {
+			fmt.Printf("    %s/%d (%s)\n", addr.Address, addr.Prefix, addr.Family)
+		}
+	}
+}
+
+// formatBandwidth renders a bandwidth limit in one direction
+func formatBandwidth(b *agentpb.BandwidthLimit) string {
+	if b.GetAverageKbytesSec() == 0 {
+		return "unlimited"
+	}
+	out := fmt.Sprintf("%d KiB/s", b.AverageKbytesSec)
+	if b.PeakKbytesSec > 0 {
+		out += fmt.Sprintf(", peak %d KiB/s", b.PeakKbytesSec)
+	}
+	if b.BurstKbytes > 0 {
+		out += fmt.Sprintf(", burst %d KiB", b.BurstKbytes)
+	}
+	return out
+}
+
+func formatLimit(v int64, unit string) string {
+	if v == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d%s", v, unit)
+}
+
+// vmExportCmd exports a stopped VM to a portable archive
+func vmExportCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "export <vm-id>",
+		Short: "Export a stopped VM to a tar archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vmID := args[0]
+			if output == "" {
+				output = vmID + ".tar"
+			}
+
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+			defer cancel()
+
+			stream, err := client.ExportVM(ctx, &agentpb.ExportVMRequest{VmId: vmID})
+			if err != nil {
+				return fmt.Errorf("failed to export VM: %w", err)
+			}
+
+			file, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create archive: %w", err)
+			}
+			defer file.Close()
+
+			fmt.Printf("Exporting VM '%s' to '%s'...\n", vmID, output)
+			var received int64
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					fmt.Println()
+					_ = os.Remove(output)
+					return fmt.Errorf("failed to export VM: %w", err)
+				}
+				if _, err := file.Write(resp.Chunk); err != nil {
+					return fmt.Errorf("failed to write archive: %w", err)
+				}
+				received += int64(len(resp.Chunk))
+				printProgress(received, 0)
+			}
+			fmt.Println()
+
+			fmt.Printf("✅ VM exported successfully: %s\n", output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Archive path (defaults to <vm-id>.tar)")
+	return cmd
+}
+
+// vmImportCmd recreates a VM from an exported archive
+func vmImportCmd() *cobra.Command {
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Import a VM from a tar archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open archive: %w", err)
+			}
+			defer file.Close()
+
+			info, err := file.Stat()
+			if err != nil {
+				return fmt.Errorf("failed to stat archive: %w", err)
+			}
+
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+			defer cancel()
+
+			stream, err := client.ImportVM(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to start import: %w", err)
+			}
+
+			err = stream.Send(&agentpb.ImportVMRequest{
+				Payload: &agentpb.ImportVMRequest_Options{
+					Options: &agentpb.ImportVMOptions{Name: name},
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to send import options: %w", err)
+			}
+
+			fmt.Printf("Importing VM from '%s'...\n", args[0])
+			err = sendChunks(file, info.Size(), func(chunk []byte) error {
+				return stream.Send(&agentpb.ImportVMRequest{
+					Payload: &agentpb.ImportVMRequest_Chunk{Chunk: chunk},
+				})
+			})
+			if err != nil {
+				return err
+			}
+
+			resp, err := stream.CloseAndRecv()
+			if err != nil {
+				return fmt.Errorf("failed to import VM: %w", err)
+			}
+
+			fmt.Printf("✅ VM imported successfully!\n")
+			fmt.Printf("  VM ID: %s\n", resp.VmId)
+			fmt.Printf("  IP Address: %s\n", resp.IpAddress)
+			fmt.Printf("  Status: %s\n", resp.Status)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "New VM name (defaults to the archived name)")
+	return cmd
+}
+
+// vmMigrateCmd live-migrates a running VM to another agent
+func vmMigrateCmd() *cobra.Command {
+	var target string
+
+	cmd := &cobra.Command{
+		Use:   "migrate <vm-id>",
+		Short: "Live-migrate a running VM to another agent",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vmID := args[0]
+
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 12*time.Hour)
+			defer cancel()
+
+			stream, err := client.MigrateVM(ctx, &agentpb.MigrateVMRequest{
+				VmId:          vmID,
+				TargetAddress: target,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to migrate VM: %w", err)
+			}
+
+			fmt.Printf("Migrating VM '%s' to %s...\n", vmID, target)
+			var last *agentpb.MigrateVMProgress
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					fmt.Println()
+					return fmt.Errorf("failed to migrate VM: %w", err)
+				}
+				last = resp
+				if resp.Phase == "transferring" {
+					printProgress(int64(resp.DataProcessed), int64(resp.DataTotal))
+				}
+			}
+			fmt.Println()
+
+			if last == nil || last.Phase != "completed" {
+				return fmt.Errorf("migration ended without completing")
+			}
+
+			fmt.Printf("✅ VM migrated to %s\n", last.TargetAddress)
+			if last.IpAddress != "" {
+				fmt.Printf("  IP Address: %s\n", last.IpAddress)
+			}
+			for _, fwd := range last.RemovedPortForwards {
+				fmt.Printf("  Removed port forward: %s %d -> %d\n", fwd.Protocol, fwd.HostPort, fwd.GuestPort)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&target, "to", "", "Tailscale address of the target agent (host or host:port)")
+	cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+// consoleEscape detaches from the console (Ctrl+])
+const consoleEscape = 0x1d
+
+// vmConsoleCmd attaches the terminal to a VM's serial console
+func vmConsoleCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "console <vm-id>",
+		Short: "Attach to a VM's serial console (Ctrl+] to detach)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vmID := args[0]
+
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			stream, err := client.AttachConsole(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to attach console: %w", err)
+			}
+			if err := stream.Send(&agentpb.AttachConsoleRequest{
+				Payload: &agentpb.AttachConsoleRequest_VmId{VmId: vmID},
+			}); err != nil {
+				return fmt.Errorf("failed to attach console: %w", err)
+			}
+
+			fmt.Printf("Connected to console of '%s'. Escape character is ^]\r\n", vmID)
+
+			fd := int(os.Stdin.Fd())
+			if term.IsTerminal(fd) {
+				state, err := term.MakeRaw(fd)
+				if err != nil {
+					return fmt.Errorf("failed to set raw mode: %w", err)
+				}
+				defer term.Restore(fd, state)
+			}
+
+			// Forward keystrokes until the escape character
+			go func() {
+				buf := make([]byte, 1024)
+				for {
+					n, err := os.Stdin.Read(buf)
+					if err != nil {
+						cancel()
+						return
+					}
+					input := buf[:n]
+					if i := bytes.IndexByte(input, consoleEscape); i >= 0 {
+						if i > 0 {
+							_ = stream.Send(&agentpb.AttachConsoleRequest{
+								Payload: &agentpb.AttachConsoleRequest_Input{Input: input[:i]},
+							})
+						}
+						cancel()
+						return
+					}
+					if err := stream.Send(&agentpb.AttachConsoleRequest{
+						Payload: &agentpb.AttachConsoleRequest_Input{Input: append([]byte(nil), input...)},
+					}); err != nil {
+						return
+					}
+				}
+			}()
+
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF || ctx.Err() != nil {
+					break
+				}
+				if err != nil {
+					return fmt.Errorf("console stream failed: %w", err)
+				}
+				os.Stdout.Write(resp.Output)
+			}
+
+			fmt.Print("\r\nDetached from console\r\n")
+			return nil
+		},
+	}
+}
+
+// vmVNCCmd prints a one-time URL for the VNC WebSocket proxy
+func vmVNCCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "vnc <vm-id>",
+		Short: "Get a one-time VNC WebSocket URL for a VM",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.CreateVNCToken(ctx, &agentpb.CreateVNCTokenRequest{VmId: args[0]})
+			if err != nil {
+				return fmt.Errorf("failed to create VNC token: %w", err)
+			}
+
+			fmt.Printf("VNC URL (single use, expires %s):\n  %s\n",
+				time.Unix(resp.ExpiresAt, 0).Format("15:04:05"), resp.Url)
+			return nil
+		},
+	}
+}
+
+// vmExecCmd runs a command inside a VM through the guest agent
+func vmExecCmd() *cobra.Command {
+	var env []string
+	var timeoutSeconds int32
+
+	cmd := &cobra.Command{
+		Use:   "exec <vm-id> -- <command> [args...]",
+		Short: "Run a command inside a VM via the guest agent",
+		Long: `Run a command inside a running VM through qemu-guest-agent.
+Stdin is forwarded when it is not a terminal. ghostctl exits with the command's exit code.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var stdin []byte
+			if !term.IsTerminal(int(os.Stdin.Fd())) {
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return fmt.Errorf("failed to read stdin: %w", err)
+				}
+				stdin = data
+			}
+
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			// Allow for the guest-side timeout on top of the request timeout
+			ctx, cancel := context.WithTimeout(context.Background(),
+				timeout+time.Duration(timeoutSeconds)*time.Second)
+			defer cancel()
+
+			resp, err := client.GuestExec(ctx, &agentpb.GuestExecRequest{
+				VmId:           args[0],
+				Command:        args[1],
+				Args:           args[2:],
+				Env:            env,
+				Stdin:          stdin,
+				TimeoutSeconds: timeoutSeconds,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to run guest command: %w", err)
+			}
+
+			os.Stdout.Write(resp.Stdout)
+			os.Stderr.Write(resp.Stderr)
+			if resp.Truncated {
+				fmt.Fprintln(os.Stderr, "warning: output was truncated by the guest agent")
+			}
+
+			if resp.ExitCode != 0 {
+				conn.Close()
+				os.Exit(int(resp.ExitCode))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&env, "env", "e", nil, "Environment variable KEY=VALUE (repeatable)")
+	cmd.Flags().Int32Var(&timeoutSeconds, "timeout-seconds", 30, "Guest command timeout in seconds")
+
+	return cmd
+}
+
+// imageCmd returns the image management command
+func imageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "image",
+		Short: "Manage VM images",
+	}
+
+	cmd.AddCommand(imageUploadCmd())
+
+	return cmd
+}
+
+// imageUploadCmd uploads a custom image to the agent
+func imageUploadCmd() *cobra.Command {
+	var (
+		name   string
+		format string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upload <file>",
+		Short: "Upload a qcow2 or raw image as a template",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			if name == "" {
+				name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			}
+			if format == "" {
+				format = "raw"
+				if ext := filepath.Ext(path); ext == ".qcow2" || ext == ".img" {
+					format = "qcow2"
+				}
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open image: %w", err)
+			}
+			defer file.Close()
+
+			info, err := file.Stat()
+			if err != nil {
+				return fmt.Errorf("failed to stat image: %w", err)
+			}
+
+			fmt.Printf("Calculating checksum of '%s'...\n", path)
+			hash := sha256.New()
+			if _, err := io.Copy(hash, file); err != nil {
+				return fmt.Errorf("failed to read image: %w", err)
+			}
+			checksum := fmt.Sprintf("%x", hash.Sum(nil))
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to rewind image: %w", err)
+			}
+
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+			defer cancel()
+
+			stream, err := client.UploadImage(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to start upload: %w", err)
+			}
+
+			err = stream.Send(&agentpb.UploadImageRequest{
+				Payload: &agentpb.UploadImageRequest_Metadata{
+					Metadata: &agentpb.ImageMetadata{
+						Name:      name,
+						Format:    format,
+						Sha256:    checksum,
+						SizeBytes: info.Size(),
+					},
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to send image metadata: %w", err)
+			}
+
+			fmt.Printf("Uploading image '%s' (%s)...\n", name, format)
+			err = sendChunks(file, info.Size(), func(chunk []byte) error {
+				return stream.Send(&agentpb.UploadImageRequest{
+					Payload: &agentpb.UploadImageRequest_Chunk{Chunk: chunk},
+				})
+			})
+			if err != nil {
+				return err
+			}
+
+			resp, err := stream.CloseAndRecv()
+			if err != nil {
+				return fmt.Errorf("failed to upload image: %w", err)
+			}
+
+			fmt.Printf("✅ Image uploaded successfully!\n")
+			fmt.Printf("  Template: %s\n", resp.Name)
+			fmt.Printf("  Format: %s\n", resp.Format)
+			fmt.Printf("  Size: %d bytes\n", resp.SizeBytes)
+			fmt.Printf("  SHA256: %s\n", resp.Sha256)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Template name (defaults to the file name)")
+	cmd.Flags().StringVar(&format, "format", "", "Image format: qcow2 or raw (defaults from file extension)")
+
+	return cmd
+}
+
+// uploadChunkSize is the size of each data message in a streaming upload
+const uploadChunkSize = 1 << 20
+
+// sendChunks streams r in uploadChunkSize pieces while printing progress.
+// A send failure stops the upload; the server error is reported by CloseAndRecv.
+func sendChunks(r io.Reader, total int64, send func([]byte) error) error {
+	buf := make([]byte, uploadChunkSize)
+	var sent int64
+	defer fmt.Println()
+
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if err := send(buf[:n]); err != nil {
+				return nil
+			}
+			sent += int64(n)
+			printProgress(sent, total)
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read file: %w", readErr)
+		}
+	}
+}
+
+// printProgress renders a single-line progress bar
+func printProgress(done, total int64) {
+	const width = 40
+
+	if total <= 0 {
+		fmt.Printf("\r  %d MB", done>>20)
+		return
+	}
+
+	filled := int(done * width / total)
+	fmt.Printf("\r  [%s%s] %3d%% (%d/%d MB)",
+		strings.Repeat("#", filled),
+		strings.Repeat(" ", width-filled),
+		done*100/total,
+		done>>20, total>>20,
+	)
+}
+
+// backupCmd returns the backup management command
+func backupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Manage VM backups",
+	}
+
+	cmd.AddCommand(backupPolicyCmd())
+	cmd.AddCommand(backupCreateCmd())
+	cmd.AddCommand(backupListCmd())
+	cmd.AddCommand(backupRestoreCmd())
+
+	return cmd
+}
+
+// backupPolicyCmd sets the backup schedule and retention of a VM
+func backupPolicyCmd() *cobra.Command {
+	var (
+		schedule   string
+		keepDaily  int32
+		keepWeekly int32
+	)
+
+	cmd := &cobra.Command{
+		Use:   "policy <vm-id>",
+		Short: "Set a VM's backup schedule (empty --schedule disables it)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.SetBackupPolicy(ctx, &agentpb.SetBackupPolicyRequest{
+				VmId:       args[0],
+				Schedule:   schedule,
+				KeepDaily:  keepDaily,
+				KeepWeekly: keepWeekly,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to set backup policy: %w", err)
+			}
+
+			if resp.Schedule == "" {
+				fmt.Printf("✅ Scheduled backups disabled for %s\n", resp.VmId)
+				return nil
+			}
+			fmt.Printf("✅ Backups of %s scheduled at '%s' (keep %d daily, %d weekly)\n",
+				resp.VmId, resp.Schedule, resp.KeepDaily, resp.KeepWeekly)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&schedule, "schedule", "", "Cron expression, e.g. \"0 3 * * *\"")
+	cmd.Flags().Int32Var(&keepDaily, "keep-daily", 0, "Daily backups to keep (0 uses the agent default)")
+	cmd.Flags().Int32Var(&keepWeekly, "keep-weekly", 0, "Weekly backups to keep (0 uses the agent default)")
+
+	return cmd
+}
+
+// backupCreateCmd backs up a VM immediately
+func backupCreateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "create <vm-id>",
+		Short: "Back up a VM now",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			fmt.Printf("Backing up VM '%s'...\n", args[0])
+
+			resp, err := client.CreateBackup(ctx, &agentpb.CreateBackupRequest{VmId: args[0]})
+			if err != nil {
+				return fmt.Errorf("failed to create backup: %w", err)
+			}
+
+			fmt.Printf("✅ Backup created: %s (%d MB)\n", resp.Backup.BackupId, resp.Backup.SizeBytes>>20)
+			return nil
+		},
+	}
+}
+
+// backupListCmd lists stored backups
+func backupListCmd() *cobra.Command {
+	var vmID string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List backups",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.ListBackups(ctx, &agentpb.ListBackupsRequest{VmId: vmID})
+			if err != nil {
+				return fmt.Errorf("failed to list backups: %w", err)
+			}
+
+			if len(resp.Backups) == 0 {
+				fmt.Println("No backups found")
+				return nil
+			}
+
+			fmt.Printf("%-38s %-20s %-20s %-6s %10s\n", "Backup ID", "VM ID", "Created", "Target", "Size (MB)")
+			fmt.Println("--------------------------------------------------------------------------------------------------")
+			for _, b := range resp.Backups {
+				fmt.Printf("%-38s %-20s %-20s %-6s %10d\n",
+					b.BackupId, b.VmId,
+					time.Unix(b.CreatedAt, 0).Format("2006-01-02 15:04:05"),
+					b.Target, b.SizeBytes>>20)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&vmID, "vm", "", "Only list backups of this VM")
+	return cmd
+}
+
+// backupRestoreCmd restores a stopped VM from a backup
+func backupRestoreCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <backup-id>",
+		Short: "Restore a stopped VM's disk from a backup",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			fmt.Printf("Restoring backup '%s'...\n", args[0])
+
+			resp, err := client.RestoreBackup(ctx, &agentpb.RestoreBackupRequest{BackupId: args[0]})
+			if err != nil {
+				return fmt.Errorf("failed to restore backup: %w", err)
+			}
+
+			fmt.Printf("✅ VM %s restored\n", resp.VmId)
+			return nil
+		},
+	}
+}
+
+// portForwardCmd returns the port forwarding command
+func portForwardCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "port-forward",
+		Aliases: []string{"pf"},
+		Short:   "Manage host port forwards to VMs",
+	}
+
+	cmd.AddCommand(portForwardAddCmd())
+	cmd.AddCommand(portForwardListCmd())
+	cmd.AddCommand(portForwardRemoveCmd())
+
+	return cmd
+}
+
+// portForwardAddCmd forwards a host port to a VM port
+func portForwardAddCmd() *cobra.Command {
+	var (
+		protocol  string
+		hostPort  int32
+		guestPort int32
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add <vm-id>",
+		Short: "Forward a host port to a VM port",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.AddPortForward(ctx, &agentpb.AddPortForwardRequest{
+				VmId:      args[0],
+				Protocol:  protocol,
+				HostPort:  hostPort,
+				GuestPort: guestPort,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to add port forward: %w", err)
+			}
+
+			fwd := resp.PortForward
+			fmt.Printf("✅ Forwarding %s host port %d to %s:%d (ID: %s)\n",
+				fwd.Protocol, fwd.HostPort, fwd.VmId, fwd.GuestPort, fwd.Id)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&protocol, "protocol", "tcp", "Protocol: tcp or udp")
+	cmd.Flags().Int32Var(&hostPort, "host-port", 0, "Host port (0 picks a free port from the agent's range)")
+	cmd.Flags().Int32Var(&guestPort, "guest-port", 0, "Port inside the VM")
+	cmd.MarkFlagRequired("guest-port")
+
+	return cmd
+}
+
+// portForwardListCmd lists port forwards
+func portForwardListCmd() *cobra.Command {
+	var vmID string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List port forwards",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.ListPortForwards(ctx, &agentpb.ListPortForwardsRequest{VmId: vmID})
+			if err != nil {
+				return fmt.Errorf("failed to list port forwards: %w", err)
+			}
+
+			if len(resp.PortForwards) == 0 {
+				fmt.Println("No port forwards found")
+				return nil
+			}
+
+			fmt.Printf("%-38s %-20s %-8s %-10s %-22s %s\n", "ID", "VM ID", "Proto", "Host Port", "Guest", "Guest IPv6")
+			fmt.Println("--------------------------------------------------------------------------------------------------")
+			for _, fwd := range resp.PortForwards {
+				guestV6 := ""
+				if fwd.GuestIpv6 != "" {
+					guestV6 = net.JoinHostPort(fwd.GuestIpv6, fmt.Sprint(fwd.GuestPort))
+				}
+				fmt.Printf("%-38s %-20s %-8s %-10d %-22s %s\n",
+					fwd.Id, fwd.VmId, fwd.Protocol, fwd.HostPort,
+					net.JoinHostPort(fwd.GuestIp, fmt.Sprint(fwd.GuestPort)), guestV6)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&vmID, "vm", "", "Only list forwards of this VM")
+	return cmd
+}
+
+// portForwardRemoveCmd removes a port forward
+func portForwardRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <id>",
+		Short: "Remove a port forward",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			if _, err := client.RemovePortForward(ctx, &agentpb.RemovePortForwardRequest{Id: args[0]}); err != nil {
+				return fmt.Errorf("failed to remove port forward: %w", err)
+			}
+
+			fmt.Printf("✅ Port forward %s removed\n", args[0])
+			return nil
+		},
+	}
+}
+
+// securityGroupCmd returns the security group command
+func securityGroupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sg",
+		Short: "Manage VM security groups (firewall)",
+	}
+
+	ruleCmd := &cobra.Command{
+		Use:   "rule",
+		Short: "Manage security group rules",
+	}
+	ruleCmd.AddCommand(securityGroupRuleAddCmd())
+	ruleCmd.AddCommand(securityGroupRuleRemoveCmd())
+
+	cmd.AddCommand(securityGroupCreateCmd())
+	cmd.AddCommand(securityGroupListCmd())
+	cmd.AddCommand(securityGroupDeleteCmd())
+	cmd.AddCommand(ruleCmd)
+	cmd.AddCommand(securityGroupAttachCmd(true))
+	cmd.AddCommand(securityGroupAttachCmd(false))
+
+	return cmd
+}
+
+// securityGroupCreateCmd creates a security group
+func securityGroupCreateCmd() *cobra.Command {
+	var description string
+
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an empty security group",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.CreateSecurityGroup(ctx, &agentpb.CreateSecurityGroupRequest{
+				Name:        args[0],
+				Description: description,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create security group: %w", err)
+			}
+
+			fmt.Printf("✅ Security group '%s' created (ID: %s)\n", resp.SecurityGroup.Name, resp.SecurityGroup.Id)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&description, "description", "", "Description")
+	return cmd
+}
+
+// securityGroupListCmd lists security groups and their rules
+func securityGroupListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List security groups and their rules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.ListSecurityGroups(ctx, &agentpb.ListSecurityGroupsRequest{})
+			if err != nil {
+				return fmt.Errorf("failed to list security groups: %w", err)
+			}
+
+			if len(resp.SecurityGroups) == 0 {
+				fmt.Println("No security groups found")
+				return nil
+			}
+
+			for _, sg := range resp.SecurityGroups {
+				fmt.Printf("%s (%s)", sg.Name, sg.Id)
+				if sg.Description != "" {
+					fmt.Printf(" - %s", sg.Description)
+				}
+				fmt.Println()
+				if len(sg.VmIds) > 0 {
+					fmt.Printf("  VMs: %s\n", strings.Join(sg.VmIds, ", "))
+				}
+				for _, rule := range sg.Rules {
+					fmt.Printf("  %-10s %-8s %-8s %-12s %s\n",
+						rule.Id, rule.Direction, rule.Protocol, formatPorts(rule), formatCIDR(rule.Cidr))
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// securityGroupDeleteCmd deletes a security group
+func securityGroupDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <group>",
+		Short: "Delete a security group that is not attached to any VM",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			if _, err := client.DeleteSecurityGroup(ctx, &agentpb.DeleteSecurityGroupRequest{Group: args[0]}); err != nil {
+				return fmt.Errorf("failed to delete security group: %w", err)
+			}
+
+			fmt.Printf("✅ Security group '%s' deleted\n", args[0])
+			return nil
+		},
+	}
+}
+
+// securityGroupRuleAddCmd adds a rule to a security group
+func securityGroupRuleAddCmd() *cobra.Command {
+	var (
+		direction string
+		protocol  string
+		ports     string
+		cidr      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add <group>",
+		Short: "Allow traffic matching a rule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rule := &agentpb.SecurityGroupRule{
+				Direction: direction,
+				Protocol:  protocol,
+				Cidr:      cidr,
+			}
+			if ports != "" {
+				if _, err := fmt.Sscanf(strings.Replace(ports, "-", " ", 1)+" 0", "%d %d", &rule.PortMin, &rule.PortMax); err != nil {
+					return fmt.Errorf("invalid port range %q", ports)
+				}
+			}
+
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.AddSecurityGroupRule(ctx, &agentpb.AddSecurityGroupRuleRequest{
+				Group: args[0],
+				Rule:  rule,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to add rule: %w", err)
+			}
+
+			added := resp.SecurityGroup.Rules[len(resp.SecurityGroup.Rules)-1]
+			fmt.Printf("✅ Rule %s added to '%s': %s %s %s %s\n",
+				added.Id, resp.SecurityGroup.Name,
+				added.Direction, added.Protocol, formatPorts(added), formatCIDR(added.Cidr))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&direction, "direction", "ingress", "Direction: ingress or egress")
+	cmd.Flags().StringVar(&protocol, "protocol", "tcp", "Protocol: tcp, udp, icmp or all")
+	cmd.Flags().StringVar(&ports, "port", "", "Port or range, e.g. 22 or 8000-8100 (tcp/udp only)")
+	cmd.Flags().StringVar(&cidr, "cidr", "", "Remote IPv4 network (default any)")
+
+	return cmd
+}
+
+// securityGroupRuleRemoveCmd removes a rule from a security group
+func securityGroupRuleRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <group> <rule-id>",
+		Short: "Remove a rule",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			_, err = client.RemoveSecurityGroupRule(ctx, &agentpb.RemoveSecurityGroupRuleRequest{
+				Group:  args[0],
+				RuleId: args[1],
+			})
+			if err != nil {
+				return fmt.Errorf("failed to remove rule: %w", err)
+			}
+
+			fmt.Printf("✅ Rule %s removed from '%s'\n", args[1], args[0])
+			return nil
+		},
+	}
+}
+
+// securityGroupAttachCmd attaches a security group to, or detaches it from, a VM
+func securityGroupAttachCmd(attach bool) *cobra.Command {
+	use, short := "attach <group> <vm-id>", "Attach a security group to a VM"
+	if !attach {
+		use, short = "detach <group> <vm-id>", "Detach a security group from a VM"
+	}
+
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			req := &agentpb.AttachSecurityGroupRequest{Group: args[0], VmId: args[1]}
+			action := client.AttachSecurityGroup
+			if !attach {
+				action = client.DetachSecurityGroup
+			}
+			resp, err := action(ctx, req)
+			if err != nil {
+				return fmt.Errorf("failed to update security groups: %w", err)
+			}
+
+			fmt.Printf("✅ Security groups of %s: %s\n", resp.VmId, strings.Join(resp.SecurityGroups, ", "))
+			return nil
+		},
+	}
+}
+
+// networkCmd manages private networks
+func networkCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "network",
+		Aliases: []string{"net"},
+		Short:   "Manage isolated private networks",
+	}
+
+	cmd.AddCommand(networkCreateCmd())
+	cmd.AddCommand(networkListCmd())
+	cmd.AddCommand(networkDeleteCmd())
+
+	return cmd
+}
+
+// networkCreateCmd creates a private network
+func networkCreateCmd() *cobra.Command {
+	var (
+		tenant    string
+		subnet    string
+		subnetV6  string
+		dhcpStart string
+		dhcpEnd   string
+		nat       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an isolated private network",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.CreateNetwork(ctx, &agentpb.CreateNetworkRequest{
+				Name:      args[0],
+				Tenant:    tenant,
+				Subnet:    subnet,
+				SubnetV6:  subnetV6,
+				DhcpStart: dhcpStart,
+				DhcpEnd:   dhcpEnd,
+				Nat:       nat,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create network: %w", err)
+			}
+
+			n := resp.Network
+			fmt.Printf("✅ Network '%s' created (ID: %s)\n", n.Name, n.Id)
+			fmt.Printf("  Bridge: %s\n", n.Bridge)
+			fmt.Printf("  Gateway: %s\n", n.Gateway)
+			fmt.Printf("  DHCP: %s - %s\n", n.DhcpStart, n.DhcpEnd)
+			if n.SubnetV6 != "" {
+				fmt.Printf("  IPv6: %s (gateway %s, SLAAC)\n", n.SubnetV6, n.GatewayV6)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&tenant, "tenant", "", "Tenant the network belongs to")
+	cmd.Flags().StringVar(&subnet, "subnet", "", "IPv4 subnet in CIDR notation (required)")
+	cmd.Flags().StringVar(&subnetV6, "subnet-v6", "", "IPv6 /64 in CIDR notation (defaults to one from the agent's prefix when IPv6 is enabled)")
+	cmd.Flags().StringVar(&dhcpStart, "dhcp-start", "", "First DHCP address (defaults to the second host address)")
+	cmd.Flags().StringVar(&dhcpEnd, "dhcp-end", "", "Last DHCP address (defaults to the last host address)")
+	cmd.Flags().BoolVar(&nat, "nat", false, "Let guests reach outside networks through the host")
+	cmd.MarkFlagRequired("subnet")
+
+	return cmd
+}
+
+// networkListCmd lists private networks
+func networkListCmd() *cobra.Command {
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List private networks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.ListNetworks(ctx, &agentpb.ListNetworksRequest{Tenant: tenant})
+			if err != nil {
+				return fmt.Errorf("failed to list networks: %w", err)
+			}
+
+			if len(resp.Networks) == 0 {
+				fmt.Println("No networks found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-16s %-18s %-24s %-12s %-5s %s\n", "Name", "Tenant", "Subnet", "IPv6 Subnet", "Bridge", "NAT", "VMs")
+			fmt.Println("----------------------------------------------------------------------------------------------------------------------")
+			for _, n := range resp.Networks {
+				fmt.Printf("%-20s %-16s %-18s %-24s %-12s %-5t %s\n",
+					n.Name, n.Tenant, n.Subnet, n.SubnetV6, n.Bridge, n.Nat, strings.Join(n.VmIds, ", "))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&tenant, "tenant", "", "Only list networks of this tenant")
+	return cmd
+}
+
+// networkDeleteCmd deletes a private network
+func networkDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <network>",
+		Short: "Delete a private network no VM is attached to",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			if _, err := client.DeleteNetwork(ctx, &agentpb.DeleteNetworkRequest{Network: args[0]}); err != nil {
+				return fmt.Errorf("failed to delete network: %w", err)
+			}
+
+			fmt.Printf("✅ Network '%s' deleted\n", args[0])
+			return nil
+		},
+	}
+}
+
+// formatPorts renders a rule's port range
+func formatPorts(rule *agentpb.SecurityGroupRule) string {
+	switch {
+	case rule.PortMin == 0:
+		return "all ports"
+	case rule.PortMax > rule.PortMin:
+		return fmt.Sprintf("%d-%d", rule.PortMin, rule.PortMax)
+	default:
+		return fmt.Sprintf("%d", rule.PortMin)
+	}
+}
+
+// formatCIDR renders a rule's remote network
+func formatCIDR(cidr string) string {
+	if cidr == "" {
+		return "any"
+	}
+	return cidr
+}
+
+// auditCmd shows the audit log
+func auditCmd() *cobra.Command {
+	var (
+		vmID    string
+		since   string
+		until   string
+		limit   int32
+		verbose bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show who changed what on the agent",
+		Long: `Show the newest events of the agent's audit log, oldest first.
+
+Every mutating call and Ghost Core command is recorded with its caller and result.
+--since and --until take a duration before now (e.g. 24h) or an RFC 3339 time.
+The log's hash chain is checked on every read and tampering is reported.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &agentpb.QueryAuditLogRequest{VmId: vmID, Limit: limit}
+			var err error
+			if req.Since, err = parseAuditTime(since); err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			if req.Until, err = parseAuditTime(until); err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.QueryAuditLog(ctx, req)
+			if err != nil {
+				return fmt.Errorf("failed to query audit log: %w", err)
+			}
+
+			if len(resp.Events) == 0 {
+				fmt.Println("No audit events found")
+			} else {
+				fmt.Printf("%-19s %-24s %-20s %-10s %-20s %-8s %8s\n", "Time", "Method", "Caller", "Role", "VM ID", "Result", "Duration")
+				fmt.Println("-------------------------------------------------------------------------------------------------------------------")
+				for _, e := range resp.Events {
+					caller := e.Subject
+					if caller == "" {
+						caller = e.Peer
+					}
+					fmt.Printf("%-19s %-24s %-20s %-10s %-20s %-8s %8s\n",
+						time.UnixMilli(e.Time).Format("2006-01-02 15:04:05"),
+						e.Method[strings.LastIndex(e.Method, "/")+1:],
+						caller, e.Role, e.VmId, e.Result,
+						(time.Duration(e.DurationMs) * time.Millisecond).String())
+					if verbose {
+						if e.Summary != "" {
+							fmt.Printf("    request: %s\n", e.Summary)
+						}
+						if e.Error != "" {
+							fmt.Printf("    error:   %s\n", e.Error)
+						}
+					}
+				}
+			}
+
+			fmt.Println()
+			if resp.Intact {
+				fmt.Println("✅ Audit log hash chain intact")
+			} else {
+				fmt.Printf("⚠️  Audit log was tampered with: %s\n", resp.IntegrityError)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&vmID, "vm", "", "Only events about this VM")
+	cmd.Flags().StringVar(&since, "since", "", "Only events after this time or duration ago")
+	cmd.Flags().StringVar(&until, "until", "", "Only events before this time or duration ago")
+	cmd.Flags().Int32Var(&limit, "limit", 50, "Newest events to show")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show requests and errors")
+	return cmd
+}
+
+// parseAuditTime parses a duration before now or an RFC 3339 time into a
+// Unix timestamp, 0 when empty
+func parseAuditTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d).Unix(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("want a duration like 24h or an RFC 3339 time")
+	}
+	return t.Unix(), nil
+}
+
+// subsystems are the parts of the agent reported by its health service
+var subsystems = []string{"libvirt", "storage", "core"}
+
+// quotaCmd shows tenant quotas, their usage and the overcommit ratios
+func quotaCmd() *cobra.Command {
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "quota",
+		Short: "Show tenant quotas and their usage",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.GetQuotas(ctx, &agentpb.GetQuotasRequest{Tenant: tenant})
+			if err != nil {
+				return fmt.Errorf("failed to get quotas: %w", err)
+			}
+
+			if o := resp.Overcommit; o != nil {
+				fmt.Printf("Overcommit: CPU %g:1, RAM %g:1, disk %g:1", o.Cpu, o.Ram, o.Disk)
+				if o.ThinProvisioning {
+					fmt.Printf(", thin provisioned disks")
+				}
+				fmt.Printf("\n\n")
+			}
+
+			if len(resp.Quotas) == 0 {
+				fmt.Println("No tenants found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-12s %-12s %-14s %s\n", "Tenant", "VMs", "vCPU", "RAM (GB)", "Disk (GB)")
+			fmt.Println("--------------------------------------------------------------------------")
+			for _, q := range resp.Quotas {
+				fmt.Printf("%-20s %-12s %-12s %-14s %s\n", q.Tenant,
+					quotaUsage(q.Usage.GetVms(), q.Limit.GetVms()),
+					quotaUsage(q.Usage.GetVcpu(), q.Limit.GetVcpu()),
+					quotaUsage(q.Usage.GetRamGb(), q.Limit.GetRamGb()),
+					quotaUsage(q.Usage.GetDiskGb(), q.Limit.GetDiskGb()),
+				)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&tenant, "tenant", "", "Only show this tenant")
+	return cmd
+}
+
+// flavorCmd shows flavors
+func flavorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "flavor",
+		Short: "Show the named VM sizes",
+	}
+
+	cmd.AddCommand(flavorListCmd())
+
+	return cmd
+}
+
+// flavorListCmd lists flavors and how many VMs of each fit
+func flavorListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List flavors and how many VMs of each fit on this host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, conn, err := connectToAgent()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			resp, err := client.ListFlavors(ctx, &agentpb.ListFlavorsRequest{})
+			if err != nil {
+				return fmt.Errorf("failed to list flavors: %w", err)
+			}
+
+			if len(resp.Flavors) == 0 {
+				fmt.Println("No flavors found")
+				return nil
+			}
+
+			fmt.Printf("%-16s %-6s %-10s %-11s %-18s %-10s %s\n", "Name", "vCPU", "RAM (GB)", "Disk (GB)", "CPU Mode", "Available", "Templates")
+			fmt.Println("------------------------------------------------------------------------------------------------")
+			for _, info := range resp.Flavors {
+				f := info.Flavor
+				cpuMode := f.CpuMode
+				if cpuMode == "" {
+					cpuMode = "default"
+				}
+				available := fmt.Sprint(info.Available)
+				if !info.Fits {
+					available = "too large"
+				}
+				templates := strings.Join(f.Templates, ", ")
+				if templates == "" {
+					templates = "any"
+				}
+				fmt.Printf("%-16s %-6d %-10d %-11d %-18s %-10s %s\n",
+					f.Name, f.Vcpu, f.RamGb, f.DiskGb, cpuMode, available, templates)
+			}
+
+			return nil
+		},
+	}
+}
+
+// quotaUsage formats usage against a limit, where 0 is unlimited
+func quotaUsage(used, limit int32) string {
+	if limit == 0 {
+		return fmt.Sprintf("%d/-", used)
+	}
+	return fmt.Sprintf("%d/%d", used, limit)
+}
+
+// statusCmd shows agent status
+func statusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show Ghost Agent status",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Try to connect
+			client, conn, err := connectToAgent()
+			if err != nil {
+				fmt.Printf("❌ Ghost Agent is not running or not reachable\n")
+				fmt.Printf("   Error: %v\n", err)
+				return nil
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			healthClient := healthpb.NewHealthClient(conn)
+			overall, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{})
+			if err != nil {
+				fmt.Printf("❌ Ghost Agent is not responding\n")
+				fmt.Printf("   Error: %v\n", err)
+				return nil
+			}
+
+			switch overall.Status {
+			case healthpb.HealthCheckResponse_SERVING:
+				fmt.Printf("✅ Ghost Agent is running\n")
+			case healthpb.HealthCheckResponse_NOT_SERVING:
+				fmt.Printf("❌ Ghost Agent is unhealthy\n")
+			default:
+				fmt.Printf("⏳ Ghost Agent is starting\n")
+			}
+			fmt.Printf("   Address: %s\n", agentAddr)
+
+			if info, err := client.GetAgentInfo(ctx, &agentpb.GetAgentInfoRequest{}); err == nil {
+				startedAt := time.Unix(info.StartedAt, 0)
+				fmt.Printf("   Name: %s\n", info.Name)
+				fmt.Printf("   Version: %s\n", info.Version)
+				fmt.Printf("   Uptime: %s\n", time.Since(startedAt).Truncate(time.Second))
+				if r := info.Resources; r != nil {
+					fmt.Printf("   CPU: %d/%d cores available\n", r.AvailableCpu, r.TotalCpu)
+					fmt.Printf("   RAM: %d/%d GB available\n", r.AvailableRamGb, r.TotalRamGb)
+					fmt.Printf("   Disk: %d/%d GB available\n", r.AvailableDiskGb, r.TotalDiskGb)
+				}
+			} else {
+				fmt.Printf("   Version: unknown (%v)\n", err)
+			}
+
+			fmt.Printf("\nSubsystems:\n")
+			for _, name := range subsystems {
+				resp, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{Service: name})
+				if err != nil {
+					fmt.Printf("  ❔ %-10s %v\n", name, status.Code(err))
+					continue
+				}
+				icon := "❌"
+				if resp.Status == healthpb.HealthCheckResponse_SERVING {
+					icon = "✅"
+				}
+				fmt.Printf("  %s %-10s %s\n", icon, name, resp.Status)
+			}
+
+			return nil
+		},
+	}
+}
+
+// versionCmd shows version
+func versionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Show ghostctl version",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println("ghostctl version 1.0.0")
+		},
+	}
+}
+
+// connectToAgent creates a gRPC connection to Ghost Agent
+func connectToAgent() (agentpb.AgentServiceClient, *grpc.ClientConn, error) {
+	creds, err := transportCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5 * time.Second),
+	}
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(token)))
+	}
+
+	conn, err := grpc.Dial(agentAddr, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to Ghost Agent at %s: %w", agentAddr, err)
+	}
+
+	client := agentpb.NewAgentServiceClient(conn)
+	return client, conn, nil
+}
+
+// transportCredentials returns TLS credentials when --tls-ca is set
+func transportCredentials() (credentials.TransportCredentials, error) {
+	if tlsCA == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	ca, err := os.ReadFile(tlsCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates in %s", tlsCA)
+	}
+
+	cfg := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	if tlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// tokenCredentials sends a bearer token with every call
+// The unix socket and the tailnet already protect it, so TLS is not required
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
 }
@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// AgentInfoResponse represents the agent's identity, build and capacity
+type AgentInfoResponse struct {
+	Name      string        `json:"name"`
+	Version   string        `json:"version"`
+	StartedAt time.Time     `json:"started_at"`
+	Resources ResourceUsage `json:"resources"`
+}
+
+// ResourceUsage represents the host's capacity left for VMs
+type ResourceUsage struct {
+	TotalCPU        int `json:"total_cpu"`
+	AvailableCPU    int `json:"available_cpu"`
+	TotalRAMGB      int `json:"total_ram_gb"`
+	AvailableRAMGB  int `json:"available_ram_gb"`
+	TotalDiskGB     int `json:"total_disk_gb"`
+	AvailableDiskGB int `json:"available_disk_gb"`
+}
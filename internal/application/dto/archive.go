@@ -0,0 +1,46 @@
+package dto
+
+import "time"
+
+// VMArchiveFormatVersion is the current version of the VM archive layout
+const VMArchiveFormatVersion = 1
+
+// Entries of a VM archive, in the order they are written
+const (
+	VMArchiveManifestFile = "manifest.json"
+	VMArchiveRecordFile   = "vm.json"
+	VMArchiveDiskFile     = "disk.qcow2"
+)
+
+// VMArchiveManifest describes the contents of a VM archive
+type VMArchiveManifest struct {
+	FormatVersion int       `json:"format_version"`
+	VMID          string    `json:"vm_id"`
+	Name          string    `json:"name"`
+	VCPU          int       `json:"vcpu"`
+	RAMGB         int       `json:"ram_gb"`
+	DiskGB        int       `json:"disk_gb"`
+	Template      string    `json:"template"`
+	DiskFile      string    `json:"disk_file"`
+	DiskSizeBytes int64     `json:"disk_size_bytes"`
+	DiskSHA256    string    `json:"disk_sha256"`
+	ExportedAt    time.Time `json:"exported_at"`
+}
+
+// ExportVMRequest represents a request to export a VM as an archive
+type ExportVMRequest struct {
+	VMID string `json:"vm_id" validate:"required"`
+}
+
+// ImportVMRequest represents a request to recreate a VM from an archive
+type ImportVMRequest struct {
+	// Name overrides the VM name stored in the archive
+	Name string `json:"name,omitempty" validate:"omitempty,min=3,max=63,hostname"`
+}
+
+// ImportVMResponse represents the response after importing a VM
+type ImportVMResponse struct {
+	VMID      string `json:"vm_id"`
+	IPAddress string `json:"ip_address"`
+	Status    string `json:"status"`
+}
@@ -0,0 +1,37 @@
+package dto
+
+import "time"
+
+// QueryAuditLogRequest represents a request to read the audit log
+type QueryAuditLogRequest struct {
+	Since time.Time `json:"since,omitempty"`
+	Until time.Time `json:"until,omitempty"` // Exclusive
+	VMID  string    `json:"vm_id,omitempty"`
+	Limit int       `json:"limit" validate:"min=0,max=10000"` // Newest events, 0 for the default
+}
+
+// QueryAuditLogResponse represents audit events, oldest first, and the result
+// of checking the log's hash chain
+type QueryAuditLogResponse struct {
+	Events         []AuditEventInfo `json:"events"`
+	Intact         bool             `json:"intact"`
+	IntegrityError string           `json:"integrity_error,omitempty"`
+}
+
+// AuditEventInfo represents a recorded operation
+type AuditEventInfo struct {
+	Seq        uint64        `json:"seq"`
+	Time       time.Time     `json:"time"`
+	Method     string        `json:"method"`
+	Subject    string        `json:"subject,omitempty"`
+	Role       string        `json:"role,omitempty"`
+	Tenant     string        `json:"tenant,omitempty"`
+	AuthMethod string        `json:"auth_method,omitempty"`
+	Peer       string        `json:"peer,omitempty"`
+	VMID       string        `json:"vm_id,omitempty"`
+	Summary    string        `json:"summary,omitempty"`
+	Result     string        `json:"result"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Hash       string        `json:"hash"`
+}
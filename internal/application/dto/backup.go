@@ -0,0 +1,61 @@
+package dto
+
+import "time"
+
+// SetBackupPolicyRequest represents a request to configure scheduled backups of a VM
+type SetBackupPolicyRequest struct {
+	VMID       string `json:"vm_id" validate:"required"`
+	Schedule   string `json:"schedule"` // Cron expression, empty disables scheduled backups
+	KeepDaily  int    `json:"keep_daily" validate:"min=0,max=366"`
+	KeepWeekly int    `json:"keep_weekly" validate:"min=0,max=520"`
+}
+
+// SetBackupPolicyResponse represents the effective backup policy of a VM
+type SetBackupPolicyResponse struct {
+	VMID       string `json:"vm_id"`
+	Schedule   string `json:"schedule"`
+	KeepDaily  int    `json:"keep_daily"`
+	KeepWeekly int    `json:"keep_weekly"`
+}
+
+// CreateBackupRequest represents a request to back up a VM now
+type CreateBackupRequest struct {
+	VMID string `json:"vm_id" validate:"required"`
+}
+
+// CreateBackupResponse represents the backup that was taken
+type CreateBackupResponse struct {
+	Backup BackupInfo `json:"backup"`
+}
+
+// ListBackupsRequest represents a request to list backups
+type ListBackupsRequest struct {
+	VMID string `json:"vm_id,omitempty"` // Optional, lists all backups when empty
+}
+
+// ListBackupsResponse represents the response with backups, newest first
+type ListBackupsResponse struct {
+	Backups []BackupInfo `json:"backups"`
+}
+
+// RestoreBackupRequest represents a request to restore a VM's disk from a backup
+type RestoreBackupRequest struct {
+	BackupID string `json:"backup_id" validate:"required"`
+}
+
+// RestoreBackupResponse represents the response after restoring a backup
+type RestoreBackupResponse struct {
+	VMID   string `json:"vm_id"`
+	Status string `json:"status"`
+}
+
+// BackupInfo represents a stored backup
+type BackupInfo struct {
+	BackupID  string    `json:"backup_id"`
+	VMID      string    `json:"vm_id"`
+	Target    string    `json:"target"`
+	Location  string    `json:"location"`
+	SizeBytes int64     `json:"size_bytes"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+// AttachConsoleRequest represents a request to attach to a VM's serial console
+type AttachConsoleRequest struct {
+	VMID string `json:"vm_id" validate:"required"`
+}
+
+// CreateVNCTokenRequest represents a request for graphical console access
+type CreateVNCTokenRequest struct {
+	VMID string `json:"vm_id" validate:"required"`
+}
+
+// CreateVNCTokenResponse represents a one-time VNC proxy token
+type CreateVNCTokenResponse struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"` // WebSocket URL including the token
+	ExpiresAt time.Time `json:"expires_at"`
+}
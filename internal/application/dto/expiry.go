@@ -0,0 +1,18 @@
+package dto
+
+// RenewVMRequest represents a request to extend or end the lease of a VM
+// Exactly one of TTLSeconds, ExpiresAt and Clear is set
+type RenewVMRequest struct {
+	VMID       string `json:"vm_id" validate:"required"`
+	TTLSeconds int64  `json:"ttl_seconds,omitempty" validate:"excluded_with=ExpiresAt Clear,omitempty,min=60"` // The lease runs out this long from now
+	ExpiresAt  int64  `json:"expires_at,omitempty" validate:"excluded_with=Clear,min=0"`                       // Or at this Unix timestamp
+	Action     string `json:"action,omitempty" validate:"excluded_with=Clear,omitempty,oneof=stop delete"`     // Defaults to the agent's default action
+	Clear      bool   `json:"clear,omitempty" validate:"required_without_all=TTLSeconds ExpiresAt"`            // Remove the lease so the VM no longer expires
+}
+
+// RenewVMResponse represents the lease now in effect for a VM
+type RenewVMResponse struct {
+	VMID         string `json:"vm_id"`
+	ExpiresAt    int64  `json:"expires_at"`              // Unix timestamp, 0 when the VM does not expire
+	ExpiryAction string `json:"expiry_action,omitempty"` // "stop" or "delete"
+}
@@ -0,0 +1,39 @@
+package dto
+
+// Flavor represents a named VM size
+type Flavor struct {
+	Name      string   `json:"name" validate:"required,max=63,hostname_rfc1123"`
+	VCPU      int      `json:"vcpu" validate:"min=1,max=32"`
+	RAMGB     int      `json:"ram_gb" validate:"min=1,max=128"`
+	DiskGB    int      `json:"disk_gb" validate:"min=10,max=1000"`
+	CPUMode   string   `json:"cpu_mode,omitempty" validate:"omitempty,oneof=host-passthrough host-model maximum"` // Hypervisor default when empty
+	Limits    VMLimits `json:"limits"`                                                                            // Zero values take the agent defaults
+	Templates []string `json:"templates,omitempty" validate:"dive,required"`                                      // Any template when empty
+	Source    string   `json:"source,omitempty"`                                                                  // "config" or "core"
+}
+
+// FlavorInfo represents a flavor and whether this host can run it
+type FlavorInfo struct {
+	Flavor    Flavor `json:"flavor"`
+	Fits      bool   `json:"fits"`      // The host's capacity can hold a VM of the flavor
+	Available int    `json:"available"` // How many more VMs of the flavor fit right now
+}
+
+// ListFlavorsRequest represents a request to list flavors
+type ListFlavorsRequest struct{}
+
+// ListFlavorsResponse represents the flavors this agent offers, by name
+type ListFlavorsResponse struct {
+	Flavors []FlavorInfo `json:"flavors"`
+}
+
+// SetFlavorsRequest represents the flavors pushed by Ghost Core
+// They replace those pushed before and override configured flavors of the same name
+type SetFlavorsRequest struct {
+	Flavors []Flavor `json:"flavors" validate:"max=256,unique=Name,dive"`
+}
+
+// SetFlavorsResponse represents the response after replacing the pushed flavors
+type SetFlavorsResponse struct {
+	Success bool `json:"success"`
+}
@@ -0,0 +1,44 @@
+package dto
+
+// GuestExecRequest represents a request to run a command inside a VM
+type GuestExecRequest struct {
+	VMID           string   `json:"vm_id" validate:"required"`
+	Command        string   `json:"command" validate:"required"`
+	Args           []string `json:"args,omitempty"`
+	Env            []string `json:"env,omitempty"` // KEY=VALUE pairs
+	Stdin          []byte   `json:"stdin,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds" validate:"min=0,max=3600"`
+}
+
+// GuestExecResponse represents the outcome of a guest command
+type GuestExecResponse struct {
+	ExitCode  int    `json:"exit_code"`
+	Stdout    []byte `json:"stdout"`
+	Stderr    []byte `json:"stderr"`
+	Truncated bool   `json:"truncated"`
+}
+
+// GuestInfo represents details reported by the guest agent
+type GuestInfo struct {
+	OSName        string            `json:"os_name"`
+	OSVersion     string            `json:"os_version"`
+	KernelRelease string            `json:"kernel_release"`
+	Hostname      string            `json:"hostname"`
+	FileSystems   []GuestFileSystem `json:"filesystems"`
+	Interfaces    []GuestInterface  `json:"interfaces"`
+}
+
+// GuestFileSystem represents a mounted filesystem inside the guest
+type GuestFileSystem struct {
+	MountPoint string `json:"mount_point"`
+	FSType     string `json:"fs_type"`
+	TotalBytes uint64 `json:"total_bytes"`
+	UsedBytes  uint64 `json:"used_bytes"`
+}
+
+// GuestInterface represents a network interface inside the guest
+type GuestInterface struct {
+	Name         string   `json:"name"`
+	HardwareAddr string   `json:"hardware_addr"`
+	Addresses    []string `json:"addresses"` // CIDR notation
+}
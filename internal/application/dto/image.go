@@ -0,0 +1,17 @@
+package dto
+
+// UploadImageRequest represents the metadata sent ahead of image data
+type UploadImageRequest struct {
+	Name      string `json:"name" validate:"required,min=3,max=63,hostname"`
+	Format    string `json:"format" validate:"required,oneof=qcow2 raw"`
+	SHA256    string `json:"sha256" validate:"required,len=64,hexadecimal"`
+	SizeBytes int64  `json:"size_bytes" validate:"min=0"`
+}
+
+// UploadImageResponse represents the registered image after an upload
+type UploadImageResponse struct {
+	Name      string `json:"name"`
+	Format    string `json:"format"`
+	SHA256    string `json:"sha256"`
+	SizeBytes int64  `json:"size_bytes"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+// BandwidthLimit represents a cap on a VM's traffic in one direction
+// Rates are in KiB/s and the burst in KiB; zero means unlimited
+type BandwidthLimit struct {
+	AverageKBps int `json:"average_kbytes_sec" validate:"min=0"`
+	PeakKBps    int `json:"peak_kbytes_sec" validate:"omitempty,excluded_without=AverageKBps,gtefield=AverageKBps"`
+	BurstKB     int `json:"burst_kbytes" validate:"min=0,excluded_without=AverageKBps"`
+}
+
+// VMLimits represents the network and disk I/O limits of a VM
+type VMLimits struct {
+	Inbound         BandwidthLimit `json:"inbound"`  // Traffic towards the guest
+	Outbound        BandwidthLimit `json:"outbound"` // Traffic from the guest
+	DiskIOPS        int64          `json:"disk_iops" validate:"min=0"`
+	DiskBytesPerSec int64          `json:"disk_bytes_sec" validate:"min=0"`
+}
+
+// UpdateVMLimitsRequest represents a request to replace the limits of a VM
+// Unset values take the agent defaults
+type UpdateVMLimitsRequest struct {
+	VMID   string   `json:"vm_id" validate:"required"`
+	Limits VMLimits `json:"limits"`
+}
+
+// UpdateVMLimitsResponse represents the limits now in effect for a VM
+type UpdateVMLimitsResponse struct {
+	VMID   string   `json:"vm_id"`
+	Limits VMLimits `json:"limits"`
+}
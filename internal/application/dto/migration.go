@@ -0,0 +1,72 @@
+package dto
+
+// MigrateVMRequest represents a request to live-migrate a VM to another agent
+type MigrateVMRequest struct {
+	VMID          string `json:"vm_id" validate:"required"`
+	TargetAddress string `json:"target_address" validate:"required"`
+}
+
+// MigrateVMProgress represents the progress of a live migration
+type MigrateVMProgress struct {
+	Phase         string `json:"phase"`
+	Percent       int    `json:"percent"`
+	DataTotal     uint64 `json:"data_total"`
+	DataProcessed uint64 `json:"data_processed"`
+	TargetAddress string `json:"target_address"`
+	IPAddress     string `json:"ip_address,omitempty"`
+
+	RemovedPortForwards []PortForwardInfo `json:"removed_port_forwards,omitempty"`
+}
+
+// MigratingVM describes a VM moving between agents
+type MigratingVM struct {
+	VMID         string            `json:"vm_id" validate:"required,min=3,max=63,hostname"`
+	Name         string            `json:"name" validate:"required"`
+	VCPU         int               `json:"vcpu" validate:"required,min=1,max=32"`
+	RAMGB        int               `json:"ram_gb" validate:"required,min=1,max=128"`
+	DiskGB       int               `json:"disk_gb" validate:"required,min=10,max=1000"`
+	Template     string            `json:"template"`
+	NetworkMode  string            `json:"network_mode" validate:"omitempty,oneof=nat bridge"`
+	Limits       VMLimits          `json:"limits"` // Carried over in the domain definition
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	ExpiresAt    int64             `json:"expires_at,omitempty"`    // Unix timestamp, 0 when the VM does not expire
+	ExpiryAction string            `json:"expiry_action,omitempty"` // "stop" or "delete"
+	// Restart policy, empty for never
+	RestartPolicy     string `json:"restart_policy,omitempty" validate:"omitempty,oneof=never always unless-stopped on-failure"`
+	RestartMaxRetries int    `json:"restart_max_retries,omitempty" validate:"min=0"`
+	// Backup policy, empty schedule without scheduled backups
+	BackupSchedule   string `json:"backup_schedule,omitempty"`
+	BackupKeepDaily  int    `json:"backup_keep_daily,omitempty" validate:"min=0"`
+	BackupKeepWeekly int    `json:"backup_keep_weekly,omitempty" validate:"min=0"`
+	// Security group names; the target must define groups of the same names
+	SecurityGroups []string `json:"security_groups,omitempty"`
+	// Private network IDs; the migrated NICs reference the networks by ID
+	Networks []string `json:"network_ids,omitempty"`
+	// Flavor the VM was sized by, empty when it was sized directly
+	Flavor  string `json:"flavor,omitempty"`
+	CPUMode string `json:"cpu_mode,omitempty" validate:"omitempty,oneof=host-passthrough host-model maximum"`
+}
+
+// PrepareMigrationRequest represents a source agent's request to reserve room for a VM
+type PrepareMigrationRequest struct {
+	VM MigratingVM `json:"vm"`
+}
+
+// PrepareMigrationResponse represents the reservation made for an incoming VM
+type PrepareMigrationResponse struct {
+	DiskPath   string `json:"disk_path"`
+	LibvirtURI string `json:"libvirt_uri"`
+}
+
+// FinishMigrationRequest represents a source agent's report on an incoming VM
+type FinishMigrationRequest struct {
+	VM        MigratingVM `json:"vm"`
+	Succeeded bool        `json:"succeeded"`
+}
+
+// FinishMigrationResponse represents the VM as registered on this agent
+type FinishMigrationResponse struct {
+	VMID      string `json:"vm_id"`
+	IPAddress string `json:"ip_address"`
+	Status    string `json:"status"`
+}
@@ -0,0 +1,56 @@
+package dto
+
+import "time"
+
+// CreateNetworkRequest represents a request to create an isolated private network
+type CreateNetworkRequest struct {
+	Name      string `json:"name" validate:"required,max=64,hostname_rfc1123"`
+	Tenant    string `json:"tenant" validate:"omitempty,max=64,hostname_rfc1123"`
+	Subnet    string `json:"subnet" validate:"required,cidrv4"`
+	DHCPStart string `json:"dhcp_start" validate:"omitempty,ipv4"`  // Defaults to the second host address
+	DHCPEnd   string `json:"dhcp_end" validate:"omitempty,ipv4"`    // Defaults to the last host address
+	SubnetV6  string `json:"subnet_v6" validate:"omitempty,cidrv6"` // IPv6 /64, defaults to the next free one of the agent's prefix
+	NAT       bool   `json:"nat"`                                   // Let guests reach outside networks through the host
+}
+
+// CreateNetworkResponse represents the network after creation
+type CreateNetworkResponse struct {
+	Network NetworkInfo `json:"network"`
+}
+
+// DeleteNetworkRequest represents a request to delete a private network
+type DeleteNetworkRequest struct {
+	Network string `json:"network" validate:"required"` // ID or name
+}
+
+// DeleteNetworkResponse represents the response after deleting a network
+type DeleteNetworkResponse struct {
+	Success bool `json:"success"`
+}
+
+// ListNetworksRequest represents a request to list private networks
+type ListNetworksRequest struct {
+	Tenant string `json:"tenant,omitempty"` // Only networks of this tenant when set
+}
+
+// ListNetworksResponse represents the response with private networks, ordered by name
+type ListNetworksResponse struct {
+	Networks []NetworkInfo `json:"networks"`
+}
+
+// NetworkInfo represents a private network
+type NetworkInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Tenant    string    `json:"tenant"`
+	Subnet    string    `json:"subnet"`
+	Gateway   string    `json:"gateway"`
+	DHCPStart string    `json:"dhcp_start"`
+	DHCPEnd   string    `json:"dhcp_end"`
+	SubnetV6  string    `json:"subnet_v6"` // Empty for IPv4-only networks
+	GatewayV6 string    `json:"gateway_v6"`
+	NAT       bool      `json:"nat"`
+	Bridge    string    `json:"bridge"`
+	VMIDs     []string  `json:"vm_ids"` // VMs with a NIC on the network
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,48 @@
+package dto
+
+import "time"
+
+// AddPortForwardRequest represents a request to forward a host port to a VM
+type AddPortForwardRequest struct {
+	VMID      string `json:"vm_id" validate:"required"`
+	Protocol  string `json:"protocol" validate:"required,oneof=tcp udp"`
+	HostPort  int    `json:"host_port" validate:"min=0,max=65535"` // 0 allocates one from the configured range
+	GuestPort int    `json:"guest_port" validate:"required,min=1,max=65535"`
+}
+
+// AddPortForwardResponse represents the forward that was created
+type AddPortForwardResponse struct {
+	PortForward PortForwardInfo `json:"port_forward"`
+}
+
+// RemovePortForwardRequest represents a request to remove a port forward
+type RemovePortForwardRequest struct {
+	ID string `json:"id" validate:"required"`
+}
+
+// RemovePortForwardResponse represents the response after removing a port forward
+type RemovePortForwardResponse struct {
+	Success bool `json:"success"`
+}
+
+// ListPortForwardsRequest represents a request to list port forwards
+type ListPortForwardsRequest struct {
+	VMID string `json:"vm_id,omitempty"` // Optional, lists all forwards when empty
+}
+
+// ListPortForwardsResponse represents the response with port forwards, ordered by host port
+type ListPortForwardsResponse struct {
+	PortForwards []PortForwardInfo `json:"port_forwards"`
+}
+
+// PortForwardInfo represents a host-to-VM port forward
+type PortForwardInfo struct {
+	ID        string    `json:"id"`
+	VMID      string    `json:"vm_id"`
+	Protocol  string    `json:"protocol"`
+	HostPort  int       `json:"host_port"`
+	GuestPort int       `json:"guest_port"`
+	GuestIP   string    `json:"guest_ip"`
+	GuestIPv6 string    `json:"guest_ipv6"`
+	CreatedAt time.Time `json:"created_at"`
+}
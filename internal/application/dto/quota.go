@@ -0,0 +1,36 @@
+package dto
+
+// GetQuotasRequest represents a request for tenant quotas and their usage
+type GetQuotasRequest struct {
+	Tenant string `json:"tenant,omitempty" validate:"omitempty,max=64"` // Only this tenant when set
+}
+
+// GetQuotasResponse represents the quotas of tenants and the host's overcommit policy
+type GetQuotasResponse struct {
+	Quotas     []TenantQuota `json:"quotas"`
+	Overcommit Overcommit    `json:"overcommit"`
+}
+
+// TenantQuota represents a tenant's quota and how much of it its VMs use
+// Zero limits are unlimited
+type TenantQuota struct {
+	Tenant string     `json:"tenant"`
+	Limit  QuotaUsage `json:"limit"`
+	Usage  QuotaUsage `json:"usage"`
+}
+
+// QuotaUsage represents a number of VMs and the resources allocated to them
+type QuotaUsage struct {
+	VMs    int `json:"vms"`
+	VCPU   int `json:"vcpu"`
+	RAMGB  int `json:"ram_gb"`
+	DiskGB int `json:"disk_gb"`
+}
+
+// Overcommit represents how many times each resource may be allocated
+type Overcommit struct {
+	CPU              float64 `json:"cpu"`
+	RAM              float64 `json:"ram"`
+	Disk             float64 `json:"disk"`
+	ThinProvisioning bool    `json:"thin_provisioning"`
+}
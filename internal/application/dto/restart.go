@@ -0,0 +1,16 @@
+package dto
+
+// SetRestartPolicyRequest represents a request to change when the agent brings a VM back up
+type SetRestartPolicyRequest struct {
+	VMID       string `json:"vm_id" validate:"required"`
+	Policy     string `json:"policy" validate:"required,oneof=never always unless-stopped on-failure"`
+	MaxRetries int    `json:"max_retries,omitempty" validate:"excluded_unless=Policy on-failure,min=0,max=100"` // Restarts after failures before giving up, 0 for no cap
+}
+
+// SetRestartPolicyResponse represents the restart policy now in effect for a VM
+type SetRestartPolicyResponse struct {
+	VMID       string `json:"vm_id"`
+	Policy     string `json:"policy"`
+	MaxRetries int    `json:"max_retries,omitempty"`
+	Autostart  bool   `json:"autostart"` // Started by libvirt when the host boots
+}
@@ -0,0 +1,74 @@
+package dto
+
+import "time"
+
+// SecurityGroupRule represents a rule admitting traffic to or from a VM
+type SecurityGroupRule struct {
+	ID        string `json:"id"`
+	Direction string `json:"direction" validate:"required,oneof=ingress egress"`
+	Protocol  string `json:"protocol" validate:"required,oneof=tcp udp icmp all"`
+	PortMin   int    `json:"port_min" validate:"min=0,max=65535"` // 0 matches all ports
+	PortMax   int    `json:"port_max" validate:"min=0,max=65535"` // Defaults to PortMin
+	CIDR      string `json:"cidr" validate:"omitempty,cidr"`      // IPv4 or IPv6, empty matches any address of both
+}
+
+// CreateSecurityGroupRequest represents a request to create a security group
+type CreateSecurityGroupRequest struct {
+	Name        string              `json:"name" validate:"required,max=64,hostname_rfc1123"`
+	Description string              `json:"description" validate:"max=255"`
+	Rules       []SecurityGroupRule `json:"rules" validate:"dive"`
+}
+
+// DeleteSecurityGroupRequest represents a request to delete a security group
+type DeleteSecurityGroupRequest struct {
+	Group string `json:"group" validate:"required"` // ID or name
+}
+
+// DeleteSecurityGroupResponse represents the response after deleting a security group
+type DeleteSecurityGroupResponse struct {
+	Success bool `json:"success"`
+}
+
+// ListSecurityGroupsResponse represents the response with security groups, ordered by name
+type ListSecurityGroupsResponse struct {
+	SecurityGroups []SecurityGroupInfo `json:"security_groups"`
+}
+
+// AddSecurityGroupRuleRequest represents a request to add a rule to a security group
+type AddSecurityGroupRuleRequest struct {
+	Group string            `json:"group" validate:"required"` // ID or name
+	Rule  SecurityGroupRule `json:"rule"`
+}
+
+// RemoveSecurityGroupRuleRequest represents a request to remove a rule from a security group
+type RemoveSecurityGroupRuleRequest struct {
+	Group  string `json:"group" validate:"required"` // ID or name
+	RuleID string `json:"rule_id" validate:"required"`
+}
+
+// SecurityGroupResponse represents a security group after a change
+type SecurityGroupResponse struct {
+	SecurityGroup SecurityGroupInfo `json:"security_group"`
+}
+
+// AttachSecurityGroupRequest represents a request to attach or detach a security group
+type AttachSecurityGroupRequest struct {
+	VMID  string `json:"vm_id" validate:"required"`
+	Group string `json:"group" validate:"required"` // ID or name
+}
+
+// AttachSecurityGroupResponse represents the security groups of a VM after the change
+type AttachSecurityGroupResponse struct {
+	VMID           string   `json:"vm_id"`
+	SecurityGroups []string `json:"security_groups"`
+}
+
+// SecurityGroupInfo represents a security group
+type SecurityGroupInfo struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Rules       []SecurityGroupRule `json:"rules"`
+	VMIDs       []string            `json:"vm_ids"` // VMs the group is attached to
+	CreatedAt   time.Time           `json:"created_at"`
+}
@@ -2,19 +2,32 @@ package dto
 
 // CreateVMRequest represents a request to create a VM
 type CreateVMRequest struct {
-	Name     string            `json:"name" validate:"required,min=3,max=63,hostname"`
-	VCPU     int               `json:"vcpu" validate:"required,min=1,max=32"`
-	RAMGB    int               `json:"ram_gb" validate:"required,min=1,max=128"`
-	DiskGB   int               `json:"disk_gb" validate:"required,min=10,max=1000"`
-	Template string            `json:"template" validate:"required,oneof=ubuntu-22.04 ubuntu-20.04 debian-12 debian-11"`
-	Metadata map[string]string `json:"metadata,omitempty"`
+	Name        string            `json:"name" validate:"required,min=3,max=63,hostname"`
+	Flavor      string            `json:"flavor,omitempty" validate:"omitempty,max=63,hostname_rfc1123"` // Sizes the VM instead of vcpu, ram_gb and disk_gb
+	VCPU        int               `json:"vcpu" validate:"required_without=Flavor,excluded_with=Flavor,omitempty,min=1,max=32"`
+	RAMGB       int               `json:"ram_gb" validate:"required_without=Flavor,excluded_with=Flavor,omitempty,min=1,max=128"`
+	DiskGB      int               `json:"disk_gb" validate:"required_without=Flavor,excluded_with=Flavor,omitempty,min=10,max=1000"`
+	Template    string            `json:"template" validate:"required,min=3,max=63,hostname"`
+	NetworkMode string            `json:"network_mode,omitempty" validate:"omitempty,oneof=nat bridge"` // Defaults to the agent's mode
+	Networks    []string          `json:"networks,omitempty" validate:"max=8,unique,dive,required"`     // Private networks (ID or name) to add a NIC on, in order
+	Limits      *VMLimits         `json:"limits,omitempty"`                                             // Unset values take the agent defaults
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	// TTLSeconds or ExpiresAt make the VM ephemeral: its lease runs out this long
+	// after creation, or at this Unix timestamp
+	TTLSeconds   int64  `json:"ttl_seconds,omitempty" validate:"excluded_with=ExpiresAt,omitempty,min=60"`
+	ExpiresAt    int64  `json:"expires_at,omitempty" validate:"min=0"`
+	ExpiryAction string `json:"expiry_action,omitempty" validate:"excluded_without_all=TTLSeconds ExpiresAt,omitempty,oneof=stop delete"` // Defaults to the agent's default action
+	// RestartPolicy decides when the agent brings the VM back up, defaults to the agent's default policy
+	RestartPolicy     string `json:"restart_policy,omitempty" validate:"omitempty,oneof=never always unless-stopped on-failure"`
+	RestartMaxRetries int    `json:"restart_max_retries,omitempty" validate:"excluded_unless=RestartPolicy on-failure,min=0,max=100"` // 0 for no cap
 }
 
 // CreateVMResponse represents the response after creating a VM
 type CreateVMResponse struct {
-	VMID      string `json:"vm_id"`
-	IPAddress string `json:"ip_address"`
-	Status    string `json:"status"`
+	VMID       string        `json:"vm_id"`
+	IPAddress  string        `json:"ip_address"` // Primary address, IPv4 when the VM has one
+	Interfaces []VMInterface `json:"interfaces"` // All known addresses, primary NIC first
+	Status     string        `json:"status"`
 }
 
 // DeleteVMRequest represents a request to delete a VM
@@ -55,16 +68,28 @@ type GetVMStatusRequest struct {
 
 // GetVMStatusResponse represents detailed VM status
 type GetVMStatusResponse struct {
-	VMID            string  `json:"vm_id"`
-	Name            string  `json:"name"`
-	Status          string  `json:"status"`
-	VCPU            int     `json:"vcpu"`
-	RAMGB           int     `json:"ram_gb"`
-	DiskGB          int     `json:"disk_gb"`
-	IPAddress       string  `json:"ip_address"`
-	UptimeSeconds   int64   `json:"uptime_seconds"`
-	CPUUsagePercent float32 `json:"cpu_usage_percent"`
-	RAMUsagePercent float32 `json:"ram_usage_percent"`
+	VMID            string            `json:"vm_id"`
+	Name            string            `json:"name"`
+	Status          string            `json:"status"`
+	Flavor          string            `json:"flavor,omitempty"` // Empty for VMs created with raw sizes
+	VCPU            int               `json:"vcpu"`
+	RAMGB           int               `json:"ram_gb"`
+	DiskGB          int               `json:"disk_gb"`
+	IPAddress       string            `json:"ip_address"`
+	Interfaces      []VMInterface     `json:"interfaces"`
+	NetworkMode     string            `json:"network_mode"`
+	UptimeSeconds   int64             `json:"uptime_seconds"`
+	CPUUsagePercent float32           `json:"cpu_usage_percent"`
+	RAMUsagePercent float32           `json:"ram_usage_percent"`
+	Guest           *GuestInfo        `json:"guest,omitempty"` // nil when the guest agent is unavailable
+	SecurityGroups  []string          `json:"security_groups"`
+	Networks        []string          `json:"networks"` // IDs of private networks with an additional NIC
+	Limits          VMLimits          `json:"limits"`   // Effective limits, zero values are unlimited
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	ExpiresAt       int64             `json:"expires_at,omitempty"`    // Unix timestamp, 0 when the VM does not expire
+	ExpiryAction    string            `json:"expiry_action,omitempty"` // "stop" or "delete"
+	RestartPolicy   string            `json:"restart_policy"`
+	Restarts        int               `json:"restarts,omitempty"` // Restarts by the agent since the VM last stayed up
 }
 
 // ListVMsRequest represents a request to list all VMs
@@ -79,8 +104,23 @@ type ListVMsResponse struct {
 
 // VMInfo represents basic VM information
 type VMInfo struct {
-	VMID      string `json:"vm_id"`
-	Name      string `json:"name"`
-	Status    string `json:"status"`
-	IPAddress string `json:"ip_address"`
+	VMID       string        `json:"vm_id"`
+	Name       string        `json:"name"`
+	Status     string        `json:"status"`
+	IPAddress  string        `json:"ip_address"`
+	Interfaces []VMInterface `json:"interfaces"`
+}
+
+// VMInterface represents a VM network interface with its addresses
+type VMInterface struct {
+	Name       string      `json:"name"`
+	MACAddress string      `json:"mac_address"`
+	Addresses  []IPAddress `json:"addresses"`
+}
+
+// IPAddress represents an address of a VM interface
+type IPAddress struct {
+	Address string `json:"address"`
+	Prefix  int    `json:"prefix"`
+	Family  string `json:"family"` // ipv4 or ipv6
 }
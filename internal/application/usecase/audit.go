@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// Events returned when a query sets no limit
+const defaultAuditLimit = 100
+
+// QueryAuditLogUseCase handles reading the audit log
+type QueryAuditLogUseCase struct {
+	auditLog  service.AuditLog
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+// NewQueryAuditLogUseCase creates a new QueryAuditLog use case
+func NewQueryAuditLogUseCase(
+	auditLog service.AuditLog,
+	logger *zap.Logger,
+) *QueryAuditLogUseCase {
+	return &QueryAuditLogUseCase{
+		auditLog:  auditLog,
+		validator: newValidator(),
+		logger:    logger,
+	}
+}
+
+// Execute returns the newest matching events and whether the log is intact
+func (uc *QueryAuditLogUseCase) Execute(ctx context.Context, req *dto.QueryAuditLogRequest) (*dto.QueryAuditLogResponse, error) {
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+	if !req.Since.IsZero() && !req.Until.IsZero() && !req.Until.After(req.Since) {
+		return nil, errors.New(errors.ErrCodeValidation, "until must be after since", nil)
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultAuditLimit
+	}
+
+	result, err := uc.auditLog.Query(ctx, service.AuditFilter{
+		Since: req.Since,
+		Until: req.Until,
+		VMID:  req.VMID,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !result.Intact {
+		uc.logger.Warn("Audit log hash chain is broken", zap.String("error", result.IntegrityError))
+	}
+
+	events := make([]dto.AuditEventInfo, len(result.Events))
+	for i, event := range result.Events {
+		events[i] = dto.AuditEventInfo{
+			Seq:        event.Seq,
+			Time:       event.Time,
+			Method:     event.Method,
+			Subject:    event.Subject,
+			Role:       string(event.Role),
+			Tenant:     event.Tenant,
+			AuthMethod: string(event.AuthMethod),
+			Peer:       event.Peer,
+			VMID:       event.VMID,
+			Summary:    event.Summary,
+			Result:     string(event.Result),
+			Error:      event.Error,
+			Duration:   event.Duration,
+			Hash:       event.Hash,
+		}
+	}
+
+	return &dto.QueryAuditLogResponse{
+		Events:         events,
+		Intact:         result.Intact,
+		IntegrityError: result.IntegrityError,
+	}, nil
+}
+
+// recordSystemEvent writes a change the agent made to a VM on its own to the
+// audit log, without failing the caller; auditLog may be nil
+func recordSystemEvent(ctx context.Context, auditLog service.AuditLog, action, vmID string, details map[string]any, err error, logger *zap.Logger) {
+	if auditLog == nil {
+		return
+	}
+
+	event := &entity.AuditEvent{
+		Time:    time.Now(),
+		Method:  "agent/" + action,
+		Subject: entity.AuditSubjectSystem,
+		VMID:    vmID,
+		Result:  entity.AuditResultSuccess,
+	}
+	if len(details) > 0 {
+		summary, _ := json.Marshal(details)
+		event.Summary = string(summary)
+	}
+	if err != nil {
+		event.Result = entity.AuditResultFailure
+		event.Error = err.Error()
+	}
+	if err := auditLog.Record(context.WithoutCancel(ctx), event); err != nil {
+		logger.Error("Failed to record audit event", zap.String("method", event.Method), zap.Error(err))
+	}
+}
@@ -0,0 +1,449 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// overlayCommitTimeout bounds merging a backup overlay back into a running VM's disk
+const overlayCommitTimeout = 30 * time.Minute
+
+// SetBackupPolicyUseCase handles configuring scheduled backups of a VM
+type SetBackupPolicyUseCase struct {
+	vmRepo    repository.VMRepository
+	scheduler service.BackupScheduler
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+// NewSetBackupPolicyUseCase creates a new SetBackupPolicy use case
+func NewSetBackupPolicyUseCase(
+	vmRepo repository.VMRepository,
+	scheduler service.BackupScheduler,
+	logger *zap.Logger,
+) *SetBackupPolicyUseCase {
+	return &SetBackupPolicyUseCase{
+		vmRepo:    vmRepo,
+		scheduler: scheduler,
+		validator: newValidator(),
+		logger:    logger,
+	}
+}
+
+// Execute sets or clears the backup policy of a VM
+func (uc *SetBackupPolicyUseCase) Execute(ctx context.Context, req *dto.SetBackupPolicyRequest) (*dto.SetBackupPolicyResponse, error) {
+	uc.logger.Info("Setting backup policy",
+		zap.String("vm_id", req.VMID),
+		zap.String("schedule", req.Schedule),
+	)
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	// 2. Get VM from repository
+	vm, err := uc.vmRepo.FindByID(ctx, req.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	// 3. Persist policy with the VM, so the schedule never runs ahead of it
+	var previous *entity.BackupPolicy
+	vm, err = uc.vmRepo.Update(ctx, vm.ID, func(vm *entity.VM) {
+		previous = vm.Backup
+		if req.Schedule == "" {
+			vm.Backup = nil
+		} else {
+			vm.Backup = &entity.BackupPolicy{
+				Schedule:   req.Schedule,
+				KeepDaily:  req.KeepDaily,
+				KeepWeekly: req.KeepWeekly,
+			}
+		}
+		vm.UpdatedAt = time.Now()
+	})
+	if vmGone(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save backup policy", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	// 4. Update schedule
+	if req.Schedule == "" {
+		uc.scheduler.Unschedule(vm.ID)
+	} else if err := uc.scheduler.Schedule(vm.ID, req.Schedule); err != nil {
+		// The previous schedule is still in place; put its policy back
+		if _, saveErr := uc.vmRepo.Update(ctx, vm.ID, func(vm *entity.VM) {
+			vm.Backup = previous
+		}); saveErr != nil && !vmGone(saveErr) {
+			uc.logger.Error("Failed to restore backup policy",
+				zap.String("vm_id", vm.ID),
+				zap.Error(saveErr),
+			)
+		}
+		return nil, errors.New(errors.ErrCodeValidation, "invalid backup schedule", err).
+			WithContext("schedule", req.Schedule)
+	}
+
+	return &dto.SetBackupPolicyResponse{
+		VMID:       vm.ID,
+		Schedule:   req.Schedule,
+		KeepDaily:  req.KeepDaily,
+		KeepWeekly: req.KeepWeekly,
+	}, nil
+}
+
+// CreateBackupUseCase handles taking a consistent backup of a VM's disk
+type CreateBackupUseCase struct {
+	hypervisor        service.HypervisorService
+	storage           service.StorageService
+	target            service.BackupTarget
+	vmRepo            repository.VMRepository
+	backupRepo        repository.BackupRepository
+	defaultKeepDaily  int
+	defaultKeepWeekly int
+	validator         *validator.Validate
+	logger            *zap.Logger
+}
+
+// NewCreateBackupUseCase creates a new CreateBackup use case
+// The default retention applies to VMs whose policy keeps nothing by either rule
+func NewCreateBackupUseCase(
+	hypervisor service.HypervisorService,
+	storage service.StorageService,
+	target service.BackupTarget,
+	vmRepo repository.VMRepository,
+	backupRepo repository.BackupRepository,
+	defaultKeepDaily int,
+	defaultKeepWeekly int,
+	logger *zap.Logger,
+) *CreateBackupUseCase {
+	return &CreateBackupUseCase{
+		hypervisor:        hypervisor,
+		storage:           storage,
+		target:            target,
+		vmRepo:            vmRepo,
+		backupRepo:        backupRepo,
+		defaultKeepDaily:  defaultKeepDaily,
+		defaultKeepWeekly: defaultKeepWeekly,
+		validator:         newValidator(),
+		logger:            logger,
+	}
+}
+
+// Execute backs up a VM and applies its retention policy
+func (uc *CreateBackupUseCase) Execute(ctx context.Context, req *dto.CreateBackupRequest) (*dto.CreateBackupResponse, error) {
+	uc.logger.Info("Creating backup", zap.String("vm_id", req.VMID))
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	// 2. Get VM from repository
+	vm, err := uc.vmRepo.FindByID(ctx, req.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	diskPath := vm.DiskPath
+	if diskPath == "" {
+		if diskPath, err = uc.storage.GetDiskPath(ctx, vm.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. Freeze the disk (running VMs write to an overlay meanwhile)
+	release, err := uc.hypervisor.SnapshotDisk(ctx, vm.ID, diskPath)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeHypervisor, "failed to snapshot disk", err).
+			WithContext("vm_id", vm.ID)
+	}
+
+	// 4. Copy the frozen disk, then always merge the overlay back
+	disk, exportErr := uc.storage.ExportDisk(ctx, vm.ID)
+
+	releaseCtx, cancel := context.WithTimeout(context.Background(), overlayCommitTimeout)
+	releaseErr := release(releaseCtx)
+	cancel()
+
+	if exportErr != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to copy disk", exportErr).
+			WithContext("vm_id", vm.ID)
+	}
+	defer disk.Close()
+
+	if releaseErr != nil {
+		return nil, errors.New(errors.ErrCodeHypervisor, "failed to commit backup overlay", releaseErr).
+			WithContext("vm_id", vm.ID)
+	}
+
+	// 5. Upload to backup target
+	backupID := uuid.NewString()
+	createdAt := time.Now().UTC()
+	key := fmt.Sprintf("%s/%s-%s.qcow2", vm.ID, createdAt.Format("20060102T150405Z"), backupID[:8])
+
+	location, err := uc.target.Put(ctx, key, disk, disk.SizeBytes)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to store backup", err).
+			WithContext("vm_id", vm.ID).
+			WithContext("target", uc.target.Name())
+	}
+
+	backup := &entity.Backup{
+		ID:        backupID,
+		VMID:      vm.ID,
+		Target:    uc.target.Name(),
+		Location:  location,
+		SizeBytes: disk.SizeBytes,
+		SHA256:    disk.SHA256,
+		CreatedAt: createdAt,
+	}
+
+	if err := uc.backupRepo.Save(ctx, backup); err != nil {
+		_ = uc.target.Delete(ctx, location)
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save backup record", err).
+			WithContext("vm_id", vm.ID)
+	}
+
+	// 6. Apply retention
+	uc.applyRetention(ctx, vm)
+
+	uc.logger.Info("Backup created successfully",
+		zap.String("vm_id", vm.ID),
+		zap.String("backup_id", backup.ID),
+		zap.Int64("size_bytes", backup.SizeBytes),
+	)
+
+	return &dto.CreateBackupResponse{
+		Backup: toBackupInfo(backup),
+	}, nil
+}
+
+// applyRetention deletes backups of vm that its policy no longer keeps
+func (uc *CreateBackupUseCase) applyRetention(ctx context.Context, vm *entity.VM) {
+	keepDaily, keepWeekly := uc.defaultKeepDaily, uc.defaultKeepWeekly
+	if vm.Backup != nil && (vm.Backup.KeepDaily > 0 || vm.Backup.KeepWeekly > 0) {
+		keepDaily, keepWeekly = vm.Backup.KeepDaily, vm.Backup.KeepWeekly
+	}
+
+	backups, err := uc.backupRepo.FindByVM(ctx, vm.ID)
+	if err != nil {
+		uc.logger.Warn("Failed to list backups for retention", zap.Error(err))
+		return
+	}
+
+	for _, backup := range entity.ExpiredBackups(backups, keepDaily, keepWeekly) {
+		// Backups taken to another target stay until that target is configured again
+		if backup.Target != uc.target.Name() {
+			continue
+		}
+		if err := uc.target.Delete(ctx, backup.Location); err != nil {
+			uc.logger.Warn("Failed to delete expired backup",
+				zap.String("backup_id", backup.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		if err := uc.backupRepo.Delete(ctx, backup.ID); err != nil {
+			uc.logger.Error("Failed to delete backup record", zap.Error(err))
+		}
+		uc.logger.Info("Expired backup deleted",
+			zap.String("vm_id", vm.ID),
+			zap.String("backup_id", backup.ID),
+		)
+	}
+}
+
+// deleteBackups deletes the backups of a deleted VM; RestoreBackup needs the
+// VM, so nothing could restore them anymore. Backups taken to another
+// target are left alone like in applyRetention
+func deleteBackups(ctx context.Context, backupRepo repository.BackupRepository, target service.BackupTarget, vmID string, logger *zap.Logger) {
+	backups, err := backupRepo.FindByVM(ctx, vmID)
+	if err != nil {
+		logger.Warn("Failed to list VM backups", zap.String("vm_id", vmID), zap.Error(err))
+		return
+	}
+
+	for _, backup := range backups {
+		if backup.Target != target.Name() {
+			continue
+		}
+		if err := target.Delete(ctx, backup.Location); err != nil {
+			logger.Warn("Failed to delete VM backup",
+				zap.String("backup_id", backup.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		if err := backupRepo.Delete(ctx, backup.ID); err != nil {
+			logger.Error("Failed to delete backup record", zap.Error(err))
+		}
+	}
+}
+
+// ListBackupsUseCase handles listing stored backups
+type ListBackupsUseCase struct {
+	backupRepo repository.BackupRepository
+	logger     *zap.Logger
+}
+
+// NewListBackupsUseCase creates a new ListBackups use case
+func NewListBackupsUseCase(
+	backupRepo repository.BackupRepository,
+	logger *zap.Logger,
+) *ListBackupsUseCase {
+	return &ListBackupsUseCase{
+		backupRepo: backupRepo,
+		logger:     logger,
+	}
+}
+
+// Execute lists backups, optionally of a single VM
+func (uc *ListBackupsUseCase) Execute(ctx context.Context, req *dto.ListBackupsRequest) (*dto.ListBackupsResponse, error) {
+	uc.logger.Debug("Listing backups", zap.String("vm_id", req.VMID))
+
+	var (
+		backups []*entity.Backup
+		err     error
+	)
+	if req.VMID != "" {
+		backups, err = uc.backupRepo.FindByVM(ctx, req.VMID)
+	} else {
+		backups, err = uc.backupRepo.FindAll(ctx)
+	}
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to list backups", err)
+	}
+
+	infos := make([]dto.BackupInfo, 0, len(backups))
+	for _, backup := range backups {
+		infos = append(infos, toBackupInfo(backup))
+	}
+
+	return &dto.ListBackupsResponse{
+		Backups: infos,
+	}, nil
+}
+
+// RestoreBackupUseCase handles restoring a VM's disk from a backup
+type RestoreBackupUseCase struct {
+	hypervisor service.HypervisorService
+	storage    service.StorageService
+	target     service.BackupTarget
+	vmRepo     repository.VMRepository
+	backupRepo repository.BackupRepository
+	validator  *validator.Validate
+	logger     *zap.Logger
+}
+
+// NewRestoreBackupUseCase creates a new RestoreBackup use case
+func NewRestoreBackupUseCase(
+	hypervisor service.HypervisorService,
+	storage service.StorageService,
+	target service.BackupTarget,
+	vmRepo repository.VMRepository,
+	backupRepo repository.BackupRepository,
+	logger *zap.Logger,
+) *RestoreBackupUseCase {
+	return &RestoreBackupUseCase{
+		hypervisor: hypervisor,
+		storage:    storage,
+		target:     target,
+		vmRepo:     vmRepo,
+		backupRepo: backupRepo,
+		validator:  newValidator(),
+		logger:     logger,
+	}
+}
+
+// Execute replaces a stopped VM's disk with the contents of a backup
+func (uc *RestoreBackupUseCase) Execute(ctx context.Context, req *dto.RestoreBackupRequest) (*dto.RestoreBackupResponse, error) {
+	uc.logger.Info("Restoring backup", zap.String("backup_id", req.BackupID))
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	// 2. Get backup and its VM
+	backup, err := uc.backupRepo.FindByID(ctx, req.BackupID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "backup not found", err).
+			WithContext("backup_id", req.BackupID)
+	}
+	if backup.Target != uc.target.Name() {
+		return nil, errors.New(errors.ErrCodeInvalidState, "backup is stored on a different target", nil).
+			WithContext("backup_id", backup.ID).
+			WithContext("target", backup.Target)
+	}
+
+	vm, err := uc.vmRepo.FindByID(ctx, backup.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", backup.VMID)
+	}
+
+	// 3. Disk can only be replaced while the VM is stopped
+	status, err := uc.hypervisor.GetVMStatus(ctx, vm.ID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeHypervisor, "failed to get VM status", err).
+			WithContext("vm_id", vm.ID)
+	}
+	if status.Status != entity.VMStatusStopped {
+		return nil, errors.New(errors.ErrCodeInvalidState, "VM must be stopped before restore", nil).
+			WithContext("vm_id", vm.ID).
+			WithContext("status", string(status.Status))
+	}
+
+	// 4. Download and replace disk
+	data, err := uc.target.Get(ctx, backup.Location)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to read backup", err).
+			WithContext("backup_id", backup.ID)
+	}
+	defer data.Close()
+
+	if _, err := uc.storage.RestoreDisk(ctx, vm.ID, data, backup.SHA256); err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to restore disk", err).
+			WithContext("backup_id", backup.ID)
+	}
+
+	uc.logger.Info("Backup restored successfully",
+		zap.String("vm_id", vm.ID),
+		zap.String("backup_id", backup.ID),
+	)
+
+	return &dto.RestoreBackupResponse{
+		VMID:   vm.ID,
+		Status: string(status.Status),
+	}, nil
+}
+
+func toBackupInfo(backup *entity.Backup) dto.BackupInfo {
+	return dto.BackupInfo{
+		BackupID:  backup.ID,
+		VMID:      backup.VMID,
+		Target:    backup.Target,
+		Location:  backup.Location,
+		SizeBytes: backup.SizeBytes,
+		SHA256:    backup.SHA256,
+		CreatedAt: backup.CreatedAt,
+	}
+}
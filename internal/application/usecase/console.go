@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// AttachConsoleUseCase handles attaching to a VM's serial console
+type AttachConsoleUseCase struct {
+	hypervisor service.HypervisorService
+	vmRepo     repository.VMRepository
+	validator  *validator.Validate
+	logger     *zap.Logger
+}
+
+// NewAttachConsoleUseCase creates a new AttachConsole use case
+func NewAttachConsoleUseCase(
+	hypervisor service.HypervisorService,
+	vmRepo repository.VMRepository,
+	logger *zap.Logger,
+) *AttachConsoleUseCase {
+	return &AttachConsoleUseCase{
+		hypervisor: hypervisor,
+		vmRepo:     vmRepo,
+		validator:  newValidator(),
+		logger:     logger,
+	}
+}
+
+// Execute opens the serial console of a running VM
+// The caller must close the returned stream
+func (uc *AttachConsoleUseCase) Execute(ctx context.Context, req *dto.AttachConsoleRequest) (io.ReadWriteCloser, error) {
+	uc.logger.Info("Attaching console", zap.String("vm_id", req.VMID))
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	// 2. VM must exist and be running
+	if err := requireRunning(ctx, uc.hypervisor, uc.vmRepo, req.VMID); err != nil {
+		return nil, err
+	}
+
+	// 3. Open console
+	console, err := uc.hypervisor.OpenConsole(ctx, req.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeHypervisor, "failed to open console", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	return console, nil
+}
+
+// CreateVNCTokenUseCase handles issuing one-time tokens for the VNC proxy
+type CreateVNCTokenUseCase struct {
+	hypervisor service.HypervisorService
+	vmRepo     repository.VMRepository
+	tokens     service.ConsoleTokenService
+	proxyURL   string
+	validator  *validator.Validate
+	logger     *zap.Logger
+}
+
+// NewCreateVNCTokenUseCase creates a new CreateVNCToken use case
+// tokens is nil when the VNC proxy is disabled
+func NewCreateVNCTokenUseCase(
+	hypervisor service.HypervisorService,
+	vmRepo repository.VMRepository,
+	tokens service.ConsoleTokenService,
+	proxyURL string,
+	logger *zap.Logger,
+) *CreateVNCTokenUseCase {
+	return &CreateVNCTokenUseCase{
+		hypervisor: hypervisor,
+		vmRepo:     vmRepo,
+		tokens:     tokens,
+		proxyURL:   proxyURL,
+		validator:  newValidator(),
+		logger:     logger,
+	}
+}
+
+// Execute issues a token for a single VNC connection to a running VM
+func (uc *CreateVNCTokenUseCase) Execute(ctx context.Context, req *dto.CreateVNCTokenRequest) (*dto.CreateVNCTokenResponse, error) {
+	uc.logger.Info("Creating VNC token", zap.String("vm_id", req.VMID))
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	if uc.tokens == nil {
+		return nil, errors.New(errors.ErrCodeInvalidState, "VNC proxy is disabled", nil)
+	}
+
+	// 2. VM must exist and be running
+	if err := requireRunning(ctx, uc.hypervisor, uc.vmRepo, req.VMID); err != nil {
+		return nil, err
+	}
+
+	// 3. Issue token
+	token, expiresAt, err := uc.tokens.Issue(req.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to issue VNC token", err)
+	}
+
+	return &dto.CreateVNCTokenResponse{
+		Token:     token,
+		URL:       uc.proxyURL + "?token=" + url.QueryEscape(token),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// requireRunning returns an error unless the VM exists and is running
+func requireRunning(ctx context.Context, hypervisor service.HypervisorService, vmRepo repository.VMRepository, vmID string) error {
+	if _, err := vmRepo.FindByID(ctx, vmID); err != nil {
+		return errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", vmID)
+	}
+
+	status, err := hypervisor.GetVMStatus(ctx, vmID)
+	if err != nil {
+		return errors.New(errors.ErrCodeHypervisor, "failed to get VM status", err).
+			WithContext("vm_id", vmID)
+	}
+	if status.Status != entity.VMStatusRunning {
+		return errors.New(errors.ErrCodeInvalidState, "VM is not running", nil).
+			WithContext("vm_id", vmID).
+			WithContext("status", string(status.Status))
+	}
+
+	return nil
+}
@@ -2,11 +2,13 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 
 	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
@@ -19,17 +21,41 @@ type CreateVMUseCase struct {
 	storage      service.StorageService
 	vmRepo       repository.VMRepository
 	resourceRepo repository.ResourceRepository
+	networkModes service.NetworkModes
+	ipam         service.IPAMService
+	firewall     service.FirewallService
+	limits       entity.VMLimits
+	networkRepo  repository.NetworkRepository
+	networks     service.PrivateNetworkService
+	quotas       *QuotaReserver
+	flavorRepo   repository.FlavorRepository
+	expiry       entity.ExpiryPolicy
+	restart      entity.RestartPolicy
 	validator    *validator.Validate
 	logger       *zap.Logger
 }
 
 // NewCreateVMUseCase creates a new CreateVM use case
+// ipam is nil when static IP management is disabled, firewall when filtering is disabled
+// limits are the agent defaults for limits the VM does not set, quotas cap the VMs of each tenant
+// flavorRepo resolves the flavors requests may size VMs by, expiry bounds the leases of ephemeral VMs
+// restart is the restart policy of VMs created without one
 func NewCreateVMUseCase(
 	hypervisor service.HypervisorService,
 	network service.NetworkService,
 	storage service.StorageService,
 	vmRepo repository.VMRepository,
 	resourceRepo repository.ResourceRepository,
+	networkModes service.NetworkModes,
+	ipam service.IPAMService,
+	firewall service.FirewallService,
+	limits entity.VMLimits,
+	networkRepo repository.NetworkRepository,
+	networks service.PrivateNetworkService,
+	quotas *QuotaReserver,
+	flavorRepo repository.FlavorRepository,
+	expiry entity.ExpiryPolicy,
+	restart entity.RestartPolicy,
 	logger *zap.Logger,
 ) *CreateVMUseCase {
 	return &CreateVMUseCase{
@@ -38,7 +64,17 @@ func NewCreateVMUseCase(
 		storage:      storage,
 		vmRepo:       vmRepo,
 		resourceRepo: resourceRepo,
-		validator:    validator.New(),
+		networkModes: networkModes,
+		ipam:         ipam,
+		firewall:     firewall,
+		limits:       limits,
+		networkRepo:  networkRepo,
+		networks:     networks,
+		quotas:       quotas,
+		flavorRepo:   flavorRepo,
+		expiry:       expiry,
+		restart:      restart,
+		validator:    newValidator(),
 		logger:       logger,
 	}
 }
@@ -47,6 +83,7 @@ func NewCreateVMUseCase(
 func (uc *CreateVMUseCase) Execute(ctx context.Context, req *dto.CreateVMRequest) (*dto.CreateVMResponse, error) {
 	uc.logger.Info("Creating VM",
 		zap.String("name", req.Name),
+		zap.String("flavor", req.Flavor),
 		zap.Int("vcpu", req.VCPU),
 		zap.Int("ram_gb", req.RAMGB),
 	)
@@ -57,6 +94,44 @@ func (uc *CreateVMUseCase) Execute(ctx context.Context, req *dto.CreateVMRequest
 			WithContext("vm_name", req.Name)
 	}
 
+	mode := uc.networkModes.Resolve(entity.NetworkMode(req.NetworkMode))
+	if !uc.networkModes.Supports(mode) {
+		return nil, errors.New(errors.ErrCodeValidation, "network mode not available on this agent", nil).
+			WithContext("vm_name", req.Name).
+			WithContext("network_mode", string(mode))
+	}
+
+	networkIDs, networkNames, err := resolveNetworks(ctx, uc.networkRepo, uc.networks, req.Networks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Size the VM by its flavor when it names one
+	vcpu, ramGB, diskGB := req.VCPU, req.RAMGB, req.DiskGB
+	limitDefaults := uc.limits
+	var flavor *entity.Flavor
+	if req.Flavor != "" {
+		flavor, err = uc.flavorRepo.FindByName(ctx, req.Flavor)
+		if err != nil {
+			return nil, errors.New(errors.ErrCodeValidation, "unknown flavor", err).
+				WithContext("vm_name", req.Name).
+				WithContext("flavor", req.Flavor)
+		}
+		if !flavor.AllowsTemplate(req.Template) {
+			return nil, errors.New(errors.ErrCodeValidation, "template not allowed for flavor", nil).
+				WithContext("vm_name", req.Name).
+				WithContext("flavor", flavor.Name).
+				WithContext("template", req.Template)
+		}
+		vcpu, ramGB, diskGB = flavor.VCPU, flavor.RAMGB, flavor.DiskGB
+		limitDefaults = flavor.Limits.WithDefaults(uc.limits)
+	}
+
+	expiry, err := newExpiry(req.TTLSeconds, req.ExpiresAt, req.ExpiryAction, uc.expiry, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	// 2. Check if VM already exists
 	exists, err := uc.vmRepo.Exists(ctx, req.Name)
 	if err != nil {
@@ -74,15 +149,26 @@ func (uc *CreateVMUseCase) Execute(ctx context.Context, req *dto.CreateVMRequest
 		return nil, errors.New(errors.ErrCodeInternal, "failed to check resources", err)
 	}
 
-	if !resources.CanAllocate(req.VCPU, req.RAMGB, req.DiskGB) {
+	if !resources.CanHold(vcpu, ramGB, diskGB) {
+		return nil, errors.New(errors.ErrCodeInvalidState, "VM does not fit on this host", nil).
+			WithContext("flavor", req.Flavor).
+			WithContext("vcpu", vcpu).
+			WithContext("ram_gb", ramGB).
+			WithContext("disk_gb", diskGB)
+	}
+	if !resources.CanAllocate(vcpu, ramGB, diskGB) {
 		return nil, errors.New(errors.ErrCodeResourceLimit, "insufficient resources", nil).
-			WithContext("requested_vcpu", req.VCPU).
-			WithContext("requested_ram_gb", req.RAMGB).
-			WithContext("requested_disk_gb", req.DiskGB).
+			WithContext("requested_vcpu", vcpu).
+			WithContext("requested_ram_gb", ramGB).
+			WithContext("requested_disk_gb", diskGB).
 			WithContext("available_vcpu", resources.AvailableCPU).
 			WithContext("available_ram_gb", resources.AvailableRAMGB).
 			WithContext("available_disk_gb", resources.AvailableDiskGB)
 	}
+	if err := uc.quotas.Reserve(ctx, req.Name, req.Metadata[entity.MetadataTenant], vcpu, ramGB, diskGB); err != nil {
+		return nil, err
+	}
+	defer uc.quotas.Release(req.Name) // Once saved, the VM counts by itself
 
 	// 4. Get base image
 	baseImage, err := uc.storage.GetImage(ctx, req.Template)
@@ -92,7 +178,7 @@ func (uc *CreateVMUseCase) Execute(ctx context.Context, req *dto.CreateVMRequest
 	}
 
 	// 5. Create disk
-	diskPath, err := uc.storage.CreateDisk(ctx, req.Name, baseImage, req.DiskGB)
+	diskPath, err := uc.storage.CreateDisk(ctx, req.Name, baseImage, diskGB)
 	if err != nil {
 		return nil, errors.New(errors.ErrCodeStorage, "failed to create disk", err).
 			WithContext("vm_name", req.Name)
@@ -101,28 +187,68 @@ func (uc *CreateVMUseCase) Execute(ctx context.Context, req *dto.CreateVMRequest
 	// 6. Create VM in hypervisor
 	vmSpec := &service.VMSpec{
 		Name:     req.Name,
-		VCPU:     req.VCPU,
-		RAMGB:    req.RAMGB,
-		DiskGB:   req.DiskGB,
+		VCPU:     vcpu,
+		RAMGB:    ramGB,
+		DiskGB:   diskGB,
 		Template: req.Template,
 		DiskPath: diskPath,
+		Network:  mode,
+		Networks: networkNames,
+	}
+	if flavor != nil {
+		vmSpec.CPUMode = flavor.CPUMode
+	}
+	if req.Limits != nil {
+		requested := toVMLimits(req.Limits)
+		vmSpec.Limits = effectiveLimits(&requested, limitDefaults)
+	} else {
+		vmSpec.Limits = effectiveLimits(nil, limitDefaults)
+	}
+
+	alloc, err := reserveAddress(ctx, uc.ipam, req.Name, mode)
+	if err != nil {
+		_ = uc.storage.DeleteDisk(ctx, req.Name)
+		return nil, err
+	}
+	if alloc != nil {
+		vmSpec.IP = alloc.IP
+		vmSpec.MAC = alloc.MAC
+	}
+
+	vmSpec.Filter, err = prepareFilter(ctx, uc.firewall, req.Name, nil)
+	if err != nil {
+		_ = uc.storage.DeleteDisk(ctx, req.Name)
+		releaseAddress(ctx, uc.ipam, req.Name, uc.logger)
+		return nil, err
 	}
 
 	vm, err := uc.hypervisor.CreateVM(ctx, vmSpec)
 	if err != nil {
-		// Cleanup disk on failure
+		// Cleanup disk, address and filter on failure
 		_ = uc.storage.DeleteDisk(ctx, req.Name)
+		releaseAddress(ctx, uc.ipam, req.Name, uc.logger)
+		removeFilter(ctx, uc.firewall, req.Name, uc.logger)
 		return nil, errors.New(errors.ErrCodeHypervisor, "failed to create VM", err).
 			WithContext("vm_name", req.Name)
 	}
 
-	// 7. Get IP address
-	ip, err := uc.network.GetVMIP(ctx, vm.ID)
+	vm.Limits = limitsOrNil(vmSpec.Limits)
+	vm.Networks = networkIDs
+	vm.Metadata = req.Metadata
+	vm.Flavor = req.Flavor
+	vm.Expiry = expiry
+	vm.Restart = newRestart(req.RestartPolicy, req.RestartMaxRetries, uc.restart)
+	applyAutostart(ctx, uc.hypervisor, vm, uc.logger)
+
+	// 7. Get IP addresses; the primary one is known up front when it was reserved
+	ifaces, err := uc.network.GetVMIP(ctx, vm.ID)
 	if err != nil {
-		uc.logger.Warn("Failed to get VM IP", zap.Error(err))
-		ip = "" // Continue without IP
+		uc.logger.Warn("Failed to get VM IP", zap.Error(err)) // Continue without IP
+	}
+	vm.Interfaces = ifaces
+	if vm.IP == "" {
+		vm.IP = entity.PrimaryIP(ifaces)
 	}
-	vm.IP = ip
 
 	// 8. Save VM to repository
 	if err := uc.vmRepo.Save(ctx, vm); err != nil {
@@ -131,7 +257,7 @@ func (uc *CreateVMUseCase) Execute(ctx context.Context, req *dto.CreateVMRequest
 	}
 
 	// 9. Update resource allocation
-	resources.Allocate(req.VCPU, req.RAMGB, req.DiskGB)
+	resources.Allocate(vcpu, ramGB, diskGB)
 	if err := uc.resourceRepo.Update(ctx, resources); err != nil {
 		uc.logger.Error("Failed to update resources", zap.Error(err))
 	}
@@ -142,8 +268,9 @@ func (uc *CreateVMUseCase) Execute(ctx context.Context, req *dto.CreateVMRequest
 	)
 
 	return &dto.CreateVMResponse{
-		VMID:      vm.ID,
-		IPAddress: vm.IP,
-		Status:    string(vm.Status),
+		VMID:       vm.ID,
+		IPAddress:  vm.IP,
+		Interfaces: toVMInterfacesDTO(vm.Interfaces),
+		Status:     string(vm.Status),
 	}, nil
 }
@@ -18,16 +18,31 @@ type DeleteVMUseCase struct {
 	storage      service.StorageService
 	vmRepo       repository.VMRepository
 	resourceRepo repository.ResourceRepository
+	ipam         service.IPAMService
+	forwardRepo  repository.PortForwardRepository
+	forwarder    service.PortForwarder
+	firewall     service.FirewallService
+	scheduler    service.BackupScheduler
+	backupRepo   repository.BackupRepository
+	target       service.BackupTarget
 	validator    *validator.Validate
 	logger       *zap.Logger
 }
 
 // NewDeleteVMUseCase creates a new DeleteVM use case
+// ipam is nil when static IP management is disabled
 func NewDeleteVMUseCase(
 	hypervisor service.HypervisorService,
 	storage service.StorageService,
 	vmRepo repository.VMRepository,
 	resourceRepo repository.ResourceRepository,
+	ipam service.IPAMService,
+	forwardRepo repository.PortForwardRepository,
+	forwarder service.PortForwarder,
+	firewall service.FirewallService,
+	scheduler service.BackupScheduler,
+	backupRepo repository.BackupRepository,
+	target service.BackupTarget,
 	logger *zap.Logger,
 ) *DeleteVMUseCase {
 	return &DeleteVMUseCase{
@@ -35,12 +50,19 @@ func NewDeleteVMUseCase(
 		storage:      storage,
 		vmRepo:       vmRepo,
 		resourceRepo: resourceRepo,
-		validator:    validator.New(),
+		ipam:         ipam,
+		forwardRepo:  forwardRepo,
+		forwarder:    forwarder,
+		firewall:     firewall,
+		scheduler:    scheduler,
+		backupRepo:   backupRepo,
+		target:       target,
+		validator:    newValidator(),
 		logger:       logger,
 	}
 }
 
-// Execute deletes a VM
+// Execute deletes a VM along with its backups on the configured target
 func (uc *DeleteVMUseCase) Execute(ctx context.Context, req *dto.DeleteVMRequest) (*dto.DeleteVMResponse, error) {
 	uc.logger.Info("Deleting VM", zap.String("vm_id", req.VMID))
 
@@ -72,6 +94,11 @@ func (uc *DeleteVMUseCase) Execute(ctx context.Context, req *dto.DeleteVMRequest
 	if err := uc.vmRepo.Delete(ctx, req.VMID); err != nil {
 		uc.logger.Error("Failed to delete VM from repository", zap.Error(err))
 	}
+	releaseAddress(ctx, uc.ipam, req.VMID, uc.logger)
+	removePortForwards(ctx, uc.forwardRepo, uc.forwarder, req.VMID, uc.logger)
+	removeFilter(ctx, uc.firewall, req.VMID, uc.logger)
+	uc.scheduler.Unschedule(req.VMID)
+	deleteBackups(ctx, uc.backupRepo, uc.target, req.VMID, uc.logger)
 
 	// 6. Release resources
 	resources, err := uc.resourceRepo.GetAvailable(ctx)
@@ -0,0 +1,305 @@
+package usecase
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// expiryStopGrace is how long an expired VM gets to shut down before it is powered off
+const expiryStopGrace = 2 * time.Minute
+
+// RenewVMUseCase handles extending and removing the lease of a VM
+type RenewVMUseCase struct {
+	vmRepo    repository.VMRepository
+	policy    entity.ExpiryPolicy
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+// NewRenewVMUseCase creates a new RenewVM use case
+func NewRenewVMUseCase(
+	vmRepo repository.VMRepository,
+	policy entity.ExpiryPolicy,
+	logger *zap.Logger,
+) *RenewVMUseCase {
+	return &RenewVMUseCase{
+		vmRepo:    vmRepo,
+		policy:    policy,
+		validator: newValidator(),
+		logger:    logger,
+	}
+}
+
+// Execute replaces the lease of a VM; a stopped VM whose lease ran out may be started again
+func (uc *RenewVMUseCase) Execute(ctx context.Context, req *dto.RenewVMRequest) (*dto.RenewVMResponse, error) {
+	uc.logger.Info("Renewing VM", zap.String("vm_id", req.VMID), zap.Bool("clear", req.Clear))
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	// 2. Get VM from repository
+	vm, err := uc.vmRepo.FindByID(ctx, req.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	// 3. Replace the lease
+	var expiry *entity.Expiry
+	if !req.Clear {
+		if expiry, err = newExpiry(req.TTLSeconds, req.ExpiresAt, req.Action, uc.policy, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	vm, err = uc.vmRepo.Update(ctx, vm.ID, func(vm *entity.VM) {
+		vm.Expiry = expiry
+		vm.UpdatedAt = time.Now()
+	})
+	if vmGone(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save VM", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	resp := &dto.RenewVMResponse{VMID: vm.ID}
+	if expiry != nil {
+		resp.ExpiresAt = expiry.At.Unix()
+		resp.ExpiryAction = string(expiry.Action)
+		uc.logger.Info("VM renewed",
+			zap.String("vm_id", vm.ID),
+			zap.Time("expires_at", expiry.At),
+			zap.String("action", string(expiry.Action)),
+		)
+	} else {
+		uc.logger.Info("VM lease removed", zap.String("vm_id", vm.ID))
+	}
+	return resp, nil
+}
+
+// ExpireVMsUseCase handles warning about and ending the leases of ephemeral VMs
+type ExpireVMsUseCase struct {
+	hypervisor service.HypervisorService
+	vmRepo     repository.VMRepository
+	deleteVM   *DeleteVMUseCase
+	reporter   service.ExpiryReporter
+	auditLog   service.AuditLog
+	policy     entity.ExpiryPolicy
+	logger     *zap.Logger
+}
+
+// NewExpireVMsUseCase creates a new ExpireVMs use case
+// deleteVM removes VMs whose lease ends in deletion, reporter is nil without Ghost Core
+func NewExpireVMsUseCase(
+	hypervisor service.HypervisorService,
+	vmRepo repository.VMRepository,
+	deleteVM *DeleteVMUseCase,
+	reporter service.ExpiryReporter,
+	auditLog service.AuditLog,
+	policy entity.ExpiryPolicy,
+	logger *zap.Logger,
+) *ExpireVMsUseCase {
+	return &ExpireVMsUseCase{
+		hypervisor: hypervisor,
+		vmRepo:     vmRepo,
+		deleteVM:   deleteVM,
+		reporter:   reporter,
+		auditLog:   auditLog,
+		policy:     policy,
+		logger:     logger,
+	}
+}
+
+// Execute warns about leases that run out soon and stops or deletes the VMs
+// whose lease ran out; a VM that failed is retried on the next run
+func (uc *ExpireVMsUseCase) Execute(ctx context.Context) error {
+	vms, err := uc.vmRepo.FindAll(ctx)
+	if err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to list VMs", err)
+	}
+
+	now := time.Now()
+	for _, vm := range vms {
+		if vm.Expiry == nil {
+			continue
+		}
+
+		var err error
+		switch {
+		case vm.Expiry.Due(now):
+			err = uc.expire(ctx, vm)
+		case vm.Expiry.Expired && vm.Expiry.Action == entity.ExpiryActionStop:
+			if now.After(vm.Expiry.At.Add(expiryStopGrace)) {
+				err = uc.powerOff(ctx, vm)
+			}
+		case vm.Expiry.WarningDue(now, uc.policy.WarnBefore):
+			err = uc.warn(ctx, vm)
+		}
+		if err != nil && !vmGone(err) {
+			uc.logger.Error("Failed to expire VM", zap.String("vm_id", vm.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// warn tells Ghost Core the lease of vm runs out soon
+func (uc *ExpireVMsUseCase) warn(ctx context.Context, vm *entity.VM) error {
+	uc.logger.Warn("VM lease runs out soon",
+		zap.String("vm_id", vm.ID),
+		zap.Time("expires_at", vm.Expiry.At),
+		zap.String("action", string(vm.Expiry.Action)),
+	)
+	uc.report(vm, entity.ExpiryPhaseWarning)
+
+	_, err := uc.vmRepo.Update(ctx, vm.ID, func(vm *entity.VM) {
+		if vm.Expiry != nil {
+			vm.Expiry.Warned = true
+		}
+	})
+	return err
+}
+
+// expire takes the action of the lease that ran out
+func (uc *ExpireVMsUseCase) expire(ctx context.Context, vm *entity.VM) error {
+	uc.logger.Info("VM lease ran out",
+		zap.String("vm_id", vm.ID),
+		zap.Time("expires_at", vm.Expiry.At),
+		zap.String("action", string(vm.Expiry.Action)),
+	)
+
+	details := map[string]any{
+		"action":     vm.Expiry.Action,
+		"expires_at": vm.Expiry.At.Unix(),
+	}
+	if vm.Expiry.Action == entity.ExpiryActionDelete {
+		_, err := uc.deleteVM.Execute(ctx, &dto.DeleteVMRequest{VMID: vm.ID})
+		if !vmGone(err) {
+			recordSystemEvent(ctx, uc.auditLog, "expire-vm", vm.ID, details, err, uc.logger)
+		}
+		if err != nil {
+			return err
+		}
+		uc.report(vm, entity.ExpiryPhaseDeleted)
+		return nil
+	}
+
+	running, err := uc.running(ctx, vm)
+	if err != nil {
+		return err
+	}
+	if running {
+		if err := uc.hypervisor.StopVM(ctx, vm.ID, false); err != nil {
+			err = errors.New(errors.ErrCodeHypervisor, "failed to stop VM", err).
+				WithContext("vm_id", vm.ID)
+			recordSystemEvent(ctx, uc.auditLog, "expire-vm", vm.ID, details, err, uc.logger)
+			return err
+		}
+	}
+
+	updated, err := uc.vmRepo.Update(ctx, vm.ID, func(vm *entity.VM) {
+		if vm.Expiry != nil {
+			vm.Expiry.Expired = true
+		}
+		vm.UpdatedAt = time.Now()
+	})
+	if err != nil {
+		return err
+	}
+	recordSystemEvent(ctx, uc.auditLog, "expire-vm", vm.ID, details, nil, uc.logger)
+	uc.report(updated, entity.ExpiryPhaseStopped)
+	return nil
+}
+
+// powerOff forces off an expired VM that ignored the shutdown request
+func (uc *ExpireVMsUseCase) powerOff(ctx context.Context, vm *entity.VM) error {
+	running, err := uc.running(ctx, vm)
+	if err != nil || !running {
+		return err
+	}
+
+	uc.logger.Warn("Expired VM did not shut down, powering it off", zap.String("vm_id", vm.ID))
+	if err := uc.hypervisor.StopVM(ctx, vm.ID, true); err != nil {
+		err = errors.New(errors.ErrCodeHypervisor, "failed to power off VM", err).
+			WithContext("vm_id", vm.ID)
+		recordSystemEvent(ctx, uc.auditLog, "power-off-expired-vm", vm.ID, nil, err, uc.logger)
+		return err
+	}
+	recordSystemEvent(ctx, uc.auditLog, "power-off-expired-vm", vm.ID, nil, nil, uc.logger)
+	return nil
+}
+
+func (uc *ExpireVMsUseCase) running(ctx context.Context, vm *entity.VM) (bool, error) {
+	status, err := uc.hypervisor.GetVMStatus(ctx, vm.ID)
+	if err != nil {
+		return false, errors.New(errors.ErrCodeHypervisor, "failed to get VM status", err).
+			WithContext("vm_id", vm.ID)
+	}
+	return status.Status == entity.VMStatusRunning, nil
+}
+
+// vmGone reports whether err is from a VM deleted while a background loop
+// handled it, which needs no retry
+func vmGone(err error) bool {
+	var appErr *errors.AppError
+	return stderrors.As(err, &appErr) && appErr.Code == errors.ErrCodeNotFound
+}
+
+// report sends an expiry phase to Ghost Core without failing the expiry
+func (uc *ExpireVMsUseCase) report(vm *entity.VM, phase entity.ExpiryPhase) {
+	if uc.reporter == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := uc.reporter.ReportVMExpiry(ctx, vm, phase); err != nil {
+		uc.logger.Warn("Failed to report VM expiry to Ghost Core",
+			zap.String("vm_id", vm.ID),
+			zap.String("phase", string(phase)),
+			zap.Error(err),
+		)
+	}
+}
+
+// newExpiry returns the lease a request asks for, checked against the policy
+// A request with neither a TTL nor an expiry time asks for none
+func newExpiry(ttlSeconds, expiresAt int64, action string, policy entity.ExpiryPolicy, now time.Time) (*entity.Expiry, error) {
+	if ttlSeconds == 0 && expiresAt == 0 {
+		return nil, nil
+	}
+
+	at := time.Unix(expiresAt, 0)
+	if ttlSeconds > 0 {
+		at = now.Add(time.Duration(ttlSeconds) * time.Second)
+	}
+	if !at.After(now) {
+		return nil, errors.New(errors.ErrCodeValidation, "expiry must be in the future", nil).
+			WithContext("expires_at", at.Unix())
+	}
+	if policy.MaxTTL > 0 && at.Sub(now) > policy.MaxTTL {
+		return nil, errors.New(errors.ErrCodeValidation, "lease longer than the agent allows", nil).
+			WithContext("expires_at", at.Unix()).
+			WithContext("max_ttl", policy.MaxTTL.String())
+	}
+
+	expiry := &entity.Expiry{At: at, Action: entity.ExpiryAction(action)}
+	if expiry.Action == "" {
+		expiry.Action = policy.DefaultAction
+	}
+	return expiry, nil
+}
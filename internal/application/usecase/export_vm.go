@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// ExportVMUseCase handles packaging a VM into a portable archive
+type ExportVMUseCase struct {
+	hypervisor service.HypervisorService
+	storage    service.StorageService
+	vmRepo     repository.VMRepository
+	validator  *validator.Validate
+	logger     *zap.Logger
+}
+
+// NewExportVMUseCase creates a new ExportVM use case
+func NewExportVMUseCase(
+	hypervisor service.HypervisorService,
+	storage service.StorageService,
+	vmRepo repository.VMRepository,
+	logger *zap.Logger,
+) *ExportVMUseCase {
+	return &ExportVMUseCase{
+		hypervisor: hypervisor,
+		storage:    storage,
+		vmRepo:     vmRepo,
+		validator:  newValidator(),
+		logger:     logger,
+	}
+}
+
+// Execute writes a tar archive of the VM to w
+// The archive holds the manifest, the VM record and a flattened qcow2 disk
+func (uc *ExportVMUseCase) Execute(ctx context.Context, req *dto.ExportVMRequest, w io.Writer) error {
+	uc.logger.Info("Exporting VM", zap.String("vm_id", req.VMID))
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	// 2. Get VM from repository
+	vm, err := uc.vmRepo.FindByID(ctx, req.VMID)
+	if err != nil {
+		return errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	// 3. Disk must not change while it is copied
+	status, err := uc.hypervisor.GetVMStatus(ctx, req.VMID)
+	if err != nil {
+		return errors.New(errors.ErrCodeHypervisor, "failed to get VM status", err).
+			WithContext("vm_id", req.VMID)
+	}
+	if status.Status != entity.VMStatusStopped {
+		return errors.New(errors.ErrCodeInvalidState, "VM must be stopped before export", nil).
+			WithContext("vm_id", req.VMID).
+			WithContext("status", string(status.Status))
+	}
+
+	// 4. Flatten disk
+	disk, err := uc.storage.ExportDisk(ctx, req.VMID)
+	if err != nil {
+		return errors.New(errors.ErrCodeStorage, "failed to export disk", err).
+			WithContext("vm_id", req.VMID)
+	}
+	defer disk.Close()
+
+	// 5. Write archive
+	manifest := &dto.VMArchiveManifest{
+		FormatVersion: dto.VMArchiveFormatVersion,
+		VMID:          vm.ID,
+		Name:          vm.Name,
+		VCPU:          vm.VCPU,
+		RAMGB:         vm.RAMGB,
+		DiskGB:        vm.DiskGB,
+		Template:      vm.Template,
+		DiskFile:      dto.VMArchiveDiskFile,
+		DiskSizeBytes: disk.SizeBytes,
+		DiskSHA256:    disk.SHA256,
+		ExportedAt:    time.Now(),
+	}
+
+	tw := tar.NewWriter(w)
+	if err := writeTarJSON(tw, dto.VMArchiveManifestFile, manifest); err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to write manifest", err)
+	}
+	if err := writeTarJSON(tw, dto.VMArchiveRecordFile, vm); err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to write VM record", err)
+	}
+
+	header := &tar.Header{
+		Name:    dto.VMArchiveDiskFile,
+		Mode:    0644,
+		Size:    disk.SizeBytes,
+		ModTime: manifest.ExportedAt,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to write disk header", err)
+	}
+	if _, err := io.Copy(tw, disk); err != nil {
+		return errors.New(errors.ErrCodeStorage, "failed to write disk", err).
+			WithContext("vm_id", req.VMID)
+	}
+	if err := tw.Close(); err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to finish archive", err)
+	}
+
+	uc.logger.Info("VM exported successfully",
+		zap.String("vm_id", req.VMID),
+		zap.Int64("disk_size_bytes", disk.SizeBytes),
+	)
+
+	return nil
+}
+
+// writeTarJSON writes v as an indented JSON file entry
+func writeTarJSON(tw *tar.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err = tw.Write(data)
+	return err
+}
+
+// readTarJSON reads the next archive entry, which must be name, into v
+func readTarJSON(tr *tar.Reader, name string, v interface{}) error {
+	header, err := tr.Next()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if header.Name != name {
+		return fmt.Errorf("expected %s, found %s", name, header.Name)
+	}
+
+	return json.NewDecoder(tr).Decode(v)
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// prepareFilter defines a new VM's filter with the given security groups when the firewall is enabled
+// It returns the filter name its interface must reference, empty without a firewall
+func prepareFilter(ctx context.Context, firewall service.FirewallService, vmID string, groupIDs []string) (string, error) {
+	if firewall == nil {
+		return "", nil
+	}
+
+	if err := firewall.ApplyVM(ctx, vmID, groupIDs); err != nil {
+		return "", errors.New(errors.ErrCodeNetwork, "failed to create VM firewall filter", err).
+			WithContext("vm_id", vmID)
+	}
+	return firewall.FilterName(vmID), nil
+}
+
+// allowForwards opens a VM's filter to the inbound traffic of its port forwards, if the firewall is enabled
+func allowForwards(ctx context.Context, firewall service.FirewallService, forwardRepo repository.PortForwardRepository, vmID string) error {
+	if firewall == nil {
+		return nil
+	}
+
+	forwards, err := forwardRepo.FindByVM(ctx, vmID)
+	if err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to list port forwards", err).
+			WithContext("vm_id", vmID)
+	}
+	return firewall.AllowForwards(ctx, vmID, forwards)
+}
+
+// removeFilter removes a VM's filter, if the firewall is enabled
+func removeFilter(ctx context.Context, firewall service.FirewallService, vmID string, logger *zap.Logger) {
+	if firewall == nil {
+		return
+	}
+	if err := firewall.RemoveVM(ctx, vmID); err != nil {
+		logger.Warn("Failed to remove VM firewall filter", zap.String("vm_id", vmID), zap.Error(err))
+	}
+}
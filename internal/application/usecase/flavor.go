@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+)
+
+// ListFlavorsUseCase handles listing flavors and what this host can fit of them
+type ListFlavorsUseCase struct {
+	flavorRepo   repository.FlavorRepository
+	resourceRepo repository.ResourceRepository
+	logger       *zap.Logger
+}
+
+// NewListFlavorsUseCase creates a new ListFlavors use case
+func NewListFlavorsUseCase(
+	flavorRepo repository.FlavorRepository,
+	resourceRepo repository.ResourceRepository,
+	logger *zap.Logger,
+) *ListFlavorsUseCase {
+	return &ListFlavorsUseCase{
+		flavorRepo:   flavorRepo,
+		resourceRepo: resourceRepo,
+		logger:       logger,
+	}
+}
+
+// Execute lists the flavors, with whether the host can hold each one and
+// how many more VMs of it fit right now
+func (uc *ListFlavorsUseCase) Execute(ctx context.Context, req *dto.ListFlavorsRequest) (*dto.ListFlavorsResponse, error) {
+	flavors, err := uc.flavorRepo.FindAll(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to list flavors", err)
+	}
+
+	res, err := uc.resourceRepo.GetAvailable(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to get available resources", err)
+	}
+
+	resp := &dto.ListFlavorsResponse{Flavors: make([]dto.FlavorInfo, len(flavors))}
+	for i, f := range flavors {
+		info := dto.FlavorInfo{
+			Flavor: toFlavorDTO(f),
+			Fits:   res.CanHold(f.VCPU, f.RAMGB, f.DiskGB),
+		}
+		if info.Fits {
+			info.Available = res.Fits(f.VCPU, f.RAMGB, f.DiskGB)
+		}
+		resp.Flavors[i] = info
+	}
+	return resp, nil
+}
+
+// SetFlavorsUseCase handles replacing the flavors pushed by Ghost Core
+type SetFlavorsUseCase struct {
+	flavorRepo repository.FlavorRepository
+	validator  *validator.Validate
+	logger     *zap.Logger
+}
+
+// NewSetFlavorsUseCase creates a new SetFlavors use case
+func NewSetFlavorsUseCase(flavorRepo repository.FlavorRepository, logger *zap.Logger) *SetFlavorsUseCase {
+	return &SetFlavorsUseCase{
+		flavorRepo: flavorRepo,
+		validator:  newValidator(),
+		logger:     logger,
+	}
+}
+
+// Execute replaces the pushed flavors; existing VMs keep their sizes
+func (uc *SetFlavorsUseCase) Execute(ctx context.Context, req *dto.SetFlavorsRequest) (*dto.SetFlavorsResponse, error) {
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	flavors := make([]*entity.Flavor, len(req.Flavors))
+	for i := range req.Flavors {
+		flavors[i] = toFlavor(&req.Flavors[i])
+	}
+	if err := uc.flavorRepo.ReplacePushed(ctx, flavors); err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save flavors", err)
+	}
+
+	uc.logger.Info("Flavors replaced", zap.Int("count", len(flavors)))
+
+	return &dto.SetFlavorsResponse{Success: true}, nil
+}
+
+func toFlavorDTO(f *entity.Flavor) dto.Flavor {
+	return dto.Flavor{
+		Name:      f.Name,
+		VCPU:      f.VCPU,
+		RAMGB:     f.RAMGB,
+		DiskGB:    f.DiskGB,
+		CPUMode:   string(f.CPUMode),
+		Limits:    toVMLimitsDTO(&f.Limits),
+		Templates: f.Templates,
+		Source:    string(f.Source),
+	}
+}
+
+// toFlavor converts a flavor pushed by Ghost Core
+func toFlavor(f *dto.Flavor) *entity.Flavor {
+	return &entity.Flavor{
+		Name:      f.Name,
+		VCPU:      f.VCPU,
+		RAMGB:     f.RAMGB,
+		DiskGB:    f.DiskGB,
+		CPUMode:   entity.CPUMode(f.CPUMode),
+		Limits:    toVMLimits(&f.Limits),
+		Templates: f.Templates,
+		Source:    entity.FlavorSourceCore,
+	}
+}
@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+)
+
+// GetAgentInfoUseCase handles describing the agent
+type GetAgentInfoUseCase struct {
+	resourceRepo repository.ResourceRepository
+	name         string
+	version      string
+	startedAt    time.Time
+	logger       *zap.Logger
+}
+
+// NewGetAgentInfoUseCase creates a new GetAgentInfo use case
+// The agent counts as started when the use case is created
+func NewGetAgentInfoUseCase(
+	resourceRepo repository.ResourceRepository,
+	name string,
+	version string,
+	logger *zap.Logger,
+) *GetAgentInfoUseCase {
+	return &GetAgentInfoUseCase{
+		resourceRepo: resourceRepo,
+		name:         name,
+		version:      version,
+		startedAt:    time.Now(),
+		logger:       logger,
+	}
+}
+
+// Execute returns the agent's name, version and available resources
+func (uc *GetAgentInfoUseCase) Execute(ctx context.Context) (*dto.AgentInfoResponse, error) {
+	res, err := uc.resourceRepo.GetAvailable(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to get available resources", err)
+	}
+
+	return &dto.AgentInfoResponse{
+		Name:      uc.name,
+		Version:   uc.version,
+		StartedAt: uc.startedAt,
+		Resources: dto.ResourceUsage{
+			TotalCPU:        res.TotalCPU,
+			AvailableCPU:    res.AvailableCPU,
+			TotalRAMGB:      res.TotalRAMGB,
+			AvailableRAMGB:  res.AvailableRAMGB,
+			TotalDiskGB:     res.TotalDiskGB,
+			AvailableDiskGB: res.AvailableDiskGB,
+		},
+	}, nil
+}
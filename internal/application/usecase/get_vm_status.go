@@ -7,6 +7,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
@@ -32,7 +33,7 @@ func NewGetVMStatusUseCase(
 		hypervisor: hypervisor,
 		network:    network,
 		vmRepo:     vmRepo,
-		validator:  validator.New(),
+		validator:  newValidator(),
 		logger:     logger,
 	}
 }
@@ -60,23 +61,55 @@ func (uc *GetVMStatusUseCase) Execute(ctx context.Context, req *dto.GetVMStatusR
 			WithContext("vm_id", req.VMID)
 	}
 
-	// 4. Get current IP
-	ip, err := uc.network.GetVMIP(ctx, req.VMID)
+	// 4. Get current IP addresses
+	ifaces, err := uc.network.GetVMIP(ctx, req.VMID)
 	if err != nil {
 		uc.logger.Warn("Failed to get VM IP", zap.Error(err))
-		ip = vm.IP // Use cached IP
+		ifaces = vm.Interfaces // Use cached addresses
+	}
+	ip := entity.PrimaryIP(ifaces)
+	if ip == "" {
+		ip = vm.IP
+	}
+
+	// 5. Get guest details when the VM is running
+	var guest *dto.GuestInfo
+	if status.Status == entity.VMStatusRunning {
+		info, err := uc.hypervisor.GetGuestInfo(ctx, req.VMID)
+		if err != nil {
+			uc.logger.Debug("Guest agent unavailable", zap.String("vm_id", req.VMID), zap.Error(err))
+		} else {
+			guest = toGuestInfo(info)
+		}
 	}
 
-	return &dto.GetVMStatusResponse{
+	resp := &dto.GetVMStatusResponse{
 		VMID:            vm.ID,
 		Name:            vm.Name,
 		Status:          string(status.Status),
+		Flavor:          vm.Flavor,
 		VCPU:            vm.VCPU,
 		RAMGB:           vm.RAMGB,
 		DiskGB:          vm.DiskGB,
 		IPAddress:       ip,
+		Interfaces:      toVMInterfacesDTO(ifaces),
+		NetworkMode:     string(vm.EffectiveNetworkMode()),
 		UptimeSeconds:   status.UptimeSeconds,
 		CPUUsagePercent: status.CPUUsagePercent,
 		RAMUsagePercent: status.RAMUsagePercent,
-	}, nil
+		Guest:           guest,
+		SecurityGroups:  vm.SecurityGroups,
+		Networks:        vm.Networks,
+		Limits:          toVMLimitsDTO(vm.Limits),
+		Metadata:        vm.Metadata,
+		RestartPolicy:   restartPolicy(vm),
+	}
+	if vm.Restart != nil {
+		resp.Restarts = vm.Restart.Restarts
+	}
+	if vm.Expiry != nil {
+		resp.ExpiresAt = vm.Expiry.At.Unix()
+		resp.ExpiryAction = string(vm.Expiry.Action)
+	}
+	return resp, nil
 }
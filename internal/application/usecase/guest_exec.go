@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// defaultGuestExecTimeout applies when the request does not set a timeout
+const defaultGuestExecTimeout = 30 * time.Second
+
+// GuestExecUseCase handles running commands inside a VM through the guest agent
+type GuestExecUseCase struct {
+	hypervisor service.HypervisorService
+	vmRepo     repository.VMRepository
+	validator  *validator.Validate
+	logger     *zap.Logger
+}
+
+// NewGuestExecUseCase creates a new GuestExec use case
+func NewGuestExecUseCase(
+	hypervisor service.HypervisorService,
+	vmRepo repository.VMRepository,
+	logger *zap.Logger,
+) *GuestExecUseCase {
+	return &GuestExecUseCase{
+		hypervisor: hypervisor,
+		vmRepo:     vmRepo,
+		validator:  newValidator(),
+		logger:     logger,
+	}
+}
+
+// Execute runs a command in a running VM and waits for it to exit
+func (uc *GuestExecUseCase) Execute(ctx context.Context, req *dto.GuestExecRequest) (*dto.GuestExecResponse, error) {
+	uc.logger.Info("Executing guest command",
+		zap.String("vm_id", req.VMID),
+		zap.String("command", req.Command),
+		zap.Strings("args", req.Args),
+	)
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	// 2. VM must exist and be running
+	if err := requireRunning(ctx, uc.hypervisor, uc.vmRepo, req.VMID); err != nil {
+		return nil, err
+	}
+
+	// 3. Run command
+	timeout := defaultGuestExecTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+
+	result, err := uc.hypervisor.GuestExec(ctx, req.VMID, &service.GuestExecSpec{
+		Command: req.Command,
+		Args:    req.Args,
+		Env:     req.Env,
+		Stdin:   req.Stdin,
+		Timeout: timeout,
+	})
+	if err != nil {
+		var appErr *errors.AppError
+		if stderrors.As(err, &appErr) && appErr.Code == errors.ErrCodeTimeout {
+			return nil, appErr
+		}
+		return nil, errors.New(errors.ErrCodeHypervisor, "guest command failed", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	uc.logger.Info("Guest command finished",
+		zap.String("vm_id", req.VMID),
+		zap.Int("exit_code", result.ExitCode),
+	)
+
+	return &dto.GuestExecResponse{
+		ExitCode:  result.ExitCode,
+		Stdout:    result.Stdout,
+		Stderr:    result.Stderr,
+		Truncated: result.Truncated,
+	}, nil
+}
+
+// toGuestInfo converts guest agent details to a DTO
+func toGuestInfo(info *service.GuestInfo) *dto.GuestInfo {
+	out := &dto.GuestInfo{
+		OSName:        info.OSName,
+		OSVersion:     info.OSVersion,
+		KernelRelease: info.KernelRelease,
+		Hostname:      info.Hostname,
+	}
+	for _, fs := range info.FileSystems {
+		out.FileSystems = append(out.FileSystems, dto.GuestFileSystem{
+			MountPoint: fs.MountPoint,
+			FSType:     fs.FSType,
+			TotalBytes: fs.TotalBytes,
+			UsedBytes:  fs.UsedBytes,
+		})
+	}
+	for _, iface := range info.Interfaces {
+		out.Interfaces = append(out.Interfaces, dto.GuestInterface{
+			Name:         iface.Name,
+			HardwareAddr: iface.HardwareAddr,
+			Addresses:    iface.Addresses,
+		})
+	}
+	return out
+}
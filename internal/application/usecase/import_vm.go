@@ -0,0 +1,239 @@
+package usecase
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// ImportVMUseCase handles recreating a VM from an exported archive
+type ImportVMUseCase struct {
+	hypervisor   service.HypervisorService
+	network      service.NetworkService
+	storage      service.StorageService
+	vmRepo       repository.VMRepository
+	resourceRepo repository.ResourceRepository
+	networkModes service.NetworkModes
+	ipam         service.IPAMService
+	firewall     service.FirewallService
+	limits       entity.VMLimits
+	quotas       *QuotaReserver
+	validator    *validator.Validate
+	logger       *zap.Logger
+}
+
+// NewImportVMUseCase creates a new ImportVM use case
+// ipam is nil when static IP management is disabled, firewall when filtering is disabled
+// limits are the agent defaults for limits the VM does not set, quotas cap the VMs of each tenant
+func NewImportVMUseCase(
+	hypervisor service.HypervisorService,
+	network service.NetworkService,
+	storage service.StorageService,
+	vmRepo repository.VMRepository,
+	resourceRepo repository.ResourceRepository,
+	networkModes service.NetworkModes,
+	ipam service.IPAMService,
+	firewall service.FirewallService,
+	limits entity.VMLimits,
+	quotas *QuotaReserver,
+	logger *zap.Logger,
+) *ImportVMUseCase {
+	return &ImportVMUseCase{
+		hypervisor:   hypervisor,
+		network:      network,
+		storage:      storage,
+		vmRepo:       vmRepo,
+		resourceRepo: resourceRepo,
+		networkModes: networkModes,
+		ipam:         ipam,
+		firewall:     firewall,
+		limits:       limits,
+		quotas:       quotas,
+		validator:    newValidator(),
+		logger:       logger,
+	}
+}
+
+// Execute reads a VM archive from r and recreates the VM on this agent
+func (uc *ImportVMUseCase) Execute(ctx context.Context, req *dto.ImportVMRequest, r io.Reader) (*dto.ImportVMResponse, error) {
+	uc.logger.Info("Importing VM", zap.String("name", req.Name))
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	// 2. Read manifest and VM record
+	tr := tar.NewReader(r)
+
+	var manifest dto.VMArchiveManifest
+	if err := readTarJSON(tr, dto.VMArchiveManifestFile, &manifest); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid VM archive", err)
+	}
+	if manifest.FormatVersion != dto.VMArchiveFormatVersion {
+		return nil, errors.New(errors.ErrCodeValidation, "unsupported VM archive version", nil).
+			WithContext("format_version", manifest.FormatVersion)
+	}
+
+	var record entity.VM
+	if err := readTarJSON(tr, dto.VMArchiveRecordFile, &record); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid VM archive", err)
+	}
+
+	name := record.Name
+	if req.Name != "" {
+		name = req.Name
+	}
+
+	// 3. Check if VM already exists
+	exists, err := uc.vmRepo.Exists(ctx, name)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to check VM existence", err).
+			WithContext("vm_name", name)
+	}
+	if exists {
+		return nil, errors.New(errors.ErrCodeConflict, "VM already exists", nil).
+			WithContext("vm_name", name)
+	}
+
+	// 4. Check available resources
+	resources, err := uc.resourceRepo.GetAvailable(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to check resources", err)
+	}
+
+	if !resources.CanAllocate(record.VCPU, record.RAMGB, record.DiskGB) {
+		return nil, errors.New(errors.ErrCodeResourceLimit, "insufficient resources", nil).
+			WithContext("requested_vcpu", record.VCPU).
+			WithContext("requested_ram_gb", record.RAMGB).
+			WithContext("requested_disk_gb", record.DiskGB).
+			WithContext("available_vcpu", resources.AvailableCPU).
+			WithContext("available_ram_gb", resources.AvailableRAMGB).
+			WithContext("available_disk_gb", resources.AvailableDiskGB)
+	}
+	if err := uc.quotas.Reserve(ctx, name, record.Tenant(), record.VCPU, record.RAMGB, record.DiskGB); err != nil {
+		return nil, err
+	}
+	defer uc.quotas.Release(name) // Once saved, the VM counts by itself
+
+	// 5. Receive disk
+	header, err := tr.Next()
+	if err != nil || header.Name != manifest.DiskFile {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid VM archive",
+			fmt.Errorf("expected %s entry", manifest.DiskFile))
+	}
+
+	diskPath, err := uc.storage.ImportDisk(ctx, name, tr, manifest.DiskSHA256)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to import disk", err).
+			WithContext("vm_name", name)
+	}
+
+	// 6. Create VM in hypervisor, falling back to the default network
+	// when the archived mode is not available on this agent
+	mode := uc.networkModes.Resolve(record.NetworkMode)
+	if !uc.networkModes.Supports(mode) {
+		uc.logger.Warn("Archived network mode not available, using default",
+			zap.String("network_mode", string(mode)),
+			zap.String("default", string(uc.networkModes.Default)),
+		)
+		mode = uc.networkModes.Default
+	}
+
+	// Private networks belong to the exporting agent, so their NICs are not recreated
+	if len(record.Networks) > 0 {
+		uc.logger.Warn("Dropping NICs on private networks of the source agent",
+			zap.String("vm_name", name),
+			zap.Strings("network_ids", record.Networks),
+		)
+	}
+
+	vmSpec := &service.VMSpec{
+		Name:     name,
+		VCPU:     record.VCPU,
+		RAMGB:    record.RAMGB,
+		DiskGB:   record.DiskGB,
+		Template: record.Template,
+		DiskPath: diskPath,
+		Network:  mode,
+		Limits:   effectiveLimits(record.Limits, uc.limits),
+		CPUMode:  record.CPUMode,
+	}
+
+	alloc, err := reserveAddress(ctx, uc.ipam, name, mode)
+	if err != nil {
+		_ = uc.storage.DeleteDisk(ctx, name)
+		return nil, err
+	}
+	if alloc != nil {
+		vmSpec.IP = alloc.IP
+		vmSpec.MAC = alloc.MAC
+	}
+
+	vmSpec.Filter, err = prepareFilter(ctx, uc.firewall, name, nil)
+	if err != nil {
+		_ = uc.storage.DeleteDisk(ctx, name)
+		releaseAddress(ctx, uc.ipam, name, uc.logger)
+		return nil, err
+	}
+
+	vm, err := uc.hypervisor.CreateVM(ctx, vmSpec)
+	if err != nil {
+		// Cleanup disk, address and filter on failure
+		_ = uc.storage.DeleteDisk(ctx, name)
+		releaseAddress(ctx, uc.ipam, name, uc.logger)
+		removeFilter(ctx, uc.firewall, name, uc.logger)
+		return nil, errors.New(errors.ErrCodeHypervisor, "failed to create VM", err).
+			WithContext("vm_name", name)
+	}
+
+	vm.Limits = limitsOrNil(vmSpec.Limits)
+	vm.Flavor = record.Flavor
+	vm.Expiry = record.Expiry
+	if record.Restart != nil {
+		vm.Restart = &entity.Restart{Policy: record.Restart.Policy, MaxRetries: record.Restart.MaxRetries}
+	}
+	applyAutostart(ctx, uc.hypervisor, vm, uc.logger)
+
+	// 7. Get IP addresses; the primary one is known up front when it was reserved
+	ifaces, err := uc.network.GetVMIP(ctx, vm.ID)
+	if err != nil {
+		uc.logger.Warn("Failed to get VM IP", zap.Error(err)) // Continue without IP
+	}
+	vm.Interfaces = ifaces
+	if vm.IP == "" {
+		vm.IP = entity.PrimaryIP(ifaces)
+	}
+
+	// 8. Save VM to repository
+	if err := uc.vmRepo.Save(ctx, vm); err != nil {
+		uc.logger.Error("Failed to save VM to repository", zap.Error(err))
+	}
+
+	// 9. Update resource allocation
+	resources.Allocate(record.VCPU, record.RAMGB, record.DiskGB)
+	if err := uc.resourceRepo.Update(ctx, resources); err != nil {
+		uc.logger.Error("Failed to update resources", zap.Error(err))
+	}
+
+	uc.logger.Info("VM imported successfully",
+		zap.String("vm_id", vm.ID),
+		zap.String("source_vm_id", manifest.VMID),
+	)
+
+	return &dto.ImportVMResponse{
+		VMID:      vm.ID,
+		IPAddress: vm.IP,
+		Status:    string(vm.Status),
+	}, nil
+}
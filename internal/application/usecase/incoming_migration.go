@@ -0,0 +1,379 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// PrepareMigrationUseCase handles reserving room for a VM migrating to this agent
+type PrepareMigrationUseCase struct {
+	storage      service.StorageService
+	vmRepo       repository.VMRepository
+	resourceRepo repository.ResourceRepository
+	libvirtURI   string
+	networkModes service.NetworkModes
+	networkRepo  repository.NetworkRepository
+	firewall     service.FirewallService
+	sgRepo       repository.SecurityGroupRepository
+	migrations   repository.IncomingMigrationRepository
+	quotas       *QuotaReserver
+	validator    *validator.Validate
+	logger       *zap.Logger
+}
+
+// NewPrepareMigrationUseCase creates a new PrepareMigration use case
+// libvirtURI is the URI source hypervisors migrate to, firewall is nil when filtering is disabled
+// quotas cap the VMs of each tenant
+func NewPrepareMigrationUseCase(
+	storage service.StorageService,
+	vmRepo repository.VMRepository,
+	resourceRepo repository.ResourceRepository,
+	libvirtURI string,
+	networkModes service.NetworkModes,
+	networkRepo repository.NetworkRepository,
+	firewall service.FirewallService,
+	sgRepo repository.SecurityGroupRepository,
+	migrations repository.IncomingMigrationRepository,
+	quotas *QuotaReserver,
+	logger *zap.Logger,
+) *PrepareMigrationUseCase {
+	return &PrepareMigrationUseCase{
+		storage:      storage,
+		vmRepo:       vmRepo,
+		resourceRepo: resourceRepo,
+		libvirtURI:   libvirtURI,
+		networkModes: networkModes,
+		networkRepo:  networkRepo,
+		firewall:     firewall,
+		sgRepo:       sgRepo,
+		migrations:   migrations,
+		quotas:       quotas,
+		validator:    newValidator(),
+		logger:       logger,
+	}
+}
+
+// Execute allocates resources and creates an empty disk for an incoming VM
+func (uc *PrepareMigrationUseCase) Execute(ctx context.Context, req *dto.PrepareMigrationRequest) (*dto.PrepareMigrationResponse, error) {
+	uc.logger.Info("Preparing incoming migration", zap.String("vm_id", req.VM.VMID))
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	// The migrated domain keeps its interface definition, so the mode must exist here
+	mode := uc.networkModes.Resolve(entity.NetworkMode(req.VM.NetworkMode))
+	if !uc.networkModes.Supports(mode) {
+		return nil, errors.New(errors.ErrCodeInvalidState, "network mode not available on this agent", nil).
+			WithContext("vm_id", req.VM.VMID).
+			WithContext("network_mode", string(mode))
+	}
+
+	// Private NICs reference their libvirt network by network ID
+	for _, id := range req.VM.Networks {
+		if _, err := uc.networkRepo.FindByID(ctx, id); err != nil {
+			return nil, errors.New(errors.ErrCodeInvalidState, "private network not defined on this agent", err).
+				WithContext("vm_id", req.VM.VMID).
+				WithContext("network_id", id)
+		}
+	}
+
+	// The VM keeps its security groups, so they must be defined here too
+	var groupIDs []string
+	if len(req.VM.SecurityGroups) > 0 {
+		if err := requireFirewall(uc.firewall); err != nil {
+			return nil, err
+		}
+		ids, err := securityGroupIDs(ctx, uc.sgRepo, req.VM.SecurityGroups)
+		if err != nil {
+			return nil, err
+		}
+		groupIDs = ids
+	}
+
+	// 2. Check if VM already exists
+	exists, err := uc.vmRepo.Exists(ctx, req.VM.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to check VM existence", err).
+			WithContext("vm_id", req.VM.VMID)
+	}
+	if exists {
+		return nil, errors.New(errors.ErrCodeConflict, "VM already exists", nil).
+			WithContext("vm_id", req.VM.VMID)
+	}
+
+	// FinishMigration only rolls back what is recorded here, and only once
+	incoming := &entity.IncomingMigration{
+		VMID:   req.VM.VMID,
+		VCPU:   req.VM.VCPU,
+		RAMGB:  req.VM.RAMGB,
+		DiskGB: req.VM.DiskGB,
+	}
+	if err := uc.migrations.Save(ctx, incoming); err != nil {
+		return nil, err
+	}
+	prepared := false
+	defer func() {
+		if !prepared {
+			_, _ = uc.migrations.Take(ctx, req.VM.VMID)
+		}
+	}()
+
+	// 3. Check available resources
+	resources, err := uc.resourceRepo.GetAvailable(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to check resources", err)
+	}
+
+	if !resources.CanAllocate(req.VM.VCPU, req.VM.RAMGB, req.VM.DiskGB) {
+		return nil, errors.New(errors.ErrCodeResourceLimit, "insufficient resources", nil).
+			WithContext("requested_vcpu", req.VM.VCPU).
+			WithContext("requested_ram_gb", req.VM.RAMGB).
+			WithContext("requested_disk_gb", req.VM.DiskGB).
+			WithContext("available_vcpu", resources.AvailableCPU).
+			WithContext("available_ram_gb", resources.AvailableRAMGB).
+			WithContext("available_disk_gb", resources.AvailableDiskGB)
+	}
+	// The quota stays reserved until the source agent finishes the migration
+	if err := uc.quotas.Reserve(ctx, req.VM.VMID, req.VM.Metadata[entity.MetadataTenant], req.VM.VCPU, req.VM.RAMGB, req.VM.DiskGB); err != nil {
+		return nil, err
+	}
+
+	// 4. Create the disk the migration copies into
+	diskPath, err := uc.storage.CreateBlankDisk(ctx, req.VM.VMID, req.VM.DiskGB)
+	if err != nil {
+		uc.quotas.Release(req.VM.VMID)
+		return nil, errors.New(errors.ErrCodeStorage, "failed to create disk", err).
+			WithContext("vm_id", req.VM.VMID)
+	}
+
+	// 5. The migrated interface references the VM's filter, so it has to hold
+	// the VM's security groups before the domain arrives
+	if _, err := prepareFilter(ctx, uc.firewall, req.VM.VMID, groupIDs); err != nil {
+		_ = uc.storage.DeleteDisk(ctx, req.VM.VMID)
+		uc.quotas.Release(req.VM.VMID)
+		return nil, err
+	}
+
+	// 6. Hold resources until the source agent finishes the migration
+	resources.Allocate(req.VM.VCPU, req.VM.RAMGB, req.VM.DiskGB)
+	if err := uc.resourceRepo.Update(ctx, resources); err != nil {
+		uc.logger.Error("Failed to update resources", zap.Error(err))
+	}
+	prepared = true
+
+	return &dto.PrepareMigrationResponse{
+		DiskPath:   diskPath,
+		LibvirtURI: uc.libvirtURI,
+	}, nil
+}
+
+// FinishMigrationUseCase handles registering or discarding a VM migrated to this agent
+type FinishMigrationUseCase struct {
+	hypervisor   service.HypervisorService
+	network      service.NetworkService
+	storage      service.StorageService
+	vmRepo       repository.VMRepository
+	resourceRepo repository.ResourceRepository
+	firewall     service.FirewallService
+	sgRepo       repository.SecurityGroupRepository
+	scheduler    service.BackupScheduler
+	migrations   repository.IncomingMigrationRepository
+	quotas       *QuotaReserver
+	validator    *validator.Validate
+	logger       *zap.Logger
+}
+
+// NewFinishMigrationUseCase creates a new FinishMigration use case
+func NewFinishMigrationUseCase(
+	hypervisor service.HypervisorService,
+	network service.NetworkService,
+	storage service.StorageService,
+	vmRepo repository.VMRepository,
+	resourceRepo repository.ResourceRepository,
+	firewall service.FirewallService,
+	sgRepo repository.SecurityGroupRepository,
+	scheduler service.BackupScheduler,
+	migrations repository.IncomingMigrationRepository,
+	quotas *QuotaReserver,
+	logger *zap.Logger,
+) *FinishMigrationUseCase {
+	return &FinishMigrationUseCase{
+		hypervisor:   hypervisor,
+		network:      network,
+		storage:      storage,
+		vmRepo:       vmRepo,
+		resourceRepo: resourceRepo,
+		firewall:     firewall,
+		sgRepo:       sgRepo,
+		scheduler:    scheduler,
+		migrations:   migrations,
+		quotas:       quotas,
+		validator:    newValidator(),
+		logger:       logger,
+	}
+}
+
+// Execute saves a migrated VM, or releases its reservation if the migration failed
+func (uc *FinishMigrationUseCase) Execute(ctx context.Context, req *dto.FinishMigrationRequest) (*dto.FinishMigrationResponse, error) {
+	uc.logger.Info("Finishing incoming migration",
+		zap.String("vm_id", req.VM.VMID),
+		zap.Bool("succeeded", req.Succeeded),
+	)
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	// 2. Only migrations this agent prepared are finished, and only once, so a
+	// bad or repeated call cannot touch the disk or resources of another VM
+	exists, err := uc.vmRepo.Exists(ctx, req.VM.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to check VM existence", err).
+			WithContext("vm_id", req.VM.VMID)
+	}
+	if exists {
+		return nil, errors.New(errors.ErrCodeConflict, "VM already exists", nil).
+			WithContext("vm_id", req.VM.VMID)
+	}
+	incoming, err := uc.migrations.Take(ctx, req.VM.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInvalidState, "no migration prepared for VM", err).
+			WithContext("vm_id", req.VM.VMID)
+	}
+	finished := false
+	defer func() {
+		if finished {
+			uc.quotas.Release(req.VM.VMID) // Once saved, the VM counts by itself
+		} else {
+			// The source agent may try again
+			_ = uc.migrations.Save(ctx, incoming)
+		}
+	}()
+
+	// 3. Release the reservation of a failed migration
+	if !req.Succeeded {
+		if err := uc.storage.DeleteDisk(ctx, incoming.VMID); err != nil {
+			uc.logger.Warn("Failed to delete disk", zap.Error(err))
+		}
+		removeFilter(ctx, uc.firewall, incoming.VMID, uc.logger)
+
+		resources, err := uc.resourceRepo.GetAvailable(ctx)
+		if err == nil {
+			resources.Release(incoming.VCPU, incoming.RAMGB, incoming.DiskGB)
+			if err := uc.resourceRepo.Update(ctx, resources); err != nil {
+				uc.logger.Error("Failed to update resources", zap.Error(err))
+			}
+		}
+		finished = true
+
+		return &dto.FinishMigrationResponse{
+			VMID: req.VM.VMID,
+		}, nil
+	}
+
+	// 4. The migrated domain must be running here
+	status, err := uc.hypervisor.GetVMStatus(ctx, req.VM.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "migrated VM not found", err).
+			WithContext("vm_id", req.VM.VMID)
+	}
+
+	diskPath, err := uc.storage.GetDiskPath(ctx, req.VM.VMID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Get IP addresses on this agent's network
+	ifaces, err := uc.network.GetVMIP(ctx, req.VM.VMID)
+	if err != nil {
+		uc.logger.Warn("Failed to get VM IP", zap.Error(err)) // Continue without IP
+	}
+
+	// 6. Save VM to repository, sized as reserved
+	now := time.Now()
+	vm := &entity.VM{
+		ID:          req.VM.VMID,
+		Name:        req.VM.Name,
+		VCPU:        incoming.VCPU,
+		RAMGB:       incoming.RAMGB,
+		DiskGB:      incoming.DiskGB,
+		Status:      status.Status,
+		IP:          entity.PrimaryIP(ifaces),
+		Interfaces:  ifaces,
+		NetworkMode: entity.NetworkMode(req.VM.NetworkMode),
+		Networks:    req.VM.Networks,
+		Template:    req.VM.Template,
+		Flavor:      req.VM.Flavor,
+		CPUMode:     entity.CPUMode(req.VM.CPUMode),
+		DiskPath:    diskPath,
+		Limits:      limitsOrNil(toVMLimits(&req.VM.Limits)),
+		Metadata:    req.VM.Metadata,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if req.VM.ExpiresAt != 0 {
+		vm.Expiry = &entity.Expiry{
+			At:     time.Unix(req.VM.ExpiresAt, 0),
+			Action: entity.ExpiryAction(req.VM.ExpiryAction),
+		}
+	}
+	vm.Restart = newRestart(req.VM.RestartPolicy, req.VM.RestartMaxRetries, entity.RestartNever)
+	applyAutostart(ctx, uc.hypervisor, vm, uc.logger)
+	for _, ref := range req.VM.SecurityGroups {
+		sg, err := findSecurityGroup(ctx, uc.sgRepo, ref)
+		if err != nil {
+			uc.logger.Warn("Security group of migrated VM is gone",
+				zap.String("vm_id", vm.ID),
+				zap.String("security_group", ref),
+			)
+			continue
+		}
+		vm.SecurityGroups = append(vm.SecurityGroups, sg.ID)
+	}
+	if req.VM.BackupSchedule != "" {
+		vm.Backup = &entity.BackupPolicy{
+			Schedule:   req.VM.BackupSchedule,
+			KeepDaily:  req.VM.BackupKeepDaily,
+			KeepWeekly: req.VM.BackupKeepWeekly,
+		}
+	}
+
+	if err := uc.vmRepo.Save(ctx, vm); err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save VM", err).
+			WithContext("vm_id", vm.ID)
+	}
+	finished = true
+
+	// 7. Keep backing the VM up on the schedule it had on the source agent
+	if vm.Backup != nil {
+		if err := uc.scheduler.Schedule(vm.ID, vm.Backup.Schedule); err != nil {
+			uc.logger.Warn("Failed to schedule backups",
+				zap.String("vm_id", vm.ID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	uc.logger.Info("Migrated VM registered",
+		zap.String("vm_id", vm.ID),
+		zap.String("ip", vm.IP),
+	)
+
+	return &dto.FinishMigrationResponse{
+		VMID:      vm.ID,
+		IPAddress: vm.IP,
+		Status:    string(vm.Status),
+	}, nil
+}
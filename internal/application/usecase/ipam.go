@@ -0,0 +1,36 @@
+package usecase
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// reserveAddress pins a static address for a NAT VM when IPAM is enabled
+// It returns nil when the VM gets its address dynamically
+func reserveAddress(ctx context.Context, ipam service.IPAMService, vmID string, mode entity.NetworkMode) (*entity.IPAllocation, error) {
+	if ipam == nil || mode != entity.NetworkModeNAT {
+		return nil, nil
+	}
+
+	alloc, err := ipam.Allocate(ctx, vmID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNetwork, "failed to allocate IP address", err).
+			WithContext("vm_id", vmID)
+	}
+	return alloc, nil
+}
+
+// releaseAddress frees a VM's static address, if it has one
+func releaseAddress(ctx context.Context, ipam service.IPAMService, vmID string, logger *zap.Logger) {
+	if ipam == nil {
+		return
+	}
+	if err := ipam.Release(ctx, vmID); err != nil {
+		logger.Warn("Failed to release IP address", zap.String("vm_id", vmID), zap.Error(err))
+	}
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// UpdateVMLimitsUseCase handles changing the network and disk limits of a VM
+type UpdateVMLimitsUseCase struct {
+	hypervisor service.HypervisorService
+	vmRepo     repository.VMRepository
+	flavorRepo repository.FlavorRepository
+	defaults   entity.VMLimits
+	validator  *validator.Validate
+	logger     *zap.Logger
+}
+
+// NewUpdateVMLimitsUseCase creates a new UpdateVMLimits use case
+// defaults fill in the limits a request leaves unset, after those of the VM's flavor
+func NewUpdateVMLimitsUseCase(
+	hypervisor service.HypervisorService,
+	vmRepo repository.VMRepository,
+	flavorRepo repository.FlavorRepository,
+	defaults entity.VMLimits,
+	logger *zap.Logger,
+) *UpdateVMLimitsUseCase {
+	return &UpdateVMLimitsUseCase{
+		hypervisor: hypervisor,
+		vmRepo:     vmRepo,
+		flavorRepo: flavorRepo,
+		defaults:   defaults,
+		validator:  newValidator(),
+		logger:     logger,
+	}
+}
+
+// Execute replaces the limits of a VM, live when it is running
+func (uc *UpdateVMLimitsUseCase) Execute(ctx context.Context, req *dto.UpdateVMLimitsRequest) (*dto.UpdateVMLimitsResponse, error) {
+	uc.logger.Info("Updating VM limits", zap.String("vm_id", req.VMID))
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	// 2. Get VM from repository
+	vm, err := uc.vmRepo.FindByID(ctx, req.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	// 3. Apply the limits in the hypervisor, defaulting to the flavor's while it exists
+	defaults := uc.defaults
+	if vm.Flavor != "" {
+		if flavor, err := uc.flavorRepo.FindByName(ctx, vm.Flavor); err == nil {
+			defaults = flavor.Limits.WithDefaults(uc.defaults)
+		} else {
+			uc.logger.Debug("Flavor of VM is gone, using the agent default limits",
+				zap.String("vm_id", vm.ID),
+				zap.String("flavor", vm.Flavor),
+			)
+		}
+	}
+	requested := toVMLimits(&req.Limits)
+	limits := effectiveLimits(&requested, defaults)
+	if err := uc.hypervisor.SetVMLimits(ctx, vm.ID, limits); err != nil {
+		return nil, err
+	}
+
+	// 4. Save VM to repository
+	vm, err = uc.vmRepo.Update(ctx, vm.ID, func(vm *entity.VM) {
+		vm.Limits = limitsOrNil(limits)
+		vm.UpdatedAt = time.Now()
+	})
+	if vmGone(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save VM", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	uc.logger.Info("VM limits updated",
+		zap.String("vm_id", vm.ID),
+		zap.Int("inbound_kbytes_sec", limits.Inbound.Average),
+		zap.Int("outbound_kbytes_sec", limits.Outbound.Average),
+		zap.Int64("disk_iops", limits.DiskIOPS),
+		zap.Int64("disk_bytes_sec", limits.DiskBytesPerSec),
+	)
+
+	return &dto.UpdateVMLimitsResponse{
+		VMID:   vm.ID,
+		Limits: toVMLimitsDTO(vm.Limits),
+	}, nil
+}
+
+// effectiveLimits returns the requested limits completed with the agent defaults
+// requested is nil when the caller asked for none
+func effectiveLimits(requested *entity.VMLimits, defaults entity.VMLimits) entity.VMLimits {
+	if requested == nil {
+		return defaults
+	}
+	return requested.WithDefaults(defaults)
+}
+
+// limitsOrNil returns limits as stored on a VM, nil when nothing is limited
+func limitsOrNil(limits entity.VMLimits) *entity.VMLimits {
+	if limits == (entity.VMLimits{}) {
+		return nil
+	}
+	return &limits
+}
+
+func toVMLimits(l *dto.VMLimits) entity.VMLimits {
+	return entity.VMLimits{
+		Inbound:         toBandwidthLimit(l.Inbound),
+		Outbound:        toBandwidthLimit(l.Outbound),
+		DiskIOPS:        l.DiskIOPS,
+		DiskBytesPerSec: l.DiskBytesPerSec,
+	}
+}
+
+func toBandwidthLimit(b dto.BandwidthLimit) entity.BandwidthLimit {
+	return entity.BandwidthLimit{
+		Average: b.AverageKBps,
+		Peak:    b.PeakKBps,
+		Burst:   b.BurstKB,
+	}
+}
+
+func toVMLimitsDTO(l *entity.VMLimits) dto.VMLimits {
+	if l == nil {
+		return dto.VMLimits{}
+	}
+	return dto.VMLimits{
+		Inbound:         toBandwidthLimitDTO(l.Inbound),
+		Outbound:        toBandwidthLimitDTO(l.Outbound),
+		DiskIOPS:        l.DiskIOPS,
+		DiskBytesPerSec: l.DiskBytesPerSec,
+	}
+}
+
+func toBandwidthLimitDTO(b entity.BandwidthLimit) dto.BandwidthLimit {
+	return dto.BandwidthLimit{
+		AverageKBps: b.Average,
+		PeakKBps:    b.Peak,
+		BurstKB:     b.Burst,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
 )
@@ -43,17 +44,19 @@ func (uc *ListVMsUseCase) Execute(ctx context.Context, req *dto.ListVMsRequest)
 	// 2. Build response
 	vmInfos := make([]dto.VMInfo, 0, len(vms))
 	for _, vm := range vms {
-		// Try to get IP
-		ip, err := uc.network.GetVMIP(ctx, vm.ID)
-		if err != nil {
+		// Try to get IP addresses
+		ifaces, _ := uc.network.GetVMIP(ctx, vm.ID)
+		ip := entity.PrimaryIP(ifaces)
+		if ip == "" {
 			ip = vm.IP // Use cached IP if available
 		}
 
 		vmInfos = append(vmInfos, dto.VMInfo{
-			VMID:      vm.ID,
-			Name:      vm.Name,
-			Status:    string(vm.Status),
-			IPAddress: ip,
+			VMID:       vm.ID,
+			Name:       vm.Name,
+			Status:     string(vm.Status),
+			IPAddress:  ip,
+			Interfaces: toVMInterfacesDTO(ifaces),
 		})
 	}
 
@@ -0,0 +1,302 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// migrationReportStep is the progress step between migration reports to Ghost Core
+const migrationReportStep = 10
+
+// The VM already runs on the target when it is registered there, so a failed
+// FinishMigration is retried before giving up
+const (
+	finishMigrationAttempts   = 3
+	finishMigrationRetryDelay = 5 * time.Second
+)
+
+// MigrateVMUseCase handles live-migrating a VM to another agent
+type MigrateVMUseCase struct {
+	hypervisor   service.HypervisorService
+	storage      service.StorageService
+	peers        service.PeerAgentService
+	scheduler    service.BackupScheduler
+	vmRepo       repository.VMRepository
+	resourceRepo repository.ResourceRepository
+	reporter     service.MigrationReporter
+	ipam         service.IPAMService
+	forwardRepo  repository.PortForwardRepository
+	forwarder    service.PortForwarder
+	firewall     service.FirewallService
+	sgRepo       repository.SecurityGroupRepository
+	auditLog     service.AuditLog
+	validator    *validator.Validate
+	logger       *zap.Logger
+}
+
+// NewMigrateVMUseCase creates a new MigrateVM use case
+// reporter may be nil when Ghost Core is unreachable, ipam when IPAM is disabled,
+// firewall when filtering is disabled
+func NewMigrateVMUseCase(
+	hypervisor service.HypervisorService,
+	storage service.StorageService,
+	peers service.PeerAgentService,
+	scheduler service.BackupScheduler,
+	vmRepo repository.VMRepository,
+	resourceRepo repository.ResourceRepository,
+	reporter service.MigrationReporter,
+	ipam service.IPAMService,
+	forwardRepo repository.PortForwardRepository,
+	forwarder service.PortForwarder,
+	firewall service.FirewallService,
+	sgRepo repository.SecurityGroupRepository,
+	auditLog service.AuditLog,
+	logger *zap.Logger,
+) *MigrateVMUseCase {
+	return &MigrateVMUseCase{
+		hypervisor:   hypervisor,
+		storage:      storage,
+		peers:        peers,
+		scheduler:    scheduler,
+		vmRepo:       vmRepo,
+		resourceRepo: resourceRepo,
+		reporter:     reporter,
+		ipam:         ipam,
+		forwardRepo:  forwardRepo,
+		forwarder:    forwarder,
+		firewall:     firewall,
+		sgRepo:       sgRepo,
+		auditLog:     auditLog,
+		validator:    newValidator(),
+		logger:       logger,
+	}
+}
+
+// Execute moves a running VM to the target agent, calling progress as it goes
+func (uc *MigrateVMUseCase) Execute(ctx context.Context, req *dto.MigrateVMRequest, progress func(*dto.MigrateVMProgress)) (*dto.MigrateVMProgress, error) {
+	uc.logger.Info("Migrating VM",
+		zap.String("vm_id", req.VMID),
+		zap.String("target", req.TargetAddress),
+	)
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	// 2. Get VM from repository
+	vm, err := uc.vmRepo.FindByID(ctx, req.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	// 3. Only running VMs can be live-migrated
+	status, err := uc.hypervisor.GetVMStatus(ctx, vm.ID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeHypervisor, "failed to get VM status", err).
+			WithContext("vm_id", vm.ID)
+	}
+	if status.Status != entity.VMStatusRunning {
+		return nil, errors.New(errors.ErrCodeInvalidState, "VM must be running to migrate", nil).
+			WithContext("vm_id", vm.ID).
+			WithContext("status", string(status.Status))
+	}
+	// libvirt refuses the migration unless the target has the same CPU model
+	if vm.CPUMode == entity.CPUModeHostPassthrough {
+		uc.logger.Warn("VM uses host-passthrough CPU, the target needs the same CPU model",
+			zap.String("vm_id", vm.ID),
+			zap.String("target", req.TargetAddress),
+		)
+	}
+
+	diskPath := vm.DiskPath
+	if diskPath == "" {
+		if diskPath, err = uc.storage.GetDiskPath(ctx, vm.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	migration := &entity.Migration{
+		VMID:          vm.ID,
+		TargetAddress: req.TargetAddress,
+		Phase:         entity.MigrationPhasePreparing,
+	}
+	uc.report(migration)
+	progress(toMigrateVMProgress(migration, nil))
+
+	// Security group IDs are local to each agent; the target looks groups up by name
+	outgoing := *vm
+	outgoing.SecurityGroups = securityGroupNames(ctx, uc.sgRepo, vm.SecurityGroups)
+
+	// 4. Reserve resources and an empty disk on the target
+	reservation, err := uc.peers.PrepareMigration(ctx, req.TargetAddress, &outgoing)
+	if err != nil {
+		uc.fail(migration, err)
+		return nil, errors.New(errors.ErrCodeResourceLimit, "target agent cannot accept VM", err).
+			WithContext("vm_id", vm.ID).
+			WithContext("target", req.TargetAddress)
+	}
+
+	// 5. Copy disk and memory while the VM keeps running
+	migration.Phase = entity.MigrationPhaseTransferring
+	uc.report(migration)
+
+	lastReported := 0
+	spec := &service.MigrationSpec{
+		DestURI:        reservation.LibvirtURI,
+		SourceDiskPath: diskPath,
+		DestDiskPath:   reservation.DiskPath,
+	}
+	err = uc.hypervisor.MigrateVM(ctx, vm.ID, spec, func(p *service.MigrationProgress) {
+		migration.Percent = p.Percent()
+		progress(toMigrateVMProgress(migration, p))
+		if migration.Percent >= lastReported+migrationReportStep {
+			lastReported = migration.Percent
+			uc.report(migration)
+		}
+	})
+	if err != nil {
+		// The VM is still running here; release the target's reservation
+		finishCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if _, ferr := uc.peers.FinishMigration(finishCtx, req.TargetAddress, &outgoing, false); ferr != nil {
+			uc.logger.Warn("Failed to release target reservation", zap.Error(ferr))
+		}
+		cancel()
+
+		uc.fail(migration, err)
+		return nil, errors.New(errors.ErrCodeHypervisor, "failed to migrate VM", err).
+			WithContext("vm_id", vm.ID).
+			WithContext("target", req.TargetAddress)
+	}
+
+	// 6. Register the VM on the target; it already runs there
+	migrated, err := uc.finish(ctx, req.TargetAddress, &outgoing)
+	if err != nil {
+		// Keep the record so the VM is not lost to both agents
+		uc.fail(migration, err)
+		return nil, errors.New(errors.ErrCodeUnavailable, "VM runs on the target agent but could not be registered there", err).
+			WithContext("vm_id", vm.ID).
+			WithContext("target", req.TargetAddress)
+	}
+
+	// 7. Forget the VM on this agent; its address and port forwards belong to this host
+	uc.scheduler.Unschedule(vm.ID)
+
+	forwards, err := uc.forwardRepo.FindByVM(ctx, vm.ID)
+	if err != nil {
+		uc.logger.Warn("Failed to list port forwards", zap.String("vm_id", vm.ID), zap.Error(err))
+	}
+	migration.RemovedPortForwards = forwards
+
+	if err := uc.storage.DeleteDisk(ctx, vm.ID); err != nil {
+		uc.logger.Warn("Failed to delete migrated disk", zap.Error(err))
+	}
+
+	if err := uc.vmRepo.Delete(ctx, vm.ID); err != nil {
+		uc.logger.Error("Failed to delete VM from repository", zap.Error(err))
+	}
+	releaseAddress(ctx, uc.ipam, vm.ID, uc.logger)
+	removePortForwards(ctx, uc.forwardRepo, uc.forwarder, vm.ID, uc.logger)
+	removeFilter(ctx, uc.firewall, vm.ID, uc.logger)
+
+	resources, err := uc.resourceRepo.GetAvailable(ctx)
+	if err == nil {
+		resources.Release(vm.VCPU, vm.RAMGB, vm.DiskGB)
+		if err := uc.resourceRepo.Update(ctx, resources); err != nil {
+			uc.logger.Error("Failed to update resources", zap.Error(err))
+		}
+	}
+
+	migration.Phase = entity.MigrationPhaseCompleted
+	migration.Percent = 100
+	migration.IPAddress = migrated.IP
+	uc.report(migration)
+	recordSystemEvent(ctx, uc.auditLog, "forget-migrated-vm", vm.ID, map[string]any{
+		"target":                req.TargetAddress,
+		"removed_port_forwards": len(forwards),
+	}, nil, uc.logger)
+
+	uc.logger.Info("VM migrated successfully",
+		zap.String("vm_id", vm.ID),
+		zap.String("target", req.TargetAddress),
+		zap.String("ip", migrated.IP),
+	)
+
+	return toMigrateVMProgress(migration, nil), nil
+}
+
+// finish registers the migrated VM on the target, retrying a few times
+func (uc *MigrateVMUseCase) finish(ctx context.Context, target string, vm *entity.VM) (*entity.VM, error) {
+	var err error
+	for attempt := 1; attempt <= finishMigrationAttempts; attempt++ {
+		var migrated *entity.VM
+		if migrated, err = uc.peers.FinishMigration(ctx, target, vm, true); err == nil {
+			return migrated, nil
+		}
+		uc.logger.Warn("Failed to register VM on target agent",
+			zap.String("vm_id", vm.ID),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+		if attempt == finishMigrationAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(finishMigrationRetryDelay):
+		}
+	}
+	return nil, err
+}
+
+// fail reports a failed migration to Ghost Core
+func (uc *MigrateVMUseCase) fail(migration *entity.Migration, err error) {
+	migration.Phase = entity.MigrationPhaseFailed
+	migration.Message = err.Error()
+	uc.report(migration)
+}
+
+// report sends migration progress to Ghost Core without failing the migration
+func (uc *MigrateVMUseCase) report(migration *entity.Migration) {
+	if uc.reporter == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := uc.reporter.ReportVMMigration(ctx, migration); err != nil {
+		uc.logger.Warn("Failed to report migration to Ghost Core",
+			zap.String("vm_id", migration.VMID),
+			zap.Error(err),
+		)
+	}
+}
+
+func toMigrateVMProgress(migration *entity.Migration, p *service.MigrationProgress) *dto.MigrateVMProgress {
+	progress := &dto.MigrateVMProgress{
+		Phase:         string(migration.Phase),
+		Percent:       migration.Percent,
+		TargetAddress: migration.TargetAddress,
+		IPAddress:     migration.IPAddress,
+	}
+	if p != nil {
+		progress.DataTotal = p.DataTotal
+		progress.DataProcessed = p.DataProcessed
+	}
+	for _, fwd := range migration.RemovedPortForwards {
+		progress.RemovedPortForwards = append(progress.RemovedPortForwards, toPortForwardInfo(fwd))
+	}
+	return progress
+}
@@ -0,0 +1,449 @@
+package usecase
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// networkMu serializes creating and deleting private networks
+var networkMu sync.Mutex
+
+// Private network subnets must leave room for the gateway and a DHCP range
+const (
+	minNetworkPrefix = 16
+	maxNetworkPrefix = 29
+)
+
+// CreateNetworkUseCase handles creating isolated private networks
+type CreateNetworkUseCase struct {
+	networkRepo repository.NetworkRepository
+	networks    service.PrivateNetworkService
+	firewall    service.FirewallService
+	ipv6Prefix  netip.Prefix // Invalid when IPv6 is disabled
+	validator   *validator.Validate
+	logger      *zap.Logger
+}
+
+// NewCreateNetworkUseCase creates a new CreateNetwork use case
+// Networks get IPv6 /64s from ipv6Prefix after the first one, which belongs to the NAT network;
+// the zero prefix disables IPv6; firewall may be nil when filtering is disabled
+func NewCreateNetworkUseCase(
+	networkRepo repository.NetworkRepository,
+	networks service.PrivateNetworkService,
+	firewall service.FirewallService,
+	ipv6Prefix netip.Prefix,
+	logger *zap.Logger,
+) *CreateNetworkUseCase {
+	return &CreateNetworkUseCase{
+		networkRepo: networkRepo,
+		networks:    networks,
+		firewall:    firewall,
+		ipv6Prefix:  ipv6Prefix,
+		validator:   newValidator(),
+		logger:      logger,
+	}
+}
+
+// Execute creates a private network with its own bridge and DHCP range
+func (uc *CreateNetworkUseCase) Execute(ctx context.Context, req *dto.CreateNetworkRequest) (*dto.CreateNetworkResponse, error) {
+	uc.logger.Info("Creating network",
+		zap.String("name", req.Name),
+		zap.String("tenant", req.Tenant),
+		zap.String("subnet", req.Subnet),
+	)
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	network, err := networkLayout(req)
+	if err != nil {
+		return nil, err
+	}
+
+	networkMu.Lock()
+	defer networkMu.Unlock()
+
+	// 2. Check the name and subnet are free
+	if _, err := uc.networkRepo.FindByName(ctx, req.Name); err == nil {
+		return nil, errors.New(errors.ErrCodeConflict, "network already exists", nil).
+			WithContext("name", req.Name)
+	}
+
+	existing, err := uc.networkRepo.FindAll(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to list networks", err)
+	}
+	for _, other := range existing {
+		if subnetsOverlap(network.Subnet, other.Subnet) {
+			return nil, errors.New(errors.ErrCodeConflict, "subnet overlaps an existing network", nil).
+				WithContext("subnet", network.Subnet).
+				WithContext("network_id", other.ID).
+				WithContext("network_subnet", other.Subnet)
+		}
+	}
+
+	// 3. Pick the IPv6 subnet
+	subnetV6, err := networkSubnetV6(req.SubnetV6, uc.ipv6Prefix, existing)
+	if err != nil {
+		return nil, err
+	}
+	if subnetV6.IsValid() {
+		network.SubnetV6 = subnetV6.String()
+		network.GatewayV6 = subnetV6.Addr().Next().String()
+	}
+
+	// 4. Define the libvirt network, then save the record
+	network.ID = uuid.NewString()
+	network.CreatedAt = time.Now()
+	if err := uc.networks.Define(ctx, network); err != nil {
+		return nil, err
+	}
+	if err := uc.networkRepo.Save(ctx, network); err != nil {
+		_ = uc.networks.Undefine(ctx, network.ID)
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save network", err)
+	}
+
+	// 5. Let the VM filters through to the other guests on the network
+	allowNetworks(ctx, uc.firewall, append(existing, network), uc.logger)
+
+	uc.logger.Info("Network created",
+		zap.String("id", network.ID),
+		zap.String("name", network.Name),
+		zap.String("bridge", network.Bridge),
+	)
+
+	return &dto.CreateNetworkResponse{
+		Network: toNetworkInfo(network, nil),
+	}, nil
+}
+
+// DeleteNetworkUseCase handles deleting private networks
+type DeleteNetworkUseCase struct {
+	networkRepo repository.NetworkRepository
+	vmRepo      repository.VMRepository
+	networks    service.PrivateNetworkService
+	firewall    service.FirewallService
+	validator   *validator.Validate
+	logger      *zap.Logger
+}
+
+// NewDeleteNetworkUseCase creates a new DeleteNetwork use case
+// firewall may be nil when filtering is disabled
+func NewDeleteNetworkUseCase(
+	networkRepo repository.NetworkRepository,
+	vmRepo repository.VMRepository,
+	networks service.PrivateNetworkService,
+	firewall service.FirewallService,
+	logger *zap.Logger,
+) *DeleteNetworkUseCase {
+	return &DeleteNetworkUseCase{
+		networkRepo: networkRepo,
+		vmRepo:      vmRepo,
+		networks:    networks,
+		firewall:    firewall,
+		validator:   newValidator(),
+		logger:      logger,
+	}
+}
+
+// Execute deletes a private network no VM is attached to
+func (uc *DeleteNetworkUseCase) Execute(ctx context.Context, req *dto.DeleteNetworkRequest) (*dto.DeleteNetworkResponse, error) {
+	uc.logger.Info("Deleting network", zap.String("network", req.Network))
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	networkMu.Lock()
+	defer networkMu.Unlock()
+
+	network, err := findNetwork(ctx, uc.networkRepo, req.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Refuse while VMs have a NIC on the network
+	if vmIDs := networkVMs(ctx, uc.vmRepo)[network.ID]; len(vmIDs) > 0 {
+		return nil, errors.New(errors.ErrCodeConflict, "network is in use by VMs", nil).
+			WithContext("network_id", network.ID).
+			WithContext("vm_ids", vmIDs)
+	}
+
+	// 3. Remove libvirt network and record
+	if err := uc.networks.Undefine(ctx, network.ID); err != nil {
+		return nil, err
+	}
+	if err := uc.networkRepo.Delete(ctx, network.ID); err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to delete network", err)
+	}
+
+	// 4. Close the VM filters to the subnet again
+	if remaining, err := uc.networkRepo.FindAll(ctx); err == nil {
+		allowNetworks(ctx, uc.firewall, remaining, uc.logger)
+	}
+
+	return &dto.DeleteNetworkResponse{
+		Success: true,
+	}, nil
+}
+
+// ListNetworksUseCase handles listing private networks
+type ListNetworksUseCase struct {
+	networkRepo repository.NetworkRepository
+	vmRepo      repository.VMRepository
+	logger      *zap.Logger
+}
+
+// NewListNetworksUseCase creates a new ListNetworks use case
+func NewListNetworksUseCase(
+	networkRepo repository.NetworkRepository,
+	vmRepo repository.VMRepository,
+	logger *zap.Logger,
+) *ListNetworksUseCase {
+	return &ListNetworksUseCase{
+		networkRepo: networkRepo,
+		vmRepo:      vmRepo,
+		logger:      logger,
+	}
+}
+
+// Execute lists private networks with the VMs attached to them
+func (uc *ListNetworksUseCase) Execute(ctx context.Context, req *dto.ListNetworksRequest) (*dto.ListNetworksResponse, error) {
+	uc.logger.Debug("Listing networks", zap.String("tenant", req.Tenant))
+
+	networks, err := uc.networkRepo.FindAll(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to list networks", err)
+	}
+
+	attached := networkVMs(ctx, uc.vmRepo)
+	infos := make([]dto.NetworkInfo, 0, len(networks))
+	for _, network := range networks {
+		if req.Tenant != "" && network.Tenant != req.Tenant {
+			continue
+		}
+		infos = append(infos, toNetworkInfo(network, attached[network.ID]))
+	}
+
+	return &dto.ListNetworksResponse{
+		Networks: infos,
+	}, nil
+}
+
+// allowNetworks lets the VM filters through to the guests on networks without
+// failing the caller; firewall may be nil when filtering is disabled
+func allowNetworks(ctx context.Context, firewall service.FirewallService, networks []*entity.Network, logger *zap.Logger) {
+	if firewall == nil {
+		return
+	}
+	if err := firewall.SetNetworks(ctx, networks); err != nil {
+		logger.Warn("Failed to open VM filters to private networks", zap.Error(err))
+	}
+}
+
+// resolveNetworks looks up the private networks a new VM gets NICs on
+// It returns their IDs and the libvirt networks to attach, in request order
+func resolveNetworks(ctx context.Context, networkRepo repository.NetworkRepository, networks service.PrivateNetworkService, refs []string) ([]string, []string, error) {
+	if len(refs) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]string, 0, len(refs))
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		network, err := findNetwork(ctx, networkRepo, ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, network.ID)
+		names = append(names, networks.NetworkName(network.ID))
+	}
+	return ids, names, nil
+}
+
+// findNetwork looks up a network by ID, then by name
+func findNetwork(ctx context.Context, networkRepo repository.NetworkRepository, ref string) (*entity.Network, error) {
+	if network, err := networkRepo.FindByID(ctx, ref); err == nil {
+		return network, nil
+	}
+	return networkRepo.FindByName(ctx, ref)
+}
+
+// networkVMs maps network IDs to the VMs with a NIC on them
+func networkVMs(ctx context.Context, vmRepo repository.VMRepository) map[string][]string {
+	attached := make(map[string][]string)
+
+	vms, err := vmRepo.FindAll(ctx)
+	if err != nil {
+		return attached
+	}
+	for _, vm := range vms {
+		for _, id := range vm.Networks {
+			attached[id] = append(attached[id], vm.ID)
+		}
+	}
+	return attached
+}
+
+// networkLayout validates the addressing of a network request and fills in defaults
+// The gateway is the first host address; the DHCP range defaults to the remaining hosts
+func networkLayout(req *dto.CreateNetworkRequest) (*entity.Network, error) {
+	_, subnet, err := net.ParseCIDR(req.Subnet)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid subnet", err).
+			WithContext("subnet", req.Subnet)
+	}
+
+	ones, _ := subnet.Mask.Size()
+	if ones < minNetworkPrefix || ones > maxNetworkPrefix {
+		return nil, errors.New(errors.ErrCodeValidation, "subnet prefix must be between /16 and /29", nil).
+			WithContext("subnet", req.Subnet)
+	}
+
+	first := binary.BigEndian.Uint32(subnet.IP.To4())
+	last := first | ^binary.BigEndian.Uint32(net.IP(subnet.Mask).To4())
+	gateway := first + 1
+
+	start, end := gateway+1, last-1
+	if req.DHCPStart != "" {
+		start = binary.BigEndian.Uint32(net.ParseIP(req.DHCPStart).To4())
+	}
+	if req.DHCPEnd != "" {
+		end = binary.BigEndian.Uint32(net.ParseIP(req.DHCPEnd).To4())
+	}
+	if start <= gateway || end >= last || start > end {
+		return nil, errors.New(errors.ErrCodeValidation, "DHCP range must lie within the subnet's host addresses, after the gateway", nil).
+			WithContext("subnet", subnet.String()).
+			WithContext("gateway", uint32ToIPString(gateway)).
+			WithContext("dhcp_start", uint32ToIPString(start)).
+			WithContext("dhcp_end", uint32ToIPString(end))
+	}
+
+	return &entity.Network{
+		Name:      req.Name,
+		Tenant:    req.Tenant,
+		Subnet:    subnet.String(),
+		Gateway:   uint32ToIPString(gateway),
+		DHCPStart: uint32ToIPString(start),
+		DHCPEnd:   uint32ToIPString(end),
+		NAT:       req.NAT,
+	}, nil
+}
+
+// networkSubnetV6 validates a requested IPv6 subnet, or picks the next free /64
+// of prefix; the zero prefix is returned for IPv4-only networks
+func networkSubnetV6(requested string, prefix netip.Prefix, existing []*entity.Network) (netip.Prefix, error) {
+	// The first /64 of the prefix belongs to the NAT network
+	reserved, _ := entity.IPv6Subnet(prefix, 0)
+	inUse := func(subnet netip.Prefix) string {
+		if reserved.IsValid() && reserved.Overlaps(subnet) {
+			return "nat network"
+		}
+		for _, other := range existing {
+			if other.SubnetV6 != "" && subnetsOverlap(subnet.String(), other.SubnetV6) {
+				return other.ID
+			}
+		}
+		return ""
+	}
+
+	if requested != "" {
+		subnet, err := netip.ParsePrefix(requested)
+		if err != nil || subnet.Bits() != 64 {
+			return netip.Prefix{}, errors.New(errors.ErrCodeValidation, "IPv6 subnet must be a /64", err).
+				WithContext("subnet_v6", requested)
+		}
+		if owner := inUse(subnet.Masked()); owner != "" {
+			return netip.Prefix{}, errors.New(errors.ErrCodeConflict, "IPv6 subnet overlaps an existing network", nil).
+				WithContext("subnet_v6", requested).
+				WithContext("network_id", owner)
+		}
+		return subnet.Masked(), nil
+	}
+
+	// A /64 prefix only covers the NAT network
+	if !prefix.IsValid() || prefix.Bits() >= 64 {
+		return netip.Prefix{}, nil
+	}
+	for i := 1; ; i++ {
+		subnet, ok := entity.IPv6Subnet(prefix, i)
+		if !ok {
+			return netip.Prefix{}, errors.New(errors.ErrCodeResourceLimit, "no free IPv6 subnets", nil).
+				WithContext("prefix", prefix.String())
+		}
+		if inUse(subnet) == "" {
+			return subnet, nil
+		}
+	}
+}
+
+// subnetsOverlap reports whether two CIDRs share any address
+func subnetsOverlap(a, b string) bool {
+	_, netA, errA := net.ParseCIDR(a)
+	_, netB, errB := net.ParseCIDR(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return netA.Contains(netB.IP) || netB.Contains(netA.IP)
+}
+
+func uint32ToIPString(n uint32) string {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip.String()
+}
+
+func toNetworkInfo(network *entity.Network, vmIDs []string) dto.NetworkInfo {
+	return dto.NetworkInfo{
+		ID:        network.ID,
+		Name:      network.Name,
+		Tenant:    network.Tenant,
+		Subnet:    network.Subnet,
+		Gateway:   network.Gateway,
+		DHCPStart: network.DHCPStart,
+		DHCPEnd:   network.DHCPEnd,
+		SubnetV6:  network.SubnetV6,
+		GatewayV6: network.GatewayV6,
+		NAT:       network.NAT,
+		Bridge:    network.Bridge,
+		VMIDs:     vmIDs,
+		CreatedAt: network.CreatedAt,
+	}
+}
+
+func toVMInterfacesDTO(ifaces []entity.VMInterface) []dto.VMInterface {
+	out := make([]dto.VMInterface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		addrs := make([]dto.IPAddress, 0, len(iface.Addresses))
+		for _, addr := range iface.Addresses {
+			addrs = append(addrs, dto.IPAddress{
+				Address: addr.Address,
+				Prefix:  addr.Prefix,
+				Family:  string(addr.Family),
+			})
+		}
+		out = append(out, dto.VMInterface{
+			Name:       iface.Name,
+			MACAddress: iface.MAC,
+			Addresses:  addrs,
+		})
+	}
+	return out
+}
@@ -0,0 +1,386 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// AddPortForwardUseCase handles forwarding host ports to VMs
+type AddPortForwardUseCase struct {
+	vmRepo         repository.VMRepository
+	network        service.NetworkService
+	forwardRepo    repository.PortForwardRepository
+	forwarder      service.PortForwarder
+	firewall       service.FirewallService
+	portRangeStart int
+	portRangeEnd   int
+	mu             sync.Mutex // Serializes host port allocation
+	validator      *validator.Validate
+	logger         *zap.Logger
+}
+
+// NewAddPortForwardUseCase creates a new AddPortForward use case
+// Host ports are allocated from portRangeStart to portRangeEnd inclusive;
+// firewall is nil when filtering is disabled
+func NewAddPortForwardUseCase(
+	vmRepo repository.VMRepository,
+	network service.NetworkService,
+	forwardRepo repository.PortForwardRepository,
+	forwarder service.PortForwarder,
+	firewall service.FirewallService,
+	portRangeStart int,
+	portRangeEnd int,
+	logger *zap.Logger,
+) *AddPortForwardUseCase {
+	return &AddPortForwardUseCase{
+		vmRepo:         vmRepo,
+		network:        network,
+		forwardRepo:    forwardRepo,
+		forwarder:      forwarder,
+		firewall:       firewall,
+		portRangeStart: portRangeStart,
+		portRangeEnd:   portRangeEnd,
+		validator:      newValidator(),
+		logger:         logger,
+	}
+}
+
+// Execute allocates a host port and programs the forward
+func (uc *AddPortForwardUseCase) Execute(ctx context.Context, req *dto.AddPortForwardRequest) (*dto.AddPortForwardResponse, error) {
+	uc.logger.Info("Adding port forward",
+		zap.String("vm_id", req.VMID),
+		zap.String("protocol", req.Protocol),
+		zap.Int("host_port", req.HostPort),
+		zap.Int("guest_port", req.GuestPort),
+	)
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+	if req.HostPort != 0 && (req.HostPort < uc.portRangeStart || req.HostPort > uc.portRangeEnd) {
+		return nil, errors.New(errors.ErrCodeValidation, "host port outside the forwarding range", nil).
+			WithContext("host_port", req.HostPort).
+			WithContext("range_start", uc.portRangeStart).
+			WithContext("range_end", uc.portRangeEnd)
+	}
+
+	// 2. Get VM from repository
+	vm, err := uc.vmRepo.FindByID(ctx, req.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	ifaces, err := uc.network.GetVMIP(ctx, vm.ID)
+	if err != nil {
+		ifaces = vm.Interfaces // Use cached addresses; the sync job updates them later
+	}
+	guestIP, guestIPv6 := forwardTargets(ifaces)
+	if guestIP == "" && guestIPv6 == "" {
+		guestIP = vm.IP
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	// 3. Allocate host port
+	forwards, err := uc.forwardRepo.FindAll(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to list port forwards", err)
+	}
+
+	protocol := entity.PortProtocol(req.Protocol)
+	used := make(map[int]bool, len(forwards))
+	for _, fwd := range forwards {
+		if fwd.Protocol == protocol {
+			used[fwd.HostPort] = true
+		}
+	}
+
+	hostPort := req.HostPort
+	if hostPort != 0 && used[hostPort] {
+		return nil, errors.New(errors.ErrCodeConflict, "host port already forwarded", nil).
+			WithContext("protocol", req.Protocol).
+			WithContext("host_port", hostPort)
+	}
+	for port := uc.portRangeStart; hostPort == 0 && port <= uc.portRangeEnd; port++ {
+		if !used[port] {
+			hostPort = port
+		}
+	}
+	if hostPort == 0 {
+		return nil, errors.New(errors.ErrCodeResourceLimit, "no free host ports", nil).
+			WithContext("protocol", req.Protocol)
+	}
+
+	// 4. Save and program the forward; the VM's filter would drop its traffic otherwise
+	fwd := &entity.PortForward{
+		ID:        uuid.NewString(),
+		VMID:      vm.ID,
+		Protocol:  protocol,
+		HostPort:  hostPort,
+		GuestPort: req.GuestPort,
+		GuestIP:   guestIP,
+		GuestIPv6: guestIPv6,
+		CreatedAt: time.Now(),
+	}
+	if err := uc.forwardRepo.Save(ctx, fwd); err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save port forward", err)
+	}
+
+	if err := allowForwards(ctx, uc.firewall, uc.forwardRepo, vm.ID); err != nil {
+		_ = uc.forwardRepo.Delete(ctx, fwd.ID)
+		_ = allowForwards(ctx, uc.firewall, uc.forwardRepo, vm.ID)
+		return nil, err
+	}
+	if err := syncPortForwards(ctx, uc.forwardRepo, uc.forwarder); err != nil {
+		_ = uc.forwardRepo.Delete(ctx, fwd.ID)
+		_ = syncPortForwards(ctx, uc.forwardRepo, uc.forwarder)
+		_ = allowForwards(ctx, uc.firewall, uc.forwardRepo, vm.ID)
+		return nil, err
+	}
+
+	uc.logger.Info("Port forward added",
+		zap.String("id", fwd.ID),
+		zap.String("vm_id", vm.ID),
+		zap.Int("host_port", hostPort),
+		zap.String("backend", uc.forwarder.Name()),
+	)
+
+	return &dto.AddPortForwardResponse{
+		PortForward: toPortForwardInfo(fwd),
+	}, nil
+}
+
+// RemovePortForwardUseCase handles removing port forwards
+type RemovePortForwardUseCase struct {
+	forwardRepo repository.PortForwardRepository
+	forwarder   service.PortForwarder
+	firewall    service.FirewallService
+	validator   *validator.Validate
+	logger      *zap.Logger
+}
+
+// NewRemovePortForwardUseCase creates a new RemovePortForward use case
+func NewRemovePortForwardUseCase(
+	forwardRepo repository.PortForwardRepository,
+	forwarder service.PortForwarder,
+	firewall service.FirewallService,
+	logger *zap.Logger,
+) *RemovePortForwardUseCase {
+	return &RemovePortForwardUseCase{
+		forwardRepo: forwardRepo,
+		forwarder:   forwarder,
+		firewall:    firewall,
+		validator:   newValidator(),
+		logger:      logger,
+	}
+}
+
+// Execute removes a port forward
+func (uc *RemovePortForwardUseCase) Execute(ctx context.Context, req *dto.RemovePortForwardRequest) (*dto.RemovePortForwardResponse, error) {
+	uc.logger.Info("Removing port forward", zap.String("id", req.ID))
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	// 2. Delete and re-sync, then close the VM's filter again
+	fwd, err := uc.forwardRepo.FindByID(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.forwardRepo.Delete(ctx, req.ID); err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to delete port forward", err)
+	}
+	if err := syncPortForwards(ctx, uc.forwardRepo, uc.forwarder); err != nil {
+		return nil, err
+	}
+	if err := allowForwards(ctx, uc.firewall, uc.forwardRepo, fwd.VMID); err != nil {
+		return nil, err
+	}
+
+	return &dto.RemovePortForwardResponse{
+		Success: true,
+	}, nil
+}
+
+// ListPortForwardsUseCase handles listing port forwards
+type ListPortForwardsUseCase struct {
+	forwardRepo repository.PortForwardRepository
+	logger      *zap.Logger
+}
+
+// NewListPortForwardsUseCase creates a new ListPortForwards use case
+func NewListPortForwardsUseCase(
+	forwardRepo repository.PortForwardRepository,
+	logger *zap.Logger,
+) *ListPortForwardsUseCase {
+	return &ListPortForwardsUseCase{
+		forwardRepo: forwardRepo,
+		logger:      logger,
+	}
+}
+
+// Execute lists port forwards, optionally of a single VM
+func (uc *ListPortForwardsUseCase) Execute(ctx context.Context, req *dto.ListPortForwardsRequest) (*dto.ListPortForwardsResponse, error) {
+	uc.logger.Debug("Listing port forwards", zap.String("vm_id", req.VMID))
+
+	var (
+		forwards []*entity.PortForward
+		err      error
+	)
+	if req.VMID != "" {
+		forwards, err = uc.forwardRepo.FindByVM(ctx, req.VMID)
+	} else {
+		forwards, err = uc.forwardRepo.FindAll(ctx)
+	}
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to list port forwards", err)
+	}
+
+	infos := make([]dto.PortForwardInfo, 0, len(forwards))
+	for _, fwd := range forwards {
+		infos = append(infos, toPortForwardInfo(fwd))
+	}
+
+	return &dto.ListPortForwardsResponse{
+		PortForwards: infos,
+	}, nil
+}
+
+// SyncPortForwardsUseCase keeps forwards pointed at current VM addresses
+// It runs at agent start and periodically, dropping forwards of VMs that are
+// gone (deleted or migrated away) and re-targeting those whose VM IP changed
+type SyncPortForwardsUseCase struct {
+	vmRepo      repository.VMRepository
+	network     service.NetworkService
+	forwardRepo repository.PortForwardRepository
+	forwarder   service.PortForwarder
+	logger      *zap.Logger
+}
+
+// NewSyncPortForwardsUseCase creates a new SyncPortForwards use case
+func NewSyncPortForwardsUseCase(
+	vmRepo repository.VMRepository,
+	network service.NetworkService,
+	forwardRepo repository.PortForwardRepository,
+	forwarder service.PortForwarder,
+	logger *zap.Logger,
+) *SyncPortForwardsUseCase {
+	return &SyncPortForwardsUseCase{
+		vmRepo:      vmRepo,
+		network:     network,
+		forwardRepo: forwardRepo,
+		forwarder:   forwarder,
+		logger:      logger,
+	}
+}
+
+// Execute refreshes guest addresses and re-applies all forwards
+func (uc *SyncPortForwardsUseCase) Execute(ctx context.Context) error {
+	forwards, err := uc.forwardRepo.FindAll(ctx)
+	if err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to list port forwards", err)
+	}
+
+	for _, fwd := range forwards {
+		if _, err := uc.vmRepo.FindByID(ctx, fwd.VMID); err != nil {
+			uc.logger.Info("Removing port forward of missing VM",
+				zap.String("id", fwd.ID),
+				zap.String("vm_id", fwd.VMID),
+			)
+			_ = uc.forwardRepo.Delete(ctx, fwd.ID)
+			continue
+		}
+
+		ifaces, err := uc.network.GetVMIP(ctx, fwd.VMID)
+		if err != nil {
+			continue
+		}
+		ip, ipv6 := forwardTargets(ifaces)
+		if (ip == "" && ipv6 == "") || (ip == fwd.GuestIP && ipv6 == fwd.GuestIPv6) {
+			continue
+		}
+
+		uc.logger.Info("VM address changed, updating port forward",
+			zap.String("id", fwd.ID),
+			zap.String("vm_id", fwd.VMID),
+			zap.String("old_ip", fwd.GuestIP),
+			zap.String("new_ip", ip),
+			zap.String("old_ipv6", fwd.GuestIPv6),
+			zap.String("new_ipv6", ipv6),
+		)
+		fwd.GuestIP = ip
+		fwd.GuestIPv6 = ipv6
+		if err := uc.forwardRepo.Save(ctx, fwd); err != nil {
+			uc.logger.Error("Failed to save port forward", zap.Error(err))
+		}
+	}
+
+	return syncPortForwards(ctx, uc.forwardRepo, uc.forwarder)
+}
+
+// forwardTargets returns the IPv4 and IPv6 address of a VM's primary NIC forwards point at
+func forwardTargets(ifaces []entity.VMInterface) (string, string) {
+	if len(ifaces) == 0 {
+		return "", ""
+	}
+	return ifaces[0].Address(entity.IPFamilyV4), ifaces[0].Address(entity.IPFamilyV6)
+}
+
+// removePortForwards drops all forwards of a VM
+func removePortForwards(ctx context.Context, forwardRepo repository.PortForwardRepository, forwarder service.PortForwarder, vmID string, logger *zap.Logger) {
+	forwards, err := forwardRepo.FindByVM(ctx, vmID)
+	if err != nil || len(forwards) == 0 {
+		return
+	}
+
+	for _, fwd := range forwards {
+		if err := forwardRepo.Delete(ctx, fwd.ID); err != nil {
+			logger.Error("Failed to delete port forward", zap.String("id", fwd.ID), zap.Error(err))
+		}
+	}
+	if err := syncPortForwards(ctx, forwardRepo, forwarder); err != nil {
+		logger.Warn("Failed to sync port forwards", zap.Error(err))
+	}
+}
+
+// syncPortForwards pushes the persisted forwards to the forwarder
+func syncPortForwards(ctx context.Context, forwardRepo repository.PortForwardRepository, forwarder service.PortForwarder) error {
+	forwards, err := forwardRepo.FindAll(ctx)
+	if err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to list port forwards", err)
+	}
+	if err := forwarder.Sync(ctx, forwards); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to apply port forwards", err).
+			WithContext("backend", forwarder.Name())
+	}
+	return nil
+}
+
+func toPortForwardInfo(fwd *entity.PortForward) dto.PortForwardInfo {
+	return dto.PortForwardInfo{
+		ID:        fwd.ID,
+		VMID:      fwd.VMID,
+		Protocol:  string(fwd.Protocol),
+		HostPort:  fwd.HostPort,
+		GuestPort: fwd.GuestPort,
+		GuestIP:   fwd.GuestIP,
+		GuestIPv6: fwd.GuestIPv6,
+		CreatedAt: fwd.CreatedAt,
+	}
+}
@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+)
+
+// QuotaReserver checks tenant quotas and counts VMs that are not saved yet
+// towards them
+// Checks and the reservations they make are serialized, so concurrent
+// requests cannot each take the last of a tenant's quota
+type QuotaReserver struct {
+	vmRepo  repository.VMRepository
+	quotas  entity.Quotas
+	mu      sync.Mutex
+	pending map[string]*entity.VM // VMs not saved yet, by ID
+}
+
+// NewQuotaReserver creates a quota reserver for the VMs in vmRepo
+func NewQuotaReserver(vmRepo repository.VMRepository, quotas entity.Quotas) *QuotaReserver {
+	return &QuotaReserver{
+		vmRepo:  vmRepo,
+		quotas:  quotas,
+		pending: make(map[string]*entity.VM),
+	}
+}
+
+// Reserve fails when one more VM of this size would take the tenant over
+// its quota; otherwise the VM counts towards the quota until Release,
+// which is called once the VM is saved or given up on
+// VMs without a tenant are not subject to quotas
+// The context pairs requested_<x> with available_<x> for each capped resource
+func (r *QuotaReserver) Reserve(ctx context.Context, vmID, tenant string, vcpu, ramGB, diskGB int) error {
+	if tenant == "" {
+		return nil
+	}
+	quota := r.quotas.For(tenant)
+	if quota.Unlimited() {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vms, err := r.vmRepo.FindAll(ctx)
+	if err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to list VMs", err)
+	}
+	var usage entity.QuotaUsage
+	saved := make(map[string]bool, len(vms))
+	for _, vm := range vms {
+		saved[vm.ID] = true
+		if vm.Tenant() == tenant {
+			usage.Add(vm)
+		}
+	}
+	for id, vm := range r.pending {
+		if !saved[id] && vm.Tenant() == tenant {
+			usage.Add(vm)
+		}
+	}
+	if quota.Allows(usage, vcpu, ramGB, diskGB) {
+		r.pending[vmID] = &entity.VM{
+			ID:       vmID,
+			VCPU:     vcpu,
+			RAMGB:    ramGB,
+			DiskGB:   diskGB,
+			Metadata: map[string]string{entity.MetadataTenant: tenant},
+		}
+		return nil
+	}
+
+	appErr := errors.New(errors.ErrCodeResourceLimit, "tenant quota exceeded", nil).
+		WithContext("tenant", tenant)
+	for _, res := range []struct {
+		name                   string
+		limit, used, requested int
+	}{
+		{"vms", quota.MaxVMs, usage.VMs, 1},
+		{"vcpu", quota.VCPU, usage.VCPU, vcpu},
+		{"ram_gb", quota.RAMGB, usage.RAMGB, ramGB},
+		{"disk_gb", quota.DiskGB, usage.DiskGB, diskGB},
+	} {
+		if res.limit == 0 {
+			continue
+		}
+		appErr.WithContext("requested_"+res.name, res.requested).
+			WithContext("available_"+res.name, max(res.limit-res.used, 0))
+	}
+	return appErr
+}
+
+// Release ends the reservation of Reserve
+func (r *QuotaReserver) Release(vmID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, vmID)
+}
+
+// tenantUsage adds up the VMs of a tenant
+func tenantUsage(ctx context.Context, vmRepo repository.VMRepository, tenant string) (entity.QuotaUsage, error) {
+	vms, err := vmRepo.FindAll(ctx)
+	if err != nil {
+		return entity.QuotaUsage{}, errors.New(errors.ErrCodeInternal, "failed to list VMs", err)
+	}
+
+	var usage entity.QuotaUsage
+	for _, vm := range vms {
+		if vm.Tenant() == tenant {
+			usage.Add(vm)
+		}
+	}
+	return usage, nil
+}
+
+// GetQuotasUseCase handles reporting tenant quotas and their usage
+type GetQuotasUseCase struct {
+	vmRepo       repository.VMRepository
+	resourceRepo repository.ResourceRepository
+	quotas       entity.Quotas
+	validator    *validator.Validate
+	logger       *zap.Logger
+}
+
+// NewGetQuotasUseCase creates a new GetQuotas use case
+func NewGetQuotasUseCase(
+	vmRepo repository.VMRepository,
+	resourceRepo repository.ResourceRepository,
+	quotas entity.Quotas,
+	logger *zap.Logger,
+) *GetQuotasUseCase {
+	return &GetQuotasUseCase{
+		vmRepo:       vmRepo,
+		resourceRepo: resourceRepo,
+		quotas:       quotas,
+		validator:    newValidator(),
+		logger:       logger,
+	}
+}
+
+// Execute returns the quotas of the requested tenant, or of all tenants with
+// a quota or VMs, along with the overcommit ratios
+func (uc *GetQuotasUseCase) Execute(ctx context.Context, req *dto.GetQuotasRequest) (*dto.GetQuotasResponse, error) {
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	quotas, err := uc.Usage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Tenant != "" {
+		quota := &entity.TenantQuota{Tenant: req.Tenant, Quota: uc.quotas.For(req.Tenant)}
+		for _, q := range quotas {
+			if q.Tenant == req.Tenant {
+				quota = q
+			}
+		}
+		quotas = []*entity.TenantQuota{quota}
+	}
+
+	res, err := uc.resourceRepo.GetAvailable(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to get available resources", err)
+	}
+
+	resp := &dto.GetQuotasResponse{
+		Quotas: make([]dto.TenantQuota, len(quotas)),
+		Overcommit: dto.Overcommit{
+			CPU:              res.Overcommit.CPU,
+			RAM:              res.Overcommit.RAM,
+			Disk:             res.Overcommit.Disk,
+			ThinProvisioning: res.ThinProvisioned,
+		},
+	}
+	for i, q := range quotas {
+		resp.Quotas[i] = dto.TenantQuota{
+			Tenant: q.Tenant,
+			Limit: dto.QuotaUsage{
+				VMs:    q.Quota.MaxVMs,
+				VCPU:   q.Quota.VCPU,
+				RAMGB:  q.Quota.RAMGB,
+				DiskGB: q.Quota.DiskGB,
+			},
+			Usage: dto.QuotaUsage(q.Usage),
+		}
+	}
+	return resp, nil
+}
+
+// Usage returns the quota and usage of every tenant with a quota or VMs,
+// ordered by tenant
+func (uc *GetQuotasUseCase) Usage(ctx context.Context) ([]*entity.TenantQuota, error) {
+	vms, err := uc.vmRepo.FindAll(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to list VMs", err)
+	}
+
+	byTenant := make(map[string]*entity.TenantQuota)
+	get := func(tenant string) *entity.TenantQuota {
+		q, ok := byTenant[tenant]
+		if !ok {
+			q = &entity.TenantQuota{Tenant: tenant, Quota: uc.quotas.For(tenant)}
+			byTenant[tenant] = q
+		}
+		return q
+	}
+	for tenant := range uc.quotas.Tenants {
+		get(tenant)
+	}
+	for _, vm := range vms {
+		if tenant := vm.Tenant(); tenant != "" {
+			get(tenant).Usage.Add(vm)
+		}
+	}
+
+	quotas := make([]*entity.TenantQuota, 0, len(byTenant))
+	for _, q := range byTenant {
+		quotas = append(quotas, q)
+	}
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].Tenant < quotas[j].Tenant })
+	return quotas, nil
+}
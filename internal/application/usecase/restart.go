@@ -0,0 +1,267 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// SetRestartPolicyUseCase handles changing the restart policy of a VM
+type SetRestartPolicyUseCase struct {
+	hypervisor service.HypervisorService
+	vmRepo     repository.VMRepository
+	validator  *validator.Validate
+	logger     *zap.Logger
+}
+
+// NewSetRestartPolicyUseCase creates a new SetRestartPolicy use case
+func NewSetRestartPolicyUseCase(
+	hypervisor service.HypervisorService,
+	vmRepo repository.VMRepository,
+	logger *zap.Logger,
+) *SetRestartPolicyUseCase {
+	return &SetRestartPolicyUseCase{
+		hypervisor: hypervisor,
+		vmRepo:     vmRepo,
+		validator:  newValidator(),
+		logger:     logger,
+	}
+}
+
+// Execute replaces the restart policy of a VM and mirrors it into libvirt autostart
+func (uc *SetRestartPolicyUseCase) Execute(ctx context.Context, req *dto.SetRestartPolicyRequest) (*dto.SetRestartPolicyResponse, error) {
+	uc.logger.Info("Setting VM restart policy",
+		zap.String("vm_id", req.VMID),
+		zap.String("policy", req.Policy),
+	)
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	// 2. Get VM from repository
+	vm, err := uc.vmRepo.FindByID(ctx, req.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	// 3. Replace the policy; whether the VM was stopped through the API carries over
+	vm, err = uc.vmRepo.Update(ctx, vm.ID, func(vm *entity.VM) {
+		restart := newRestart(req.Policy, req.MaxRetries, entity.RestartNever)
+		if restart != nil && vm.Restart != nil {
+			restart.Stopped = vm.Restart.Stopped
+		}
+		vm.Restart = restart
+		vm.UpdatedAt = time.Now()
+	})
+	if vmGone(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save VM", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	// 4. Mirror the policy into libvirt
+	if err := uc.hypervisor.SetAutostart(ctx, vm.ID, vm.Autostart()); err != nil {
+		return nil, err
+	}
+
+	uc.logger.Info("VM restart policy set",
+		zap.String("vm_id", vm.ID),
+		zap.String("policy", req.Policy),
+		zap.Bool("autostart", vm.Autostart()),
+	)
+
+	return &dto.SetRestartPolicyResponse{
+		VMID:       vm.ID,
+		Policy:     req.Policy,
+		MaxRetries: req.MaxRetries,
+		Autostart:  vm.Autostart(),
+	}, nil
+}
+
+// RestartVMsUseCase handles bringing VMs that are down back up under their restart policy
+type RestartVMsUseCase struct {
+	hypervisor service.HypervisorService
+	vmRepo     repository.VMRepository
+	auditLog   service.AuditLog
+	backoff    entity.RestartBackoff
+	logger     *zap.Logger
+}
+
+// NewRestartVMsUseCase creates a new RestartVMs use case
+func NewRestartVMsUseCase(
+	hypervisor service.HypervisorService,
+	vmRepo repository.VMRepository,
+	auditLog service.AuditLog,
+	backoff entity.RestartBackoff,
+	logger *zap.Logger,
+) *RestartVMsUseCase {
+	return &RestartVMsUseCase{
+		hypervisor: hypervisor,
+		vmRepo:     vmRepo,
+		auditLog:   auditLog,
+		backoff:    backoff,
+		logger:     logger,
+	}
+}
+
+// Restore brings VMs back up when the agent starts, after the networks,
+// firewall and port forwards they depend on were reconciled
+// always VMs come back even if they were stopped through the API; VMs that
+// kept failing stay backed off from, so on-failure VMs that gave up stay down
+func (uc *RestartVMsUseCase) Restore(ctx context.Context) error {
+	return uc.run(ctx, true)
+}
+
+// Execute restarts VMs that went down since the last run, backing off
+// from VMs that keep going down; a VM that failed is retried on the next run
+func (uc *RestartVMsUseCase) Execute(ctx context.Context) error {
+	return uc.run(ctx, false)
+}
+
+func (uc *RestartVMsUseCase) run(ctx context.Context, agentStart bool) error {
+	vms, err := uc.vmRepo.FindAll(ctx)
+	if err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to list VMs", err)
+	}
+
+	for _, vm := range vms {
+		if err := uc.check(ctx, vm, agentStart, time.Now()); err != nil && !vmGone(err) {
+			uc.logger.Error("Failed to restart VM", zap.String("vm_id", vm.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// check mirrors the policy of vm into libvirt autostart and restarts vm if
+// the policy wants it up; vm is shared with other callers, so changes go
+// through the repository
+func (uc *RestartVMsUseCase) check(ctx context.Context, vm *entity.VM, agentStart bool, now time.Time) error {
+	status, err := uc.hypervisor.GetVMStatus(ctx, vm.ID)
+	if err != nil {
+		return err
+	}
+
+	// Leases, imports and migrations change autostart without touching libvirt
+	if status.Autostart != vm.Autostart() {
+		if err := uc.hypervisor.SetAutostart(ctx, vm.ID, vm.Autostart()); err != nil {
+			return err
+		}
+	}
+
+	if vm.Restart == nil || (vm.Expiry != nil && vm.Expiry.Expired) {
+		return nil
+	}
+	restart := *vm.Restart
+
+	// The backoff starts over once the VM stayed up, also across agent restarts
+	healthy := status.Status == entity.VMStatusRunning && now.Sub(restart.LastRestart) >= uc.backoff.Max
+	if restart.Restarts > 0 && healthy {
+		if _, err := uc.vmRepo.Update(ctx, vm.ID, func(vm *entity.VM) {
+			if vm.Restart != nil {
+				vm.Restart.Restarts = 0
+			}
+		}); err != nil {
+			return err
+		}
+		restart.Restarts = 0
+	}
+	if status.Status == entity.VMStatusRunning || status.Status == entity.VMStatusPaused {
+		return nil
+	}
+
+	failed := status.Crashed || status.Status == entity.VMStatusError
+	if !restart.Wants(failed, agentStart) {
+		return nil
+	}
+	if restart.GaveUp() {
+		uc.logger.Debug("VM keeps failing, not restarting it",
+			zap.String("vm_id", vm.ID),
+			zap.Int("restarts", restart.Restarts),
+		)
+		return nil
+	}
+	if restart.Restarts > 0 && now.Before(restart.LastRestart.Add(uc.backoff.Delay(restart.Restarts))) {
+		return nil
+	}
+
+	uc.logger.Info("Restarting VM",
+		zap.String("vm_id", vm.ID),
+		zap.String("policy", string(restart.Policy)),
+		zap.String("status", string(status.Status)),
+		zap.Bool("crashed", status.Crashed),
+		zap.Int("restarts", restart.Restarts),
+	)
+
+	// A crashed domain that libvirt kept around has to be torn down first
+	if status.Status == entity.VMStatusError {
+		_ = uc.hypervisor.StopVM(ctx, vm.ID, true)
+	}
+	startErr := uc.hypervisor.StartVM(ctx, vm.ID)
+	recordSystemEvent(ctx, uc.auditLog, "restart-vm", vm.ID, map[string]any{
+		"policy":   restart.Policy,
+		"status":   status.Status,
+		"crashed":  status.Crashed,
+		"restarts": restart.Restarts,
+	}, startErr, uc.logger)
+
+	// Failed starts count too, so a VM that cannot start is backed off from
+	if _, err := uc.vmRepo.Update(ctx, vm.ID, func(vm *entity.VM) {
+		if vm.Restart == nil {
+			return
+		}
+		vm.Restart.Restarts++
+		vm.Restart.LastRestart = now
+		if startErr == nil {
+			vm.Restart.Stopped = false
+		}
+		vm.UpdatedAt = now
+	}); err != nil && !vmGone(err) {
+		uc.logger.Error("Failed to save VM to repository", zap.Error(err))
+	}
+	return startErr
+}
+
+// newRestart returns the restart policy a request asks for, fallback when
+// it asks for none; never needs no policy
+func newRestart(policy string, maxRetries int, fallback entity.RestartPolicy) *entity.Restart {
+	p := entity.RestartPolicy(policy)
+	if p == "" {
+		p = fallback
+	}
+	if p == entity.RestartNever {
+		return nil
+	}
+	return &entity.Restart{Policy: p, MaxRetries: maxRetries}
+}
+
+// restartPolicy names the restart policy of vm
+func restartPolicy(vm *entity.VM) string {
+	if vm.Restart == nil {
+		return string(entity.RestartNever)
+	}
+	return string(vm.Restart.Policy)
+}
+
+// applyAutostart mirrors the restart policy of vm into libvirt without failing the caller
+func applyAutostart(ctx context.Context, hypervisor service.HypervisorService, vm *entity.VM, logger *zap.Logger) {
+	if err := hypervisor.SetAutostart(ctx, vm.ID, vm.Autostart()); err != nil {
+		logger.Warn("Failed to set VM autostart",
+			zap.String("vm_id", vm.ID),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,564 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// securityGroupMu serializes read-modify-write of security groups and VM attachments
+var securityGroupMu sync.Mutex
+
+// CreateSecurityGroupUseCase handles creating security groups
+type CreateSecurityGroupUseCase struct {
+	sgRepo    repository.SecurityGroupRepository
+	firewall  service.FirewallService
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+// NewCreateSecurityGroupUseCase creates a new CreateSecurityGroup use case
+// firewall is nil when the firewall is disabled
+func NewCreateSecurityGroupUseCase(
+	sgRepo repository.SecurityGroupRepository,
+	firewall service.FirewallService,
+	logger *zap.Logger,
+) *CreateSecurityGroupUseCase {
+	return &CreateSecurityGroupUseCase{
+		sgRepo:    sgRepo,
+		firewall:  firewall,
+		validator: newValidator(),
+		logger:    logger,
+	}
+}
+
+// Execute creates a security group
+func (uc *CreateSecurityGroupUseCase) Execute(ctx context.Context, req *dto.CreateSecurityGroupRequest) (*dto.SecurityGroupResponse, error) {
+	uc.logger.Info("Creating security group",
+		zap.String("name", req.Name),
+		zap.Int("rules", len(req.Rules)),
+	)
+
+	// 1. Validate input
+	if err := requireFirewall(uc.firewall); err != nil {
+		return nil, err
+	}
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	rules := make([]entity.SecurityGroupRule, 0, len(req.Rules))
+	for _, r := range req.Rules {
+		rule, err := toSecurityGroupRule(r)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	securityGroupMu.Lock()
+	defer securityGroupMu.Unlock()
+
+	// 2. Check if the name is taken
+	if _, err := uc.sgRepo.FindByName(ctx, req.Name); err == nil {
+		return nil, errors.New(errors.ErrCodeConflict, "security group already exists", nil).
+			WithContext("name", req.Name)
+	}
+
+	// 3. Define the filter, then save the group
+	now := time.Now()
+	sg := &entity.SecurityGroup{
+		ID:          uuid.NewString(),
+		Name:        req.Name,
+		Description: req.Description,
+		Rules:       rules,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := uc.firewall.DefineGroup(ctx, sg); err != nil {
+		return nil, err
+	}
+	if err := uc.sgRepo.Save(ctx, sg); err != nil {
+		_ = uc.firewall.UndefineGroup(ctx, sg.ID)
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save security group", err)
+	}
+
+	uc.logger.Info("Security group created",
+		zap.String("id", sg.ID),
+		zap.String("name", sg.Name),
+	)
+
+	return &dto.SecurityGroupResponse{
+		SecurityGroup: toSecurityGroupInfo(sg, nil),
+	}, nil
+}
+
+// DeleteSecurityGroupUseCase handles deleting security groups
+type DeleteSecurityGroupUseCase struct {
+	sgRepo    repository.SecurityGroupRepository
+	vmRepo    repository.VMRepository
+	firewall  service.FirewallService
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+// NewDeleteSecurityGroupUseCase creates a new DeleteSecurityGroup use case
+func NewDeleteSecurityGroupUseCase(
+	sgRepo repository.SecurityGroupRepository,
+	vmRepo repository.VMRepository,
+	firewall service.FirewallService,
+	logger *zap.Logger,
+) *DeleteSecurityGroupUseCase {
+	return &DeleteSecurityGroupUseCase{
+		sgRepo:    sgRepo,
+		vmRepo:    vmRepo,
+		firewall:  firewall,
+		validator: newValidator(),
+		logger:    logger,
+	}
+}
+
+// Execute deletes a security group that is not attached to any VM
+func (uc *DeleteSecurityGroupUseCase) Execute(ctx context.Context, req *dto.DeleteSecurityGroupRequest) (*dto.DeleteSecurityGroupResponse, error) {
+	uc.logger.Info("Deleting security group", zap.String("group", req.Group))
+
+	// 1. Validate input
+	if err := requireFirewall(uc.firewall); err != nil {
+		return nil, err
+	}
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	securityGroupMu.Lock()
+	defer securityGroupMu.Unlock()
+
+	sg, err := findSecurityGroup(ctx, uc.sgRepo, req.Group)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Refuse while VM filters reference the group
+	if vmIDs := attachedVMs(ctx, uc.vmRepo)[sg.ID]; len(vmIDs) > 0 {
+		return nil, errors.New(errors.ErrCodeConflict, "security group is attached to VMs", nil).
+			WithContext("security_group_id", sg.ID).
+			WithContext("vm_ids", vmIDs)
+	}
+
+	// 3. Remove filter and record
+	if err := uc.firewall.UndefineGroup(ctx, sg.ID); err != nil {
+		return nil, err
+	}
+	if err := uc.sgRepo.Delete(ctx, sg.ID); err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to delete security group", err)
+	}
+
+	return &dto.DeleteSecurityGroupResponse{
+		Success: true,
+	}, nil
+}
+
+// ListSecurityGroupsUseCase handles listing security groups
+type ListSecurityGroupsUseCase struct {
+	sgRepo repository.SecurityGroupRepository
+	vmRepo repository.VMRepository
+	logger *zap.Logger
+}
+
+// NewListSecurityGroupsUseCase creates a new ListSecurityGroups use case
+func NewListSecurityGroupsUseCase(
+	sgRepo repository.SecurityGroupRepository,
+	vmRepo repository.VMRepository,
+	logger *zap.Logger,
+) *ListSecurityGroupsUseCase {
+	return &ListSecurityGroupsUseCase{
+		sgRepo: sgRepo,
+		vmRepo: vmRepo,
+		logger: logger,
+	}
+}
+
+// Execute lists all security groups with the VMs they are attached to
+func (uc *ListSecurityGroupsUseCase) Execute(ctx context.Context) (*dto.ListSecurityGroupsResponse, error) {
+	uc.logger.Debug("Listing security groups")
+
+	groups, err := uc.sgRepo.FindAll(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to list security groups", err)
+	}
+
+	attached := attachedVMs(ctx, uc.vmRepo)
+	infos := make([]dto.SecurityGroupInfo, 0, len(groups))
+	for _, sg := range groups {
+		infos = append(infos, toSecurityGroupInfo(sg, attached[sg.ID]))
+	}
+
+	return &dto.ListSecurityGroupsResponse{
+		SecurityGroups: infos,
+	}, nil
+}
+
+// UpdateSecurityGroupRulesUseCase handles adding and removing security group rules
+// Rule changes apply to running VMs the group is attached to
+type UpdateSecurityGroupRulesUseCase struct {
+	sgRepo    repository.SecurityGroupRepository
+	vmRepo    repository.VMRepository
+	firewall  service.FirewallService
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+// NewUpdateSecurityGroupRulesUseCase creates a new UpdateSecurityGroupRules use case
+func NewUpdateSecurityGroupRulesUseCase(
+	sgRepo repository.SecurityGroupRepository,
+	vmRepo repository.VMRepository,
+	firewall service.FirewallService,
+	logger *zap.Logger,
+) *UpdateSecurityGroupRulesUseCase {
+	return &UpdateSecurityGroupRulesUseCase{
+		sgRepo:    sgRepo,
+		vmRepo:    vmRepo,
+		firewall:  firewall,
+		validator: newValidator(),
+		logger:    logger,
+	}
+}
+
+// AddRule adds a rule to a security group
+func (uc *UpdateSecurityGroupRulesUseCase) AddRule(ctx context.Context, req *dto.AddSecurityGroupRuleRequest) (*dto.SecurityGroupResponse, error) {
+	uc.logger.Info("Adding security group rule",
+		zap.String("group", req.Group),
+		zap.String("direction", req.Rule.Direction),
+		zap.String("protocol", req.Rule.Protocol),
+	)
+
+	// 1. Validate input
+	if err := requireFirewall(uc.firewall); err != nil {
+		return nil, err
+	}
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+	rule, err := toSecurityGroupRule(req.Rule)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.update(ctx, req.Group, func(sg *entity.SecurityGroup) error {
+		sg.Rules = append(sg.Rules, rule)
+		return nil
+	})
+}
+
+// RemoveRule removes a rule from a security group
+func (uc *UpdateSecurityGroupRulesUseCase) RemoveRule(ctx context.Context, req *dto.RemoveSecurityGroupRuleRequest) (*dto.SecurityGroupResponse, error) {
+	uc.logger.Info("Removing security group rule",
+		zap.String("group", req.Group),
+		zap.String("rule_id", req.RuleID),
+	)
+
+	// 1. Validate input
+	if err := requireFirewall(uc.firewall); err != nil {
+		return nil, err
+	}
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	return uc.update(ctx, req.Group, func(sg *entity.SecurityGroup) error {
+		rules := make([]entity.SecurityGroupRule, 0, len(sg.Rules))
+		for _, rule := range sg.Rules {
+			if rule.ID != req.RuleID {
+				rules = append(rules, rule)
+			}
+		}
+		if len(rules) == len(sg.Rules) {
+			return errors.New(errors.ErrCodeNotFound, "rule not found", nil).
+				WithContext("security_group_id", sg.ID).
+				WithContext("rule_id", req.RuleID)
+		}
+		sg.Rules = rules
+		return nil
+	})
+}
+
+// update applies change to a copy of a group, redefines its filter and saves it
+func (uc *UpdateSecurityGroupRulesUseCase) update(ctx context.Context, ref string, change func(sg *entity.SecurityGroup) error) (*dto.SecurityGroupResponse, error) {
+	securityGroupMu.Lock()
+	defer securityGroupMu.Unlock()
+
+	// 2. Get group
+	sg, err := findSecurityGroup(ctx, uc.sgRepo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *sg
+	updated.Rules = append([]entity.SecurityGroupRule{}, sg.Rules...)
+	if err := change(&updated); err != nil {
+		return nil, err
+	}
+	updated.UpdatedAt = time.Now()
+
+	// 3. Redefine the filter, then save the group
+	if err := uc.firewall.DefineGroup(ctx, &updated); err != nil {
+		return nil, err
+	}
+	if err := uc.sgRepo.Save(ctx, &updated); err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save security group", err).
+			WithContext("security_group_id", sg.ID)
+	}
+
+	return &dto.SecurityGroupResponse{
+		SecurityGroup: toSecurityGroupInfo(&updated, attachedVMs(ctx, uc.vmRepo)[sg.ID]),
+	}, nil
+}
+
+// AttachSecurityGroupUseCase handles attaching security groups to and detaching them from VMs
+type AttachSecurityGroupUseCase struct {
+	sgRepo    repository.SecurityGroupRepository
+	vmRepo    repository.VMRepository
+	firewall  service.FirewallService
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+// NewAttachSecurityGroupUseCase creates a new AttachSecurityGroup use case
+func NewAttachSecurityGroupUseCase(
+	sgRepo repository.SecurityGroupRepository,
+	vmRepo repository.VMRepository,
+	firewall service.FirewallService,
+	logger *zap.Logger,
+) *AttachSecurityGroupUseCase {
+	return &AttachSecurityGroupUseCase{
+		sgRepo:    sgRepo,
+		vmRepo:    vmRepo,
+		firewall:  firewall,
+		validator: newValidator(),
+		logger:    logger,
+	}
+}
+
+// Attach attaches a security group to a VM; attaching twice is a no-op
+func (uc *AttachSecurityGroupUseCase) Attach(ctx context.Context, req *dto.AttachSecurityGroupRequest) (*dto.AttachSecurityGroupResponse, error) {
+	uc.logger.Info("Attaching security group",
+		zap.String("vm_id", req.VMID),
+		zap.String("group", req.Group),
+	)
+
+	return uc.update(ctx, req, func(groups []string, groupID string) ([]string, error) {
+		for _, id := range groups {
+			if id == groupID {
+				return groups, nil
+			}
+		}
+		return append(append([]string{}, groups...), groupID), nil
+	})
+}
+
+// Detach detaches a security group from a VM
+func (uc *AttachSecurityGroupUseCase) Detach(ctx context.Context, req *dto.AttachSecurityGroupRequest) (*dto.AttachSecurityGroupResponse, error) {
+	uc.logger.Info("Detaching security group",
+		zap.String("vm_id", req.VMID),
+		zap.String("group", req.Group),
+	)
+
+	return uc.update(ctx, req, func(groups []string, groupID string) ([]string, error) {
+		remaining := make([]string, 0, len(groups))
+		for _, id := range groups {
+			if id != groupID {
+				remaining = append(remaining, id)
+			}
+		}
+		if len(remaining) == len(groups) {
+			return nil, errors.New(errors.ErrCodeNotFound, "security group not attached to VM", nil).
+				WithContext("vm_id", req.VMID).
+				WithContext("security_group_id", groupID)
+		}
+		return remaining, nil
+	})
+}
+
+// update changes the security groups of a VM and re-applies its filter
+func (uc *AttachSecurityGroupUseCase) update(
+	ctx context.Context,
+	req *dto.AttachSecurityGroupRequest,
+	change func(groups []string, groupID string) ([]string, error),
+) (*dto.AttachSecurityGroupResponse, error) {
+	// 1. Validate input
+	if err := requireFirewall(uc.firewall); err != nil {
+		return nil, err
+	}
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
+	}
+
+	securityGroupMu.Lock()
+	defer securityGroupMu.Unlock()
+
+	// 2. Get VM and group
+	vm, err := uc.vmRepo.FindByID(ctx, req.VMID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", req.VMID)
+	}
+	sg, err := findSecurityGroup(ctx, uc.sgRepo, req.Group)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := change(vm.SecurityGroups, sg.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Apply the filter; running VMs pick it up immediately
+	if err := uc.firewall.ApplyVM(ctx, vm.ID, groups); err != nil {
+		return nil, err
+	}
+
+	vm, err = uc.vmRepo.Update(ctx, vm.ID, func(vm *entity.VM) {
+		vm.SecurityGroups = groups
+		vm.UpdatedAt = time.Now()
+	})
+	if vmGone(err) {
+		// Deleted meanwhile; do not leave the filter behind
+		removeFilter(ctx, uc.firewall, req.VMID, uc.logger)
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save VM", err).
+			WithContext("vm_id", req.VMID)
+	}
+
+	return &dto.AttachSecurityGroupResponse{
+		VMID:           vm.ID,
+		SecurityGroups: groups,
+	}, nil
+}
+
+// requireFirewall rejects security group changes when the firewall is disabled
+func requireFirewall(firewall service.FirewallService) error {
+	if firewall == nil {
+		return errors.New(errors.ErrCodeInvalidState, "firewall is disabled on this agent", nil)
+	}
+	return nil
+}
+
+// findSecurityGroup looks up a security group by ID, then by name
+func findSecurityGroup(ctx context.Context, sgRepo repository.SecurityGroupRepository, ref string) (*entity.SecurityGroup, error) {
+	if sg, err := sgRepo.FindByID(ctx, ref); err == nil {
+		return sg, nil
+	}
+	return sgRepo.FindByName(ctx, ref)
+}
+
+// securityGroupIDs resolves references to security groups defined on this agent
+func securityGroupIDs(ctx context.Context, sgRepo repository.SecurityGroupRepository, refs []string) ([]string, error) {
+	ids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		sg, err := findSecurityGroup(ctx, sgRepo, ref)
+		if err != nil {
+			return nil, errors.New(errors.ErrCodeInvalidState, "security group not defined on this agent", err).
+				WithContext("security_group", ref)
+		}
+		ids = append(ids, sg.ID)
+	}
+	return ids, nil
+}
+
+// securityGroupNames returns the names of security groups, which unlike
+// their IDs mean the same on other agents
+func securityGroupNames(ctx context.Context, sgRepo repository.SecurityGroupRepository, ids []string) []string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if sg, err := sgRepo.FindByID(ctx, id); err == nil {
+			names = append(names, sg.Name)
+			continue
+		}
+		names = append(names, id)
+	}
+	return names
+}
+
+// attachedVMs maps security group IDs to the VMs they are attached to
+func attachedVMs(ctx context.Context, vmRepo repository.VMRepository) map[string][]string {
+	attached := make(map[string][]string)
+
+	vms, err := vmRepo.FindAll(ctx)
+	if err != nil {
+		return attached
+	}
+	for _, vm := range vms {
+		for _, id := range vm.SecurityGroups {
+			attached[id] = append(attached[id], vm.ID)
+		}
+	}
+	return attached
+}
+
+// toSecurityGroupRule validates a rule and assigns it an ID
+// PortMax defaults to PortMin, so a single port needs only PortMin
+func toSecurityGroupRule(r dto.SecurityGroupRule) (entity.SecurityGroupRule, error) {
+	if err := newValidator().Struct(r); err != nil {
+		return entity.SecurityGroupRule{}, errors.New(errors.ErrCodeValidation, "invalid rule", err)
+	}
+
+	protocol := entity.RuleProtocol(r.Protocol)
+	if protocol != entity.RuleProtocolTCP && protocol != entity.RuleProtocolUDP && (r.PortMin != 0 || r.PortMax != 0) {
+		return entity.SecurityGroupRule{}, errors.New(errors.ErrCodeValidation, "ports are only valid for tcp and udp rules", nil).
+			WithContext("protocol", r.Protocol)
+	}
+
+	portMax := r.PortMax
+	if portMax == 0 {
+		portMax = r.PortMin
+	}
+	if portMax < r.PortMin || (r.PortMin == 0 && portMax != 0) {
+		return entity.SecurityGroupRule{}, errors.New(errors.ErrCodeValidation, "invalid port range", nil).
+			WithContext("port_min", r.PortMin).
+			WithContext("port_max", r.PortMax)
+	}
+
+	return entity.SecurityGroupRule{
+		ID:        uuid.NewString()[:8],
+		Direction: entity.RuleDirection(r.Direction),
+		Protocol:  protocol,
+		PortMin:   r.PortMin,
+		PortMax:   portMax,
+		CIDR:      r.CIDR,
+	}, nil
+}
+
+func toSecurityGroupInfo(sg *entity.SecurityGroup, vmIDs []string) dto.SecurityGroupInfo {
+	rules := make([]dto.SecurityGroupRule, len(sg.Rules))
+	for i, rule := range sg.Rules {
+		rules[i] = dto.SecurityGroupRule{
+			ID:        rule.ID,
+			Direction: string(rule.Direction),
+			Protocol:  string(rule.Protocol),
+			PortMin:   rule.PortMin,
+			PortMax:   rule.PortMax,
+			CIDR:      rule.CIDR,
+		}
+	}
+
+	return dto.SecurityGroupInfo{
+		ID:          sg.ID,
+		Name:        sg.Name,
+		Description: sg.Description,
+		Rules:       rules,
+		VMIDs:       vmIDs,
+		CreatedAt:   sg.CreatedAt,
+	}
+}
@@ -2,18 +2,22 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 
 	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
 )
 
 // StartVMUseCase handles VM start business logic
 type StartVMUseCase struct {
 	hypervisor service.HypervisorService
+	vmRepo     repository.VMRepository
 	validator  *validator.Validate
 	logger     *zap.Logger
 }
@@ -21,11 +25,13 @@ type StartVMUseCase struct {
 // NewStartVMUseCase creates a new StartVM use case
 func NewStartVMUseCase(
 	hypervisor service.HypervisorService,
+	vmRepo repository.VMRepository,
 	logger *zap.Logger,
 ) *StartVMUseCase {
 	return &StartVMUseCase{
 		hypervisor: hypervisor,
-		validator:  validator.New(),
+		vmRepo:     vmRepo,
+		validator:  newValidator(),
 		logger:     logger,
 	}
 }
@@ -39,12 +45,39 @@ func (uc *StartVMUseCase) Execute(ctx context.Context, req *dto.StartVMRequest)
 		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
 	}
 
-	// 2. Start VM
+	// 2. VMs whose lease ran out stay stopped until renewed
+	vm, err := uc.vmRepo.FindByID(ctx, req.VMID)
+	if err == nil && vm.Expiry != nil && vm.Expiry.Expired {
+		return nil, errors.New(errors.ErrCodeInvalidState, "VM lease expired, renew it first", nil).
+			WithContext("vm_id", req.VMID).
+			WithContext("expires_at", vm.Expiry.At.Unix())
+	}
+
+	// 3. Start VM
 	if err := uc.hypervisor.StartVM(ctx, req.VMID); err != nil {
 		return nil, errors.New(errors.ErrCodeHypervisor, "failed to start VM", err).
 			WithContext("vm_id", req.VMID)
 	}
 
+	// 4. A VM started through the API is up for its restart policy again
+	if err == nil && vm.Restart != nil && (vm.Restart.Stopped || vm.Restart.Restarts > 0) {
+		vm, err = uc.vmRepo.Update(ctx, vm.ID, func(vm *entity.VM) {
+			if vm.Restart == nil {
+				return
+			}
+			vm.Restart.Stopped = false
+			vm.Restart.Restarts = 0
+			vm.UpdatedAt = time.Now()
+		})
+		if err != nil {
+			if !vmGone(err) {
+				uc.logger.Error("Failed to save VM to repository", zap.Error(err))
+			}
+		} else {
+			applyAutostart(ctx, uc.hypervisor, vm, uc.logger)
+		}
+	}
+
 	uc.logger.Info("VM started successfully", zap.String("vm_id", req.VMID))
 
 	return &dto.StartVMResponse{
@@ -2,18 +2,22 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 
 	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
 )
 
 // StopVMUseCase handles VM stop business logic
 type StopVMUseCase struct {
 	hypervisor service.HypervisorService
+	vmRepo     repository.VMRepository
 	validator  *validator.Validate
 	logger     *zap.Logger
 }
@@ -21,11 +25,13 @@ type StopVMUseCase struct {
 // NewStopVMUseCase creates a new StopVM use case
 func NewStopVMUseCase(
 	hypervisor service.HypervisorService,
+	vmRepo repository.VMRepository,
 	logger *zap.Logger,
 ) *StopVMUseCase {
 	return &StopVMUseCase{
 		hypervisor: hypervisor,
-		validator:  validator.New(),
+		vmRepo:     vmRepo,
+		validator:  newValidator(),
 		logger:     logger,
 	}
 }
@@ -42,8 +48,18 @@ func (uc *StopVMUseCase) Execute(ctx context.Context, req *dto.StopVMRequest) (*
 		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err)
 	}
 
-	// 2. Stop VM
+	// 2. Keep the VM down under unless-stopped and always; this is recorded
+	// first so the restart loop does not bring the VM back while it stops
+	stopped := false
+	if vm, err := uc.vmRepo.FindByID(ctx, req.VMID); err == nil && vm.Restart != nil && !vm.Restart.Stopped {
+		stopped = uc.markStopped(ctx, req.VMID, true)
+	}
+
+	// 3. Stop VM
 	if err := uc.hypervisor.StopVM(ctx, req.VMID, req.Force); err != nil {
+		if stopped {
+			uc.markStopped(ctx, req.VMID, false)
+		}
 		return nil, errors.New(errors.ErrCodeHypervisor, "failed to stop VM", err).
 			WithContext("vm_id", req.VMID)
 	}
@@ -54,3 +70,20 @@ func (uc *StopVMUseCase) Execute(ctx context.Context, req *dto.StopVMRequest) (*
 		Status: "stopped",
 	}, nil
 }
+
+// markStopped records whether the VM was stopped through the API and mirrors
+// it into libvirt autostart; it reports whether the change was saved
+func (uc *StopVMUseCase) markStopped(ctx context.Context, vmID string, stopped bool) bool {
+	vm, err := uc.vmRepo.Update(ctx, vmID, func(vm *entity.VM) {
+		if vm.Restart != nil {
+			vm.Restart.Stopped = stopped
+		}
+		vm.UpdatedAt = time.Now()
+	})
+	if err != nil {
+		uc.logger.Error("Failed to save VM to repository", zap.Error(err))
+		return false
+	}
+	applyAutostart(ctx, uc.hypervisor, vm, uc.logger)
+	return true
+}
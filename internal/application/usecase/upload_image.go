@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// UploadImageUseCase handles registering user-supplied images as templates
+type UploadImageUseCase struct {
+	storage   service.StorageService
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+// NewUploadImageUseCase creates a new UploadImage use case
+func NewUploadImageUseCase(
+	storage service.StorageService,
+	logger *zap.Logger,
+) *UploadImageUseCase {
+	return &UploadImageUseCase{
+		storage:   storage,
+		validator: newValidator(),
+		logger:    logger,
+	}
+}
+
+// Execute stores the image data read from data and registers it as a template
+func (uc *UploadImageUseCase) Execute(ctx context.Context, req *dto.UploadImageRequest, data io.Reader) (*dto.UploadImageResponse, error) {
+	uc.logger.Info("Uploading image",
+		zap.String("name", req.Name),
+		zap.String("format", req.Format),
+		zap.Int64("size_bytes", req.SizeBytes),
+	)
+
+	// 1. Validate input
+	if err := uc.validator.Struct(req); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid request", err).
+			WithContext("image_name", req.Name)
+	}
+
+	// 2. Store, validate and register the image
+	image, err := uc.storage.ImportImage(ctx, &service.ImageImportSpec{
+		Name:      req.Name,
+		Format:    entity.ImageFormat(req.Format),
+		SHA256:    strings.ToLower(req.SHA256),
+		SizeBytes: req.SizeBytes,
+	}, data)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.logger.Info("Image uploaded successfully",
+		zap.String("name", image.Name),
+		zap.Int64("size_bytes", image.SizeBytes),
+	)
+
+	return &dto.UploadImageResponse{
+		Name:      image.Name,
+		Format:    string(image.Format),
+		SHA256:    image.Checksum,
+		SizeBytes: image.SizeBytes,
+	}, nil
+}
@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// AuditResult is the outcome of an audited operation
+type AuditResult string
+
+const (
+	AuditResultSuccess AuditResult = "success"
+	AuditResultFailure AuditResult = "failure"
+	AuditResultDenied  AuditResult = "denied"
+)
+
+// AuditSubjectSystem is the subject of changes the agent makes on its own,
+// such as expiring, restarting and migrating VMs
+const AuditSubjectSystem = "system"
+
+// AuditEvent records an operation on the agent and who attempted it
+type AuditEvent struct {
+	Seq        uint64 // Position in the log, assigned when recorded
+	Time       time.Time
+	Method     string // Full gRPC method name, core/<type> for Ghost Core commands, or agent/<action> for the agent's own changes
+	Subject    string // Caller, empty when unauthenticated
+	Role       Role
+	Tenant     string
+	AuthMethod AuthMethod
+	Peer       string // Remote address
+	VMID       string
+	Summary    string // The request, without payloads
+	Result     AuditResult
+	Error      string
+	Duration   time.Duration
+	Hash       string // Chains the event to the ones before, assigned when recorded
+}
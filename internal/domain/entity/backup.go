@@ -0,0 +1,74 @@
+package entity
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BackupPolicy defines when a VM is backed up and how many backups are kept
+type BackupPolicy struct {
+	Schedule   string // Cron expression, e.g. "0 3 * * *"
+	KeepDaily  int    // Number of most recent days to keep one backup for
+	KeepWeekly int    // Number of most recent weeks to keep one backup for
+}
+
+// Backup represents a stored copy of a VM's disk
+type Backup struct {
+	ID        string
+	VMID      string
+	Target    string // Backup target that holds the data, e.g. "local" or "s3"
+	Location  string // Target-specific location (file path or object key)
+	SizeBytes int64
+	SHA256    string
+	CreatedAt time.Time
+}
+
+// ExpiredBackups returns the backups not retained by a daily/weekly policy.
+// The newest backup of each of the last keepDaily days and keepWeekly
+// ISO weeks is kept. A policy keeping nothing by either rule keeps everything.
+func ExpiredBackups(backups []*Backup, keepDaily, keepWeekly int) []*Backup {
+	if keepDaily <= 0 && keepWeekly <= 0 {
+		return nil
+	}
+
+	sorted := make([]*Backup, len(backups))
+	copy(sorted, backups)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+
+	keep := make(map[string]bool)
+	markNewestPerBucket(sorted, keepDaily, keep, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	markNewestPerBucket(sorted, keepWeekly, keep, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+
+	expired := make([]*Backup, 0)
+	for _, b := range sorted {
+		if !keep[b.ID] {
+			expired = append(expired, b)
+		}
+	}
+	return expired
+}
+
+// markNewestPerBucket marks the newest backup of each of the first n buckets
+// Backups must be sorted newest first
+func markNewestPerBucket(sorted []*Backup, n int, keep map[string]bool, bucket func(time.Time) string) {
+	seen := make(map[string]bool)
+	for _, b := range sorted {
+		if len(seen) >= n {
+			return
+		}
+		key := bucket(b.CreatedAt.UTC())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		keep[b.ID] = true
+	}
+}
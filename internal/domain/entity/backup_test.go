@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiredBackups(t *testing.T) {
+	// Saturday 2025-10-18; 2025-10-13 is the Monday of its ISO week
+	day := func(d, h int) time.Time {
+		return time.Date(2025, 10, d, h, 0, 0, 0, time.UTC)
+	}
+	backups := []*Backup{
+		{ID: "18-03", CreatedAt: day(18, 3)},
+		{ID: "18-15", CreatedAt: day(18, 15)},
+		{ID: "17-03", CreatedAt: day(17, 3)},
+		{ID: "16-03", CreatedAt: day(16, 3)},
+		{ID: "12-03", CreatedAt: day(12, 3)}, // Sunday, previous week
+		{ID: "11-03", CreatedAt: day(11, 3)},
+		{ID: "05-03", CreatedAt: day(5, 3)},
+		{ID: "01-03", CreatedAt: day(1, 3)},
+	}
+
+	tests := []struct {
+		name       string
+		keepDaily  int
+		keepWeekly int
+		want       []string
+	}{
+		{
+			name: "no policy keeps everything",
+			want: nil,
+		},
+		{
+			name:      "daily keeps the newest of each day",
+			keepDaily: 2,
+			want:      []string{"18-03", "16-03", "12-03", "11-03", "05-03", "01-03"},
+		},
+		{
+			name:       "weekly keeps the newest of each week",
+			keepWeekly: 2,
+			want:       []string{"18-03", "17-03", "16-03", "11-03", "05-03", "01-03"},
+		},
+		{
+			name:       "daily and weekly add up",
+			keepDaily:  3,
+			keepWeekly: 4,
+			want:       []string{"18-03", "11-03", "01-03"},
+		},
+		{
+			name:       "more buckets than backups",
+			keepDaily:  30,
+			keepWeekly: 10,
+			want:       []string{"18-03"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, b := range ExpiredBackups(backups, tt.keepDaily, tt.keepWeekly) {
+				got = append(got, b.ID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package entity
+
+import "time"
+
+// ExpiryAction is what happens to a VM when its lease runs out
+type ExpiryAction string
+
+const (
+	ExpiryActionStop   ExpiryAction = "stop"   // Shut the VM down, keeping its disk
+	ExpiryActionDelete ExpiryAction = "delete" // Delete the VM and its disk
+)
+
+// ExpiryPhase is a step at the end of a VM's lease, reported to Ghost Core
+type ExpiryPhase string
+
+const (
+	ExpiryPhaseWarning ExpiryPhase = "warning" // The lease runs out soon
+	ExpiryPhaseStopped ExpiryPhase = "stopped"
+	ExpiryPhaseDeleted ExpiryPhase = "deleted"
+)
+
+// Expiry is the lease of an ephemeral VM
+type Expiry struct {
+	At      time.Time
+	Action  ExpiryAction
+	Warned  bool // The warning for this lease went out
+	Expired bool // The action for this lease was taken
+}
+
+// Due reports whether the lease ran out and its action is still to be taken
+func (e *Expiry) Due(now time.Time) bool {
+	return !e.Expired && !now.Before(e.At)
+}
+
+// WarningDue reports whether the lease runs out within before and nobody was warned yet
+func (e *Expiry) WarningDue(now time.Time, before time.Duration) bool {
+	return !e.Warned && !e.Expired && !now.Before(e.At.Add(-before))
+}
+
+// ExpiryPolicy holds the agent's rules for VM leases
+type ExpiryPolicy struct {
+	DefaultAction ExpiryAction  // For leases that do not choose
+	MaxTTL        time.Duration // Longest lease from now, 0 for no cap
+	WarnBefore    time.Duration // How long before expiry the warning goes out
+}
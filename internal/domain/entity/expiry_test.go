@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiryDue(t *testing.T) {
+	at := time.Date(2025, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		expiry      Expiry
+		now         time.Time
+		want        bool
+		wantWarning bool
+	}{
+		{name: "far off", expiry: Expiry{At: at}, now: at.Add(-2 * time.Hour)},
+		{name: "within warning", expiry: Expiry{At: at}, now: at.Add(-time.Hour), wantWarning: true},
+		{name: "warned already", expiry: Expiry{At: at, Warned: true}, now: at.Add(-time.Minute)},
+		{name: "runs out now", expiry: Expiry{At: at, Warned: true}, now: at, want: true},
+		{name: "ran out unwarned", expiry: Expiry{At: at}, now: at.Add(time.Hour), want: true, wantWarning: true},
+		{name: "action taken", expiry: Expiry{At: at, Expired: true}, now: at.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.expiry.Due(tt.now))
+			assert.Equal(t, tt.wantWarning, tt.expiry.WarningDue(tt.now, time.Hour))
+		})
+	}
+}
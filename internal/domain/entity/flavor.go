@@ -0,0 +1,37 @@
+package entity
+
+import "slices"
+
+// CPUMode is how a VM's CPU model relates to the host's
+type CPUMode string
+
+const (
+	CPUModeHostPassthrough CPUMode = "host-passthrough" // Exactly the host CPU, not migratable to other models
+	CPUModeHostModel       CPUMode = "host-model"       // Closest named model to the host CPU
+	CPUModeMaximum         CPUMode = "maximum"          // Every feature the hypervisor can offer
+)
+
+// FlavorSource says where a flavor was defined
+type FlavorSource string
+
+const (
+	FlavorSourceConfig FlavorSource = "config" // agent.yaml
+	FlavorSourceCore   FlavorSource = "core"   // Pushed by Ghost Core
+)
+
+// Flavor is a named VM size
+type Flavor struct {
+	Name      string
+	VCPU      int
+	RAMGB     int
+	DiskGB    int
+	CPUMode   CPUMode  // Empty for the hypervisor default
+	Limits    VMLimits // Unset values take the agent defaults
+	Templates []string // Templates VMs of the flavor may use, any when empty
+	Source    FlavorSource
+}
+
+// AllowsTemplate reports whether VMs of the flavor may use the template
+func (f *Flavor) AllowsTemplate(template string) bool {
+	return len(f.Templates) == 0 || slices.Contains(f.Templates, template)
+}
@@ -0,0 +1,36 @@
+package entity
+
+// Role is the set of permissions granted to an API caller
+type Role string
+
+const (
+	RoleCore     Role = "core"      // Ghost Core and peer agents: everything
+	RoleOperator Role = "operator"  // Host owner: everything but agent-to-agent calls
+	RoleReadOnly Role = "read-only" // Monitoring: reading state only
+)
+
+// IsValid reports whether r is a known role
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleCore, RoleOperator, RoleReadOnly:
+		return true
+	}
+	return false
+}
+
+// AuthMethod is how a caller proved its identity
+type AuthMethod string
+
+const (
+	AuthMethodTLS  AuthMethod = "mtls"
+	AuthMethodJWT  AuthMethod = "jwt"
+	AuthMethodUnix AuthMethod = "unix"
+)
+
+// Identity is an authenticated API caller
+type Identity struct {
+	Subject string // Certificate common name, token subject or local user name
+	Role    Role
+	Tenant  string // Limits the caller to VMs of this tenant, empty for all tenants
+	Method  AuthMethod
+}
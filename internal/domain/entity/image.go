@@ -2,17 +2,40 @@ package entity
 
 import "time"
 
+// ImageFormat represents the on-disk format of an image
+type ImageFormat string
+
+const (
+	ImageFormatQCOW2 ImageFormat = "qcow2"
+	ImageFormatRaw   ImageFormat = "raw"
+)
+
+// ImageSource describes where an image came from
+type ImageSource string
+
+const (
+	ImageSourceBuiltin ImageSource = "builtin" // Downloaded from the built-in template URL map
+	ImageSourceUpload  ImageSource = "upload"  // Uploaded by a user via UploadImage
+)
+
 // Image represents a cached OS image
 type Image struct {
-	Name      string    // e.g., "ubuntu-22.04"
-	Path      string    // Local file path
-	URL       string    // Download URL
-	Checksum  string    // SHA256 checksum
-	SizeBytes int64     // File size in bytes
-	CachedAt  time.Time // When it was cached
+	Name      string      // e.g., "ubuntu-22.04"
+	Path      string      // Local file path
+	URL       string      // Download URL (empty for uploaded images)
+	Checksum  string      // SHA256 checksum
+	SizeBytes int64       // File size in bytes
+	Format    ImageFormat // Format of the cached file (always qcow2 once cached)
+	Source    ImageSource // Built-in template or user upload
+	CachedAt  time.Time   // When it was cached
 }
 
 // IsValid checks if image checksum matches
 func (i *Image) IsValid(actualChecksum string) bool {
 	return i.Checksum == actualChecksum
 }
+
+// IsUploaded returns true if the image was uploaded by a user
+func (i *Image) IsUploaded() bool {
+	return i.Source == ImageSourceUpload
+}
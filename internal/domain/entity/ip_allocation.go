@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// IPAllocation is a static address reserved for a VM on a libvirt network
+type IPAllocation struct {
+	VMID      string
+	Network   string // libvirt network name
+	IP        string
+	IPv6      string // SLAAC address derived from MAC, empty when the network has no IPv6 /64
+	MAC       string
+	CreatedAt time.Time
+}
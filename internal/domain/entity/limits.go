@@ -0,0 +1,40 @@
+package entity
+
+// BandwidthLimit caps the traffic of a VM interface in one direction
+// Rates are in KiB/s and the burst in KiB, as libvirt expects; zero means unlimited
+type BandwidthLimit struct {
+	Average int
+	Peak    int
+	Burst   int
+}
+
+// IsSet returns true if the limit caps traffic at all
+func (b BandwidthLimit) IsSet() bool {
+	return b.Average > 0
+}
+
+// VMLimits holds the network and disk I/O limits of a VM; zero fields are unlimited
+type VMLimits struct {
+	Inbound         BandwidthLimit // Traffic towards the guest
+	Outbound        BandwidthLimit // Traffic from the guest
+	DiskIOPS        int64          // Total read and write operations per second
+	DiskBytesPerSec int64          // Total read and write throughput
+}
+
+// WithDefaults returns the limits with unset values taken from defaults
+// A direction with its own average keeps its own peak and burst
+func (l VMLimits) WithDefaults(defaults VMLimits) VMLimits {
+	if !l.Inbound.IsSet() {
+		l.Inbound = defaults.Inbound
+	}
+	if !l.Outbound.IsSet() {
+		l.Outbound = defaults.Outbound
+	}
+	if l.DiskIOPS == 0 {
+		l.DiskIOPS = defaults.DiskIOPS
+	}
+	if l.DiskBytesPerSec == 0 {
+		l.DiskBytesPerSec = defaults.DiskBytesPerSec
+	}
+	return l
+}
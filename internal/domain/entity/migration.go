@@ -0,0 +1,33 @@
+package entity
+
+// MigrationPhase represents the stage of a live migration
+type MigrationPhase string
+
+const (
+	MigrationPhasePreparing    MigrationPhase = "preparing"    // Reserving resources on the target agent
+	MigrationPhaseTransferring MigrationPhase = "transferring" // Copying disk and memory
+	MigrationPhaseCompleted    MigrationPhase = "completed"    // VM runs on the target agent
+	MigrationPhaseFailed       MigrationPhase = "failed"       // VM stays registered on the source agent
+)
+
+// Migration describes the progress of a VM moving to another agent
+type Migration struct {
+	VMID          string
+	TargetAddress string // Tailscale address of the target agent
+	Phase         MigrationPhase
+	Percent       int    // Transfer progress, 0-100
+	IPAddress     string // IP of the VM on the target, set once completed
+	Message       string // Failure reason, set when failed
+
+	// Port forwards are bound to the source host and are not recreated on the target
+	RemovedPortForwards []*PortForward // Set once completed
+}
+
+// IncomingMigration is the room an agent set aside for a VM migrating to it
+// Releasing it gives back exactly what was reserved, whatever the source reports
+type IncomingMigration struct {
+	VMID   string
+	VCPU   int
+	RAMGB  int
+	DiskGB int
+}
@@ -0,0 +1,97 @@
+package entity
+
+import (
+	"net/netip"
+	"time"
+)
+
+// NetworkMode selects how a VM's network interface is attached to the host
+type NetworkMode string
+
+const (
+	// NetworkModeNAT attaches the VM to a libvirt NAT network with DHCP
+	NetworkModeNAT NetworkMode = "nat"
+	// NetworkModeBridge attaches the VM to a host bridge on the LAN
+	NetworkModeBridge NetworkMode = "bridge"
+)
+
+// Network is an isolated private network VMs attach additional NICs to
+// Each network has its own bridge and DHCP server; VMs on different networks cannot reach each other
+type Network struct {
+	ID        string
+	Name      string
+	Tenant    string // Owner label, empty for shared networks
+	Subnet    string // IPv4 CIDR
+	Gateway   string // Host address on the bridge, also the DHCP and DNS server
+	DHCPStart string
+	DHCPEnd   string
+	SubnetV6  string // IPv6 /64 guests configure themselves in by SLAAC, empty for IPv4 only
+	GatewayV6 string // Host address on the bridge in SubnetV6
+	NAT       bool   // Whether guests reach outside networks through the host
+	Bridge    string // Host bridge, assigned when the network is defined
+	CreatedAt time.Time
+}
+
+// IPFamily is the address family of an IP address
+type IPFamily string
+
+const (
+	IPFamilyV4 IPFamily = "ipv4"
+	IPFamilyV6 IPFamily = "ipv6"
+)
+
+// IPAddress is an address configured on a guest interface
+type IPAddress struct {
+	Address string
+	Prefix  int // Prefix length, 0 when unknown
+	Family  IPFamily
+}
+
+// VMInterface is a guest network interface with its addresses
+type VMInterface struct {
+	Name      string // Guest interface name, or the host tap device when the guest agent is unavailable
+	MAC       string
+	Addresses []IPAddress
+}
+
+// Address returns the interface's first address of family, empty when it has none
+func (i VMInterface) Address(family IPFamily) string {
+	for _, addr := range i.Addresses {
+		if addr.Family == family {
+			return addr.Address
+		}
+	}
+	return ""
+}
+
+// PrimaryIP returns the address a VM is reached at: the first IPv4 address
+// of the first interface, or its first IPv6 address on IPv6-only networks
+func PrimaryIP(ifaces []VMInterface) string {
+	if len(ifaces) == 0 {
+		return ""
+	}
+	if ip := ifaces[0].Address(IPFamilyV4); ip != "" {
+		return ip
+	}
+	return ifaces[0].Address(IPFamilyV6)
+}
+
+// IPv6Subnet returns the index-th /64 subnet of prefix
+// It reports false when prefix is not an IPv6 prefix of /64 or shorter, or has no such subnet
+func IPv6Subnet(prefix netip.Prefix, index int) (netip.Prefix, bool) {
+	if !prefix.IsValid() || !prefix.Addr().Is6() || prefix.Bits() > 64 || index < 0 {
+		return netip.Prefix{}, false
+	}
+	if free := 64 - prefix.Bits(); free < 63 && index >= 1<<free {
+		return netip.Prefix{}, false
+	}
+
+	b := prefix.Masked().Addr().As16()
+	hi := uint64(b[0])<<56 | uint64(b[1])<<48 | uint64(b[2])<<40 | uint64(b[3])<<32 |
+		uint64(b[4])<<24 | uint64(b[5])<<16 | uint64(b[6])<<8 | uint64(b[7])
+	hi += uint64(index)
+	for i := 0; i < 8; i++ {
+		b[i] = byte(hi >> (56 - 8*i))
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(b), 64), true
+}
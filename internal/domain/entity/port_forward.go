@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// PortProtocol is the transport protocol of a port forward
+type PortProtocol string
+
+const (
+	PortProtocolTCP PortProtocol = "tcp"
+	PortProtocolUDP PortProtocol = "udp"
+)
+
+// PortForward maps a host port to a port on a VM
+type PortForward struct {
+	ID        string
+	VMID      string
+	Protocol  PortProtocol
+	HostPort  int
+	GuestPort int
+	GuestIP   string // VM address the forward currently points at
+	GuestIPv6 string // VM IPv6 address for clients connecting over IPv6, empty when the VM has none
+	CreatedAt time.Time
+}
@@ -0,0 +1,64 @@
+package entity
+
+// Quota caps what the VMs of one tenant may allocate
+// Zero values are unlimited
+type Quota struct {
+	MaxVMs int
+	VCPU   int
+	RAMGB  int
+	DiskGB int
+}
+
+// Unlimited reports whether the quota caps nothing
+func (q Quota) Unlimited() bool {
+	return q == Quota{}
+}
+
+// Allows reports whether a VM of this size fits in the quota next to usage
+func (q Quota) Allows(usage QuotaUsage, vcpu, ramGB, diskGB int) bool {
+	return fits(q.MaxVMs, usage.VMs, 1) &&
+		fits(q.VCPU, usage.VCPU, vcpu) &&
+		fits(q.RAMGB, usage.RAMGB, ramGB) &&
+		fits(q.DiskGB, usage.DiskGB, diskGB)
+}
+
+func fits(limit, used, requested int) bool {
+	return limit == 0 || used+requested <= limit
+}
+
+// QuotaUsage is what the VMs of one tenant have allocated
+type QuotaUsage struct {
+	VMs    int
+	VCPU   int
+	RAMGB  int
+	DiskGB int
+}
+
+// Add counts a VM towards the usage
+func (u *QuotaUsage) Add(vm *VM) {
+	u.VMs++
+	u.VCPU += vm.VCPU
+	u.RAMGB += vm.RAMGB
+	u.DiskGB += vm.DiskGB
+}
+
+// TenantQuota is the quota of a tenant and how much of it is used
+type TenantQuota struct {
+	Tenant string
+	Quota  Quota
+	Usage  QuotaUsage
+}
+
+// Quotas are the quotas of all tenants
+type Quotas struct {
+	Default Quota            // Applies to tenants without their own
+	Tenants map[string]Quota // By tenant
+}
+
+// For returns the quota of a tenant
+func (q Quotas) For(tenant string) Quota {
+	if quota, ok := q.Tenants[tenant]; ok {
+		return quota
+	}
+	return q.Default
+}
@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaAllows(t *testing.T) {
+	usage := QuotaUsage{VMs: 2, VCPU: 4, RAMGB: 8, DiskGB: 100}
+
+	tests := []struct {
+		name  string
+		quota Quota
+		vcpu  int
+		ramGB int
+		want  bool
+	}{
+		{name: "unlimited", quota: Quota{}, vcpu: 64, ramGB: 512, want: true},
+		{name: "fits exactly", quota: Quota{MaxVMs: 3, VCPU: 6, RAMGB: 12, DiskGB: 150}, vcpu: 2, ramGB: 4, want: true},
+		{name: "too many VMs", quota: Quota{MaxVMs: 2}, vcpu: 1, ramGB: 1, want: false},
+		{name: "too many vCPUs", quota: Quota{VCPU: 5}, vcpu: 2, ramGB: 1, want: false},
+		{name: "too much RAM", quota: Quota{RAMGB: 10}, vcpu: 1, ramGB: 4, want: false},
+		{name: "too much disk", quota: Quota{DiskGB: 120}, vcpu: 1, ramGB: 1, want: false},
+		{name: "only set limits count", quota: Quota{DiskGB: 1000}, vcpu: 64, ramGB: 512, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.quota.Allows(usage, tt.vcpu, tt.ramGB, 50))
+		})
+	}
+}
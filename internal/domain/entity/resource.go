@@ -13,13 +13,57 @@ type Resource struct {
 	TotalDiskGB    int // Total disk space in GB
 	AvailableDiskGB int // Available disk space in GB
 	ReservedDiskGB int // Reserved disk space for owner
+
+	Overcommit      Overcommit // Ratios the available resources were scaled by
+	ThinProvisioned bool       // Disk is allocated by size but also checked against the space VM disks use
+	FreeDiskGB      int        // Under thin provisioning, disk space VM disks have not used yet
+}
+
+// Overcommit is how many times each physical resource may be allocated to VMs
+// A ratio of 1 allocates exactly the physical capacity
+type Overcommit struct {
+	CPU  float64
+	RAM  float64
+	Disk float64
+}
+
+// Allocatable returns how much of a resource may be allocated to VMs when
+// usable units of it are physically there
+func Allocatable(usable int, ratio float64) int {
+	return int(float64(usable) * ratio)
 }
 
 // CanAllocate checks if resources can be allocated for a VM
 func (r *Resource) CanAllocate(vcpu, ramGB, diskGB int) bool {
 	return r.AvailableCPU >= vcpu &&
 		r.AvailableRAMGB >= ramGB &&
-		r.AvailableDiskGB >= diskGB
+		r.AvailableDiskGB >= diskGB &&
+		(!r.ThinProvisioned || r.FreeDiskGB >= diskGB)
+}
+
+// CanHold checks if the host could fit a VM of this size with no VMs on it
+func (r *Resource) CanHold(vcpu, ramGB, diskGB int) bool {
+	return Allocatable(r.TotalCPU-r.ReservedCPU, r.Overcommit.CPU) >= vcpu &&
+		Allocatable(r.TotalRAMGB-r.ReservedRAMGB, r.Overcommit.RAM) >= ramGB &&
+		Allocatable(r.TotalDiskGB-r.ReservedDiskGB, r.Overcommit.Disk) >= diskGB
+}
+
+// Fits returns how many more VMs of this size the available resources hold
+func (r *Resource) Fits(vcpu, ramGB, diskGB int) int {
+	n := -1
+	disk := r.AvailableDiskGB
+	if r.ThinProvisioned {
+		disk = min(disk, r.FreeDiskGB)
+	}
+	for _, pair := range [][2]int{{r.AvailableCPU, vcpu}, {r.AvailableRAMGB, ramGB}, {disk, diskGB}} {
+		if pair[1] <= 0 {
+			continue
+		}
+		if fit := max(pair[0], 0) / pair[1]; n < 0 || fit < n {
+			n = fit
+		}
+	}
+	return max(n, 0)
 }
 
 // Allocate reduces available resources
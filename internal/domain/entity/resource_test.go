@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceCanAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource Resource
+		diskGB   int
+		want     bool
+		wantFits int
+	}{
+		{
+			name:     "thick fits",
+			resource: Resource{AvailableCPU: 8, AvailableRAMGB: 16, AvailableDiskGB: 100},
+			diskGB:   50,
+			want:     true,
+			wantFits: 2,
+		},
+		{
+			name:     "thick full",
+			resource: Resource{AvailableCPU: 8, AvailableRAMGB: 16, AvailableDiskGB: 40},
+			diskGB:   50,
+			want:     false,
+			wantFits: 0,
+		},
+		{
+			name:     "thick ignores free disk",
+			resource: Resource{AvailableCPU: 8, AvailableRAMGB: 16, AvailableDiskGB: 100, FreeDiskGB: 0},
+			diskGB:   50,
+			want:     true,
+			wantFits: 2,
+		},
+		{
+			name:     "thin fits",
+			resource: Resource{AvailableCPU: 8, AvailableRAMGB: 16, AvailableDiskGB: 200, FreeDiskGB: 150, ThinProvisioned: true},
+			diskGB:   50,
+			want:     true,
+			wantFits: 3,
+		},
+		{
+			name:     "thin allocations used up",
+			resource: Resource{AvailableCPU: 8, AvailableRAMGB: 16, AvailableDiskGB: 40, FreeDiskGB: 400, ThinProvisioned: true},
+			diskGB:   50,
+			want:     false,
+			wantFits: 0,
+		},
+		{
+			name:     "thin host disk filled by guests",
+			resource: Resource{AvailableCPU: 8, AvailableRAMGB: 16, AvailableDiskGB: 400, FreeDiskGB: 40, ThinProvisioned: true},
+			diskGB:   50,
+			want:     false,
+			wantFits: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.resource.CanAllocate(2, 4, tt.diskGB))
+			assert.Equal(t, tt.wantFits, tt.resource.Fits(2, 4, tt.diskGB))
+		})
+	}
+}
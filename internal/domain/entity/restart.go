@@ -0,0 +1,57 @@
+package entity
+
+import "time"
+
+// RestartPolicy decides when the agent brings a VM that is down back up
+type RestartPolicy string
+
+const (
+	RestartNever         RestartPolicy = "never"
+	RestartAlways        RestartPolicy = "always"         // Also after a guest shutdown; a VM stopped through the API comes back when the agent starts
+	RestartUnlessStopped RestartPolicy = "unless-stopped" // Like always, but a VM stopped through the API stays down
+	RestartOnFailure     RestartPolicy = "on-failure"     // Only after the VM crashed
+)
+
+// Restart is the restart policy of a VM and the agent's restarts under it
+type Restart struct {
+	Policy      RestartPolicy
+	MaxRetries  int  // Restarts after failures before giving up, 0 for no cap
+	Stopped     bool // Stopped through the API
+	Restarts    int  // Restarts since the VM last stayed up, for backoff
+	LastRestart time.Time
+}
+
+// Wants reports whether the policy brings a VM that is down back up
+// failed is set when the VM crashed, agentStart on the first check after the agent started
+func (r *Restart) Wants(failed, agentStart bool) bool {
+	switch r.Policy {
+	case RestartAlways:
+		return agentStart || !r.Stopped
+	case RestartUnlessStopped:
+		return !r.Stopped
+	case RestartOnFailure:
+		return failed
+	default:
+		return false
+	}
+}
+
+// GaveUp reports whether the VM failed more often than the policy retries
+func (r *Restart) GaveUp() bool {
+	return r.Policy == RestartOnFailure && r.MaxRetries > 0 && r.Restarts >= r.MaxRetries
+}
+
+// RestartBackoff spaces out the restarts of a VM that keeps going down
+type RestartBackoff struct {
+	Initial time.Duration // Wait after the first restart
+	Max     time.Duration // Cap of the doubling wait; a VM up this long counts as healthy again
+}
+
+// Delay returns how long to wait after the given number of restarts
+func (b RestartBackoff) Delay(restarts int) time.Duration {
+	delay := b.Initial
+	for i := 1; i < restarts && delay < b.Max; i++ {
+		delay *= 2
+	}
+	return min(delay, b.Max)
+}
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartBackoffDelay(t *testing.T) {
+	backoff := RestartBackoff{Initial: 10 * time.Second, Max: 5 * time.Minute}
+
+	tests := []struct {
+		restarts int
+		want     time.Duration
+	}{
+		{restarts: 0, want: 10 * time.Second},
+		{restarts: 1, want: 10 * time.Second},
+		{restarts: 2, want: 20 * time.Second},
+		{restarts: 3, want: 40 * time.Second},
+		{restarts: 5, want: 160 * time.Second},
+		{restarts: 6, want: 5 * time.Minute},
+		{restarts: 1000, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, backoff.Delay(tt.restarts), "restarts=%d", tt.restarts)
+	}
+}
+
+func TestRestartWants(t *testing.T) {
+	tests := []struct {
+		name       string
+		restart    Restart
+		failed     bool
+		agentStart bool
+		want       bool
+	}{
+		{name: "always after shutdown", restart: Restart{Policy: RestartAlways}, want: true},
+		{name: "always stopped through the API", restart: Restart{Policy: RestartAlways, Stopped: true}, want: false},
+		{name: "always stopped, agent start", restart: Restart{Policy: RestartAlways, Stopped: true}, agentStart: true, want: true},
+		{name: "unless-stopped after shutdown", restart: Restart{Policy: RestartUnlessStopped}, want: true},
+		{name: "unless-stopped stopped, agent start", restart: Restart{Policy: RestartUnlessStopped, Stopped: true}, agentStart: true, want: false},
+		{name: "on-failure after shutdown", restart: Restart{Policy: RestartOnFailure}, agentStart: true, want: false},
+		{name: "on-failure after crash", restart: Restart{Policy: RestartOnFailure}, failed: true, want: true},
+		{name: "never", restart: Restart{Policy: RestartNever}, failed: true, agentStart: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.restart.Wants(tt.failed, tt.agentStart))
+		})
+	}
+}
+
+func TestRestartGaveUp(t *testing.T) {
+	tests := []struct {
+		name    string
+		restart Restart
+		want    bool
+	}{
+		{name: "below max retries", restart: Restart{Policy: RestartOnFailure, MaxRetries: 3, Restarts: 2}, want: false},
+		{name: "at max retries", restart: Restart{Policy: RestartOnFailure, MaxRetries: 3, Restarts: 3}, want: true},
+		{name: "no cap", restart: Restart{Policy: RestartOnFailure, Restarts: 100}, want: false},
+		{name: "only on-failure gives up", restart: Restart{Policy: RestartAlways, MaxRetries: 3, Restarts: 5}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.restart.GaveUp())
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+// RuleDirection is the traffic direction a security group rule admits, seen from the VM
+type RuleDirection string
+
+const (
+	RuleDirectionIngress RuleDirection = "ingress"
+	RuleDirectionEgress  RuleDirection = "egress"
+)
+
+// RuleProtocol is the protocol a security group rule matches
+type RuleProtocol string
+
+const (
+	RuleProtocolTCP  RuleProtocol = "tcp"
+	RuleProtocolUDP  RuleProtocol = "udp"
+	RuleProtocolICMP RuleProtocol = "icmp"
+	RuleProtocolAll  RuleProtocol = "all"
+)
+
+// SecurityGroupRule admits traffic matching direction, protocol, ports and CIDR
+type SecurityGroupRule struct {
+	ID        string
+	Direction RuleDirection
+	Protocol  RuleProtocol
+	PortMin   int // 0 matches all ports; tcp and udp only
+	PortMax   int
+	CIDR      string // Remote IPv4 or IPv6 network, empty matches any address of both families
+}
+
+// SecurityGroup is a named set of allow rules attachable to VMs
+// Ingress is denied and egress allowed, except to private ranges, unless a rule says otherwise
+type SecurityGroup struct {
+	ID          string
+	Name        string
+	Description string
+	Rules       []SecurityGroupRule
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
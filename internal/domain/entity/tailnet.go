@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"net/netip"
+	"time"
+)
+
+// TailnetState is the backend state of tailscaled
+type TailnetState string
+
+const (
+	TailnetStateNoState    TailnetState = "NoState"
+	TailnetStateNeedsLogin TailnetState = "NeedsLogin"
+	TailnetStateStopped    TailnetState = "Stopped"
+	TailnetStateStarting   TailnetState = "Starting"
+	TailnetStateRunning    TailnetState = "Running"
+)
+
+// TailnetStatus describes this host's connection to the tailnet
+type TailnetStatus struct {
+	State     TailnetState
+	HostName  string
+	DNSName   string   // MagicDNS name, empty without MagicDNS
+	Tailnet   string   // Name of the tailnet
+	IPs       []string // Tailscale addresses of this host
+	Routes    []string // Subnet routes the tailnet sends through this host
+	Health    []string // Problems reported by tailscaled
+	UpdatedAt time.Time
+}
+
+// Up reports whether the host is connected and has a tailnet address
+func (s *TailnetStatus) Up() bool {
+	return s != nil && s.State == TailnetStateRunning && len(s.IPs) > 0
+}
+
+// IP returns the address the host is reached at, IPv4 unless it only has IPv6
+func (s *TailnetStatus) IP() string {
+	if ip := s.IPv4(); ip != "" {
+		return ip
+	}
+	return s.IPv6()
+}
+
+// IPv4 returns the host's IPv4 tailnet address, empty when it has none
+func (s *TailnetStatus) IPv4() string {
+	return s.address(netip.Addr.Is4)
+}
+
+// IPv6 returns the host's IPv6 tailnet address, empty when it has none
+func (s *TailnetStatus) IPv6() string {
+	return s.address(netip.Addr.Is6)
+}
+
+func (s *TailnetStatus) address(family func(netip.Addr) bool) string {
+	if s == nil {
+		return ""
+	}
+	for _, ip := range s.IPs {
+		if addr, err := netip.ParseAddr(ip); err == nil && family(addr) {
+			return ip
+		}
+	}
+	return ""
+}
@@ -12,19 +12,64 @@ const (
 	VMStatusError   VMStatus = "error"
 )
 
+// MetadataTenant is the metadata key of the tenant owning a VM
+const MetadataTenant = "tenant"
+
 // VM represents a virtual machine entity
 type VM struct {
-	ID        string
-	Name      string
-	VCPU      int
-	RAMGB     int
-	DiskGB    int
-	Status    VMStatus
-	IP        string
-	Template  string
-	DiskPath  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID             string
+	Name           string
+	VCPU           int
+	RAMGB          int
+	DiskGB         int
+	Status         VMStatus
+	IP             string
+	Interfaces     []VMInterface // Last known guest addresses, primary NIC first
+	NetworkMode    NetworkMode   // Empty for VMs created before network modes, treated as NAT
+	Template       string
+	DiskPath       string
+	Backup         *BackupPolicy // Scheduled backups, nil when disabled
+	SecurityGroups []string      // IDs of attached security groups
+	Networks       []string      // IDs of private networks with an additional NIC, in NIC order
+	Limits         *VMLimits     // Effective network and disk limits, nil when unlimited
+	Flavor         string        // Flavor the VM was sized by, empty for raw sizes
+	CPUMode        CPUMode       // Empty for the hypervisor default
+	Expiry         *Expiry       // Lease of an ephemeral VM, nil when it does not expire
+	Restart        *Restart      // Restart policy, nil when the VM is never restarted
+	Metadata       map[string]string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Clone returns a copy of the VM that shares none of its policies, lists or metadata
+func (v *VM) Clone() *VM {
+	c := *v
+	c.Interfaces = append([]VMInterface(nil), v.Interfaces...)
+	c.SecurityGroups = append([]string(nil), v.SecurityGroups...)
+	c.Networks = append([]string(nil), v.Networks...)
+	if v.Backup != nil {
+		backup := *v.Backup
+		c.Backup = &backup
+	}
+	if v.Limits != nil {
+		limits := *v.Limits
+		c.Limits = &limits
+	}
+	if v.Expiry != nil {
+		expiry := *v.Expiry
+		c.Expiry = &expiry
+	}
+	if v.Restart != nil {
+		restart := *v.Restart
+		c.Restart = &restart
+	}
+	if v.Metadata != nil {
+		c.Metadata = make(map[string]string, len(v.Metadata))
+		for k, val := range v.Metadata {
+			c.Metadata[k] = val
+		}
+	}
+	return &c
 }
 
 // IsRunning returns true if VM is in running state
@@ -36,3 +81,25 @@ func (v *VM) IsRunning() bool {
 func (v *VM) IsStopped() bool {
 	return v.Status == VMStatusStopped
 }
+
+// Autostart reports whether the hypervisor should start the VM when the host boots
+func (v *VM) Autostart() bool {
+	if v.Restart == nil || (v.Expiry != nil && v.Expiry.Expired) {
+		return false
+	}
+	return v.Restart.Policy == RestartAlways ||
+		(v.Restart.Policy == RestartUnlessStopped && !v.Restart.Stopped)
+}
+
+// Tenant returns the tenant owning the VM, empty when it has none
+func (v *VM) Tenant() string {
+	return v.Metadata[MetadataTenant]
+}
+
+// EffectiveNetworkMode returns the VM's network mode, treating unset as NAT
+func (v *VM) EffectiveNetworkMode() NetworkMode {
+	if v.NetworkMode == "" {
+		return NetworkModeNAT
+	}
+	return v.NetworkMode
+}
@@ -13,6 +13,9 @@ const (
 	ErrCodeStorage       ErrorCode = "STORAGE_ERROR"
 	ErrCodeNotFound      ErrorCode = "NOT_FOUND"
 	ErrCodeConflict      ErrorCode = "CONFLICT"
+	ErrCodeInvalidState  ErrorCode = "INVALID_STATE"
+	ErrCodeTimeout       ErrorCode = "TIMEOUT"
+	ErrCodeUnavailable   ErrorCode = "UNAVAILABLE"
 	ErrCodeInternal      ErrorCode = "INTERNAL_ERROR"
 )
 
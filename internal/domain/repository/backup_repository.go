@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// BackupRepository defines the interface for backup record persistence
+type BackupRepository interface {
+	// Save persists a backup record
+	Save(ctx context.Context, backup *entity.Backup) error
+	
+	// FindByID retrieves a backup by ID
+	FindByID(ctx context.Context, id string) (*entity.Backup, error)
+	
+	// FindByVM retrieves all backups of a VM, newest first
+	FindByVM(ctx context.Context, vmID string) ([]*entity.Backup, error)
+	
+	// FindAll retrieves all backups, newest first
+	FindAll(ctx context.Context) ([]*entity.Backup, error)
+	
+	// Delete removes a backup record
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// FlavorRepository defines the interface for flavor persistence
+// Flavors pushed by Ghost Core take precedence over configured ones of the same name
+type FlavorRepository interface {
+	// FindByName retrieves a flavor by name
+	FindByName(ctx context.Context, name string) (*entity.Flavor, error)
+
+	// FindAll retrieves all flavors, ordered by name
+	FindAll(ctx context.Context) ([]*entity.Flavor, error)
+
+	// ReplacePushed replaces the flavors pushed by Ghost Core
+	ReplacePushed(ctx context.Context, flavors []*entity.Flavor) error
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// IncomingMigrationRepository keeps the VMs PrepareMigration reserved room for
+type IncomingMigrationRepository interface {
+	// Save records a reservation; it fails when the VM already has one
+	Save(ctx context.Context, migration *entity.IncomingMigration) error
+	
+	// Take removes and returns the reservation of a VM
+	Take(ctx context.Context, vmID string) (*entity.IncomingMigration, error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// IPAllocationRepository defines the interface for static IP allocation persistence
+type IPAllocationRepository interface {
+	// Save persists an allocation
+	Save(ctx context.Context, alloc *entity.IPAllocation) error
+	
+	// FindByVM retrieves the allocation of a VM
+	FindByVM(ctx context.Context, vmID string) (*entity.IPAllocation, error)
+	
+	// FindAll retrieves all allocations
+	FindAll(ctx context.Context) ([]*entity.IPAllocation, error)
+	
+	// Delete removes the allocation of a VM
+	Delete(ctx context.Context, vmID string) error
+}
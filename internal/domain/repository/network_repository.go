@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// NetworkRepository defines the interface for private network persistence
+type NetworkRepository interface {
+	// Save persists a network
+	Save(ctx context.Context, network *entity.Network) error
+	
+	// FindByID retrieves a network by ID
+	FindByID(ctx context.Context, id string) (*entity.Network, error)
+	
+	// FindByName retrieves a network by name
+	FindByName(ctx context.Context, name string) (*entity.Network, error)
+	
+	// FindAll retrieves all networks, ordered by name
+	FindAll(ctx context.Context) ([]*entity.Network, error)
+	
+	// Delete removes a network
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// PortForwardRepository defines the interface for port forward persistence
+type PortForwardRepository interface {
+	// Save persists a port forward
+	Save(ctx context.Context, fwd *entity.PortForward) error
+	
+	// FindByID retrieves a port forward by ID
+	FindByID(ctx context.Context, id string) (*entity.PortForward, error)
+	
+	// FindByVM retrieves all port forwards of a VM
+	FindByVM(ctx context.Context, vmID string) ([]*entity.PortForward, error)
+	
+	// FindAll retrieves all port forwards, ordered by host port
+	FindAll(ctx context.Context) ([]*entity.PortForward, error)
+	
+	// Delete removes a port forward
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// SecurityGroupRepository defines the interface for security group persistence
+type SecurityGroupRepository interface {
+	// Save persists a security group
+	Save(ctx context.Context, sg *entity.SecurityGroup) error
+	
+	// FindByID retrieves a security group by ID
+	FindByID(ctx context.Context, id string) (*entity.SecurityGroup, error)
+	
+	// FindByName retrieves a security group by name
+	FindByName(ctx context.Context, name string) (*entity.SecurityGroup, error)
+	
+	// FindAll retrieves all security groups, ordered by name
+	FindAll(ctx context.Context) ([]*entity.SecurityGroup, error)
+	
+	// Delete removes a security group
+	Delete(ctx context.Context, id string) error
+}
@@ -11,6 +11,10 @@ type VMRepository interface {
 	// Save persists a VM
 	Save(ctx context.Context, vm *entity.VM) error
 	
+	// Update changes a copy of a stored VM and saves it in one step, so the VM
+	// is not brought back when it was deleted in the meantime
+	Update(ctx context.Context, id string, update func(vm *entity.VM)) (*entity.VM, error)
+	
 	// FindByID retrieves a VM by ID
 	FindByID(ctx context.Context, id string) (*entity.VM, error)
 	
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// AuditLog records operations on the agent
+type AuditLog interface {
+	// Record appends an event to the log
+	Record(ctx context.Context, event *entity.AuditEvent) error
+
+	// Query returns the events matching filter, oldest first, and checks that
+	// the log has not been tampered with
+	Query(ctx context.Context, filter AuditFilter) (*AuditQueryResult, error)
+}
+
+// AuditFilter selects audit events
+// Zero values match all events
+type AuditFilter struct {
+	Since time.Time
+	Until time.Time
+	VMID  string
+	Limit int // Keeps the newest events
+}
+
+// AuditQueryResult holds the events found by a query
+type AuditQueryResult struct {
+	Events []*entity.AuditEvent
+	// Intact is false when an event was changed, removed or inserted;
+	// IntegrityError describes the first such place
+	Intact         bool
+	IntegrityError string
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// TokenVerifier checks bearer tokens presented by API callers
+type TokenVerifier interface {
+	// Verify checks a token's signature and claims and returns the caller it
+	// was issued to
+	Verify(ctx context.Context, token string) (*entity.Identity, error)
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"io"
+)
+
+// BackupTarget defines where backup data is stored
+type BackupTarget interface {
+	// Name returns the target type, e.g. "local" or "s3"
+	Name() string
+	
+	// Put stores backup data under key
+	// Returns the target-specific location of the stored data
+	Put(ctx context.Context, key string, data io.Reader, sizeBytes int64) (string, error)
+	
+	// Get opens stored backup data
+	Get(ctx context.Context, location string) (io.ReadCloser, error)
+	
+	// Delete removes stored backup data
+	Delete(ctx context.Context, location string) error
+}
+
+// BackupScheduler defines the interface for per-VM backup schedules
+type BackupScheduler interface {
+	// Schedule registers or replaces the cron schedule of a VM
+	Schedule(vmID string, schedule string) error
+	
+	// Unschedule removes the schedule of a VM
+	Unschedule(vmID string)
+}
@@ -0,0 +1,12 @@
+package service
+
+import "time"
+
+// ConsoleTokenService issues one-time tokens for graphical console access
+type ConsoleTokenService interface {
+	// Issue creates a token granting a single VNC connection to a VM
+	Issue(vmID string) (token string, expiresAt time.Time, err error)
+	
+	// Redeem consumes a token and returns the VM it grants access to
+	Redeem(token string) (vmID string, err error)
+}
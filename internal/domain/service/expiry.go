@@ -0,0 +1,12 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// ExpiryReporter reports the end of VM leases to Ghost Core
+type ExpiryReporter interface {
+	ReportVMExpiry(ctx context.Context, vm *entity.VM, phase entity.ExpiryPhase) error
+}
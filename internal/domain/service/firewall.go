@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// FirewallService enforces security groups on VM interfaces
+// Every VM interface references a per-VM filter holding the default policy
+// and the rules of the VM's security groups; changes apply to running VMs
+type FirewallService interface {
+	// DefineGroup creates or updates the rules of a security group
+	DefineGroup(ctx context.Context, sg *entity.SecurityGroup) error
+	
+	// UndefineGroup removes a security group no VM references anymore
+	UndefineGroup(ctx context.Context, groupID string) error
+	
+	// ApplyVM sets the security groups of a VM, creating its filter if needed
+	ApplyVM(ctx context.Context, vmID string, groupIDs []string) error
+	
+	// AllowForwards admits the inbound traffic of a VM's port forwards,
+	// which the default policy would drop
+	AllowForwards(ctx context.Context, vmID string, forwards []*entity.PortForward) error
+	
+	// SetNetworks lets guests reach the VM networks, the NAT network and the
+	// given private networks, even where they fall in blocked ranges
+	SetNetworks(ctx context.Context, networks []*entity.Network) error
+	
+	// RemoveVM removes a VM's filter once its domain is gone
+	RemoveVM(ctx context.Context, vmID string) error
+	
+	// FilterName returns the name of the filter a VM's interface references
+	FilterName(vmID string) string
+}
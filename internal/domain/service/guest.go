@@ -0,0 +1,45 @@
+package service
+
+import "time"
+
+// GuestExecSpec describes a command to run inside a guest via the guest agent
+type GuestExecSpec struct {
+	Command string
+	Args    []string
+	Env     []string // KEY=value pairs
+	Stdin   []byte
+	Timeout time.Duration
+}
+
+// GuestExecResult is the outcome of a finished guest command
+type GuestExecResult struct {
+	ExitCode  int
+	Stdout    []byte
+	Stderr    []byte
+	Truncated bool // The guest agent cut off stdout or stderr
+}
+
+// GuestInfo is what the guest agent reports about the guest OS
+type GuestInfo struct {
+	OSName        string
+	OSVersion     string
+	KernelRelease string
+	Hostname      string
+	FileSystems   []GuestFileSystem
+	Interfaces    []GuestInterface
+}
+
+// GuestFileSystem is a filesystem mounted inside the guest
+type GuestFileSystem struct {
+	MountPoint string
+	FSType     string
+	TotalBytes uint64
+	UsedBytes  uint64
+}
+
+// GuestInterface is a network interface inside the guest
+type GuestInterface struct {
+	Name         string
+	HardwareAddr string
+	Addresses    []string // CIDR notation
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"io"
 	
 	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
 )
@@ -14,7 +15,13 @@ type VMSpec struct {
 	DiskGB   int
 	Template string
 	DiskPath string
-	IP       string
+	IP       string // Static address, skips waiting for a lease when set
+	MAC      string // Interface MAC, generated by libvirt when empty
+	Network  entity.NetworkMode
+	Filter   string // Network filter every interface references, none when empty
+	Limits   entity.VMLimits
+	Networks []string       // libvirt networks of additional NICs, in order
+	CPUMode  entity.CPUMode // Hypervisor default when empty
 }
 
 // VMStatusInfo contains detailed VM status information
@@ -23,6 +30,8 @@ type VMStatusInfo struct {
 	UptimeSeconds    int64
 	CPUUsagePercent  float32
 	RAMUsagePercent  float32
+	Crashed          bool // Went down because the guest or QEMU crashed
+	Autostart        bool // Started by the hypervisor when the host boots
 }
 
 // HypervisorService defines the interface for hypervisor operations
@@ -39,9 +48,38 @@ type HypervisorService interface {
 	// StopVM stops a running virtual machine
 	StopVM(ctx context.Context, id string, force bool) error
 	
+	// SetAutostart sets whether the hypervisor starts the VM when the host boots
+	SetAutostart(ctx context.Context, id string, autostart bool) error
+	
+	// SetVMLimits changes the network and disk limits of a VM
+	// Running VMs are updated live, and the limits persist across restarts
+	SetVMLimits(ctx context.Context, id string, limits entity.VMLimits) error
+	
 	// GetVMStatus retrieves detailed status of a VM
 	GetVMStatus(ctx context.Context, id string) (*VMStatusInfo, error)
 	
+	// SnapshotDisk redirects a running VM's writes to a temporary overlay
+	// so diskPath can be copied consistently without stopping the VM.
+	// The returned release function merges the overlay back with a block commit.
+	SnapshotDisk(ctx context.Context, id string, diskPath string) (func(context.Context) error, error)
+	
+	// MigrateVM live-migrates a running VM and its disk to another host
+	// progress is called periodically until the migration finishes
+	MigrateVM(ctx context.Context, id string, spec *MigrationSpec, progress func(*MigrationProgress)) error
+	
+	// OpenConsole connects to a running VM's serial console
+	// Closing the returned stream detaches from the console
+	OpenConsole(ctx context.Context, id string) (io.ReadWriteCloser, error)
+	
+	// GetVNCAddress returns the host address of a running VM's VNC server
+	GetVNCAddress(ctx context.Context, id string) (string, error)
+	
+	// GuestExec runs a command inside a running VM through the QEMU guest agent
+	GuestExec(ctx context.Context, id string, spec *GuestExecSpec) (*GuestExecResult, error)
+	
+	// GetGuestInfo queries OS, hostname, filesystem and interface details from the guest agent
+	GetGuestInfo(ctx context.Context, id string) (*GuestInfo, error)
+	
 	// ListVMs lists all VMs managed by the hypervisor
 	ListVMs(ctx context.Context) ([]*entity.VM, error)
 	
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// IPAMService defines the interface for static IP address management
+// Allocations are pinned with DHCP host reservations, so a VM keeps its
+// address across reboots and it is known before the VM boots
+type IPAMService interface {
+	// Allocate reserves an address and MAC for a VM on the NAT network,
+	// returning the existing allocation if the VM already has one
+	Allocate(ctx context.Context, vmID string) (*entity.IPAllocation, error)
+
+	// Release frees a VM's address and removes its DHCP reservation
+	Release(ctx context.Context, vmID string) error
+
+	// Get returns a VM's allocation
+	Get(ctx context.Context, vmID string) (*entity.IPAllocation, error)
+
+	// Reconcile re-applies persisted reservations and reports address conflicts
+	Reconcile(ctx context.Context) error
+}
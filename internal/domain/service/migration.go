@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// MigrationSpec describes where a live migration sends a VM
+type MigrationSpec struct {
+	DestURI        string // Libvirt URI of the target host
+	SourceDiskPath string // Disk path on this host
+	DestDiskPath   string // Disk path pre-created on the target host
+}
+
+// MigrationProgress reports how much data a migration has transferred
+type MigrationProgress struct {
+	DataTotal     uint64
+	DataProcessed uint64
+	DataRemaining uint64
+}
+
+// Percent returns the transferred share of the data, 0-100
+func (p *MigrationProgress) Percent() int {
+	if p.DataTotal == 0 {
+		return 0
+	}
+	return int(p.DataProcessed * 100 / p.DataTotal)
+}
+
+// MigrationReservation describes what a target agent set aside for an incoming VM
+type MigrationReservation struct {
+	DiskPath   string // Empty disk the migration copies into
+	LibvirtURI string // Libvirt URI the source hypervisor migrates to
+}
+
+// PeerAgentService defines the interface for calling other Ghost agents
+type PeerAgentService interface {
+	// PrepareMigration reserves resources and a disk for vm on the agent at address
+	PrepareMigration(ctx context.Context, address string, vm *entity.VM) (*MigrationReservation, error)
+	
+	// FinishMigration tells the agent at address whether the migration of vm succeeded
+	// On success it returns the VM as registered on the target agent
+	FinishMigration(ctx context.Context, address string, vm *entity.VM, succeeded bool) (*entity.VM, error)
+}
+
+// MigrationReporter reports migration progress to Ghost Core
+type MigrationReporter interface {
+	ReportVMMigration(ctx context.Context, migration *entity.Migration) error
+}
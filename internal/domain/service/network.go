@@ -1,6 +1,10 @@
 package service
 
-import "context"
+import (
+	"context"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
 
 // NetworkService defines the interface for network operations
 // This is designed to be extensible for future networking implementations
@@ -13,6 +17,31 @@ type NetworkService interface {
 	// ReleaseIP releases an IP address from a VM
 	ReleaseIP(ctx context.Context, vmID string) error
 	
-	// GetVMIP retrieves the current IP of a VM
-	GetVMIP(ctx context.Context, vmID string) (string, error)
+	// GetVMIP retrieves the current addresses of a VM, per interface with
+	// their family; the interface of the VM's primary NIC comes first
+	GetVMIP(ctx context.Context, vmID string) ([]entity.VMInterface, error)
+}
+
+// NetworkModes describes the network modes an agent can attach VMs to
+type NetworkModes struct {
+	Default   entity.NetworkMode
+	Available []entity.NetworkMode
+}
+
+// Supports reports whether mode is available on this agent
+func (m NetworkModes) Supports(mode entity.NetworkMode) bool {
+	for _, available := range m.Available {
+		if available == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns mode, or the default when mode is empty
+func (m NetworkModes) Resolve(mode entity.NetworkMode) entity.NetworkMode {
+	if mode == "" {
+		return m.Default
+	}
+	return mode
 }
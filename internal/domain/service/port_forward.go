@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+	
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// PortForwarder programs host-to-VM port forwards
+type PortForwarder interface {
+	// Name returns the backend type, e.g. "nftables" or "proxy"
+	Name() string
+	
+	// Sync replaces the active forwards with forwards
+	Sync(ctx context.Context, forwards []*entity.PortForward) error
+	
+	// Close removes all forwards
+	Close() error
+}
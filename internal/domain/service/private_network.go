@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+	
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// PrivateNetworkService manages the isolated networks VMs attach additional NICs to
+type PrivateNetworkService interface {
+	// Define creates a network, assigns its bridge and starts it
+	// The network is started again after host reboots; defining an existing network starts it if needed
+	Define(ctx context.Context, network *entity.Network) error
+	
+	// Undefine stops and removes a network no VM is attached to
+	Undefine(ctx context.Context, networkID string) error
+	
+	// NetworkName returns the name of the libvirt network VM interfaces reference
+	NetworkName(networkID string) string
+}
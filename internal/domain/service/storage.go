@@ -1,6 +1,27 @@
 package service
 
-import "context"
+import (
+	"context"
+	"io"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// ImageImportSpec describes an image supplied by a client
+type ImageImportSpec struct {
+	Name      string
+	Format    entity.ImageFormat // Declared format of the uploaded data
+	SHA256    string             // Declared SHA256 of the uploaded data
+	SizeBytes int64              // Declared size, 0 if unknown
+}
+
+// DiskExport is a standalone copy of a VM disk ready to be streamed
+// Closing it removes the temporary copy
+type DiskExport struct {
+	io.ReadCloser
+	SizeBytes int64
+	SHA256    string
+}
 
 // StorageService defines the interface for storage operations
 type StorageService interface {
@@ -8,13 +29,35 @@ type StorageService interface {
 	// Returns the local path to the image
 	GetImage(ctx context.Context, template string) (string, error)
 	
+	// ImportImage validates uploaded image data and registers it as a template
+	// Raw images are converted to qcow2 before being cached
+	ImportImage(ctx context.Context, spec *ImageImportSpec, data io.Reader) (*entity.Image, error)
+	
 	// CreateDisk creates a new disk for a VM from a base image
 	// Returns the path to the created disk
 	CreateDisk(ctx context.Context, vmID string, baseImage string, sizeGB int) (string, error)
 	
+	// CreateBlankDisk creates an empty standalone disk for a VM
+	// Used as the destination of an incoming live migration
+	CreateBlankDisk(ctx context.Context, vmID string, sizeGB int) (string, error)
+	
 	// DeleteDisk deletes a VM's disk
 	DeleteDisk(ctx context.Context, vmID string) error
 	
 	// GetDiskPath returns the path to a VM's disk
 	GetDiskPath(ctx context.Context, vmID string) (string, error)
+	
+	// ExportDisk creates a flattened copy of a VM's disk without its backing image
+	ExportDisk(ctx context.Context, vmID string) (*DiskExport, error)
+	
+	// ImportDisk stores a standalone qcow2 disk for a VM
+	// Returns the path to the stored disk
+	ImportDisk(ctx context.Context, vmID string, data io.Reader, expectedChecksum string) (string, error)
+	
+	// RestoreDisk replaces a VM's disk with a standalone qcow2 disk
+	// Returns the path to the restored disk
+	RestoreDisk(ctx context.Context, vmID string, data io.Reader, expectedChecksum string) (string, error)
+	
+	// Ping checks that disks and images can be written
+	Ping(ctx context.Context) error
 }
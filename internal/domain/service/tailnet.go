@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// TailnetService tracks this host's connection to the tailnet
+type TailnetService interface {
+	// Status returns the last known status
+	Status(ctx context.Context) (*entity.TailnetStatus, error)
+
+	// Watch returns the current status, then every change of state or addresses,
+	// until ctx is done
+	Watch(ctx context.Context) <-chan *entity.TailnetStatus
+
+	// AdvertiseRoutes offers subnets to the tailnet through this host,
+	// keeping routes advertised by others
+	AdvertiseRoutes(ctx context.Context, routes []string) error
+}
@@ -2,7 +2,9 @@ package apiclient
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/avast/retry-go/v4"
@@ -11,24 +13,28 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
 	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/observability"
 	"github.com/iammahbubalam/ghost-agent/pkg/ghostapi"
 )
 
 // Client handles communication with Ghost Core API
 type Client struct {
-	apiURL      string
-	conn        *grpc.ClientConn
-	client      ghostapi.GhostCoreServiceClient
-	logger      *zap.Logger
-	metrics     *observability.Metrics
+	apiURL    string
+	conn      *grpc.ClientConn
+	client    ghostapi.GhostCoreServiceClient
+	logger    *zap.Logger
+	metrics   *observability.Metrics
+	agentName string
+	auditLog  service.AuditLog
+
+	mu          sync.RWMutex // Guards agentID and tailscaleIP, which change on re-registration
 	agentID     string
-	agentName   string
 	tailscaleIP string
 }
 
 // NewClient creates a new Ghost Core API client
-func NewClient(apiURL, agentName, tailscaleIP string, logger *zap.Logger, metrics *observability.Metrics) (*Client, error) {
+func NewClient(apiURL, agentName, tailscaleIP string, auditLog service.AuditLog, logger *zap.Logger, metrics *observability.Metrics) (*Client, error) {
 	logger.Info("Connecting to Ghost Core API", zap.String("url", apiURL))
 
 	// TODO: Add mTLS credentials when Ghost Core is ready
@@ -48,31 +54,27 @@ func NewClient(apiURL, agentName, tailscaleIP string, logger *zap.Logger, metric
 		logger:      logger,
 		metrics:     metrics,
 		agentName:   agentName,
+		auditLog:    auditLog,
 		tailscaleIP: tailscaleIP,
 	}, nil
 }
 
 // RegisterAgent registers this agent with Ghost Core
+// The agent registers under its current tailnet address, see SetTailscaleIP
 func (c *Client) RegisterAgent(ctx context.Context, resources *entity.Resource, version string) (string, error) {
+	tailscaleIP := c.TailscaleIP()
 	c.logger.Info("Registering agent with Ghost Core",
 		zap.String("agent_name", c.agentName),
-		zap.String("tailscale_ip", c.tailscaleIP),
+		zap.String("tailscale_ip", tailscaleIP),
 		zap.Int("total_cpu", resources.TotalCPU),
 		zap.Int("total_ram_gb", resources.TotalRAMGB),
 	)
 
 	req := &ghostapi.RegisterAgentRequest{
 		AgentName:   c.agentName,
-		TailscaleIp: c.tailscaleIP,
+		TailscaleIp: tailscaleIP,
 		Version:     version,
-		Resources: &ghostapi.ResourceInfo{
-			TotalCpu:       int32(resources.TotalCPU),
-			AvailableCpu:   int32(resources.AvailableCPU),
-			TotalRamGb:     int32(resources.TotalRAMGB),
-			AvailableRamGb: int32(resources.AvailableRAMGB),
-			TotalDiskGb:    int32(resources.TotalDiskGB),
-			AvailableDiskGb: int32(resources.AvailableDiskGB),
-		},
+		Resources:   toResourceInfo(resources),
 	}
 
 	resp, err := c.client.RegisterAgent(ctx, req)
@@ -84,20 +86,23 @@ func (c *Client) RegisterAgent(ctx context.Context, resources *entity.Resource,
 		return "", fmt.Errorf("registration failed: %s", resp.Message)
 	}
 
+	c.mu.Lock()
 	c.agentID = resp.AgentId
+	c.mu.Unlock()
+
 	c.logger.Info("Agent registered successfully",
-		zap.String("agent_id", c.agentID),
+		zap.String("agent_id", resp.AgentId),
 		zap.String("message", resp.Message),
 	)
 
-	return c.agentID, nil
+	return resp.AgentId, nil
 }
 
 // SendHeartbeat sends periodic heartbeat to Ghost Core
-func (c *Client) SendHeartbeat(ctx context.Context, resources *entity.Resource, vms []*entity.VM) error {
+func (c *Client) SendHeartbeat(ctx context.Context, resources *entity.Resource, vms []*entity.VM, forwards []*entity.PortForward, quotas []*entity.TenantQuota) error {
 	return retry.Do(
 		func() error {
-			return c.sendHeartbeatInternal(ctx, resources, vms)
+			return c.sendHeartbeatInternal(ctx, resources, vms, forwards, quotas)
 		},
 		retry.Attempts(3),
 		retry.Delay(1*time.Second),
@@ -113,12 +118,22 @@ func (c *Client) SendHeartbeat(ctx context.Context, resources *entity.Resource,
 	)
 }
 
-func (c *Client) sendHeartbeatInternal(ctx context.Context, resources *entity.Resource, vms []*entity.VM) error {
+func (c *Client) sendHeartbeatInternal(ctx context.Context, resources *entity.Resource, vms []*entity.VM, forwards []*entity.PortForward, quotas []*entity.TenantQuota) error {
 	start := time.Now()
 	defer func() {
 		c.metrics.APICallLatency.WithLabelValues("heartbeat").Observe(time.Since(start).Seconds())
 	}()
 
+	// Group port forwards by VM
+	vmForwards := make(map[string][]*ghostapi.PortForward)
+	for _, fwd := range forwards {
+		vmForwards[fwd.VMID] = append(vmForwards[fwd.VMID], &ghostapi.PortForward{
+			Protocol:  string(fwd.Protocol),
+			HostPort:  int32(fwd.HostPort),
+			GuestPort: int32(fwd.GuestPort),
+		})
+	}
+
 	// Convert VMs to protobuf
 	vmInfos := make([]*ghostapi.VMInfo, len(vms))
 	for i, vm := range vms {
@@ -129,25 +144,25 @@ func (c *Client) sendHeartbeatInternal(ctx context.Context, resources *entity.Re
 			IpAddress: vm.IP,
 			Vcpu:      int32(vm.VCPU),
 			RamGb:     int32(vm.RAMGB),
+			PortForwards: vmForwards[vm.ID],
+			Interfaces:   toVMInterfaces(vm.Interfaces),
+		}
+		if vm.Expiry != nil {
+			vmInfos[i].ExpiresAt = vm.Expiry.At.Unix()
 		}
 	}
 
 	req := &ghostapi.HeartbeatRequest{
-		AgentId:   c.agentID,
-		Timestamp: time.Now().Unix(),
-		Resources: &ghostapi.ResourceInfo{
-			TotalCpu:       int32(resources.TotalCPU),
-			AvailableCpu:   int32(resources.AvailableCPU),
-			TotalRamGb:     int32(resources.TotalRAMGB),
-			AvailableRamGb: int32(resources.AvailableRAMGB),
-			TotalDiskGb:    int32(resources.TotalDiskGB),
-			AvailableDiskGb: int32(resources.AvailableDiskGB),
-		},
-		Vms: vmInfos,
+		AgentId:     c.GetAgentID(),
+		TailscaleIp: c.TailscaleIP(),
+		Timestamp:   time.Now().Unix(),
+		Resources:   toResourceInfo(resources),
+		Vms:         vmInfos,
+		Quotas:      toTenantQuotas(quotas),
 	}
 
 	c.logger.Debug("Sending heartbeat",
-		zap.String("agent_id", c.agentID),
+		zap.String("agent_id", c.GetAgentID()),
 		zap.Int("vms_count", len(vms)),
 		zap.Int("available_cpu", resources.AvailableCPU),
 	)
@@ -169,11 +184,36 @@ func (c *Client) sendHeartbeatInternal(ctx context.Context, resources *entity.Re
 	if len(resp.Commands) > 0 {
 		c.logger.Info("Received commands from Ghost Core", zap.Int("count", len(resp.Commands)))
 		// TODO: Implement command processing
+		for _, cmd := range resp.Commands {
+			c.recordCommand(ctx, cmd)
+		}
 	}
 
 	return nil
 }
 
+// recordCommand writes a command from Ghost Core to the audit log
+// Commands are not executed yet, so they are recorded as failed
+func (c *Client) recordCommand(ctx context.Context, cmd *ghostapi.Command) {
+	summary, _ := json.Marshal(map[string]any{
+		"command_id": cmd.CommandId,
+		"params":     cmd.Params,
+	})
+	event := &entity.AuditEvent{
+		Time:    time.Now(),
+		Method:  "core/" + cmd.Type,
+		Subject: c.apiURL,
+		Role:    entity.RoleCore,
+		VMID:    cmd.Params["vm_id"],
+		Summary: string(summary),
+		Result:  entity.AuditResultFailure,
+		Error:   "command not supported by this agent",
+	}
+	if err := c.auditLog.Record(ctx, event); err != nil {
+		c.logger.Error("Failed to record audit event", zap.String("command_id", cmd.CommandId), zap.Error(err))
+	}
+}
+
 // ReportVMCreated reports a newly created VM to Ghost Core
 func (c *Client) ReportVMCreated(ctx context.Context, vm *entity.VM) error {
 	c.logger.Info("Reporting VM creation to Ghost Core",
@@ -182,7 +222,7 @@ func (c *Client) ReportVMCreated(ctx context.Context, vm *entity.VM) error {
 	)
 
 	req := &ghostapi.ReportVMCreatedRequest{
-		AgentId:   c.agentID,
+		AgentId:   c.GetAgentID(),
 		VmId:      vm.ID,
 		VmName:    vm.Name,
 		Vcpu:      int32(vm.VCPU),
@@ -190,6 +230,7 @@ func (c *Client) ReportVMCreated(ctx context.Context, vm *entity.VM) error {
 		DiskGb:    int32(vm.DiskGB),
 		IpAddress: vm.IP,
 		Template:  vm.Template,
+		Interfaces: toVMInterfaces(vm.Interfaces),
 	}
 
 	resp, err := c.client.ReportVMCreated(ctx, req)
@@ -209,7 +250,7 @@ func (c *Client) ReportVMDeleted(ctx context.Context, vmID string) error {
 	c.logger.Info("Reporting VM deletion to Ghost Core", zap.String("vm_id", vmID))
 
 	req := &ghostapi.ReportVMDeletedRequest{
-		AgentId: c.agentID,
+		AgentId: c.GetAgentID(),
 		VmId:    vmID,
 	}
 
@@ -233,7 +274,7 @@ func (c *Client) ReportVMStatusChange(ctx context.Context, vmID string, status e
 	)
 
 	req := &ghostapi.ReportVMStatusChangeRequest{
-		AgentId: c.agentID,
+		AgentId: c.GetAgentID(),
 		VmId:    vmID,
 		Status:  string(status),
 	}
@@ -250,8 +291,68 @@ func (c *Client) ReportVMStatusChange(ctx context.Context, vmID string, status e
 	return nil
 }
 
+// ReportVMMigration reports live migration progress to Ghost Core
+func (c *Client) ReportVMMigration(ctx context.Context, migration *entity.Migration) error {
+	c.logger.Debug("Reporting VM migration to Ghost Core",
+		zap.String("vm_id", migration.VMID),
+		zap.String("phase", string(migration.Phase)),
+		zap.Int("percent", migration.Percent),
+	)
+
+	req := &ghostapi.ReportVMMigrationRequest{
+		AgentId:       c.GetAgentID(),
+		VmId:          migration.VMID,
+		TargetAddress: migration.TargetAddress,
+		Phase:         string(migration.Phase),
+		Percent:       int32(migration.Percent),
+		IpAddress:     migration.IPAddress,
+		Message:       migration.Message,
+	}
+
+	resp, err := c.client.ReportVMMigration(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to report VM migration: %w", err)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("VM migration report rejected")
+	}
+
+	return nil
+}
+
+// ReportVMExpiry reports the end of a VM's lease to Ghost Core
+func (c *Client) ReportVMExpiry(ctx context.Context, vm *entity.VM, phase entity.ExpiryPhase) error {
+	c.logger.Debug("Reporting VM expiry to Ghost Core",
+		zap.String("vm_id", vm.ID),
+		zap.String("phase", string(phase)),
+	)
+
+	req := &ghostapi.ReportVMExpiryRequest{
+		AgentId:  c.GetAgentID(),
+		VmId:     vm.ID,
+		Phase:    string(phase),
+		Metadata: vm.Metadata,
+	}
+	if vm.Expiry != nil {
+		req.ExpiresAt = vm.Expiry.At.Unix()
+		req.Action = string(vm.Expiry.Action)
+	}
+
+	resp, err := c.client.ReportVMExpiry(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to report VM expiry: %w", err)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("VM expiry report rejected")
+	}
+
+	return nil
+}
+
 // StartHeartbeat starts the heartbeat goroutine
-func (c *Client) StartHeartbeat(ctx context.Context, interval time.Duration, getResources func() *entity.Resource, getVMs func() []*entity.VM) {
+func (c *Client) StartHeartbeat(ctx context.Context, interval time.Duration, getResources func() *entity.Resource, getVMs func() []*entity.VM, getPortForwards func() []*entity.PortForward, getQuotas func() []*entity.TenantQuota) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -265,8 +366,10 @@ func (c *Client) StartHeartbeat(ctx context.Context, interval time.Duration, get
 		case <-ticker.C:
 			resources := getResources()
 			vms := getVMs()
+			forwards := getPortForwards()
+			quotas := getQuotas()
 
-			if err := c.SendHeartbeat(ctx, resources, vms); err != nil {
+			if err := c.SendHeartbeat(ctx, resources, vms, forwards, quotas); err != nil {
 				c.logger.Error("Heartbeat failed", zap.Error(err))
 				c.metrics.HeartbeatSuccess.Set(0)
 			} else {
@@ -278,10 +381,10 @@ func (c *Client) StartHeartbeat(ctx context.Context, interval time.Duration, get
 
 // UnregisterAgent unregisters the agent from Ghost Core
 func (c *Client) UnregisterAgent(ctx context.Context) error {
-	c.logger.Info("Unregistering agent from Ghost Core", zap.String("agent_id", c.agentID))
+	c.logger.Info("Unregistering agent from Ghost Core", zap.String("agent_id", c.GetAgentID()))
 
 	req := &ghostapi.UnregisterAgentRequest{
-		AgentId: c.agentID,
+		AgentId: c.GetAgentID(),
 	}
 
 	resp, err := c.client.UnregisterAgent(ctx, req)
@@ -305,16 +408,97 @@ func (c *Client) Close() error {
 	return nil
 }
 
-// Ping checks if the API connection is alive
-func (c *Client) Ping() error {
+// Ping checks that Ghost Core answers
+// Returns the round trip time
+func (c *Client) Ping(ctx context.Context) (time.Duration, error) {
 	if c.conn == nil {
-		return fmt.Errorf("not connected to Ghost Core API")
+		return 0, fmt.Errorf("not connected to Ghost Core API")
 	}
-	// TODO: Add actual ping RPC when Ghost Core implements it
-	return nil
+
+	sent := time.Now()
+	_, err := c.client.Ping(ctx, &ghostapi.PingRequest{
+		AgentId:   c.GetAgentID(),
+		Timestamp: sent.UnixMilli(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ping failed: %w", err)
+	}
+	return time.Since(sent), nil
 }
 
 // GetAgentID returns the agent ID
 func (c *Client) GetAgentID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.agentID
 }
+
+// TailscaleIP returns the tailnet address the agent registers with
+func (c *Client) TailscaleIP() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tailscaleIP
+}
+
+// SetTailscaleIP changes the tailnet address sent to Ghost Core
+// Heartbeats carry it right away; call RegisterAgent to update the registration
+func (c *Client) SetTailscaleIP(ip string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tailscaleIP = ip
+}
+
+// toResourceInfo converts the host's resources to protobuf
+func toResourceInfo(resources *entity.Resource) *ghostapi.ResourceInfo {
+	return &ghostapi.ResourceInfo{
+		TotalCpu:         int32(resources.TotalCPU),
+		AvailableCpu:     int32(resources.AvailableCPU),
+		TotalRamGb:       int32(resources.TotalRAMGB),
+		AvailableRamGb:   int32(resources.AvailableRAMGB),
+		TotalDiskGb:      int32(resources.TotalDiskGB),
+		AvailableDiskGb:  int32(resources.AvailableDiskGB),
+		CpuOvercommit:    resources.Overcommit.CPU,
+		RamOvercommit:    resources.Overcommit.RAM,
+		DiskOvercommit:   resources.Overcommit.Disk,
+		ThinProvisioning: resources.ThinProvisioned,
+	}
+}
+
+// toTenantQuotas converts tenant quotas and their usage to protobuf
+func toTenantQuotas(quotas []*entity.TenantQuota) []*ghostapi.TenantQuota {
+	out := make([]*ghostapi.TenantQuota, len(quotas))
+	for i, q := range quotas {
+		out[i] = &ghostapi.TenantQuota{
+			Tenant:    q.Tenant,
+			MaxVms:    int32(q.Quota.MaxVMs),
+			MaxVcpu:   int32(q.Quota.VCPU),
+			MaxRamGb:  int32(q.Quota.RAMGB),
+			MaxDiskGb: int32(q.Quota.DiskGB),
+			Vms:       int32(q.Usage.VMs),
+			Vcpu:      int32(q.Usage.VCPU),
+			RamGb:     int32(q.Usage.RAMGB),
+			DiskGb:    int32(q.Usage.DiskGB),
+		}
+	}
+	return out
+}
+
+// toVMInterfaces converts a VM's known addresses to protobuf
+func toVMInterfaces(ifaces []entity.VMInterface) []*ghostapi.VMInterface {
+	out := make([]*ghostapi.VMInterface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		pb := &ghostapi.VMInterface{
+			Name:       iface.Name,
+			MacAddress: iface.MAC,
+		}
+		for _, addr := range iface.Addresses {
+			pb.Addresses = append(pb.Addresses, &ghostapi.IPAddress{
+				Address: addr.Address,
+				Prefix:  int32(addr.Prefix),
+				Family:  string(addr.Family),
+			})
+		}
+		out = append(out, pb)
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package apiclient
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// KeepRegistered keeps the agent registered with Ghost Core under its current
+// tailnet address until ctx is done
+// Registration is refused while the tailnet is down, retried every retryInterval
+// after failures and repeated when the tailnet address changes.
+// onRegistered runs once, after the first successful registration
+func (c *Client) KeepRegistered(
+	ctx context.Context,
+	tailnet service.TailnetService,
+	retryInterval time.Duration,
+	version string,
+	getResources func() *entity.Resource,
+	onRegistered func(),
+) {
+	changes := tailnet.Watch(ctx)
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	var (
+		status     *entity.TailnetStatus
+		registered bool // Registered under the current address
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case status = <-changes:
+			if ip := status.IP(); status.Up() && ip != c.TailscaleIP() {
+				if c.TailscaleIP() != "" {
+					c.logger.Info("Tailnet address changed, registering again",
+						zap.String("old_ip", c.TailscaleIP()),
+						zap.String("new_ip", ip),
+					)
+				}
+				c.SetTailscaleIP(ip)
+				registered = false
+			}
+		case <-ticker.C:
+		}
+
+		if registered || !status.Up() {
+			continue
+		}
+
+		if _, err := c.RegisterAgent(ctx, getResources(), version); err != nil {
+			c.logger.Warn("Failed to register agent, will retry",
+				zap.Duration("retry_interval", retryInterval),
+				zap.Error(err),
+			)
+			continue
+		}
+		registered = true
+
+		if onRegistered != nil {
+			onRegistered()
+			onRegistered = nil
+		}
+	}
+}
@@ -0,0 +1,538 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// Each line ends with the hash of everything before it: {...,"hash":"<hex>"}
+const hashField = `,"hash":"`
+
+// Longest line read back, summaries are far shorter
+const maxLineSize = 1 << 20
+
+// FileLog implements AuditLog with one JSON object per line, in files rotated
+// by size
+// Every line holds the hash of the line before and is hashed itself, so
+// changing, removing or inserting lines breaks the chain. The chain alone
+// cannot tell cutting events off its ends, so the first and last event are
+// also kept in a head file next to the log.
+// Anyone who can write both files can rewrite the whole chain and the head;
+// the log is tamper-evident against API callers and careless edits, not root.
+type FileLog struct {
+	path     string
+	headPath string
+	maxSize  int64
+	maxFiles int
+	logger   *zap.Logger
+
+	mu        sync.Mutex
+	file      *os.File
+	size      int64
+	seq       uint64
+	lastHash  string
+	firstSeq  uint64 // Oldest kept event, moves on when rotation removes files
+	openError string // Mismatch between the log and its head found on open
+}
+
+// head is where the chain started and ended when the log was last written
+type head struct {
+	FirstSeq uint64 `json:"first_seq"`
+	Seq      uint64 `json:"seq"`
+	Hash     string `json:"hash"`
+}
+
+// snapshot is the log as it was when a query started
+// Open files survive rotation, and the current file is only read up to size,
+// so events recorded meanwhile do not show up half-checked
+type snapshot struct {
+	paths     []string
+	files     []*os.File // nil for files removed before they could be opened
+	size      int64      // Length of the current file, the last one
+	firstSeq  uint64
+	lastHash  string
+	openError string
+}
+
+type record struct {
+	Seq        uint64    `json:"seq"`
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Subject    string    `json:"subject,omitempty"`
+	Role       string    `json:"role,omitempty"`
+	Tenant     string    `json:"tenant,omitempty"`
+	AuthMethod string    `json:"auth_method,omitempty"`
+	Peer       string    `json:"peer,omitempty"`
+	VMID       string    `json:"vm_id,omitempty"`
+	Summary    string    `json:"summary,omitempty"`
+	Result     string    `json:"result"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	PrevHash   string    `json:"prev_hash"`
+}
+
+// NewFileLog opens the audit log at path, creating it if needed, and continues
+// its hash chain
+// The file is rotated once it reaches maxSizeMB; maxFiles rotated files are kept.
+// The head is kept at path with a .head suffix
+func NewFileLog(path string, maxSizeMB, maxFiles int, logger *zap.Logger) (*FileLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, err
+	}
+
+	l := &FileLog{
+		path:     path,
+		headPath: path + ".head",
+		maxSize:  int64(maxSizeMB) << 20,
+		maxFiles: maxFiles,
+		logger:   logger,
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	// Continue after the last event, which may be in the last rotated file
+	files, err := l.files()
+	if err != nil {
+		l.file.Close()
+		return nil, err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		rec, hash, err := lastRecord(files[i])
+		if err != nil {
+			logger.Warn("Failed to read last audit event, starting a new chain",
+				zap.String("file", files[i]),
+				zap.Error(err),
+			)
+			break
+		}
+		if rec != nil {
+			l.seq, l.lastHash = rec.Seq, hash
+			break
+		}
+	}
+	l.firstSeq = firstSeq(files)
+
+	// The head outlives events cut off the log; continuing from it keeps the gap visible
+	h, err := readHead(l.headPath)
+	switch {
+	case err != nil:
+		logger.Warn("Failed to read audit log head", zap.String("file", l.headPath), zap.Error(err))
+	case h != nil:
+		if h.Seq != l.seq || h.Hash != l.lastHash {
+			l.openError = fmt.Sprintf("log ends at event %d, head at event %d", l.seq, h.Seq)
+		} else if h.FirstSeq != l.firstSeq {
+			l.openError = fmt.Sprintf("log starts at event %d, head at event %d", l.firstSeq, h.FirstSeq)
+		}
+		l.seq, l.lastHash, l.firstSeq = h.Seq, h.Hash, h.FirstSeq
+	}
+	if l.openError != "" {
+		logger.Error("Audit log does not match its head", zap.String("error", l.openError))
+	}
+	l.writeHead()
+
+	return l, nil
+}
+
+// Record appends an event to the log and sets its sequence number and hash
+func (l *FileLog) Record(ctx context.Context, event *entity.AuditEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec := toRecord(event)
+	rec.Seq = l.seq + 1
+	rec.PrevHash = l.lastHash
+
+	line, hash, err := encode(rec)
+	if err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to encode audit event", err)
+	}
+
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return errors.New(errors.ErrCodeInternal, "failed to rotate audit log", err)
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to write audit log", err)
+	}
+
+	l.seq, l.lastHash = rec.Seq, hash
+	if l.firstSeq == 0 {
+		l.firstSeq = rec.Seq
+	}
+	l.writeHead()
+	event.Seq, event.Hash = rec.Seq, hash
+	return nil
+}
+
+// Query returns the events matching filter, oldest first, verifying the hash
+// chain of all files on the way
+// Only taking the snapshot holds the lock; checking it does not block Record
+func (l *FileLog) Query(ctx context.Context, filter service.AuditFilter) (*service.AuditQueryResult, error) {
+	snap, err := l.snapshot()
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to open audit log files", err)
+	}
+	defer snap.close()
+	return l.check(snap, filter)
+}
+
+// check verifies the hash chain of a snapshot and collects the events matching filter
+func (l *FileLog) check(snap *snapshot, filter service.AuditFilter) (*service.AuditQueryResult, error) {
+	result := &service.AuditQueryResult{Intact: true}
+	broken := func(where, msg string) {
+		if result.Intact {
+			result.Intact = false
+			result.IntegrityError = where + ": " + msg
+		}
+	}
+	if snap.openError != "" {
+		broken(filepath.Base(l.headPath), snap.openError)
+	}
+
+	var (
+		prevHash string
+		prevSeq  uint64
+		started  bool // The oldest kept event anchors the chain
+	)
+	for i, path := range snap.paths {
+		if snap.files[i] == nil {
+			continue
+		}
+		var r io.Reader = snap.files[i]
+		if i == len(snap.paths)-1 {
+			r = io.LimitReader(r, snap.size)
+		}
+		err := scanLines(r, func(n int, line []byte) {
+			where := fmt.Sprintf("%s:%d", filepath.Base(path), n)
+			rec, hash, err := decode(line)
+			if err != nil {
+				broken(where, err.Error())
+				return
+			}
+			if started && (rec.PrevHash != prevHash || rec.Seq != prevSeq+1) {
+				broken(where, "event does not follow the one before")
+			}
+			if !started && snap.firstSeq != 0 && rec.Seq != snap.firstSeq {
+				broken(where, "events missing at the start")
+			}
+			started, prevHash, prevSeq = true, hash, rec.Seq
+
+			if matches(rec, filter) {
+				result.Events = append(result.Events, toEvent(rec, hash))
+			}
+		})
+		if err != nil {
+			return nil, errors.New(errors.ErrCodeInternal, "failed to read audit log", err).
+				WithContext("file", path)
+		}
+	}
+	if prevHash != snap.lastHash {
+		broken(filepath.Base(l.path), "events missing at the end")
+	}
+
+	if filter.Limit > 0 && len(result.Events) > filter.Limit {
+		result.Events = result.Events[len(result.Events)-filter.Limit:]
+	}
+	return result, nil
+}
+
+// Close closes the log file
+func (l *FileLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// snapshot opens the files and notes the ends of the chain
+func (l *FileLog) snapshot() (*snapshot, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	paths, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{
+		paths:     paths,
+		files:     make([]*os.File, len(paths)),
+		size:      l.size,
+		firstSeq:  l.firstSeq,
+		lastHash:  l.lastHash,
+		openError: l.openError,
+	}
+	for i, path := range paths {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			snap.close()
+			return nil, err
+		}
+		snap.files[i] = file
+	}
+	return snap, nil
+}
+
+func (s *snapshot) close() {
+	for _, file := range s.files {
+		if file != nil {
+			file.Close()
+		}
+	}
+}
+
+func (l *FileLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// rotate renames the current file after the time and starts a new one,
+// removing the oldest rotated files beyond maxFiles
+func (l *FileLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(l.path)
+	rotated := strings.TrimSuffix(l.path, ext) + "-" + time.Now().UTC().Format("20060102T150405.000000000") + ext
+	if err := os.Rename(l.path, rotated); err != nil {
+		return err
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+
+	files, err := l.files()
+	if err != nil {
+		return err
+	}
+	rotatedFiles := files[:len(files)-1]
+	removed := false
+	for len(rotatedFiles) > l.maxFiles {
+		if err := os.Remove(rotatedFiles[0]); err != nil {
+			l.logger.Warn("Failed to remove old audit log", zap.String("file", rotatedFiles[0]), zap.Error(err))
+		}
+		rotatedFiles = rotatedFiles[1:]
+		removed = true
+	}
+	if removed {
+		l.firstSeq = firstSeq(rotatedFiles)
+		l.writeHead()
+	}
+
+	l.logger.Info("Rotated audit log", zap.String("file", rotated))
+	return nil
+}
+
+// files returns the rotated files, oldest first, then the current one
+func (l *FileLog) files() ([]string, error) {
+	ext := filepath.Ext(l.path)
+	rotated, err := filepath.Glob(strings.TrimSuffix(l.path, ext) + "-*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(rotated)
+	return append(rotated, l.path), nil
+}
+
+// writeHead replaces the head file with the current ends of the chain
+// A lost update shows up as a mismatch on the next open, so it only warns
+func (l *FileLog) writeHead() {
+	data, err := json.Marshal(&head{FirstSeq: l.firstSeq, Seq: l.seq, Hash: l.lastHash})
+	if err == nil {
+		tmp := l.headPath + ".tmp"
+		if err = os.WriteFile(tmp, data, 0600); err == nil {
+			err = os.Rename(tmp, l.headPath)
+		}
+	}
+	if err != nil {
+		l.logger.Warn("Failed to write audit log head", zap.String("file", l.headPath), zap.Error(err))
+	}
+}
+
+// readHead reads a head file, nil when there is none yet
+func readHead(path string) (*head, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var h head
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("invalid head: %w", err)
+	}
+	return &h, nil
+}
+
+// firstSeq returns the sequence number of the first readable event in files, 0 when there is none
+func firstSeq(files []string) uint64 {
+	for _, path := range files {
+		var seq uint64
+		_ = eachLine(path, func(_ int, line []byte) {
+			if seq != 0 {
+				return
+			}
+			if rec, _, err := decode(line); err == nil {
+				seq = rec.Seq
+			}
+		})
+		if seq != 0 {
+			return seq
+		}
+	}
+	return 0
+}
+
+// encode turns a record into a line ending with its hash
+func encode(rec *record) ([]byte, string, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	line := append(data[:len(data)-1], hashField...)
+	line = append(line, hash...)
+	line = append(line, "\"}\n"...)
+	return line, hash, nil
+}
+
+// decode parses a line and checks its hash
+func decode(line []byte) (*record, string, error) {
+	i := bytes.LastIndex(line, []byte(hashField))
+	if i < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, "", fmt.Errorf("event has no hash")
+	}
+	hash := string(line[i+len(hashField) : len(line)-2])
+	body := append(line[:i:i], '}')
+
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, "", fmt.Errorf("event was modified")
+	}
+
+	var rec record
+	if err := json.Unmarshal(body, &rec); err != nil {
+		return nil, "", fmt.Errorf("invalid event: %w", err)
+	}
+	return &rec, hash, nil
+}
+
+// lastRecord returns the last event of a file, nil when it has none
+func lastRecord(path string) (*record, string, error) {
+	var last []byte
+	err := eachLine(path, func(_ int, line []byte) {
+		last = append(last[:0], line...)
+	})
+	if err != nil || last == nil {
+		return nil, "", err
+	}
+	return decode(last)
+}
+
+// eachLine calls fn with every non-empty line of a file and its number
+func eachLine(path string, fn func(n int, line []byte)) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return scanLines(file, fn)
+}
+
+// scanLines calls fn with every non-empty line read from r and its number
+func scanLines(r io.Reader, fn func(n int, line []byte)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) > 0 {
+			fn(n, scanner.Bytes())
+		}
+	}
+	return scanner.Err()
+}
+
+func matches(rec *record, filter service.AuditFilter) bool {
+	if !filter.Since.IsZero() && rec.Time.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && !rec.Time.Before(filter.Until) {
+		return false
+	}
+	return filter.VMID == "" || rec.VMID == filter.VMID
+}
+
+func toRecord(event *entity.AuditEvent) *record {
+	return &record{
+		Time:       event.Time.UTC(),
+		Method:     event.Method,
+		Subject:    event.Subject,
+		Role:       string(event.Role),
+		Tenant:     event.Tenant,
+		AuthMethod: string(event.AuthMethod),
+		Peer:       event.Peer,
+		VMID:       event.VMID,
+		Summary:    event.Summary,
+		Result:     string(event.Result),
+		Error:      event.Error,
+		DurationMS: event.Duration.Milliseconds(),
+	}
+}
+
+func toEvent(rec *record, hash string) *entity.AuditEvent {
+	return &entity.AuditEvent{
+		Seq:        rec.Seq,
+		Time:       rec.Time,
+		Method:     rec.Method,
+		Subject:    rec.Subject,
+		Role:       entity.Role(rec.Role),
+		Tenant:     rec.Tenant,
+		AuthMethod: entity.AuthMethod(rec.AuthMethod),
+		Peer:       rec.Peer,
+		VMID:       rec.VMID,
+		Summary:    rec.Summary,
+		Result:     entity.AuditResult(rec.Result),
+		Error:      rec.Error,
+		Duration:   time.Duration(rec.DurationMS) * time.Millisecond,
+		Hash:       hash,
+	}
+}
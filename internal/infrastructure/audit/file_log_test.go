@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	rec := &record{
+		Seq:      7,
+		Time:     time.Date(2025, 10, 18, 12, 0, 0, 0, time.UTC),
+		Method:   "/agent.AgentService/DeleteVM",
+		Subject:  "alice",
+		VMID:     "vm-abc123",
+		Result:   "ok",
+		PrevHash: "ab12",
+	}
+	line, hash, err := encode(rec)
+	require.NoError(t, err)
+	require.True(t, bytes.HasSuffix(line, []byte("\"}\n")))
+
+	tests := []struct {
+		name    string
+		line    []byte
+		wantErr string
+	}{
+		{name: "intact", line: bytes.TrimSuffix(line, []byte("\n"))},
+		{name: "changed field", line: bytes.Replace(line[:len(line)-1], []byte("alice"), []byte("mallory"), 1), wantErr: "event was modified"},
+		{name: "changed hash", line: bytes.Replace(line[:len(line)-1], []byte(hash), []byte(hash[1:]+"0"), 1), wantErr: "event was modified"},
+		{name: "no hash", line: []byte(`{"seq":7}`), wantErr: "event has no hash"},
+		{name: "cut off", line: line[:len(line)/2], wantErr: "event has no hash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotHash, err := decode(tt.line)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, hash, gotHash)
+			assert.Equal(t, rec, got)
+		})
+	}
+}
+
+func TestFileLogChain(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper changes the log files after five events were recorded, then the log is reopened
+		tamper    func(t *testing.T, path string)
+		intact    bool
+		wantError string
+	}{
+		{
+			name:   "untouched",
+			tamper: func(*testing.T, string) {},
+			intact: true,
+		},
+		{
+			name: "line changed",
+			tamper: func(t *testing.T, path string) {
+				replaceInFile(t, path, `"vm-3"`, `"vm-9"`)
+			},
+			wantError: "audit.log:3: event was modified",
+		},
+		{
+			name: "line removed",
+			tamper: func(t *testing.T, path string) {
+				editLines(t, path, func(lines [][]byte) [][]byte {
+					return append(lines[:2:2], lines[3:]...)
+				})
+			},
+			wantError: "audit.log:3: event does not follow the one before",
+		},
+		{
+			name: "last line removed",
+			tamper: func(t *testing.T, path string) {
+				editLines(t, path, func(lines [][]byte) [][]byte {
+					return lines[:len(lines)-1]
+				})
+			},
+			wantError: "audit.log.head: log ends at event 4, head at event 5",
+		},
+		{
+			name: "first line removed",
+			tamper: func(t *testing.T, path string) {
+				editLines(t, path, func(lines [][]byte) [][]byte {
+					return lines[1:]
+				})
+			},
+			wantError: "audit.log.head: log starts at event 2, head at event 1",
+		},
+		{
+			name: "head removed",
+			tamper: func(t *testing.T, path string) {
+				require.NoError(t, os.Remove(path+".head"))
+			},
+			intact: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			l, err := NewFileLog(path, 0, 0, zap.NewNop())
+			require.NoError(t, err)
+			for i := 1; i <= 5; i++ {
+				event := &entity.AuditEvent{Time: time.Now(), Method: "CreateVM", VMID: "vm-" + string(rune('0'+i)), Result: "ok"}
+				require.NoError(t, l.Record(context.Background(), event))
+				assert.Equal(t, uint64(i), event.Seq)
+			}
+			require.NoError(t, l.Close())
+
+			tt.tamper(t, path)
+
+			l, err = NewFileLog(path, 0, 0, zap.NewNop())
+			require.NoError(t, err)
+			defer l.Close()
+
+			result, err := l.Query(context.Background(), service.AuditFilter{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.intact, result.Intact)
+			assert.Equal(t, tt.wantError, result.IntegrityError)
+		})
+	}
+}
+
+func TestFileLogRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	// Every event gets its own file; two rotated files are kept
+	l, err := NewFileLog(path, 0, 2, zap.NewNop())
+	require.NoError(t, err)
+	l.maxSize = 1
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, l.Record(context.Background(), &entity.AuditEvent{Time: time.Now(), Method: "StopVM", Result: "ok"}))
+	}
+
+	result, err := l.Query(context.Background(), service.AuditFilter{})
+	require.NoError(t, err)
+	assert.True(t, result.Intact, result.IntegrityError)
+	require.Len(t, result.Events, 3)
+	assert.Equal(t, uint64(3), result.Events[0].Seq)
+	require.NoError(t, l.Close())
+
+	// Deleting the oldest kept file by hand looks like rotation, except to the head
+	files, err := l.files()
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(files[0]))
+
+	l, err = NewFileLog(path, 0, 2, zap.NewNop())
+	require.NoError(t, err)
+	defer l.Close()
+
+	result, err = l.Query(context.Background(), service.AuditFilter{})
+	require.NoError(t, err)
+	assert.False(t, result.Intact)
+	assert.Equal(t, "audit.log.head: log starts at event 4, head at event 3", result.IntegrityError)
+}
+
+func TestFileLogQueryDuringRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := NewFileLog(path, 0, 1, zap.NewNop())
+	require.NoError(t, err)
+	defer l.Close()
+	l.maxSize = 1
+
+	record := func() {
+		require.NoError(t, l.Record(context.Background(), &entity.AuditEvent{Time: time.Now(), Method: "StopVM", Result: "ok"}))
+	}
+	record()
+	record()
+
+	// Events recorded after the snapshot, rotating away its files, leave it intact
+	snap, err := l.snapshot()
+	require.NoError(t, err)
+	defer snap.close()
+	record()
+	record()
+	record()
+
+	result, err := l.check(snap, service.AuditFilter{})
+	require.NoError(t, err)
+	assert.True(t, result.Intact, result.IntegrityError)
+	require.Len(t, result.Events, 2)
+	assert.Equal(t, uint64(1), result.Events[0].Seq)
+	assert.Equal(t, uint64(2), result.Events[1].Seq)
+}
+
+func replaceInFile(t *testing.T, path, old, new string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), old)
+	require.NoError(t, os.WriteFile(path, bytes.Replace(data, []byte(old), []byte(new), 1), 0600))
+}
+
+func editLines(t *testing.T, path string, edit func(lines [][]byte) [][]byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	lines = lines[:len(lines)-1] // Empty after the last newline
+	require.NoError(t, os.WriteFile(path, bytes.Join(edit(lines), nil), 0600))
+}
@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// Allowed clock difference between Ghost Core and the agent
+const clockSkew = 30 * time.Second
+
+// JWTVerifier implements TokenVerifier for JWTs signed by Ghost Core
+// Tokens must be signed with Core's key (RS256, ES256 or EdDSA), carry a role
+// and expire; a tenant claim limits them to that tenant's VMs
+type JWTVerifier struct {
+	key      crypto.PublicKey
+	alg      string
+	issuer   string
+	audience string
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Subject   string        `json:"sub"`
+	Issuer    string        `json:"iss"`
+	Audience  audienceClaim `json:"aud"`
+	ExpiresAt *float64      `json:"exp"`
+	NotBefore *float64      `json:"nbf"`
+	Role      string        `json:"role"`
+	Tenant    string        `json:"tenant"`
+}
+
+// audienceClaim is the aud claim, which is a string or a list of strings
+type audienceClaim []string
+
+func (a *audienceClaim) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audienceClaim{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// NewJWTVerifier creates a verifier for tokens signed by the PEM encoded public
+// key at keyPath
+// Empty issuer or audience skip checking that claim
+func NewJWTVerifier(keyPath, issuer, audience string) (*JWTVerifier, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in %s", keyPath)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token public key: %w", err)
+	}
+
+	v := &JWTVerifier{key: key, issuer: issuer, audience: audience}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		v.alg = "RS256"
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported token key curve %s, want P-256", k.Curve.Params().Name)
+		}
+		v.alg = "ES256"
+	case ed25519.PublicKey:
+		v.alg = "EdDSA"
+	default:
+		return nil, fmt.Errorf("unsupported token key type %T", key)
+	}
+	return v, nil
+}
+
+// Verify checks a token's signature and claims and returns the caller it was
+// issued to
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*entity.Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("malformed token", nil)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalidToken("malformed token header", err)
+	}
+	// The algorithm is fixed by the key, never chosen by the token
+	if header.Alg != v.alg {
+		return nil, invalidToken("unexpected signing algorithm", nil).
+			WithContext("alg", header.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalidToken("malformed token signature", err)
+	}
+	if !v.verifySignature([]byte(parts[0]+"."+parts[1]), sig) {
+		return nil, invalidToken("invalid token signature", nil)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, invalidToken("malformed token claims", err)
+	}
+	if err := v.checkClaims(&claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &entity.Identity{
+		Subject: claims.Subject,
+		Role:    entity.Role(claims.Role),
+		Tenant:  claims.Tenant,
+		Method:  entity.AuthMethodJWT,
+	}, nil
+}
+
+func (v *JWTVerifier) verifySignature(signed, sig []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch key := v.key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	case *ecdsa.PublicKey:
+		// JWS carries r and s as fixed size big-endian integers
+		if len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(key, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, signed, sig)
+	}
+	return false
+}
+
+func (v *JWTVerifier) checkClaims(claims *jwtClaims, now time.Time) *errors.AppError {
+	if claims.ExpiresAt == nil {
+		return invalidToken("token does not expire", nil)
+	}
+	if now.After(unixTime(*claims.ExpiresAt).Add(clockSkew)) {
+		return invalidToken("token expired", nil)
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(unixTime(*claims.NotBefore)) {
+		return invalidToken("token not valid yet", nil)
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return invalidToken("unexpected token issuer", nil).
+			WithContext("issuer", claims.Issuer)
+	}
+	if v.audience != "" && !slices.Contains(claims.Audience, v.audience) {
+		return invalidToken("token is not meant for this agent", nil)
+	}
+	if claims.Subject == "" {
+		return invalidToken("token has no subject", nil)
+	}
+	if !entity.Role(claims.Role).IsValid() {
+		return invalidToken("token has no valid role", nil).
+			WithContext("role", claims.Role)
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func invalidToken(msg string, err error) *errors.AppError {
+	return errors.New(errors.ErrCodeValidation, msg, err)
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"net"
+	"syscall"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// peerCredentials reads the credentials of the process connected to conn
+func peerCredentials(conn *net.UnixConn) (*UnixPeerInfo, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return nil, err
+	}
+	if credErr != nil {
+		return nil, credErr
+	}
+
+	return &UnixPeerInfo{
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+		UID:            cred.Uid,
+		GID:            cred.Gid,
+		PID:            cred.Pid,
+	}, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package auth
+
+import (
+	"fmt"
+	"net"
+)
+
+// peerCredentials is only supported on Linux
+func peerCredentials(conn *net.UnixConn) (*UnixPeerInfo, error) {
+	return nil, fmt.Errorf("peer credentials are not supported on this platform")
+}
@@ -0,0 +1,100 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// LocalTarget implements BackupTarget on a local directory
+// The directory can be a mounted external disk or network share
+type LocalTarget struct {
+	dir    string
+	logger *zap.Logger
+}
+
+// NewLocalTarget creates a new local directory backup target
+func NewLocalTarget(dir string, logger *zap.Logger) (*LocalTarget, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	return &LocalTarget{
+		dir:    dir,
+		logger: logger,
+	}, nil
+}
+
+// Name returns the target type
+func (t *LocalTarget) Name() string {
+	return "local"
+}
+
+// Put stores backup data under key
+func (t *LocalTarget) Put(ctx context.Context, key string, data io.Reader, sizeBytes int64) (string, error) {
+	path := filepath.Join(t.dir, filepath.FromSlash(key))
+	t.logger.Debug("Writing backup", zap.String("path", path))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return "", errors.New(errors.ErrCodeStorage, "failed to create backup directory", err)
+	}
+
+	// Write to temp file first, then rename so partial backups are never listed
+	tempPath := path + ".partial"
+	out, err := os.Create(tempPath)
+	if err != nil {
+		return "", errors.New(errors.ErrCodeStorage, "failed to create backup file", err).
+			WithContext("path", tempPath)
+	}
+
+	if _, err := io.Copy(out, data); err != nil {
+		out.Close()
+		_ = os.Remove(tempPath)
+		return "", errors.New(errors.ErrCodeStorage, "failed to write backup", err).
+			WithContext("path", tempPath)
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tempPath)
+		return "", errors.New(errors.ErrCodeStorage, "failed to write backup", err).
+			WithContext("path", tempPath)
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return "", errors.New(errors.ErrCodeStorage, "failed to store backup", err).
+			WithContext("path", path)
+	}
+
+	return path, nil
+}
+
+// Get opens stored backup data
+func (t *LocalTarget) Get(ctx context.Context, location string) (io.ReadCloser, error) {
+	file, err := os.Open(location)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New(errors.ErrCodeNotFound, "backup data not found", err).
+				WithContext("path", location)
+		}
+		return nil, errors.New(errors.ErrCodeStorage, "failed to open backup", err).
+			WithContext("path", location)
+	}
+
+	return file, nil
+}
+
+// Delete removes stored backup data
+func (t *LocalTarget) Delete(ctx context.Context, location string) error {
+	if err := os.Remove(location); err != nil && !os.IsNotExist(err) {
+		return errors.New(errors.ErrCodeStorage, "failed to delete backup", err).
+			WithContext("path", location)
+	}
+
+	return nil
+}
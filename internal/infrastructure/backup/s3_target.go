@@ -0,0 +1,89 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// S3Target implements BackupTarget on an S3-compatible object store
+type S3Target struct {
+	client *minio.Client
+	bucket string
+	prefix string
+	logger *zap.Logger
+}
+
+// NewS3Target creates a new S3-compatible backup target
+func NewS3Target(endpoint, region, bucket, prefix, accessKey, secretKey string, useSSL bool, logger *zap.Logger) (*S3Target, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	return &S3Target{
+		client: client,
+		bucket: bucket,
+		prefix: prefix,
+		logger: logger,
+	}, nil
+}
+
+// Name returns the target type
+func (t *S3Target) Name() string {
+	return "s3"
+}
+
+// Put stores backup data under key
+func (t *S3Target) Put(ctx context.Context, key string, data io.Reader, sizeBytes int64) (string, error) {
+	objectKey := path.Join(t.prefix, key)
+	t.logger.Debug("Uploading backup",
+		zap.String("bucket", t.bucket),
+		zap.String("key", objectKey),
+	)
+
+	_, err := t.client.PutObject(ctx, t.bucket, objectKey, data, sizeBytes, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		return "", errors.New(errors.ErrCodeStorage, "failed to upload backup", err).
+			WithContext("bucket", t.bucket).
+			WithContext("key", objectKey)
+	}
+
+	return objectKey, nil
+}
+
+// Get opens stored backup data
+func (t *S3Target) Get(ctx context.Context, location string) (io.ReadCloser, error) {
+	object, err := t.client.GetObject(ctx, t.bucket, location, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to download backup", err).
+			WithContext("bucket", t.bucket).
+			WithContext("key", location)
+	}
+
+	return object, nil
+}
+
+// Delete removes stored backup data
+func (t *S3Target) Delete(ctx context.Context, location string) error {
+	if err := t.client.RemoveObject(ctx, t.bucket, location, minio.RemoveObjectOptions{}); err != nil {
+		return errors.New(errors.ErrCodeStorage, "failed to delete backup", err).
+			WithContext("bucket", t.bucket).
+			WithContext("key", location)
+	}
+
+	return nil
+}
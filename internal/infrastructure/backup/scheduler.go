@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// RunFunc performs a backup of a single VM
+type RunFunc func(ctx context.Context, vmID string) error
+
+// Scheduler implements BackupScheduler using cron expressions
+type Scheduler struct {
+	cron    *cron.Cron
+	run     RunFunc
+	timeout time.Duration
+	entries map[string]cron.EntryID // vmID -> cron entry
+	mu      sync.Mutex
+	logger  *zap.Logger
+}
+
+// NewScheduler creates a new backup scheduler
+// timeout bounds a single backup run
+func NewScheduler(run RunFunc, timeout time.Duration, logger *zap.Logger) *Scheduler {
+	c := cron.New(cron.WithChain(
+		cron.Recover(cronLogger{logger}),
+		cron.SkipIfStillRunning(cronLogger{logger}),
+	))
+
+	return &Scheduler{
+		cron:    c,
+		run:     run,
+		timeout: timeout,
+		entries: make(map[string]cron.EntryID),
+		logger:  logger,
+	}
+}
+
+// ValidateSchedule checks that schedule is a valid cron expression
+func ValidateSchedule(schedule string) error {
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return fmt.Errorf("invalid cron schedule %q: %w", schedule, err)
+	}
+	return nil
+}
+
+// Schedule registers or replaces the cron schedule of a VM
+func (s *Scheduler) Schedule(vmID string, schedule string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// An invalid schedule leaves the current one in place
+	id, err := s.cron.AddFunc(schedule, func() {
+		s.runBackup(vmID)
+	})
+	if err != nil {
+		return fmt.Errorf("invalid cron schedule %q: %w", schedule, err)
+	}
+
+	if old, ok := s.entries[vmID]; ok {
+		s.cron.Remove(old)
+	}
+	s.entries[vmID] = id
+	s.logger.Info("Backup scheduled",
+		zap.String("vm_id", vmID),
+		zap.String("schedule", schedule),
+	)
+
+	return nil
+}
+
+// Unschedule removes the schedule of a VM
+func (s *Scheduler) Unschedule(vmID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.entries[vmID]; ok {
+		s.cron.Remove(id)
+		delete(s.entries, vmID)
+		s.logger.Info("Backup unscheduled", zap.String("vm_id", vmID))
+	}
+}
+
+// Start starts running scheduled backups in the background
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop stops the scheduler and waits for running backups to finish
+func (s *Scheduler) Stop(ctx context.Context) {
+	done := s.cron.Stop()
+	select {
+	case <-done.Done():
+	case <-ctx.Done():
+		s.logger.Warn("Timed out waiting for running backups")
+	}
+}
+
+func (s *Scheduler) runBackup(vmID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	s.logger.Info("Running scheduled backup", zap.String("vm_id", vmID))
+	if err := s.run(ctx, vmID); err != nil {
+		s.logger.Error("Scheduled backup failed",
+			zap.String("vm_id", vmID),
+			zap.Error(err),
+		)
+	}
+}
+
+// cronLogger adapts zap to the cron.Logger interface
+type cronLogger struct {
+	logger *zap.Logger
+}
+
+func (l cronLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Sugar().Debugw(msg, keysAndValues...)
+}
+
+func (l cronLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.logger.Sugar().Errorw(msg, append(keysAndValues, "error", err)...)
+}
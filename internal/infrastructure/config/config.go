@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -17,6 +20,21 @@ type Config struct {
 	Logging  LoggingConfig  `mapstructure:"logging"`
 	Metrics  MetricsConfig  `mapstructure:"metrics"`
 	Health   HealthConfig   `mapstructure:"health"`
+	Backup   BackupConfig   `mapstructure:"backup"`
+	Console  ConsoleConfig  `mapstructure:"console"`
+	IPAM     IPAMConfig     `mapstructure:"ipam"`
+	IPv6     IPv6Config     `mapstructure:"ipv6"`
+	PortForward PortForwardConfig `mapstructure:"port_forward"`
+	Firewall    FirewallConfig    `mapstructure:"firewall"`
+	Limits      LimitsConfig      `mapstructure:"limits"`
+	Tailscale   TailscaleConfig   `mapstructure:"tailscale"`
+	Auth        AuthConfig        `mapstructure:"auth"`
+	Audit       AuditConfig       `mapstructure:"audit"`
+	Quotas      QuotasConfig      `mapstructure:"quotas"`
+	// Flavors holds the named VM sizes by name; names are lowercase
+	Flavors map[string]FlavorConfig `mapstructure:"flavors" validate:"dive"`
+	Expiry  ExpiryConfig            `mapstructure:"expiry"`
+	Restart RestartConfig           `mapstructure:"restart"`
 }
 
 type AgentConfig struct {
@@ -31,20 +49,142 @@ type LibvirtConfig struct {
 	StoragePool string `mapstructure:"storage_pool" validate:"required"`
 	Network     string `mapstructure:"network" validate:"required"`
 	ImageCache  string `mapstructure:"image_cache" validate:"required"`
+	// NetworkMode is the default for new VMs: nat or bridge
+	NetworkMode string `mapstructure:"network_mode" validate:"required,oneof=nat bridge"`
+	// Bridge is the host bridge for bridge mode, empty disables bridging
+	Bridge string `mapstructure:"bridge"`
+	// MigrationURI is the libvirt URI other agents migrate VMs to,
+	// defaults to qemu+tcp://<tailscale-ip>/system
+	MigrationURI string `mapstructure:"migration_uri"`
 }
 
 type ResourceConfig struct {
 	ReservedCPU    int `mapstructure:"reserved_cpu" validate:"min=0"`
 	ReservedRAMGB  int `mapstructure:"reserved_ram_gb" validate:"min=0"`
 	ReservedDiskGB int `mapstructure:"reserved_disk_gb" validate:"min=0"`
+	// Overcommit is how many times each resource may be allocated to VMs
+	Overcommit OvercommitConfig `mapstructure:"overcommit"`
+	// ThinProvisioning also checks new disks against the space VM disks leave free
+	ThinProvisioning bool `mapstructure:"thin_provisioning"`
+}
+
+// OvercommitConfig holds the allocation ratios, e.g. 4 for 4 vCPUs per core
+type OvercommitConfig struct {
+	CPU  float64 `mapstructure:"cpu" validate:"gt=0"`
+	RAM  float64 `mapstructure:"ram" validate:"gt=0"`
+	Disk float64 `mapstructure:"disk" validate:"gt=0"`
+}
+
+// QuotasConfig caps what the VMs of each tenant may allocate
+type QuotasConfig struct {
+	// Default applies to tenants without their own quota
+	Default QuotaConfig `mapstructure:"default"`
+	// Tenants holds quotas by tenant; names are lowercase
+	Tenants map[string]QuotaConfig `mapstructure:"tenants" validate:"dive"`
+}
+
+// QuotaConfig is the quota of one tenant
+// Zero values are unlimited
+type QuotaConfig struct {
+	MaxVMs int `mapstructure:"max_vms" validate:"min=0"`
+	VCPU   int `mapstructure:"vcpu" validate:"min=0"`
+	RAMGB  int `mapstructure:"ram_gb" validate:"min=0"`
+	DiskGB int `mapstructure:"disk_gb" validate:"min=0"`
+}
+
+// FlavorConfig is a named VM size
+type FlavorConfig struct {
+	VCPU   int `mapstructure:"vcpu" validate:"min=1"`
+	RAMGB  int `mapstructure:"ram_gb" validate:"min=1"`
+	DiskGB int `mapstructure:"disk_gb" validate:"min=1"`
+	// CPUMode is host-passthrough, host-model or maximum, empty for the hypervisor default
+	CPUMode string `mapstructure:"cpu_mode" validate:"omitempty,oneof=host-passthrough host-model maximum"`
+	// Limits override the default limits, zero values keep them
+	Limits LimitsConfig `mapstructure:"limits"`
+	// Templates restricts the templates VMs of the flavor may use
+	Templates []string `mapstructure:"templates"`
+}
+
+// ExpiryConfig controls the leases of ephemeral VMs
+type ExpiryConfig struct {
+	// DefaultAction is stop or delete, for leases that do not choose
+	DefaultAction string `mapstructure:"default_action" validate:"oneof=stop delete"`
+	// MaxTTL caps leases, 0 for no cap
+	MaxTTL time.Duration `mapstructure:"max_ttl" validate:"min=0"`
+	// WarnBefore is how long before expiry the warning goes out
+	WarnBefore time.Duration `mapstructure:"warn_before" validate:"min=0"`
+	// CheckInterval is how often expired VMs are looked for
+	CheckInterval time.Duration `mapstructure:"check_interval" validate:"required"`
+}
+
+// RestartConfig controls how VMs that are down are brought back up
+type RestartConfig struct {
+	// DefaultPolicy is the restart policy of VMs created without one
+	DefaultPolicy string `mapstructure:"default_policy" validate:"oneof=never always unless-stopped on-failure"`
+	// Backoff is the wait after the first restart, doubling up to MaxBackoff
+	Backoff time.Duration `mapstructure:"backoff" validate:"required"`
+	// MaxBackoff caps the wait; a VM up this long counts as healthy again
+	MaxBackoff time.Duration `mapstructure:"max_backoff" validate:"required,gtefield=Backoff"`
+	// CheckInterval is how often VMs that are down are looked for
+	CheckInterval time.Duration `mapstructure:"check_interval" validate:"required"`
 }
 
 type GRPCConfig struct {
-	ListenAddr string `mapstructure:"listen_addr" validate:"required"`
-	TLSEnabled bool   `mapstructure:"tls_enabled"`
-	TLSCert    string `mapstructure:"tls_cert"`
-	TLSKey     string `mapstructure:"tls_key"`
-	TLSCA      string `mapstructure:"tls_ca"`
+	// ListenAddr is host:port, or tailnet:<port> to only listen on the tailnet
+	// addresses; empty only serves UnixSocket
+	ListenAddr string `mapstructure:"listen_addr" validate:"required_without=UnixSocket,omitempty,hostname_port"`
+	// UnixSocket is a socket for local clients, empty disables it
+	UnixSocket      string `mapstructure:"unix_socket"`
+	UnixSocketMode  string `mapstructure:"unix_socket_mode"` // Octal, e.g. "0660"
+	UnixSocketGroup string `mapstructure:"unix_socket_group"`
+	TLSEnabled      bool   `mapstructure:"tls_enabled"`
+	TLSCert         string `mapstructure:"tls_cert" validate:"required_if=TLSEnabled true"`
+	TLSKey          string `mapstructure:"tls_key" validate:"required_if=TLSEnabled true"`
+	TLSCA           string `mapstructure:"tls_ca"` // Verifies client and peer agent certificates
+
+	// Limits protect the host from callers sending more than it can handle
+	RateLimit   RateLimitConfig   `mapstructure:"rate_limit"`
+	Concurrency ConcurrencyConfig `mapstructure:"concurrency"`
+
+	// Reflection serves the gRPC server reflection service
+	Reflection bool `mapstructure:"reflection"`
+}
+
+// RateLimitConfig limits how often the API may be called
+type RateLimitConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// PerClient limits each caller, told apart by identity or address
+	PerClient RateConfig `mapstructure:"per_client"`
+	// PerMethod limits RPCs across all callers by name, e.g. CreateVM
+	PerMethod map[string]RateConfig `mapstructure:"per_method" validate:"dive"`
+	// ClientIdleTimeout forgets callers that sent nothing for this long
+	ClientIdleTimeout time.Duration `mapstructure:"client_idle_timeout" validate:"min=0"`
+}
+
+// RateConfig is a token bucket, a zero rate is unlimited
+type RateConfig struct {
+	Rate  float64 `mapstructure:"rate" validate:"min=0"` // Requests per second
+	Burst int     `mapstructure:"burst" validate:"required_with=Rate,min=0"`
+}
+
+// ConcurrencyConfig caps the calls of heavy RPCs running at once
+type ConcurrencyConfig struct {
+	// MaxConcurrent is the number of calls of each RPC by name, e.g. CreateVM,
+	// that run at once
+	MaxConcurrent map[string]int `mapstructure:"max_concurrent" validate:"dive,min=0"`
+	// QueueSize calls of an RPC wait for a free slot, later ones are rejected
+	QueueSize int `mapstructure:"queue_size" validate:"min=0"`
+	// QueueTimeout bounds the wait, besides the caller's deadline
+	QueueTimeout time.Duration `mapstructure:"queue_timeout" validate:"min=0"`
+}
+
+// SocketMode returns the permissions of the unix socket
+func (c GRPCConfig) SocketMode() (os.FileMode, error) {
+	mode, err := strconv.ParseUint(c.UnixSocketMode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid grpc.unix_socket_mode %q", c.UnixSocketMode)
+	}
+	return os.FileMode(mode), nil
 }
 
 type LoggingConfig struct {
@@ -63,8 +203,133 @@ type MetricsConfig struct {
 }
 
 type HealthConfig struct {
-	ListenAddr string `mapstructure:"listen_addr"`
+	// ListenAddr may use "tailnet" as its host to follow the tailnet addresses
+	ListenAddr string `mapstructure:"listen_addr" validate:"required,hostname_port"`
 	Path       string `mapstructure:"path"`
+	// CheckInterval is how often the gRPC health service probes subsystems
+	CheckInterval time.Duration `mapstructure:"check_interval" validate:"required"`
+	// Gateway serves AgentService as JSON under /v1/ next to the health checks,
+	// over HTTPS with the gRPC TLS configuration unless they listen on the tailnet
+	Gateway bool `mapstructure:"gateway"`
+}
+
+type BackupConfig struct {
+	Target     string         `mapstructure:"target" validate:"required,oneof=local s3"`
+	LocalDir   string         `mapstructure:"local_dir" validate:"required_if=Target local"`
+	KeepDaily  int            `mapstructure:"keep_daily" validate:"min=0"`
+	KeepWeekly int            `mapstructure:"keep_weekly" validate:"min=0"`
+	Timeout    time.Duration  `mapstructure:"timeout" validate:"required"`
+	S3         BackupS3Config `mapstructure:"s3"`
+}
+
+type BackupS3Config struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	Prefix    string `mapstructure:"prefix"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	UseSSL    bool   `mapstructure:"use_ssl"`
+}
+
+type ConsoleConfig struct {
+	VNCEnabled bool          `mapstructure:"vnc_enabled"`
+	ListenAddr string        `mapstructure:"listen_addr" validate:"required_if=VNCEnabled true"`
+	PublicURL  string        `mapstructure:"public_url"` // Defaults to ws://<tailscale-ip>:<port>/vnc
+	TokenTTL   time.Duration `mapstructure:"token_ttl" validate:"min=0"`
+}
+
+type IPAMConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Subnet  string `mapstructure:"subnet" validate:"required_if=Enabled true,omitempty,cidrv4"`
+	// RangeStart and RangeEnd bound allocations, empty uses the whole subnet
+	RangeStart string `mapstructure:"range_start" validate:"omitempty,ipv4"`
+	RangeEnd   string `mapstructure:"range_end" validate:"omitempty,ipv4"`
+}
+
+// IPv6Config enables dual-stack networking
+type IPv6Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Prefix is a ULA or delegated prefix of /48 to /64 that networks get /64
+	// subnets from, empty derives a ULA /48 from the agent name
+	Prefix string `mapstructure:"prefix" validate:"omitempty,cidrv6"`
+}
+
+type PortForwardConfig struct {
+	// Backend is "nftables" (DNAT rules) or "proxy" (userspace relay)
+	Backend        string        `mapstructure:"backend" validate:"required,oneof=nftables proxy"`
+	ListenAddr     string        `mapstructure:"listen_addr" validate:"required,ip"` // Proxy backend only
+	PortRangeStart int           `mapstructure:"port_range_start" validate:"required,min=1,max=65535"`
+	PortRangeEnd   int           `mapstructure:"port_range_end" validate:"required,min=1,max=65535,gtefield=PortRangeStart"`
+	SyncInterval   time.Duration `mapstructure:"sync_interval" validate:"required"`
+}
+
+type FirewallConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// BlockedCIDRs are ranges guests may not reach unless a security group allows it
+	BlockedCIDRs []string `mapstructure:"blocked_cidrs" validate:"dive,cidr"`
+}
+
+// TailscaleConfig configures access to the local tailscaled
+type TailscaleConfig struct {
+	// Socket is the path of tailscaled's local API socket
+	Socket string `mapstructure:"socket" validate:"required"`
+	// StartupTimeout bounds how long startup waits for the tailnet to come up
+	StartupTimeout time.Duration `mapstructure:"startup_timeout" validate:"min=0"`
+	// PollInterval is how often the status is refreshed besides change notifications
+	PollInterval time.Duration `mapstructure:"poll_interval" validate:"required"`
+	// AdvertiseRoutes offers the VM subnets of the NAT network to the tailnet
+	AdvertiseRoutes bool `mapstructure:"advertise_routes"`
+}
+
+// AuthConfig controls who may call the gRPC API
+// Callers are identified by a token issued by Ghost Core, a client certificate
+// signed by grpc.tls_ca or the user of a unix socket peer
+type AuthConfig struct {
+	// Enabled rejects unauthenticated callers and checks roles; when disabled
+	// every caller has full access
+	Enabled bool `mapstructure:"enabled"`
+	// LocalRoles maps unix socket peers by user name to roles
+	LocalRoles map[string]string `mapstructure:"local_roles" validate:"dive,oneof=core operator read-only"`
+	// LocalDefaultRole is the role of other local users, empty denies them
+	LocalDefaultRole string `mapstructure:"local_default_role" validate:"omitempty,oneof=core operator read-only"`
+	// CertRoles maps client certificate common names to roles
+	CertRoles map[string]string `mapstructure:"cert_roles" validate:"dive,oneof=core operator read-only"`
+	JWT       JWTConfig         `mapstructure:"jwt"`
+}
+
+// JWTConfig configures the tokens Ghost Core issues to callers
+type JWTConfig struct {
+	// PublicKey is the PEM file of Core's signing key, empty rejects tokens
+	PublicKey string `mapstructure:"public_key"`
+	// Issuer is the expected iss claim, empty accepts any
+	Issuer string `mapstructure:"issuer"`
+	// Audience is the expected aud claim, defaults to agent.name
+	Audience string `mapstructure:"audience"`
+}
+
+// AuditConfig configures the audit log
+type AuditConfig struct {
+	Path string `mapstructure:"path" validate:"required"`
+	// MaxSizeMB rotates the file once it reaches this size
+	MaxSizeMB int `mapstructure:"max_size_mb" validate:"min=1"`
+	// MaxFiles is the number of rotated files kept
+	MaxFiles int `mapstructure:"max_files" validate:"min=1"`
+}
+
+// LimitsConfig holds the default limits of VMs that do not set their own
+// Zero values are unlimited
+type LimitsConfig struct {
+	Inbound         BandwidthConfig `mapstructure:"inbound"`
+	Outbound        BandwidthConfig `mapstructure:"outbound"`
+	DiskIOPS        int64           `mapstructure:"disk_iops" validate:"min=0"`
+	DiskBytesPerSec int64           `mapstructure:"disk_bytes_sec" validate:"min=0"`
+}
+
+type BandwidthConfig struct {
+	AverageKBps int `mapstructure:"average_kbytes_sec" validate:"min=0"`
+	PeakKBps    int `mapstructure:"peak_kbytes_sec" validate:"min=0"`
+	BurstKB     int `mapstructure:"burst_kbytes" validate:"min=0"`
 }
 
 // Load loads configuration from file and environment variables
@@ -76,6 +341,66 @@ func Load(configPath string) (*Config, error) {
 	viper.SetEnvPrefix("GHOST")
 	viper.AutomaticEnv()
 
+	// Defaults for sections added after the initial release
+	viper.SetDefault("libvirt.network_mode", "nat")
+	viper.SetDefault("resources.overcommit.cpu", 1.0)
+	viper.SetDefault("resources.overcommit.ram", 1.0)
+	viper.SetDefault("resources.overcommit.disk", 1.0)
+	viper.SetDefault("resources.thin_provisioning", false)
+	viper.SetDefault("expiry.default_action", "stop")
+	viper.SetDefault("expiry.max_ttl", "0s")
+	viper.SetDefault("expiry.warn_before", "15m")
+	viper.SetDefault("expiry.check_interval", "1m")
+	viper.SetDefault("restart.default_policy", "never")
+	viper.SetDefault("restart.backoff", "10s")
+	viper.SetDefault("restart.max_backoff", "5m")
+	viper.SetDefault("restart.check_interval", "30s")
+	viper.SetDefault("backup.target", "local")
+	viper.SetDefault("backup.local_dir", "/var/lib/ghost/backups")
+	viper.SetDefault("backup.keep_daily", 7)
+	viper.SetDefault("backup.keep_weekly", 4)
+	viper.SetDefault("backup.timeout", "2h")
+	viper.SetDefault("backup.s3.use_ssl", true)
+	viper.SetDefault("console.vnc_enabled", false)
+	viper.SetDefault("console.listen_addr", "0.0.0.0:9093")
+	viper.SetDefault("console.token_ttl", "60s")
+	viper.SetDefault("ipam.enabled", true)
+	viper.SetDefault("ipam.subnet", "192.168.122.0/24")
+	viper.SetDefault("ipam.range_start", "192.168.122.100")
+	viper.SetDefault("ipam.range_end", "192.168.122.254")
+	viper.SetDefault("ipv6.enabled", false)
+	viper.SetDefault("port_forward.backend", "proxy")
+	viper.SetDefault("port_forward.listen_addr", "0.0.0.0")
+	viper.SetDefault("port_forward.port_range_start", 20000)
+	viper.SetDefault("port_forward.port_range_end", 20999)
+	viper.SetDefault("port_forward.sync_interval", "30s")
+	viper.SetDefault("firewall.enabled", true)
+	viper.SetDefault("firewall.blocked_cidrs", []string{
+		"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "169.254.0.0/16", "100.64.0.0/10", "fc00::/7",
+	})
+	viper.SetDefault("grpc.unix_socket", "/run/ghost/agent.sock")
+	viper.SetDefault("grpc.unix_socket_mode", "0660")
+	viper.SetDefault("grpc.rate_limit.enabled", true)
+	viper.SetDefault("grpc.rate_limit.per_client.rate", 20)
+	viper.SetDefault("grpc.rate_limit.per_client.burst", 50)
+	viper.SetDefault("grpc.rate_limit.client_idle_timeout", "10m")
+	viper.SetDefault("grpc.concurrency.max_concurrent", map[string]int{"CreateVM": 2})
+	viper.SetDefault("grpc.concurrency.queue_size", 10)
+	viper.SetDefault("grpc.concurrency.queue_timeout", "5m")
+	viper.SetDefault("grpc.reflection", false)
+	viper.SetDefault("health.listen_addr", "0.0.0.0:9092")
+	viper.SetDefault("health.check_interval", "15s")
+	viper.SetDefault("health.gateway", false)
+	viper.SetDefault("tailscale.socket", "/var/run/tailscale/tailscaled.sock")
+	viper.SetDefault("tailscale.startup_timeout", "30s")
+	viper.SetDefault("tailscale.poll_interval", "30s")
+	viper.SetDefault("tailscale.advertise_routes", false)
+	viper.SetDefault("auth.enabled", true)
+	viper.SetDefault("auth.local_roles", map[string]string{"root": "operator"})
+	viper.SetDefault("audit.path", "/var/lib/ghost/audit/audit.log")
+	viper.SetDefault("audit.max_size_mb", 50)
+	viper.SetDefault("audit.max_files", 10)
+
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
@@ -90,6 +415,28 @@ func Load(configPath string) (*Config, error) {
 	if err := validate.Struct(&cfg); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
+	if cfg.Libvirt.NetworkMode == "bridge" && cfg.Libvirt.Bridge == "" {
+		return nil, fmt.Errorf("config validation failed: libvirt.bridge is required for the bridge network mode")
+	}
+	if _, err := cfg.GRPC.SocketMode(); cfg.GRPC.UnixSocket != "" && err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+	if cfg.Auth.JWT.Audience == "" {
+		cfg.Auth.JWT.Audience = cfg.Agent.Name
+	}
+	if cfg.Health.Gateway {
+		// The gateway takes bearer tokens, which must not cross the network in plaintext
+		if !cfg.Auth.Enabled {
+			return nil, fmt.Errorf("config validation failed: health.gateway requires auth.enabled")
+		}
+		host, _, _ := net.SplitHostPort(cfg.Health.ListenAddr) // Validated above
+		if host != "tailnet" && !cfg.GRPC.TLSEnabled {
+			return nil, fmt.Errorf("config validation failed: health.gateway requires grpc.tls_enabled unless health.listen_addr is on the tailnet")
+		}
+	}
+	if cfg.Backup.Target == "s3" && (cfg.Backup.S3.Endpoint == "" || cfg.Backup.S3.Bucket == "") {
+		return nil, fmt.Errorf("config validation failed: backup.s3.endpoint and backup.s3.bucket are required for the s3 target")
+	}
 
 	return &cfg, nil
 }
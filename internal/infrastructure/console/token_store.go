@@ -0,0 +1,74 @@
+package console
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// tokenBytes is the amount of randomness in a console token
+const tokenBytes = 32
+
+type consoleToken struct {
+	vmID      string
+	expiresAt time.Time
+}
+
+// TokenStore implements ConsoleTokenService with in-memory one-time tokens
+type TokenStore struct {
+	ttl    time.Duration
+	mu     sync.Mutex
+	tokens map[string]consoleToken
+}
+
+// NewTokenStore creates a token store whose tokens expire after ttl
+func NewTokenStore(ttl time.Duration) *TokenStore {
+	return &TokenStore{
+		ttl:    ttl,
+		tokens: make(map[string]consoleToken),
+	}
+}
+
+// Issue creates a token granting a single VNC connection to a VM
+func (s *TokenStore) Issue(vmID string) (string, time.Time, error) {
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	expiresAt := time.Now().Add(s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purgeExpired()
+	s.tokens[token] = consoleToken{vmID: vmID, expiresAt: expiresAt}
+
+	return token, expiresAt, nil
+}
+
+// Redeem consumes a token and returns the VM it grants access to
+func (s *TokenStore) Redeem(token string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[token]
+	delete(s.tokens, token)
+
+	if !ok || time.Now().After(t.expiresAt) {
+		return "", fmt.Errorf("invalid or expired console token")
+	}
+
+	return t.vmID, nil
+}
+
+func (s *TokenStore) purgeExpired() {
+	now := time.Now()
+	for token, t := range s.tokens {
+		if now.After(t.expiresAt) {
+			delete(s.tokens, token)
+		}
+	}
+}
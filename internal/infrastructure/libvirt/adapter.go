@@ -2,7 +2,12 @@ package libvirt
 
 import (
 	"context"
+	"encoding/xml"
+	stderrors "errors"
 	"fmt"
+	"net"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,16 +20,24 @@ import (
 	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
 )
 
+// primaryDiskTarget is the guest device name of a VM's root disk
+const primaryDiskTarget = "vda"
+
+// breakerTimeout is how long the circuit breaker stays open before letting
+// calls through again
+const breakerTimeout = 30 * time.Second
+
 // Adapter implements HypervisorService using Libvirt
 type Adapter struct {
 	conn           *libvirt.Connect
 	circuitBreaker *gobreaker.CircuitBreaker
+	networks       NetworkOptions
 	logger         *zap.Logger
 	mu             sync.RWMutex
 }
 
 // NewAdapter creates a new Libvirt adapter with circuit breaker
-func NewAdapter(uri string, logger *zap.Logger) (*Adapter, error) {
+func NewAdapter(uri string, networks NetworkOptions, logger *zap.Logger) (*Adapter, error) {
 	conn, err := libvirt.NewConnect(uri)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to libvirt: %w", err)
@@ -35,7 +48,7 @@ func NewAdapter(uri string, logger *zap.Logger) (*Adapter, error) {
 		Name:        "libvirt",
 		MaxRequests: 3,
 		Interval:    time.Minute,
-		Timeout:     30 * time.Second,
+		Timeout:     breakerTimeout,
 		ReadyToTrip: func(counts gobreaker.Counts) bool {
 			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
 			return counts.Requests >= 3 && failureRatio >= 0.6
@@ -52,12 +65,41 @@ func NewAdapter(uri string, logger *zap.Logger) (*Adapter, error) {
 	adapter := &Adapter{
 		conn:           conn,
 		circuitBreaker: cb,
+		networks:       networks,
 		logger:         logger,
 	}
 
 	return adapter, nil
 }
 
+// execute runs fn through the circuit breaker
+// Failures that say why libvirt refused are classified, so callers can tell
+// them from libvirt being down
+func (a *Adapter) execute(fn func() (interface{}, error)) (interface{}, error) {
+	result, err := a.circuitBreaker.Execute(fn)
+	if err == nil {
+		return result, nil
+	}
+
+	if stderrors.Is(err, gobreaker.ErrOpenState) || stderrors.Is(err, gobreaker.ErrTooManyRequests) {
+		return nil, errors.New(errors.ErrCodeUnavailable, "hypervisor is unavailable", err).
+			WithContext("retry_after", breakerTimeout)
+	}
+
+	var lvErr libvirt.Error
+	if stderrors.As(err, &lvErr) {
+		switch lvErr.Code {
+		case libvirt.ERR_NO_DOMAIN:
+			return nil, errors.New(errors.ErrCodeNotFound, "domain not found", err)
+		case libvirt.ERR_OPERATION_INVALID:
+			return nil, errors.New(errors.ErrCodeInvalidState, "operation is invalid in the domain's state", err)
+		case libvirt.ERR_OPERATION_TIMEOUT:
+			return nil, errors.New(errors.ErrCodeTimeout, "hypervisor operation timed out", err)
+		}
+	}
+	return nil, err
+}
+
 // CreateVM creates a new virtual machine
 func (a *Adapter) CreateVM(ctx context.Context, spec *service.VMSpec) (*entity.VM, error) {
 	a.logger.Info("Creating VM",
@@ -67,7 +109,7 @@ func (a *Adapter) CreateVM(ctx context.Context, spec *service.VMSpec) (*entity.V
 	)
 
 	// Execute with circuit breaker
-	result, err := a.circuitBreaker.Execute(func() (interface{}, error) {
+	result, err := a.execute(func() (interface{}, error) {
 		return a.createVMInternal(ctx, spec)
 	})
 
@@ -84,7 +126,10 @@ func (a *Adapter) createVMInternal(ctx context.Context, spec *service.VMSpec) (*
 	defer a.mu.Unlock()
 
 	// Generate VM XML
-	xml := a.generateVMXML(spec)
+	xml, err := a.generateVMXML(spec)
+	if err != nil {
+		return nil, err
+	}
 
 	// Define domain
 	domain, err := a.conn.DomainDefineXML(xml)
@@ -98,25 +143,30 @@ func (a *Adapter) createVMInternal(ctx context.Context, spec *service.VMSpec) (*
 		return nil, fmt.Errorf("failed to start domain: %w", err)
 	}
 
-	// Wait for IP address (with timeout)
-	ip, err := a.waitForIP(domain, 2*time.Minute)
-	if err != nil {
-		a.logger.Warn("Failed to get VM IP", zap.Error(err))
-		ip = "" // Continue without IP
+	// Wait for IP address (with timeout) unless it was reserved up front
+	ip := spec.IP
+	if ip == "" {
+		ip, err = a.waitForIP(domain, spec.Network, 2*time.Minute)
+		if err != nil {
+			a.logger.Warn("Failed to get VM IP", zap.Error(err))
+			ip = "" // Continue without IP
+		}
 	}
 
 	vm := &entity.VM{
-		ID:        spec.Name,
-		Name:      spec.Name,
-		VCPU:      spec.VCPU,
-		RAMGB:     spec.RAMGB,
-		DiskGB:    spec.DiskGB,
-		Status:    entity.VMStatusRunning,
-		IP:        ip,
-		Template:  spec.Template,
-		DiskPath:  spec.DiskPath,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:          spec.Name,
+		Name:        spec.Name,
+		VCPU:        spec.VCPU,
+		RAMGB:       spec.RAMGB,
+		DiskGB:      spec.DiskGB,
+		Status:      entity.VMStatusRunning,
+		IP:          ip,
+		NetworkMode: spec.Network,
+		Template:    spec.Template,
+		DiskPath:    spec.DiskPath,
+		CPUMode:     spec.CPUMode,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 
 	return vm, nil
@@ -126,7 +176,7 @@ func (a *Adapter) createVMInternal(ctx context.Context, spec *service.VMSpec) (*
 func (a *Adapter) DeleteVM(ctx context.Context, id string) error {
 	a.logger.Info("Deleting VM", zap.String("id", id))
 
-	_, err := a.circuitBreaker.Execute(func() (interface{}, error) {
+	_, err := a.execute(func() (interface{}, error) {
 		return nil, a.deleteVMInternal(ctx, id)
 	})
 
@@ -172,7 +222,7 @@ func (a *Adapter) deleteVMInternal(ctx context.Context, id string) error {
 func (a *Adapter) StartVM(ctx context.Context, id string) error {
 	a.logger.Info("Starting VM", zap.String("id", id))
 
-	_, err := a.circuitBreaker.Execute(func() (interface{}, error) {
+	_, err := a.execute(func() (interface{}, error) {
 		return nil, a.startVMInternal(ctx, id)
 	})
 
@@ -205,7 +255,7 @@ func (a *Adapter) startVMInternal(ctx context.Context, id string) error {
 func (a *Adapter) StopVM(ctx context.Context, id string, force bool) error {
 	a.logger.Info("Stopping VM", zap.String("id", id), zap.Bool("force", force))
 
-	_, err := a.circuitBreaker.Execute(func() (interface{}, error) {
+	_, err := a.execute(func() (interface{}, error) {
 		return nil, a.stopVMInternal(ctx, id, force)
 	})
 
@@ -252,21 +302,346 @@ func (a *Adapter) GetVMStatus(ctx context.Context, id string) (*service.VMStatus
 	}
 	defer domain.Free()
 
-	state, _, err := domain.GetState()
+	state, reason, err := domain.GetState()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get domain state: %w", err)
 	}
+	autostart, err := domain.GetAutostart()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain autostart: %w", err)
+	}
 
 	status := &service.VMStatusInfo{
 		Status:          a.mapLibvirtState(state),
 		UptimeSeconds:   0, // TODO: Calculate uptime
 		CPUUsagePercent: 0, // TODO: Get CPU usage
 		RAMUsagePercent: 0, // TODO: Get RAM usage
+		Crashed: state == libvirt.DOMAIN_CRASHED ||
+			(state == libvirt.DOMAIN_SHUTOFF && libvirt.DomainShutoffReason(reason) == libvirt.DOMAIN_SHUTOFF_CRASHED),
+		Autostart: autostart,
 	}
 
 	return status, nil
 }
 
+// SnapshotDisk redirects a running VM's writes to a temporary overlay
+func (a *Adapter) SnapshotDisk(ctx context.Context, id string, diskPath string) (func(context.Context) error, error) {
+	a.logger.Info("Creating backup snapshot", zap.String("id", id))
+
+	overlayPath := diskPath + ".backup-overlay"
+
+	// A commit that failed during an earlier backup left the VM writing to its overlay
+	_, err := a.execute(func() (interface{}, error) {
+		return nil, a.commitLeftoverOverlay(ctx, id, overlayPath)
+	})
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeHypervisor, "failed to commit leftover backup overlay", err).
+			WithContext("vm_id", id)
+	}
+
+	result, err := a.execute(func() (interface{}, error) {
+		return a.snapshotDiskInternal(ctx, id, overlayPath)
+	})
+
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeHypervisor, "failed to snapshot disk", err).
+			WithContext("vm_id", id)
+	}
+
+	return result.(func(context.Context) error), nil
+}
+
+func (a *Adapter) snapshotDiskInternal(ctx context.Context, id string, overlayPath string) (func(context.Context) error, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	domain, err := a.conn.LookupDomainByName(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup domain: %w", err)
+	}
+	defer domain.Free()
+
+	state, _, err := domain.GetState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain state: %w", err)
+	}
+
+	// A stopped VM's disk is already consistent
+	if state != libvirt.DOMAIN_RUNNING {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	xml := fmt.Sprintf(`
+<domainsnapshot>
+  <name>ghost-backup-%d</name>
+  <disks>
+    <disk name='%s' snapshot='external'>
+      <source file='%s'/>
+    </disk>
+  </disks>
+</domainsnapshot>
+`, time.Now().Unix(), primaryDiskTarget, overlayPath)
+
+	flags := libvirt.DOMAIN_SNAPSHOT_CREATE_DISK_ONLY |
+		libvirt.DOMAIN_SNAPSHOT_CREATE_ATOMIC |
+		libvirt.DOMAIN_SNAPSHOT_CREATE_NO_METADATA
+
+	// Quiescing needs the guest agent; fall back to a crash-consistent snapshot
+	snapshot, err := domain.CreateSnapshotXML(xml, flags|libvirt.DOMAIN_SNAPSHOT_CREATE_QUIESCE)
+	if err != nil {
+		a.logger.Debug("Quiesced snapshot unavailable, using crash-consistent snapshot",
+			zap.String("id", id),
+			zap.Error(err),
+		)
+		snapshot, err = domain.CreateSnapshotXML(xml, flags)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create external snapshot: %w", err)
+	}
+	snapshot.Free()
+
+	return func(ctx context.Context) error {
+		_, err := a.execute(func() (interface{}, error) {
+			return nil, a.commitOverlay(ctx, id, overlayPath)
+		})
+		return err
+	}, nil
+}
+
+// commitLeftoverOverlay merges an overlay that an earlier backup failed to
+// commit, so the next snapshot does not stack another one on top of it
+func (a *Adapter) commitLeftoverOverlay(ctx context.Context, id string, overlayPath string) error {
+	a.mu.RLock()
+	domain, err := a.conn.LookupDomainByName(id)
+	a.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to lookup domain: %w", err)
+	}
+	defer domain.Free()
+
+	source, err := diskSource(domain, primaryDiskTarget)
+	if err != nil {
+		return err
+	}
+	if source != overlayPath {
+		// The pivot went through but removing the overlay did not
+		if err := os.Remove(overlayPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale backup overlay: %w", err)
+		}
+		return nil
+	}
+
+	state, _, err := domain.GetState()
+	if err != nil {
+		return fmt.Errorf("failed to get domain state: %w", err)
+	}
+	// Only a running domain can commit its active layer
+	if state != libvirt.DOMAIN_RUNNING {
+		return errors.New(errors.ErrCodeInvalidState, "VM disk has an uncommitted backup overlay, start the VM to merge it", nil)
+	}
+
+	a.logger.Warn("Committing leftover backup overlay", zap.String("id", id))
+	return a.commitDomainOverlay(ctx, domain, overlayPath)
+}
+
+// commitOverlay merges a backup overlay into its base disk and pivots back to it
+func (a *Adapter) commitOverlay(ctx context.Context, id string, overlayPath string) error {
+	a.logger.Info("Committing backup overlay", zap.String("id", id))
+
+	a.mu.RLock()
+	domain, err := a.conn.LookupDomainByName(id)
+	a.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to lookup domain: %w", err)
+	}
+	defer domain.Free()
+
+	return a.commitDomainOverlay(ctx, domain, overlayPath)
+}
+
+// commitDomainOverlay runs the active block commit of domain's root disk
+// The adapter lock is held while the disk chain changes, not while the commit
+// copies, which can take a while for a busy VM
+func (a *Adapter) commitDomainOverlay(ctx context.Context, domain *libvirt.Domain, overlayPath string) (err error) {
+	flags := libvirt.DOMAIN_BLOCK_COMMIT_ACTIVE | libvirt.DOMAIN_BLOCK_COMMIT_SHALLOW
+	a.mu.Lock()
+	err = domain.BlockCommit(primaryDiskTarget, "", "", 0, flags)
+	a.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to start block commit: %w", err)
+	}
+
+	// Without the job the VM keeps writing to the overlay, which the next
+	// snapshot commits
+	defer func() {
+		if err == nil {
+			return
+		}
+		a.mu.Lock()
+		abortErr := domain.BlockJobAbort(primaryDiskTarget, 0)
+		a.mu.Unlock()
+		if abortErr != nil {
+			a.logger.Warn("Failed to abort block commit", zap.Error(abortErr))
+		}
+	}()
+
+	// An active commit becomes ready once the overlay is fully merged;
+	// an empty overlay is ready at 0/0
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		info, err := domain.GetBlockJobInfo(primaryDiskTarget, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get block job info: %w", err)
+		}
+		if info.Cur == info.End {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("block commit did not finish: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	a.mu.Lock()
+	err = domain.BlockJobAbort(primaryDiskTarget, libvirt.DOMAIN_BLOCK_JOB_ABORT_PIVOT)
+	a.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to pivot to base disk: %w", err)
+	}
+
+	if err := os.Remove(overlayPath); err != nil && !os.IsNotExist(err) {
+		a.logger.Warn("Failed to remove backup overlay",
+			zap.String("path", overlayPath),
+			zap.Error(err),
+		)
+	}
+
+	return nil
+}
+
+// diskSource returns the file a domain's disk currently reads and writes
+func diskSource(domain *libvirt.Domain, target string) (string, error) {
+	desc, err := domain.GetXMLDesc(0)
+	if err != nil {
+		return "", fmt.Errorf("failed to get domain XML: %w", err)
+	}
+
+	var def struct {
+		Disks []struct {
+			Source struct {
+				File string `xml:"file,attr"`
+			} `xml:"source"`
+			Target struct {
+				Dev string `xml:"dev,attr"`
+			} `xml:"target"`
+		} `xml:"devices>disk"`
+	}
+	if err := xml.Unmarshal([]byte(desc), &def); err != nil {
+		return "", fmt.Errorf("failed to parse domain XML: %w", err)
+	}
+
+	for _, disk := range def.Disks {
+		if disk.Target.Dev == target {
+			return disk.Source.File, nil
+		}
+	}
+	return "", fmt.Errorf("domain has no disk %s", target)
+}
+
+// MigrateVM live-migrates a running VM and its disk to another host
+func (a *Adapter) MigrateVM(ctx context.Context, id string, spec *service.MigrationSpec, progress func(*service.MigrationProgress)) error {
+	a.logger.Info("Migrating VM",
+		zap.String("id", id),
+		zap.String("dest_uri", spec.DestURI),
+	)
+
+	_, err := a.execute(func() (interface{}, error) {
+		return nil, a.migrateVMInternal(ctx, id, spec, progress)
+	})
+
+	if err != nil {
+		return errors.New(errors.ErrCodeHypervisor, "failed to migrate VM", err).
+			WithContext("vm_id", id).
+			WithContext("dest_uri", spec.DestURI)
+	}
+
+	return nil
+}
+
+func (a *Adapter) migrateVMInternal(ctx context.Context, id string, spec *service.MigrationSpec, progress func(*service.MigrationProgress)) error {
+	// The adapter lock is not held for the whole transfer, which can take hours
+	a.mu.RLock()
+	domain, err := a.conn.LookupDomainByName(id)
+	a.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to lookup domain: %w", err)
+	}
+	defer domain.Free()
+
+	xml, err := domain.GetXMLDesc(libvirt.DOMAIN_XML_MIGRATABLE)
+	if err != nil {
+		return fmt.Errorf("failed to get domain XML: %w", err)
+	}
+
+	// Point the disk at the path the target agent prepared
+	xml = strings.NewReplacer(
+		"'"+spec.SourceDiskPath+"'", "'"+spec.DestDiskPath+"'",
+		`"`+spec.SourceDiskPath+`"`, `"`+spec.DestDiskPath+`"`,
+	).Replace(xml)
+
+	params := &libvirt.DomainMigrateParameters{
+		DestXMLSet:      true,
+		DestXML:         xml,
+		MigrateDisksSet: true,
+		MigrateDisks:    []string{primaryDiskTarget},
+	}
+
+	flags := libvirt.MIGRATE_LIVE |
+		libvirt.MIGRATE_PEER2PEER |
+		libvirt.MIGRATE_NON_SHARED_DISK |
+		libvirt.MIGRATE_PERSIST_DEST |
+		libvirt.MIGRATE_UNDEFINE_SOURCE |
+		libvirt.MIGRATE_AUTO_CONVERGE
+
+	done := make(chan error, 1)
+	go func() {
+		done <- domain.MigrateToURI3(spec.DestURI, params, flags)
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("migration failed: %w", err)
+			}
+			return nil
+		case <-ctx.Done():
+			if err := domain.AbortJob(); err != nil {
+				a.logger.Warn("Failed to abort migration", zap.String("id", id), zap.Error(err))
+			}
+			<-done
+			return fmt.Errorf("migration aborted: %w", ctx.Err())
+		case <-ticker.C:
+			info, err := domain.GetJobInfo()
+			if err != nil || info.Type == libvirt.DOMAIN_JOB_NONE {
+				continue
+			}
+			progress(&service.MigrationProgress{
+				DataTotal:     info.DataTotal,
+				DataProcessed: info.DataProcessed,
+				DataRemaining: info.DataRemaining,
+			})
+		}
+	}
+}
+
 // ListVMs lists all VMs
 func (a *Adapter) ListVMs(ctx context.Context) ([]*entity.VM, error) {
 	a.mu.RLock()
@@ -334,15 +709,27 @@ func (a *Adapter) mapLibvirtState(state libvirt.DomainState) entity.VMStatus {
 	}
 }
 
-func (a *Adapter) waitForIP(domain *libvirt.Domain, timeout time.Duration) (string, error) {
+func (a *Adapter) waitForIP(domain *libvirt.Domain, mode entity.NetworkMode, timeout time.Duration) (string, error) {
 	deadline := time.Now().Add(timeout)
 	
+	// Additional NICs on private networks get leases too; only the first NIC counts
+	mac, err := interfaceMAC(domain)
+	if err != nil {
+		return "", err
+	}
+	
 	for time.Now().Before(deadline) {
-		ifaces, err := domain.ListAllInterfaceAddresses(libvirt.DOMAIN_INTERFACE_ADDRESSES_SRC_LEASE)
-		if err == nil && len(ifaces) > 0 {
+		for _, source := range addressSources(mode) {
+			ifaces, err := domain.ListAllInterfaceAddresses(source)
+			if err != nil {
+				continue
+			}
 			for _, iface := range ifaces {
-				if len(iface.Addrs) > 0 {
-					return iface.Addrs[0].Addr, nil
+				if !strings.EqualFold(iface.Hwaddr, mac) {
+					continue
+				}
+				if ip := primaryAddress(iface.Addrs); ip != "" {
+					return ip, nil
 				}
 			}
 		}
@@ -352,7 +739,35 @@ func (a *Adapter) waitForIP(domain *libvirt.Domain, timeout time.Duration) (stri
 	return "", fmt.Errorf("timeout waiting for IP address")
 }
 
-func (a *Adapter) generateVMXML(spec *service.VMSpec) string {
+// primaryAddress returns the first IPv4 address, or the first global IPv6
+// address when there is none; link-local addresses are not reachable
+func primaryAddress(addrs []libvirt.DomainIPAddress) string {
+	var v6 string
+	for _, addr := range addrs {
+		if addr.Type == libvirt.IP_ADDR_TYPE_IPV4 {
+			return addr.Addr
+		}
+		if ip := net.ParseIP(addr.Addr); v6 == "" && ip != nil && !ip.IsLinkLocalUnicast() {
+			v6 = addr.Addr
+		}
+	}
+	return v6
+}
+
+// cpuXML returns the cpu element of a domain, empty for the hypervisor default
+func cpuXML(mode entity.CPUMode) string {
+	if mode == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n  <cpu mode='%s'/>", mode)
+}
+
+func (a *Adapter) generateVMXML(spec *service.VMSpec) (string, error) {
+	iface, err := a.networks.interfaceXML(spec.Network, spec.MAC, spec.Filter, spec.Limits)
+	if err != nil {
+		return "", err
+	}
+
 	// Simplified XML generation - in production, use proper XML templating
 	return fmt.Sprintf(`
 <domain type='kvm'>
@@ -362,19 +777,20 @@ func (a *Adapter) generateVMXML(spec *service.VMSpec) string {
   <os>
     <type arch='x86_64'>hvm</type>
     <boot dev='hd'/>
-  </os>
+  </os>%s
   <devices>
     <disk type='file' device='disk'>
       <driver name='qemu' type='qcow2'/>
       <source file='%s'/>
-      <target dev='vda' bus='virtio'/>
+      <target dev='%s' bus='virtio'/>%s
     </disk>
-    <interface type='network'>
-      <source network='default'/>
-      <model type='virtio'/>
-    </interface>
+    %s
     <console type='pty'/>
+    <channel type='unix'>
+      <target type='virtio' name='%s'/>
+    </channel>
+    <graphics type='vnc' autoport='yes' listen='127.0.0.1'/>
   </devices>
 </domain>
-`, spec.Name, spec.RAMGB, spec.VCPU, spec.DiskPath)
+`, spec.Name, spec.RAMGB, spec.VCPU, cpuXML(spec.CPUMode), spec.DiskPath, primaryDiskTarget, iotuneXML(spec.Limits), iface+a.networks.privateInterfacesXML(spec.Networks, spec.Filter), guestAgentChannel), nil
 }
@@ -0,0 +1,43 @@
+package libvirt
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// SetAutostart sets whether libvirt starts the VM when the host boots
+func (a *Adapter) SetAutostart(ctx context.Context, id string, autostart bool) error {
+	a.logger.Info("Setting VM autostart", zap.String("id", id), zap.Bool("autostart", autostart))
+
+	_, err := a.execute(func() (interface{}, error) {
+		return nil, a.setAutostartInternal(id, autostart)
+	})
+
+	if err != nil {
+		return errors.New(errors.ErrCodeHypervisor, "failed to set VM autostart", err).
+			WithContext("vm_id", id)
+	}
+
+	return nil
+}
+
+func (a *Adapter) setAutostartInternal(id string, autostart bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	domain, err := a.conn.LookupDomainByName(id)
+	if err != nil {
+		return fmt.Errorf("failed to lookup domain: %w", err)
+	}
+	defer domain.Free()
+
+	if err := domain.SetAutostart(autostart); err != nil {
+		return fmt.Errorf("failed to set autostart: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package libvirt
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"go.uber.org/zap"
+	"libvirt.org/go/libvirt"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// OpenConsole connects to a running VM's serial console
+func (a *Adapter) OpenConsole(ctx context.Context, id string) (io.ReadWriteCloser, error) {
+	a.logger.Info("Opening console", zap.String("id", id))
+
+	result, err := a.execute(func() (interface{}, error) {
+		return a.openConsoleInternal(id)
+	})
+
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeHypervisor, "failed to open console", err).
+			WithContext("vm_id", id)
+	}
+
+	return result.(io.ReadWriteCloser), nil
+}
+
+func (a *Adapter) openConsoleInternal(id string) (io.ReadWriteCloser, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	domain, err := a.conn.LookupDomainByName(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup domain: %w", err)
+	}
+	defer domain.Free()
+
+	stream, err := a.conn.NewStream(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream: %w", err)
+	}
+
+	// FORCE takes the console over from a previous, possibly stale, session
+	if err := domain.OpenConsole("", stream, libvirt.DOMAIN_CONSOLE_FORCE); err != nil {
+		stream.Free()
+		return nil, fmt.Errorf("failed to open console: %w", err)
+	}
+
+	return &consoleStream{stream: stream}, nil
+}
+
+// GetVNCAddress returns the host address of a running VM's VNC server
+func (a *Adapter) GetVNCAddress(ctx context.Context, id string) (string, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	domain, err := a.conn.LookupDomainByName(id)
+	if err != nil {
+		return "", errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", id)
+	}
+	defer domain.Free()
+
+	desc, err := domain.GetXMLDesc(0)
+	if err != nil {
+		return "", errors.New(errors.ErrCodeHypervisor, "failed to get domain XML", err).
+			WithContext("vm_id", id)
+	}
+
+	var def struct {
+		Graphics []struct {
+			Type   string `xml:"type,attr"`
+			Port   int    `xml:"port,attr"`
+			Listen string `xml:"listen,attr"`
+		} `xml:"devices>graphics"`
+	}
+	if err := xml.Unmarshal([]byte(desc), &def); err != nil {
+		return "", errors.New(errors.ErrCodeHypervisor, "failed to parse domain XML", err).
+			WithContext("vm_id", id)
+	}
+
+	for _, g := range def.Graphics {
+		// Autoport is only resolved while the domain runs
+		if g.Type != "vnc" || g.Port <= 0 {
+			continue
+		}
+		listen := g.Listen
+		if listen == "" || listen == "0.0.0.0" {
+			listen = "127.0.0.1"
+		}
+		return net.JoinHostPort(listen, fmt.Sprint(g.Port)), nil
+	}
+
+	return "", errors.New(errors.ErrCodeInvalidState, "VM has no active VNC display", nil).
+		WithContext("vm_id", id)
+}
+
+// consoleStream adapts a libvirt console stream to io.ReadWriteCloser
+type consoleStream struct {
+	stream    *libvirt.Stream
+	closeOnce sync.Once
+	mu        sync.Mutex
+	closed    bool
+}
+
+func (c *consoleStream) Read(p []byte) (int, error) {
+	n, err := c.stream.Recv(p)
+	if err != nil {
+		if c.isClosed() {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func (c *consoleStream) Write(p []byte) (int, error) {
+	if c.isClosed() {
+		return 0, io.ErrClosedPipe
+	}
+	return c.stream.Send(p)
+}
+
+// Close aborts the stream, which also unblocks a pending Read
+func (c *consoleStream) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+
+		err = c.stream.Abort()
+		c.stream.Free()
+	})
+	return err
+}
+
+func (c *consoleStream) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
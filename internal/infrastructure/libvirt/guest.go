@@ -0,0 +1,184 @@
+package libvirt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"libvirt.org/go/libvirt"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// guestAgentChannel is the virtio channel name qemu-guest-agent listens on
+const guestAgentChannel = "org.qemu.guest_agent.0"
+
+// guestExecPollInterval is how often a running guest command is polled
+const guestExecPollInterval = 200 * time.Millisecond
+
+// GuestExec runs a command inside a running VM through the QEMU guest agent
+// Guest agent failures say nothing about libvirt health, so the circuit breaker is bypassed
+func (a *Adapter) GuestExec(ctx context.Context, id string, spec *service.GuestExecSpec) (*service.GuestExecResult, error) {
+	a.logger.Info("Executing guest command",
+		zap.String("id", id),
+		zap.String("command", spec.Command),
+	)
+
+	domain, err := a.lookupDomain(id)
+	if err != nil {
+		return nil, err
+	}
+	defer domain.Free()
+
+	args := map[string]interface{}{
+		"path":           spec.Command,
+		"arg":            spec.Args,
+		"env":            spec.Env,
+		"capture-output": true,
+	}
+	if len(spec.Stdin) > 0 {
+		args["input-data"] = base64.StdEncoding.EncodeToString(spec.Stdin)
+	}
+
+	var started struct {
+		Return struct {
+			PID int `json:"pid"`
+		} `json:"return"`
+	}
+	if err := agentCommand(domain, "guest-exec", args, &started); err != nil {
+		return nil, errors.New(errors.ErrCodeHypervisor, "failed to start guest command", err).
+			WithContext("vm_id", id)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, spec.Timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(guestExecPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var status struct {
+			Return struct {
+				Exited       bool   `json:"exited"`
+				ExitCode     int    `json:"exitcode"`
+				OutData      string `json:"out-data"`
+				ErrData      string `json:"err-data"`
+				OutTruncated bool   `json:"out-truncated"`
+				ErrTruncated bool   `json:"err-truncated"`
+			} `json:"return"`
+		}
+		if err := agentCommand(domain, "guest-exec-status", map[string]interface{}{"pid": started.Return.PID}, &status); err != nil {
+			return nil, errors.New(errors.ErrCodeHypervisor, "failed to get guest command status", err).
+				WithContext("vm_id", id)
+		}
+
+		if status.Return.Exited {
+			stdout, _ := base64.StdEncoding.DecodeString(status.Return.OutData)
+			stderr, _ := base64.StdEncoding.DecodeString(status.Return.ErrData)
+			return &service.GuestExecResult{
+				ExitCode:  status.Return.ExitCode,
+				Stdout:    stdout,
+				Stderr:    stderr,
+				Truncated: status.Return.OutTruncated || status.Return.ErrTruncated,
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.New(errors.ErrCodeTimeout, "guest command timed out", ctx.Err()).
+				WithContext("vm_id", id).
+				WithContext("pid", started.Return.PID)
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetGuestInfo queries OS, hostname, filesystem and interface details from the guest agent
+func (a *Adapter) GetGuestInfo(ctx context.Context, id string) (*service.GuestInfo, error) {
+	domain, err := a.lookupDomain(id)
+	if err != nil {
+		return nil, err
+	}
+	defer domain.Free()
+
+	types := libvirt.DOMAIN_GUEST_INFO_OS |
+		libvirt.DOMAIN_GUEST_INFO_HOSTNAME |
+		libvirt.DOMAIN_GUEST_INFO_FILESYSTEM
+	raw, err := domain.GetGuestInfo(types, 0)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeHypervisor, "guest agent unavailable", err).
+			WithContext("vm_id", id)
+	}
+
+	info := &service.GuestInfo{
+		Hostname: raw.Hostname,
+	}
+	if raw.OS != nil {
+		info.OSName = raw.OS.PrettyName
+		if info.OSName == "" {
+			info.OSName = raw.OS.Name
+		}
+		info.OSVersion = raw.OS.Version
+		info.KernelRelease = raw.OS.KernelRelease
+	}
+	for _, fs := range raw.FileSystems {
+		info.FileSystems = append(info.FileSystems, service.GuestFileSystem{
+			MountPoint: fs.MountPoint,
+			FSType:     fs.FSType,
+			TotalBytes: fs.TotalBytes,
+			UsedBytes:  fs.UsedBytes,
+		})
+	}
+
+	ifaces, err := domain.ListAllInterfaceAddresses(libvirt.DOMAIN_INTERFACE_ADDRESSES_SRC_AGENT)
+	if err != nil {
+		a.logger.Debug("Failed to get guest interfaces", zap.String("id", id), zap.Error(err))
+	}
+	for _, iface := range ifaces {
+		gi := service.GuestInterface{
+			Name:         iface.Name,
+			HardwareAddr: iface.Hwaddr,
+		}
+		for _, addr := range iface.Addrs {
+			gi.Addresses = append(gi.Addresses, fmt.Sprintf("%s/%d", addr.Addr, addr.Prefix))
+		}
+		info.Interfaces = append(info.Interfaces, gi)
+	}
+
+	return info, nil
+}
+
+// lookupDomain finds a domain by name; the caller must free it
+func (a *Adapter) lookupDomain(id string) (*libvirt.Domain, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	domain, err := a.conn.LookupDomainByName(id)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", id)
+	}
+	return domain, nil
+}
+
+// agentCommand sends a QMP-style command to the guest agent and decodes the reply into out
+func agentCommand(domain *libvirt.Domain, command string, args interface{}, out interface{}) error {
+	req, err := json.Marshal(map[string]interface{}{
+		"execute":   command,
+		"arguments": args,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := domain.QemuAgentCommand(string(req), libvirt.DOMAIN_QEMU_AGENT_COMMAND_DEFAULT, 0)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(resp), out)
+}
@@ -0,0 +1,154 @@
+package libvirt
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+
+	"go.uber.org/zap"
+	"libvirt.org/go/libvirt"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// SetVMLimits changes the network and disk limits of a VM
+// Running VMs are updated live, and the limits persist across restarts
+func (a *Adapter) SetVMLimits(ctx context.Context, id string, limits entity.VMLimits) error {
+	a.logger.Info("Setting VM limits", zap.String("id", id))
+
+	_, err := a.execute(func() (interface{}, error) {
+		return nil, a.setVMLimitsInternal(id, limits)
+	})
+
+	if err != nil {
+		return errors.New(errors.ErrCodeHypervisor, "failed to set VM limits", err).
+			WithContext("vm_id", id)
+	}
+
+	return nil
+}
+
+func (a *Adapter) setVMLimitsInternal(id string, limits entity.VMLimits) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	domain, err := a.conn.LookupDomainByName(id)
+	if err != nil {
+		return fmt.Errorf("failed to lookup domain: %w", err)
+	}
+	defer domain.Free()
+
+	active, err := domain.IsActive()
+	if err != nil {
+		return fmt.Errorf("failed to get domain state: %w", err)
+	}
+	flags := libvirt.DOMAIN_AFFECT_CONFIG
+	if active {
+		flags |= libvirt.DOMAIN_AFFECT_LIVE
+	}
+
+	mac, err := interfaceMAC(domain)
+	if err != nil {
+		return err
+	}
+
+	// An average of zero removes the limit of that direction
+	bandwidth := &libvirt.DomainInterfaceParameters{
+		BandwidthInAverageSet:  true,
+		BandwidthInAverage:     uint(limits.Inbound.Average),
+		BandwidthInPeakSet:     true,
+		BandwidthInPeak:        uint(limits.Inbound.Peak),
+		BandwidthInBurstSet:    true,
+		BandwidthInBurst:       uint(limits.Inbound.Burst),
+		BandwidthOutAverageSet: true,
+		BandwidthOutAverage:    uint(limits.Outbound.Average),
+		BandwidthOutPeakSet:    true,
+		BandwidthOutPeak:       uint(limits.Outbound.Peak),
+		BandwidthOutBurstSet:   true,
+		BandwidthOutBurst:      uint(limits.Outbound.Burst),
+	}
+	if err := domain.SetInterfaceParameters(mac, bandwidth, flags); err != nil {
+		return fmt.Errorf("failed to set interface bandwidth: %w", err)
+	}
+
+	iotune := &libvirt.DomainBlockIoTuneParameters{
+		TotalIopsSecSet:  true,
+		TotalIopsSec:     uint64(limits.DiskIOPS),
+		TotalBytesSecSet: true,
+		TotalBytesSec:    uint64(limits.DiskBytesPerSec),
+	}
+	if err := domain.SetBlockIoTune(primaryDiskTarget, iotune, flags); err != nil {
+		return fmt.Errorf("failed to set disk I/O limits: %w", err)
+	}
+
+	return nil
+}
+
+// interfaceMAC returns the MAC address of a domain's first interface,
+// which identifies the interface in both the live and the persistent config
+func interfaceMAC(domain *libvirt.Domain) (string, error) {
+	desc, err := domain.GetXMLDesc(0)
+	if err != nil {
+		return "", fmt.Errorf("failed to get domain XML: %w", err)
+	}
+
+	var def struct {
+		Interfaces []struct {
+			MAC struct {
+				Address string `xml:"address,attr"`
+			} `xml:"mac"`
+		} `xml:"devices>interface"`
+	}
+	if err := xml.Unmarshal([]byte(desc), &def); err != nil {
+		return "", fmt.Errorf("failed to parse domain XML: %w", err)
+	}
+
+	if len(def.Interfaces) == 0 || def.Interfaces[0].MAC.Address == "" {
+		return "", fmt.Errorf("domain has no network interface")
+	}
+	return def.Interfaces[0].MAC.Address, nil
+}
+
+// bandwidthXML returns the bandwidth element of a domain interface, empty when unlimited
+func bandwidthXML(limits entity.VMLimits) string {
+	if !limits.Inbound.IsSet() && !limits.Outbound.IsSet() {
+		return ""
+	}
+
+	out := "\n      <bandwidth>"
+	if limits.Inbound.IsSet() {
+		out += "\n        <inbound" + rateAttrs(limits.Inbound) + "/>"
+	}
+	if limits.Outbound.IsSet() {
+		out += "\n        <outbound" + rateAttrs(limits.Outbound) + "/>"
+	}
+	return out + "\n      </bandwidth>"
+}
+
+func rateAttrs(b entity.BandwidthLimit) string {
+	attrs := fmt.Sprintf(" average='%d'", b.Average)
+	if b.Peak > 0 {
+		attrs += fmt.Sprintf(" peak='%d'", b.Peak)
+	}
+	if b.Burst > 0 {
+		attrs += fmt.Sprintf(" burst='%d'", b.Burst)
+	}
+	return attrs
+}
+
+// iotuneXML returns the iotune element of a domain disk, empty when unlimited
+func iotuneXML(limits entity.VMLimits) string {
+	if limits.DiskIOPS == 0 && limits.DiskBytesPerSec == 0 {
+		return ""
+	}
+
+	out := "\n      <iotune>"
+	if limits.DiskIOPS > 0 {
+		out += fmt.Sprintf("\n        <total_iops_sec>%d</total_iops_sec>", limits.DiskIOPS)
+	}
+	if limits.DiskBytesPerSec > 0 {
+		out += fmt.Sprintf("\n        <total_bytes_sec>%d</total_bytes_sec>", limits.DiskBytesPerSec)
+	}
+	return out + "\n      </iotune>"
+}
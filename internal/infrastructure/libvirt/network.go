@@ -0,0 +1,81 @@
+package libvirt
+
+import (
+	"fmt"
+
+	"libvirt.org/go/libvirt"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// NetworkOptions names the host networks VM interfaces attach to
+type NetworkOptions struct {
+	Network string // libvirt network used in NAT mode
+	Bridge  string // host bridge used in bridge mode, empty when bridging is disabled
+}
+
+// interfaceXML returns the domain interface element for a network mode
+// mac is optional; libvirt generates one when it is empty
+// filter names the network filter the interface references, none when empty
+func (o NetworkOptions) interfaceXML(mode entity.NetworkMode, mac string, filter string, limits entity.VMLimits) (string, error) {
+	extraXML := ""
+	if mac != "" {
+		extraXML += fmt.Sprintf("\n      <mac address='%s'/>", mac)
+	}
+	if filter != "" {
+		extraXML += fmt.Sprintf("\n      <filterref filter='%s'/>", filter)
+	}
+	extraXML += bandwidthXML(limits)
+
+	switch mode {
+	case entity.NetworkModeNAT, "":
+		return fmt.Sprintf(`<interface type='network'>
+      <source network='%s'/>%s
+      <model type='virtio'/>
+    </interface>`, o.Network, extraXML), nil
+	case entity.NetworkModeBridge:
+		if o.Bridge == "" {
+			return "", fmt.Errorf("bridge networking is not configured on this agent")
+		}
+		return fmt.Sprintf(`<interface type='bridge'>
+      <source bridge='%s'/>%s
+      <model type='virtio'/>
+    </interface>`, o.Bridge, extraXML), nil
+	default:
+		return "", fmt.Errorf("unknown network mode %q", mode)
+	}
+}
+
+// privateInterfacesXML returns the interface elements of a VM's additional NICs,
+// one per libvirt network; they reference the same filter as the primary NIC,
+// so guests cannot get around it, but carry no limits
+func (o NetworkOptions) privateInterfacesXML(networks []string, filter string) string {
+	filterXML := ""
+	if filter != "" {
+		filterXML = fmt.Sprintf("\n      <filterref filter='%s'/>", filter)
+	}
+
+	out := ""
+	for _, name := range networks {
+		out += fmt.Sprintf(`
+    <interface type='network'>
+      <source network='%s'/>%s
+      <model type='virtio'/>
+    </interface>`, name, filterXML)
+	}
+	return out
+}
+
+// addressSources returns where to look up a VM's IP, in order of preference
+// Bridged VMs get their address from the LAN, so libvirt has no lease for them
+func addressSources(mode entity.NetworkMode) []libvirt.DomainInterfaceAddressesSource {
+	if mode == entity.NetworkModeBridge {
+		return []libvirt.DomainInterfaceAddressesSource{
+			libvirt.DOMAIN_INTERFACE_ADDRESSES_SRC_AGENT,
+			libvirt.DOMAIN_INTERFACE_ADDRESSES_SRC_ARP,
+		}
+	}
+	return []libvirt.DomainInterfaceAddressesSource{
+		libvirt.DOMAIN_INTERFACE_ADDRESSES_SRC_LEASE,
+	}
+}
@@ -0,0 +1,172 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// TailnetHost is the listen address host that stands for the tailnet addresses
+const TailnetHost = "tailnet"
+
+// How often binding is retried while a new tailnet address is not usable yet
+const bindRetryInterval = 5 * time.Second
+
+// Tailnet accepts TCP connections on the host's tailnet addresses only
+// It binds once the tailnet is up and moves to the new addresses when they
+// change; connections accepted before keep working
+type Tailnet struct {
+	port      string
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+	cancel    context.CancelFunc
+	logger    *zap.Logger
+
+	listeners map[string]net.Listener // By tailnet address, owned by follow
+}
+
+// NewTailnet creates a listener on port of the tailnet addresses
+func NewTailnet(tailnet service.TailnetService, port string, logger *zap.Logger) *Tailnet {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Tailnet{
+		port:      port,
+		conns:     make(chan net.Conn),
+		done:      make(chan struct{}),
+		cancel:    cancel,
+		logger:    logger,
+		listeners: make(map[string]net.Listener),
+	}
+	go l.follow(ctx, tailnet.Watch(ctx))
+	return l
+}
+
+// Accept waits for the next connection on any tailnet address
+func (l *Tailnet) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops listening on all addresses
+func (l *Tailnet) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+		l.cancel()
+	})
+	return nil
+}
+
+// Addr returns tailnet:<port>, since the addresses change
+func (l *Tailnet) Addr() net.Addr {
+	return tailnetAddr(net.JoinHostPort(TailnetHost, l.port))
+}
+
+// follow binds to the tailnet addresses as they change until ctx is done
+// While the tailnet is down, the last addresses stay bound
+func (l *Tailnet) follow(ctx context.Context, changes <-chan *entity.TailnetStatus) {
+	retry := time.NewTicker(bindRetryInterval)
+	defer retry.Stop()
+
+	var want, bound []string
+	for {
+		select {
+		case <-ctx.Done():
+			for _, lis := range l.listeners {
+				lis.Close()
+			}
+			return
+		case status := <-changes:
+			if status.Up() {
+				want = status.IPs
+			}
+		case <-retry.C:
+		}
+
+		if slices.Equal(want, bound) {
+			continue
+		}
+		if err := l.bind(want); err != nil {
+			l.logger.Warn("Failed to listen on tailnet addresses, will retry",
+				zap.Strings("ips", want),
+				zap.Error(err),
+			)
+			continue
+		}
+		bound = want
+
+		l.logger.Info("Listening on tailnet addresses",
+			zap.Strings("ips", bound),
+			zap.String("port", l.port),
+		)
+	}
+}
+
+// bind listens on ips, keeping listeners of addresses that did not change
+func (l *Tailnet) bind(ips []string) error {
+	next := make(map[string]net.Listener, len(ips))
+	for _, ip := range ips {
+		if lis, ok := l.listeners[ip]; ok {
+			next[ip] = lis
+			continue
+		}
+
+		lis, err := net.Listen("tcp", net.JoinHostPort(ip, l.port))
+		if err != nil {
+			for ip, lis := range next {
+				if _, ok := l.listeners[ip]; !ok {
+					lis.Close()
+				}
+			}
+			return err
+		}
+		next[ip] = lis
+		go l.accept(lis)
+	}
+
+	for ip, lis := range l.listeners {
+		if _, ok := next[ip]; !ok {
+			lis.Close()
+		}
+	}
+	l.listeners = next
+	return nil
+}
+
+// accept hands connections of one address to Accept until it is closed
+func (l *Tailnet) accept(lis net.Listener) {
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			l.logger.Warn("Failed to accept connection", zap.String("addr", lis.Addr().String()), zap.Error(err))
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		select {
+		case l.conns <- conn:
+		case <-l.done:
+			conn.Close()
+			return
+		}
+	}
+}
+
+type tailnetAddr string
+
+func (a tailnetAddr) Network() string { return "tcp" }
+func (a tailnetAddr) String() string  { return string(a) }
@@ -0,0 +1,66 @@
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Unix listens on a unix socket at path for local clients such as ghostctl
+// Access is controlled by the socket's mode and group; a stale socket left by
+// an earlier run is replaced, one still in use is not
+func Unix(path string, mode os.FileMode, group string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setOwnership(path, mode, group); err != nil {
+		lis.Close()
+		return nil, err
+	}
+	return lis, nil
+}
+
+// setOwnership applies the socket's mode and group
+func setOwnership(path string, mode os.FileMode, group string) error {
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			return fmt.Errorf("unknown socket group %q: %w", group, err)
+		}
+		gid, err := strconv.Atoi(g.Gid)
+		if err != nil {
+			return fmt.Errorf("invalid gid of group %q: %w", group, err)
+		}
+		if err := os.Chown(path, -1, gid); err != nil {
+			return fmt.Errorf("failed to set socket group: %w", err)
+		}
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		return fmt.Errorf("failed to set socket mode: %w", err)
+	}
+	return nil
+}
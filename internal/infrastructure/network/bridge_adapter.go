@@ -0,0 +1,93 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"libvirt.org/go/libvirt"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// BridgeAdapter implements NetworkService for VMs attached to a host bridge
+// Addresses come from the LAN's DHCP server, so they are discovered through
+// the guest agent or the host ARP table instead of libvirt leases
+type BridgeAdapter struct {
+	conn   *libvirt.Connect
+	bridge string
+	logger *zap.Logger
+}
+
+// NewBridgeAdapter creates a new bridged network adapter
+func NewBridgeAdapter(conn *libvirt.Connect, bridge string, logger *zap.Logger) *BridgeAdapter {
+	return &BridgeAdapter{
+		conn:   conn,
+		bridge: bridge,
+		logger: logger,
+	}
+}
+
+// AssignIP waits for a bridged VM to obtain an address from the LAN
+func (b *BridgeAdapter) AssignIP(ctx context.Context, vmID string) (string, error) {
+	b.logger.Info("Waiting for VM address on bridge",
+		zap.String("vm_id", vmID),
+		zap.String("bridge", b.bridge),
+	)
+
+	deadline := time.Now().Add(2 * time.Minute)
+	for time.Now().Before(deadline) {
+		ifaces, err := b.GetVMIP(ctx, vmID)
+		if ip := entity.PrimaryIP(ifaces); err == nil && ip != "" {
+			return ip, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", errors.New(errors.ErrCodeNetwork, "failed to get VM address", ctx.Err()).
+				WithContext("vm_id", vmID)
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	return "", errors.New(errors.ErrCodeNetwork, "failed to get VM address",
+		fmt.Errorf("timeout waiting for address on bridge %s", b.bridge)).
+		WithContext("vm_id", vmID)
+}
+
+// ReleaseIP releases an IP address from a VM
+// The LAN's DHCP server owns bridged leases, so there is nothing to release
+func (b *BridgeAdapter) ReleaseIP(ctx context.Context, vmID string) error {
+	return nil
+}
+
+// GetVMIP retrieves the current addresses of a bridged VM from the guest agent, then the ARP table
+// The ARP table only holds IPv4 addresses; IPv6 addresses need the guest agent
+func (b *BridgeAdapter) GetVMIP(ctx context.Context, vmID string) ([]entity.VMInterface, error) {
+	domain, err := b.conn.LookupDomainByName(vmID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", vmID)
+	}
+	defer domain.Free()
+
+	mac := primaryMAC(domain)
+	for _, source := range []libvirt.DomainInterfaceAddressesSource{
+		libvirt.DOMAIN_INTERFACE_ADDRESSES_SRC_AGENT,
+		libvirt.DOMAIN_INTERFACE_ADDRESSES_SRC_ARP,
+	} {
+		ifaces, err := domain.ListAllInterfaceAddresses(source)
+		if err != nil {
+			continue
+		}
+		if found := guestInterfaces(ifaces, mac); entity.PrimaryIP(found) != "" {
+			return found, nil
+		}
+	}
+
+	return nil, errors.New(errors.ErrCodeNetwork, "no IP address found", nil).
+		WithContext("vm_id", vmID).
+		WithContext("bridge", b.bridge)
+}
@@ -0,0 +1,472 @@
+package network
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+
+	"go.uber.org/zap"
+	"libvirt.org/go/libvirt"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// Filter names and rule priorities; libvirt orders the rules of a VM's
+// filter and everything it references by priority, lowest first
+const (
+	baseFilterName     = "ghost-base"
+	networksFilterName = "ghost-networks"
+
+	priorityBaseAllow    = 100 // DHCP, DNS and IPv6 neighbor discovery
+	priorityGroupEgress  = 200 // Lets security groups open blocked ranges
+	priorityNetworkHost  = 240 // The host's addresses on the VM networks
+	priorityNetworks     = 250 // Lets guests reach each other on the VM networks
+	priorityBlocked      = 300
+	priorityGroupIngress = 500
+	priorityForwardAllow = 500
+	priorityEgressAllow  = 900
+	priorityIngressDrop  = 1000
+)
+
+// NWFilterFirewall implements FirewallService with libvirt network filters
+// Each security group is a filter of allow rules; each VM filter references
+// the base filter with the default policy, a filter admitting the VM's port
+// forwards and the VM's security groups. The base filter references the
+// filter opening the VM networks, whose subnets usually fall in blocked ranges
+type NWFilterFirewall struct {
+	conn   *libvirt.Connect
+	nat    []netip.Prefix // The host's addresses on the NAT network, in their subnets
+	logger *zap.Logger
+}
+
+type filterXML struct {
+	XMLName xml.Name       `xml:"filter"`
+	Name    string         `xml:"name,attr"`
+	Chain   string         `xml:"chain,attr"`
+	UUID    string         `xml:"uuid,omitempty"`
+	Refs    []filterRefXML `xml:"filterref"`
+	Rules   []ruleXML      `xml:"rule"`
+}
+
+type filterRefXML struct {
+	Filter string `xml:"filter,attr"`
+}
+
+type ruleXML struct {
+	Action    string   `xml:"action,attr"`
+	Direction string   `xml:"direction,attr"`
+	Priority  int      `xml:"priority,attr"`
+	Match     matchXML `xml:",any"`
+}
+
+// matchXML is a protocol element such as <tcp/> or <all/>
+type matchXML struct {
+	XMLName      xml.Name
+	SrcIPAddr    string `xml:"srcipaddr,attr,omitempty"`
+	SrcIPMask    string `xml:"srcipmask,attr,omitempty"`
+	DstIPAddr    string `xml:"dstipaddr,attr,omitempty"`
+	DstIPMask    string `xml:"dstipmask,attr,omitempty"`
+	SrcPortStart int    `xml:"srcportstart,attr,omitempty"`
+	DstPortStart int    `xml:"dstportstart,attr,omitempty"`
+	DstPortEnd   int    `xml:"dstportend,attr,omitempty"`
+	Type         int    `xml:"type,attr,omitempty"` // ICMP type
+}
+
+// NewNWFilterFirewall creates a new firewall and defines the base filter
+// Guests may not reach blockedCIDRs unless a security group allows it, except
+// for the other guests on natNetwork, the libvirt network of NAT mode
+func NewNWFilterFirewall(conn *libvirt.Connect, blockedCIDRs []string, natNetwork string, logger *zap.Logger) (*NWFilterFirewall, error) {
+	nat, err := networkAddresses(conn, natNetwork)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &NWFilterFirewall{
+		conn:   conn,
+		nat:    nat,
+		logger: logger,
+	}
+
+	// The base filter references the networks filter, so it must exist first
+	if err := f.defineNetworks(nil); err != nil {
+		return nil, fmt.Errorf("failed to define networks filter: %w", err)
+	}
+
+	base := &filterXML{
+		Name:  baseFilterName,
+		Chain: "root",
+		Refs:  []filterRefXML{{Filter: networksFilterName}},
+		Rules: []ruleXML{
+			// DHCP and DNS keep working towards blocked ranges such as the gateway
+			acceptRule("out", priorityBaseAllow, matchXML{XMLName: xml.Name{Local: "udp"}, DstPortStart: 67, DstPortEnd: 68}),
+			acceptRule("in", priorityBaseAllow, matchXML{XMLName: xml.Name{Local: "udp"}, SrcPortStart: 67, DstPortStart: 68}),
+			acceptRule("out", priorityBaseAllow, matchXML{XMLName: xml.Name{Local: "udp"}, DstPortStart: 53}),
+			acceptRule("out", priorityBaseAllow, matchXML{XMLName: xml.Name{Local: "tcp"}, DstPortStart: 53}),
+			acceptRule("out", priorityBaseAllow, matchXML{XMLName: xml.Name{Local: "udp-ipv6"}, DstPortStart: 547}),
+			acceptRule("in", priorityBaseAllow, matchXML{XMLName: xml.Name{Local: "udp-ipv6"}, SrcPortStart: 547, DstPortStart: 546}),
+			acceptRule("out", priorityBaseAllow, matchXML{XMLName: xml.Name{Local: "udp-ipv6"}, DstPortStart: 53}),
+			acceptRule("out", priorityBaseAllow, matchXML{XMLName: xml.Name{Local: "tcp-ipv6"}, DstPortStart: 53}),
+		},
+	}
+	// Router and neighbor discovery are unsolicited, so ingress must admit them for SLAAC
+	for _, icmpType := range []int{133, 134, 135, 136, 137} {
+		base.Rules = append(base.Rules,
+			acceptRule("out", priorityBaseAllow, matchXML{XMLName: xml.Name{Local: "icmpv6"}, Type: icmpType}),
+			acceptRule("in", priorityBaseAllow, matchXML{XMLName: xml.Name{Local: "icmpv6"}, Type: icmpType}),
+		)
+	}
+	for _, cidr := range blockedCIDRs {
+		addr, mask, v6, err := splitCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		base.Rules = append(base.Rules, ruleXML{
+			Action:    "drop",
+			Direction: "out",
+			Priority:  priorityBlocked,
+			Match:     matchXML{XMLName: xml.Name{Local: protocolElement("all", v6)}, DstIPAddr: addr, DstIPMask: mask},
+		})
+	}
+	base.Rules = append(base.Rules,
+		acceptRule("out", priorityEgressAllow, matchXML{XMLName: xml.Name{Local: "all"}}),
+		acceptRule("out", priorityEgressAllow, matchXML{XMLName: xml.Name{Local: "all-ipv6"}}),
+		ruleXML{Action: "drop", Direction: "in", Priority: priorityIngressDrop, Match: matchXML{XMLName: xml.Name{Local: "all"}}},
+		ruleXML{Action: "drop", Direction: "in", Priority: priorityIngressDrop, Match: matchXML{XMLName: xml.Name{Local: "all-ipv6"}}},
+	)
+
+	if err := f.define(base); err != nil {
+		return nil, fmt.Errorf("failed to define base filter: %w", err)
+	}
+
+	logger.Info("Firewall base filter defined",
+		zap.String("filter", baseFilterName),
+		zap.Strings("blocked_cidrs", blockedCIDRs),
+	)
+
+	return f, nil
+}
+
+// DefineGroup creates or updates the rules of a security group
+func (f *NWFilterFirewall) DefineGroup(ctx context.Context, sg *entity.SecurityGroup) error {
+	def := &filterXML{
+		Name:  groupFilterName(sg.ID),
+		Chain: "root",
+	}
+
+	for _, rule := range sg.Rules {
+		addr, mask, v6, err := splitCIDR(rule.CIDR)
+		if err != nil {
+			return errors.New(errors.ErrCodeValidation, "invalid rule CIDR", err).
+				WithContext("security_group_id", sg.ID).
+				WithContext("rule_id", rule.ID)
+		}
+
+		// A rule without CIDR matches both address families
+		families := []bool{v6}
+		if rule.CIDR == "" {
+			families = []bool{false, true}
+		}
+
+		for _, v6 := range families {
+			match := matchXML{XMLName: xml.Name{Local: protocolElement(string(rule.Protocol), v6)}}
+			if rule.Protocol == entity.RuleProtocolTCP || rule.Protocol == entity.RuleProtocolUDP {
+				match.DstPortStart = rule.PortMin
+				if rule.PortMax > rule.PortMin {
+					match.DstPortEnd = rule.PortMax
+				}
+			}
+
+			if rule.Direction == entity.RuleDirectionIngress {
+				match.SrcIPAddr, match.SrcIPMask = addr, mask
+				def.Rules = append(def.Rules, acceptRule("in", priorityGroupIngress, match))
+			} else {
+				match.DstIPAddr, match.DstIPMask = addr, mask
+				def.Rules = append(def.Rules, acceptRule("out", priorityGroupEgress, match))
+			}
+		}
+	}
+
+	if err := f.define(def); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to define security group filter", err).
+			WithContext("security_group_id", sg.ID)
+	}
+
+	f.logger.Debug("Security group filter defined",
+		zap.String("security_group_id", sg.ID),
+		zap.Int("rules", len(sg.Rules)),
+	)
+
+	return nil
+}
+
+// UndefineGroup removes a security group no VM references anymore
+func (f *NWFilterFirewall) UndefineGroup(ctx context.Context, groupID string) error {
+	if err := f.undefine(groupFilterName(groupID)); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to remove security group filter", err).
+			WithContext("security_group_id", groupID)
+	}
+	return nil
+}
+
+// ApplyVM sets the security groups of a VM, creating its filter if needed
+func (f *NWFilterFirewall) ApplyVM(ctx context.Context, vmID string, groupIDs []string) error {
+	def := &filterXML{
+		Name:  f.FilterName(vmID),
+		Chain: "root",
+		Refs:  []filterRefXML{{Filter: baseFilterName}, {Filter: forwardFilterName(vmID)}},
+	}
+	for _, id := range groupIDs {
+		def.Refs = append(def.Refs, filterRefXML{Filter: groupFilterName(id)})
+	}
+
+	// The forward filter must exist before it is referenced; its rules are kept
+	if existing, err := f.conn.LookupNWFilterByName(forwardFilterName(vmID)); err == nil {
+		existing.Free()
+	} else if err := f.define(&filterXML{Name: forwardFilterName(vmID), Chain: "root"}); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to define VM forward filter", err).
+			WithContext("vm_id", vmID)
+	}
+
+	if err := f.define(def); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to define VM filter", err).
+			WithContext("vm_id", vmID)
+	}
+
+	f.logger.Debug("VM filter defined",
+		zap.String("vm_id", vmID),
+		zap.Strings("security_groups", groupIDs),
+	)
+
+	return nil
+}
+
+// AllowForwards admits the inbound traffic of a VM's port forwards
+// Forwarded connections keep the client's address, so any source is admitted
+func (f *NWFilterFirewall) AllowForwards(ctx context.Context, vmID string, forwards []*entity.PortForward) error {
+	def := &filterXML{
+		Name:  forwardFilterName(vmID),
+		Chain: "root",
+	}
+	for _, fwd := range forwards {
+		families := []bool{false}
+		if fwd.GuestIPv6 != "" {
+			families = append(families, true)
+		}
+		for _, v6 := range families {
+			match := matchXML{XMLName: xml.Name{Local: protocolElement(string(fwd.Protocol), v6)}, DstPortStart: fwd.GuestPort}
+			def.Rules = append(def.Rules, acceptRule("in", priorityForwardAllow, match))
+		}
+	}
+
+	if err := f.define(def); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to define VM forward filter", err).
+			WithContext("vm_id", vmID)
+	}
+
+	f.logger.Debug("VM forward filter defined",
+		zap.String("vm_id", vmID),
+		zap.Int("forwards", len(forwards)),
+	)
+
+	return nil
+}
+
+// SetNetworks lets guests reach the NAT network and networks although they
+// fall in blocked ranges; the host's addresses on them stay blocked apart
+// from DHCP and DNS
+func (f *NWFilterFirewall) SetNetworks(ctx context.Context, networks []*entity.Network) error {
+	if err := f.defineNetworks(networks); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to define networks filter", err)
+	}
+
+	f.logger.Debug("Networks filter defined", zap.Int("networks", len(networks)))
+
+	return nil
+}
+
+// defineNetworks defines the networks filter for the NAT network and networks
+func (f *NWFilterFirewall) defineNetworks(networks []*entity.Network) error {
+	hosts := append([]netip.Prefix(nil), f.nat...)
+	for _, n := range networks {
+		hosts = append(hosts, gatewayAddresses(n)...)
+	}
+
+	def := &filterXML{
+		Name:  networksFilterName,
+		Chain: "root",
+	}
+	for _, host := range hosts {
+		all := xml.Name{Local: protocolElement("all", host.Addr().Is6())}
+		subnet := host.Masked()
+		def.Rules = append(def.Rules,
+			ruleXML{
+				Action:    "drop",
+				Direction: "out",
+				Priority:  priorityNetworkHost,
+				Match:     matchXML{XMLName: all, DstIPAddr: host.Addr().String(), DstIPMask: strconv.Itoa(host.Addr().BitLen())},
+			},
+			acceptRule("out", priorityNetworks, matchXML{XMLName: all, DstIPAddr: subnet.Addr().String(), DstIPMask: strconv.Itoa(subnet.Bits())}),
+		)
+	}
+
+	return f.define(def)
+}
+
+// RemoveVM removes a VM's filter once its domain is gone
+func (f *NWFilterFirewall) RemoveVM(ctx context.Context, vmID string) error {
+	if err := f.undefine(f.FilterName(vmID)); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to remove VM filter", err).
+			WithContext("vm_id", vmID)
+	}
+	if err := f.undefine(forwardFilterName(vmID)); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to remove VM forward filter", err).
+			WithContext("vm_id", vmID)
+	}
+	return nil
+}
+
+// FilterName returns the name of the filter a VM's interface references
+func (f *NWFilterFirewall) FilterName(vmID string) string {
+	return "ghost-vm-" + vmID
+}
+
+// define creates or replaces a filter, keeping the UUID of an existing one
+// since libvirt refuses to redefine a filter under a new UUID
+func (f *NWFilterFirewall) define(def *filterXML) error {
+	if existing, err := f.conn.LookupNWFilterByName(def.Name); err == nil {
+		uuid, err := existing.GetUUIDString()
+		existing.Free()
+		if err != nil {
+			return err
+		}
+		def.UUID = uuid
+	}
+
+	data, err := xml.Marshal(def)
+	if err != nil {
+		return err
+	}
+
+	filter, err := f.conn.NWFilterDefineXML(string(data))
+	if err != nil {
+		return err
+	}
+	return filter.Free()
+}
+
+// undefine removes a filter, ignoring filters that do not exist
+func (f *NWFilterFirewall) undefine(name string) error {
+	filter, err := f.conn.LookupNWFilterByName(name)
+	if err != nil {
+		if lerr, ok := err.(libvirt.Error); ok && lerr.Code == libvirt.ERR_NO_NWFILTER {
+			return nil
+		}
+		return err
+	}
+	defer filter.Free()
+
+	return filter.Undefine()
+}
+
+// networkAddresses returns the host's addresses on a libvirt network, each
+// with the length of its subnet
+func networkAddresses(conn *libvirt.Connect, name string) ([]netip.Prefix, error) {
+	lvNet, err := conn.LookupNetworkByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up network %s: %w", name, err)
+	}
+	defer lvNet.Free()
+
+	desc, err := lvNet.GetXMLDesc(libvirt.NETWORK_XML_INACTIVE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network XML: %w", err)
+	}
+	var def networkXML
+	if err := xml.Unmarshal([]byte(desc), &def); err != nil {
+		return nil, fmt.Errorf("failed to parse network XML: %w", err)
+	}
+
+	var hosts []netip.Prefix
+	for _, ip := range def.IPs {
+		addr, err := netip.ParseAddr(ip.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q in network XML", ip.Address)
+		}
+		bits := ip.Prefix
+		if ip.Netmask != "" {
+			bits, _ = net.IPMask(net.ParseIP(ip.Netmask).To4()).Size()
+		}
+		hosts = append(hosts, netip.PrefixFrom(addr, bits))
+	}
+	return hosts, nil
+}
+
+// gatewayAddresses returns the host's addresses on a private network, each
+// with the length of its subnet; the network was validated when it was created
+func gatewayAddresses(n *entity.Network) []netip.Prefix {
+	var hosts []netip.Prefix
+	for _, ip := range [][2]string{{n.Gateway, n.Subnet}, {n.GatewayV6, n.SubnetV6}} {
+		gateway, err := netip.ParseAddr(ip[0])
+		if err != nil {
+			continue
+		}
+		subnet, err := netip.ParsePrefix(ip[1])
+		if err != nil {
+			continue
+		}
+		hosts = append(hosts, netip.PrefixFrom(gateway, subnet.Bits()))
+	}
+	return hosts
+}
+
+func groupFilterName(groupID string) string {
+	return "ghost-sg-" + groupID
+}
+
+func forwardFilterName(vmID string) string {
+	return "ghost-fwd-" + vmID
+}
+
+func acceptRule(direction string, priority int, match matchXML) ruleXML {
+	return ruleXML{
+		Action:    "accept",
+		Direction: direction,
+		Priority:  priority,
+		Match:     match,
+	}
+}
+
+// splitCIDR returns the address and prefix length of a CIDR, and whether it is IPv6
+// An empty or catch-all CIDR yields empty strings, matching any address
+func splitCIDR(cidr string) (string, string, bool, error) {
+	if cidr == "" {
+		return "", "", false, nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", "", false, fmt.Errorf("invalid CIDR %q", cidr)
+	}
+
+	v6 := ipNet.IP.To4() == nil
+	ones, _ := ipNet.Mask.Size()
+	if ones == 0 {
+		return "", "", v6, nil
+	}
+	return ipNet.IP.String(), strconv.Itoa(ones), v6, nil
+}
+
+// protocolElement returns the nwfilter element matching protocol in an address family
+func protocolElement(protocol string, v6 bool) string {
+	if !v6 {
+		return protocol
+	}
+	if protocol == string(entity.RuleProtocolICMP) {
+		return "icmpv6"
+	}
+	return protocol + "-ipv6"
+}
@@ -0,0 +1,418 @@
+package network
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"libvirt.org/go/libvirt"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+)
+
+// IPAM implements IPAMService for a libvirt NAT network
+// IPv4 addresses are pinned with DHCP host reservations written through NetworkUpdate;
+// on dual-stack networks guests configure IPv6 by SLAAC, so their IPv6 address follows from the MAC
+type IPAM struct {
+	conn     *libvirt.Connect
+	network  string
+	subnet   *net.IPNet
+	start    uint32
+	end      uint32
+	subnetV6 netip.Prefix // Invalid when the network has no IPv6 /64
+	repo     repository.IPAllocationRepository
+	mu       sync.Mutex
+	logger   *zap.Logger
+}
+
+// dhcpHost is a DHCP host reservation in a libvirt network definition
+type dhcpHost struct {
+	MAC  string `xml:"mac,attr"`
+	Name string `xml:"name,attr"`
+	IP   string `xml:"ip,attr"`
+}
+
+// NewIPAM creates a new IPAM for the named libvirt network
+// rangeStart and rangeEnd bound allocations; empty values use the whole subnet
+// minus the network, gateway and broadcast addresses
+// subnetV6 is the network's IPv6 /64, or the zero prefix when it has none
+func NewIPAM(
+	conn *libvirt.Connect,
+	network string,
+	subnet string,
+	rangeStart string,
+	rangeEnd string,
+	subnetV6 netip.Prefix,
+	repo repository.IPAllocationRepository,
+	logger *zap.Logger,
+) (*IPAM, error) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil || ipNet.IP.To4() == nil {
+		return nil, fmt.Errorf("invalid IPv4 subnet %q", subnet)
+	}
+
+	first := ipToUint32(ipNet.IP)
+	ones, bits := ipNet.Mask.Size()
+	last := first | (1<<uint(bits-ones) - 1)
+
+	start, end := first+2, last-1 // skip network, gateway and broadcast
+	if rangeStart != "" {
+		if start, err = parseRangeIP(ipNet, rangeStart); err != nil {
+			return nil, err
+		}
+	}
+	if rangeEnd != "" {
+		if end, err = parseRangeIP(ipNet, rangeEnd); err != nil {
+			return nil, err
+		}
+	}
+	if start > end {
+		return nil, fmt.Errorf("IPAM range start %s is after end %s", uint32ToIP(start), uint32ToIP(end))
+	}
+	if subnetV6.IsValid() && subnetV6.Bits() != 64 {
+		return nil, fmt.Errorf("IPv6 subnet %s of network %s is not a /64, which SLAAC requires", subnetV6, network)
+	}
+
+	return &IPAM{
+		conn:     conn,
+		network:  network,
+		subnet:   ipNet,
+		start:    start,
+		end:      end,
+		subnetV6: subnetV6,
+		repo:     repo,
+		logger:   logger,
+	}, nil
+}
+
+// Allocate reserves an address and MAC for a VM on the NAT network
+func (m *IPAM) Allocate(ctx context.Context, vmID string) (*entity.IPAllocation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, err := m.repo.FindByVM(ctx, vmID); err == nil {
+		return existing, nil
+	}
+
+	network, err := m.lookupNetwork()
+	if err != nil {
+		return nil, err
+	}
+	defer network.Free()
+
+	used, err := m.usedAddresses(ctx, network)
+	if err != nil {
+		return nil, err
+	}
+
+	ip := ""
+	for candidate := m.start; candidate <= m.end; candidate++ {
+		if addr := uint32ToIP(candidate).String(); !used[addr] {
+			ip = addr
+			break
+		}
+	}
+	if ip == "" {
+		return nil, errors.New(errors.ErrCodeResourceLimit, "no free IP addresses", nil).
+			WithContext("network", m.network).
+			WithContext("range", fmt.Sprintf("%s-%s", uint32ToIP(m.start), uint32ToIP(m.end)))
+	}
+
+	mac, err := randomMAC()
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to generate MAC address", err)
+	}
+
+	alloc := &entity.IPAllocation{
+		VMID:      vmID,
+		Network:   m.network,
+		IP:        ip,
+		MAC:       mac,
+		CreatedAt: time.Now(),
+	}
+	m.assignIPv6(alloc)
+
+	if err := m.updateHost(network, libvirt.NETWORK_UPDATE_COMMAND_ADD_LAST, alloc); err != nil {
+		return nil, errors.New(errors.ErrCodeNetwork, "failed to add DHCP reservation", err).
+			WithContext("vm_id", vmID).
+			WithContext("ip", ip)
+	}
+
+	if err := m.repo.Save(ctx, alloc); err != nil {
+		_ = m.updateHost(network, libvirt.NETWORK_UPDATE_COMMAND_DELETE, alloc)
+		return nil, errors.New(errors.ErrCodeInternal, "failed to save IP allocation", err).
+			WithContext("vm_id", vmID)
+	}
+
+	m.logger.Info("IP address allocated",
+		zap.String("vm_id", vmID),
+		zap.String("ip", ip),
+		zap.String("ipv6", alloc.IPv6),
+		zap.String("mac", mac),
+	)
+
+	return alloc, nil
+}
+
+// Release frees a VM's address and removes its DHCP reservation
+func (m *IPAM) Release(ctx context.Context, vmID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alloc, err := m.repo.FindByVM(ctx, vmID)
+	if err != nil {
+		return nil // Nothing allocated
+	}
+
+	network, err := m.lookupNetwork()
+	if err != nil {
+		return err
+	}
+	defer network.Free()
+
+	if err := m.updateHost(network, libvirt.NETWORK_UPDATE_COMMAND_DELETE, alloc); err != nil {
+		m.logger.Warn("Failed to remove DHCP reservation",
+			zap.String("vm_id", vmID),
+			zap.String("ip", alloc.IP),
+			zap.Error(err),
+		)
+	}
+
+	if err := m.repo.Delete(ctx, vmID); err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to delete IP allocation", err).
+			WithContext("vm_id", vmID)
+	}
+
+	m.logger.Info("IP address released", zap.String("vm_id", vmID), zap.String("ip", alloc.IP))
+	return nil
+}
+
+// Get returns a VM's allocation
+func (m *IPAM) Get(ctx context.Context, vmID string) (*entity.IPAllocation, error) {
+	alloc, err := m.repo.FindByVM(ctx, vmID)
+	if err != nil {
+		return nil, err
+	}
+	m.assignIPv6(alloc)
+	return alloc, nil
+}
+
+// Reconcile re-applies persisted reservations missing from the network and
+// reports addresses claimed by another MAC or allocated twice
+func (m *IPAM) Reconcile(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	allocations, err := m.repo.FindAll(ctx)
+	if err != nil {
+		return errors.New(errors.ErrCodeInternal, "failed to load IP allocations", err)
+	}
+
+	network, err := m.lookupNetwork()
+	if err != nil {
+		return err
+	}
+	defer network.Free()
+
+	hosts, err := m.dhcpHosts(network)
+	if err != nil {
+		return err
+	}
+	leases, err := network.GetDHCPLeases()
+	if err != nil {
+		m.logger.Debug("Failed to get DHCP leases", zap.Error(err))
+	}
+
+	var conflicts []string
+	owners := make(map[string]string)
+	for _, alloc := range allocations {
+		// Allocations made before the network had IPv6, or had another subnet, get their SLAAC address
+		if m.assignIPv6(alloc) {
+			if err := m.repo.Save(ctx, alloc); err != nil {
+				m.logger.Warn("Failed to save IPv6 address", zap.String("vm_id", alloc.VMID), zap.Error(err))
+			}
+		}
+
+		if owner, ok := owners[alloc.IP]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s allocated to both %s and %s", alloc.IP, owner, alloc.VMID))
+			continue
+		}
+		owners[alloc.IP] = alloc.VMID
+
+		if ip := net.ParseIP(alloc.IP); ip == nil || !m.subnet.Contains(ip) {
+			conflicts = append(conflicts, fmt.Sprintf("%s of %s is outside subnet %s", alloc.IP, alloc.VMID, m.subnet))
+		}
+
+		reserved, conflicting := false, false
+		for _, host := range hosts {
+			if host.IP != alloc.IP {
+				continue
+			}
+			if strings.EqualFold(host.MAC, alloc.MAC) {
+				reserved = true
+			} else {
+				conflicting = true
+				conflicts = append(conflicts, fmt.Sprintf("%s of %s is reserved for MAC %s", alloc.IP, alloc.VMID, host.MAC))
+			}
+		}
+		for _, lease := range leases {
+			if lease.IPaddr == alloc.IP && !strings.EqualFold(lease.Mac, alloc.MAC) {
+				conflicts = append(conflicts, fmt.Sprintf("%s of %s is leased to MAC %s", alloc.IP, alloc.VMID, lease.Mac))
+			}
+		}
+
+		if !reserved && !conflicting {
+			if err := m.updateHost(network, libvirt.NETWORK_UPDATE_COMMAND_ADD_LAST, alloc); err != nil {
+				m.logger.Error("Failed to restore DHCP reservation",
+					zap.String("vm_id", alloc.VMID),
+					zap.String("ip", alloc.IP),
+					zap.Error(err),
+				)
+				continue
+			}
+			m.logger.Info("DHCP reservation restored",
+				zap.String("vm_id", alloc.VMID),
+				zap.String("ip", alloc.IP),
+			)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return errors.New(errors.ErrCodeConflict, "IP allocation conflicts detected", nil).
+			WithContext("network", m.network).
+			WithContext("conflicts", conflicts)
+	}
+
+	return nil
+}
+
+// assignIPv6 sets the SLAAC address of an allocation on dual-stack networks
+// It reports whether the allocation changed
+func (m *IPAM) assignIPv6(alloc *entity.IPAllocation) bool {
+	if !m.subnetV6.IsValid() {
+		return false
+	}
+	ipv6, err := slaacAddress(m.subnetV6, alloc.MAC)
+	if err != nil || ipv6 == alloc.IPv6 {
+		return false
+	}
+	alloc.IPv6 = ipv6
+	return true
+}
+
+// usedAddresses collects addresses that must not be handed out: allocations,
+// existing reservations, active leases and the gateway
+func (m *IPAM) usedAddresses(ctx context.Context, network *libvirt.Network) (map[string]bool, error) {
+	used := map[string]bool{
+		uint32ToIP(ipToUint32(m.subnet.IP) + 1).String(): true,
+	}
+
+	allocations, err := m.repo.FindAll(ctx)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeInternal, "failed to load IP allocations", err)
+	}
+	for _, alloc := range allocations {
+		used[alloc.IP] = true
+	}
+
+	hosts, err := m.dhcpHosts(network)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		used[host.IP] = true
+	}
+
+	leases, err := network.GetDHCPLeases()
+	if err != nil {
+		m.logger.Debug("Failed to get DHCP leases", zap.Error(err))
+	}
+	for _, lease := range leases {
+		used[lease.IPaddr] = true
+	}
+
+	return used, nil
+}
+
+// dhcpHosts returns the DHCP host reservations in the network definition
+func (m *IPAM) dhcpHosts(network *libvirt.Network) ([]dhcpHost, error) {
+	desc, err := network.GetXMLDesc(0)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNetwork, "failed to get network XML", err).
+			WithContext("network", m.network)
+	}
+
+	var def struct {
+		IPs []struct {
+			Hosts []dhcpHost `xml:"dhcp>host"`
+		} `xml:"ip"`
+	}
+	if err := xml.Unmarshal([]byte(desc), &def); err != nil {
+		return nil, errors.New(errors.ErrCodeNetwork, "failed to parse network XML", err).
+			WithContext("network", m.network)
+	}
+
+	var hosts []dhcpHost
+	for _, ip := range def.IPs {
+		hosts = append(hosts, ip.Hosts...)
+	}
+	return hosts, nil
+}
+
+// updateHost adds or deletes a DHCP host reservation in the live and persistent config
+func (m *IPAM) updateHost(network *libvirt.Network, cmd libvirt.NetworkUpdateCommand, alloc *entity.IPAllocation) error {
+	flags := libvirt.NETWORK_UPDATE_AFFECT_CONFIG
+	if active, err := network.IsActive(); err == nil && active {
+		flags |= libvirt.NETWORK_UPDATE_AFFECT_LIVE
+	}
+
+	host := fmt.Sprintf("<host mac='%s' name='%s' ip='%s'/>", alloc.MAC, alloc.VMID, alloc.IP)
+	return network.Update(cmd, libvirt.NETWORK_SECTION_IP_DHCP_HOST, -1, host, flags)
+}
+
+func (m *IPAM) lookupNetwork() (*libvirt.Network, error) {
+	network, err := m.conn.LookupNetworkByName(m.network)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNetwork, "libvirt network not found", err).
+			WithContext("network", m.network)
+	}
+	return network, nil
+}
+
+// parseRangeIP parses an IPAM range bound that must lie inside the subnet
+func parseRangeIP(subnet *net.IPNet, s string) (uint32, error) {
+	ip := net.ParseIP(s)
+	if ip == nil || ip.To4() == nil || !subnet.Contains(ip) {
+		return 0, fmt.Errorf("IPAM range address %q is not in subnet %s", s, subnet)
+	}
+	return ipToUint32(ip), nil
+}
+
+// randomMAC returns a random address in the QEMU/KVM OUI
+func randomMAC() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", b[0], b[1], b[2]), nil
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uint32ToIP(n uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
@@ -0,0 +1,150 @@
+package network
+
+import (
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"go.uber.org/zap"
+	"libvirt.org/go/libvirt"
+)
+
+// ulaRange holds unique local addresses (RFC 4193); they are only routed
+// inside the site, so guests reach the internet through NAT66
+var ulaRange = netip.MustParsePrefix("fc00::/7")
+
+// IPv6Prefix returns the prefix VM networks get their /64 subnets from
+// An empty prefix derives a stable ULA /48 from seed, usually the agent name
+func IPv6Prefix(prefix, seed string) (netip.Prefix, error) {
+	if prefix == "" {
+		sum := sha256.Sum256([]byte(seed))
+		var b [16]byte
+		b[0] = 0xfd
+		copy(b[1:6], sum[:5]) // 40-bit global ID
+		return netip.PrefixFrom(netip.AddrFrom16(b), 48), nil
+	}
+
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil || !p.Addr().Is6() || p.Addr().Is4In6() {
+		return netip.Prefix{}, fmt.Errorf("invalid IPv6 prefix %q", prefix)
+	}
+	if p.Bits() > 64 {
+		return netip.Prefix{}, fmt.Errorf("IPv6 prefix %s is longer than /64", p)
+	}
+	return p.Masked(), nil
+}
+
+// IsULA reports whether a prefix is a unique local range
+func IsULA(prefix netip.Prefix) bool {
+	return ulaRange.Overlaps(prefix)
+}
+
+// EnableIPv6 adds subnet to a libvirt network without an IPv6 range, with the
+// host on its first address and SLAAC for guests; ULA subnets are NATed
+// It returns the network's IPv6 subnet, which is the existing one when the
+// network already has IPv6. Libvirt only applies the change when the network
+// restarts, since restarting it here would cut off running VMs
+func EnableIPv6(conn *libvirt.Connect, name string, subnet netip.Prefix, logger *zap.Logger) (netip.Prefix, error) {
+	lvNet, err := conn.LookupNetworkByName(name)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("failed to look up network %s: %w", name, err)
+	}
+	defer lvNet.Free()
+
+	desc, err := lvNet.GetXMLDesc(libvirt.NETWORK_XML_INACTIVE)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("failed to get network XML: %w", err)
+	}
+
+	if existing, ok, err := networkIPv6Subnet(desc); err != nil || ok {
+		return existing, err
+	}
+
+	gateway := subnet.Addr().Next()
+	ip := fmt.Sprintf("  <ip family='ipv6' address='%s' prefix='%d'/>\n</network>", gateway, subnet.Bits())
+	updated := strings.Replace(desc, "</network>", ip, 1)
+	if IsULA(subnet) {
+		updated = enableNAT66(updated)
+	}
+
+	defined, err := conn.NetworkDefineXML(updated)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("failed to update network %s: %w", name, err)
+	}
+	defined.Free()
+
+	if active, err := lvNet.IsActive(); err == nil && active {
+		logger.Warn("IPv6 added to network; it takes effect once the network is restarted",
+			zap.String("network", name),
+			zap.String("subnet", subnet.String()),
+		)
+	} else {
+		logger.Info("IPv6 added to network", zap.String("network", name), zap.String("subnet", subnet.String()))
+	}
+
+	return subnet, nil
+}
+
+// networkIPv6Subnet returns the IPv6 subnet of a network definition
+func networkIPv6Subnet(desc string) (netip.Prefix, bool, error) {
+	var def struct {
+		IPs []struct {
+			Family  string `xml:"family,attr"`
+			Address string `xml:"address,attr"`
+			Prefix  int    `xml:"prefix,attr"`
+		} `xml:"ip"`
+	}
+	if err := xml.Unmarshal([]byte(desc), &def); err != nil {
+		return netip.Prefix{}, false, fmt.Errorf("failed to parse network XML: %w", err)
+	}
+
+	for _, ip := range def.IPs {
+		if ip.Family != "ipv6" {
+			continue
+		}
+		addr, err := netip.ParseAddr(ip.Address)
+		if err != nil {
+			return netip.Prefix{}, false, fmt.Errorf("invalid IPv6 address %q in network XML", ip.Address)
+		}
+		return netip.PrefixFrom(addr, ip.Prefix).Masked(), true, nil
+	}
+	return netip.Prefix{}, false, nil
+}
+
+// enableNAT66 turns on IPv6 masquerading in the forward element of a NAT network
+func enableNAT66(desc string) string {
+	switch {
+	case strings.Contains(desc, "ipv6='yes'"):
+		return desc
+	case strings.Contains(desc, "<nat>"):
+		return strings.Replace(desc, "<nat>", "<nat ipv6='yes'>", 1)
+	case strings.Contains(desc, "<nat "):
+		return strings.Replace(desc, "<nat ", "<nat ipv6='yes' ", 1)
+	case strings.Contains(desc, "<forward mode='nat'/>"):
+		return strings.Replace(desc, "<forward mode='nat'/>", "<forward mode='nat'>\n    <nat ipv6='yes'/>\n  </forward>", 1)
+	}
+	return desc
+}
+
+// slaacAddress returns the address a guest with mac configures in a /64 by
+// SLAAC with an EUI-64 interface identifier
+// Guests using stable privacy or temporary addresses pick other addresses
+func slaacAddress(subnet netip.Prefix, mac string) (string, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return "", fmt.Errorf("invalid MAC address %q", mac)
+	}
+	if subnet.Bits() != 64 {
+		return "", fmt.Errorf("SLAAC requires a /64, got %s", subnet)
+	}
+
+	b := subnet.Masked().Addr().As16()
+	b[8] = hw[0] ^ 0x02 // Flip the universal/local bit
+	b[9], b[10] = hw[1], hw[2]
+	b[11], b[12] = 0xff, 0xfe
+	b[13], b[14], b[15] = hw[3], hw[4], hw[5]
+	return netip.AddrFrom16(b).String(), nil
+}
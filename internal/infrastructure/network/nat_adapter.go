@@ -3,34 +3,46 @@ package network
 import (
 	"context"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 	"libvirt.org/go/libvirt"
 
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
 )
 
 // NATAdapter implements NetworkService using simple NAT networking
 // This is the v1.0 implementation - designed to be replaced with more advanced networking later
 type NATAdapter struct {
 	conn   *libvirt.Connect
+	ipam   service.IPAMService // nil when static IP management is disabled
 	logger *zap.Logger
 }
 
 // NewNATAdapter creates a new NAT network adapter
-func NewNATAdapter(conn *libvirt.Connect, logger *zap.Logger) *NATAdapter {
+func NewNATAdapter(conn *libvirt.Connect, ipam service.IPAMService, logger *zap.Logger) *NATAdapter {
 	return &NATAdapter{
 		conn:   conn,
+		ipam:   ipam,
 		logger: logger,
 	}
 }
 
 // AssignIP assigns an IP address to a VM
-// In NAT mode, this is handled by DHCP automatically
+// With IPAM the reserved address is returned immediately, otherwise DHCP assigns one
 func (n *NATAdapter) AssignIP(ctx context.Context, vmID string) (string, error) {
 	n.logger.Debug("Assigning IP via NAT DHCP", zap.String("vm_id", vmID))
 	
+	if n.ipam != nil {
+		if alloc, err := n.ipam.Get(ctx, vmID); err == nil {
+			return alloc.IP, nil
+		}
+	}
+	
 	// In NAT mode, IP is assigned by libvirt's DHCP server automatically
 	// We just need to wait for it and return it
 	ip, err := n.waitForDHCPLease(vmID, 2*time.Minute)
@@ -43,34 +55,130 @@ func (n *NATAdapter) AssignIP(ctx context.Context, vmID string) (string, error)
 }
 
 // ReleaseIP releases an IP address from a VM
-// In NAT mode, this is handled automatically when VM is deleted
+// Dynamic leases expire on their own; static reservations are removed via IPAM
 func (n *NATAdapter) ReleaseIP(ctx context.Context, vmID string) error {
-	n.logger.Debug("Releasing IP (NAT mode - automatic)", zap.String("vm_id", vmID))
-	// In NAT mode, IP is released automatically when VM is destroyed
+	n.logger.Debug("Releasing IP", zap.String("vm_id", vmID))
+	if n.ipam != nil {
+		return n.ipam.Release(ctx, vmID)
+	}
 	return nil
 }
 
-// GetVMIP retrieves the current IP of a VM
-func (n *NATAdapter) GetVMIP(ctx context.Context, vmID string) (string, error) {
+// GetVMIP retrieves the current addresses of a VM
+// DHCP leases only carry IPv4 addresses; SLAAC addresses are reported by the guest agent
+func (n *NATAdapter) GetVMIP(ctx context.Context, vmID string) ([]entity.VMInterface, error) {
 	domain, err := n.conn.LookupDomainByName(vmID)
 	if err != nil {
-		return "", errors.New(errors.ErrCodeNotFound, "VM not found", err).
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
 			WithContext("vm_id", vmID)
 	}
 	defer domain.Free()
 	
+	// Additional NICs on private networks have leases too; the first NIC is listed first
+	mac := primaryMAC(domain)
+	
+	// The guest agent knows addresses without a lease and the guest's interface names;
+	// leases fill in when it is not installed
+	var found []entity.VMInterface
+	if agentIfaces, agentErr := domain.ListAllInterfaceAddresses(libvirt.DOMAIN_INTERFACE_ADDRESSES_SRC_AGENT); agentErr == nil {
+		found = guestInterfaces(agentIfaces, mac)
+	}
 	ifaces, err := domain.ListAllInterfaceAddresses(libvirt.DOMAIN_INTERFACE_ADDRESSES_SRC_LEASE)
-	if err != nil {
-		return "", errors.New(errors.ErrCodeNetwork, "failed to get interfaces", err).
-			WithContext("vm_id", vmID)
+	found = mergeInterfaces(found, guestInterfaces(ifaces, mac))
+	if entity.PrimaryIP(found) != "" {
+		return found, nil
 	}
 	
-	if len(ifaces) == 0 || len(ifaces[0].Addrs) == 0 {
-		return "", errors.New(errors.ErrCodeNetwork, "no IP address found", nil).
+	// A reserved address is authoritative even before the guest requests it
+	if n.ipam != nil {
+		if alloc, allocErr := n.ipam.Get(ctx, vmID); allocErr == nil {
+			return []entity.VMInterface{allocationInterface(alloc)}, nil
+		}
+	}
+	
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNetwork, "failed to get interfaces", err).
 			WithContext("vm_id", vmID)
 	}
 	
-	return ifaces[0].Addrs[0].Addr, nil
+	return nil, errors.New(errors.ErrCodeNetwork, "no IP address found", nil).
+		WithContext("vm_id", vmID)
+}
+
+// guestInterfaces converts libvirt interface addresses, skipping loopback and
+// link-local addresses and interfaces left without any
+// The interface with hardware address mac, the VM's primary NIC, is moved first
+func guestInterfaces(ifaces []libvirt.DomainInterface, mac string) []entity.VMInterface {
+	var primary, others []entity.VMInterface
+	for _, iface := range ifaces {
+		if iface.Name == "lo" {
+			continue
+		}
+		
+		vmIface := entity.VMInterface{Name: iface.Name, MAC: strings.ToLower(iface.Hwaddr)}
+		for _, addr := range iface.Addrs {
+			ip := net.ParseIP(addr.Addr)
+			if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+				continue
+			}
+			family := entity.IPFamilyV4
+			if addr.Type == libvirt.IP_ADDR_TYPE_IPV6 {
+				family = entity.IPFamilyV6
+			}
+			vmIface.Addresses = append(vmIface.Addresses, entity.IPAddress{
+				Address: addr.Addr,
+				Prefix:  int(addr.Prefix),
+				Family:  family,
+			})
+		}
+		if len(vmIface.Addresses) == 0 {
+			continue
+		}
+		
+		if mac != "" && strings.EqualFold(iface.Hwaddr, mac) {
+			primary = append(primary, vmIface)
+		} else {
+			others = append(others, vmIface)
+		}
+	}
+	return append(primary, others...)
+}
+
+// mergeInterfaces adds the interfaces and addresses of extra missing from base, matched by MAC
+func mergeInterfaces(base, extra []entity.VMInterface) []entity.VMInterface {
+	for _, iface := range extra {
+		i := 0
+		for i < len(base) && !strings.EqualFold(base[i].MAC, iface.MAC) {
+			i++
+		}
+		if i == len(base) {
+			base = append(base, iface)
+			continue
+		}
+		
+		for _, addr := range iface.Addresses {
+			known := false
+			for _, existing := range base[i].Addresses {
+				known = known || existing.Address == addr.Address
+			}
+			if !known {
+				base[i].Addresses = append(base[i].Addresses, addr)
+			}
+		}
+	}
+	return base
+}
+
+// allocationInterface describes the primary NIC of a VM by its IPAM reservation
+func allocationInterface(alloc *entity.IPAllocation) entity.VMInterface {
+	iface := entity.VMInterface{
+		MAC:       alloc.MAC,
+		Addresses: []entity.IPAddress{{Address: alloc.IP, Family: entity.IPFamilyV4}},
+	}
+	if alloc.IPv6 != "" {
+		iface.Addresses = append(iface.Addresses, entity.IPAddress{Address: alloc.IPv6, Prefix: 64, Family: entity.IPFamilyV6})
+	}
+	return iface
 }
 
 // waitForDHCPLease waits for a VM to get an IP from DHCP
@@ -78,8 +186,8 @@ func (n *NATAdapter) waitForDHCPLease(vmID string, timeout time.Duration) (strin
 	deadline := time.Now().Add(timeout)
 	
 	for time.Now().Before(deadline) {
-		ip, err := n.GetVMIP(context.Background(), vmID)
-		if err == nil && ip != "" {
+		ifaces, err := n.GetVMIP(context.Background(), vmID)
+		if ip := entity.PrimaryIP(ifaces); err == nil && ip != "" {
 			return ip, nil
 		}
 		time.Sleep(2 * time.Second)
@@ -0,0 +1,206 @@
+package network
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"go.uber.org/zap"
+	"libvirt.org/go/libvirt"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// PrivateNetworks implements PrivateNetworkService with libvirt networks
+// Each network gets its own bridge and dnsmasq; without NAT it has no route
+// off the host, and libvirt rejects forwarding between its networks either way
+// IPv6 subnets are configured by SLAAC and, when they are ULAs, NATed like IPv4
+type PrivateNetworks struct {
+	conn   *libvirt.Connect
+	logger *zap.Logger
+}
+
+type networkXML struct {
+	XMLName xml.Name    `xml:"network"`
+	Name    string      `xml:"name"`
+	UUID    string      `xml:"uuid,omitempty"`
+	Forward *forwardXML `xml:"forward,omitempty"`
+	Bridge  bridgeXML   `xml:"bridge"`
+	IPs     []netIPXML  `xml:"ip"`
+}
+
+type forwardXML struct {
+	Mode string  `xml:"mode,attr"`
+	NAT  *natXML `xml:"nat,omitempty"`
+}
+
+type natXML struct {
+	IPv6 string `xml:"ipv6,attr"`
+}
+
+type bridgeXML struct {
+	Name  string `xml:"name,attr"`
+	STP   string `xml:"stp,attr"`
+	Delay int    `xml:"delay,attr"`
+}
+
+type netIPXML struct {
+	Family  string   `xml:"family,attr,omitempty"`
+	Address string   `xml:"address,attr"`
+	Netmask string   `xml:"netmask,attr,omitempty"`
+	Prefix  int      `xml:"prefix,attr,omitempty"`
+	DHCP    *dhcpXML `xml:"dhcp,omitempty"`
+}
+
+type dhcpXML struct {
+	Range struct {
+		Start string `xml:"start,attr"`
+		End   string `xml:"end,attr"`
+	} `xml:"range"`
+}
+
+// NewPrivateNetworks creates a new private network manager
+func NewPrivateNetworks(conn *libvirt.Connect, logger *zap.Logger) *PrivateNetworks {
+	return &PrivateNetworks{
+		conn:   conn,
+		logger: logger,
+	}
+}
+
+// Define creates a network, assigns its bridge and starts it
+// The network is started again after host reboots; defining an existing network starts it if needed
+func (p *PrivateNetworks) Define(ctx context.Context, network *entity.Network) error {
+	_, subnet, err := net.ParseCIDR(network.Subnet)
+	if err != nil {
+		return errors.New(errors.ErrCodeValidation, "invalid network subnet", err).
+			WithContext("network_id", network.ID)
+	}
+
+	network.Bridge = bridgeName(network.ID)
+
+	dhcp := &dhcpXML{}
+	dhcp.Range.Start = network.DHCPStart
+	dhcp.Range.End = network.DHCPEnd
+
+	def := &networkXML{
+		Name:   p.NetworkName(network.ID),
+		Bridge: bridgeXML{Name: network.Bridge, STP: "on"},
+		IPs: []netIPXML{{
+			Address: network.Gateway,
+			Netmask: net.IP(subnet.Mask).String(),
+			DHCP:    dhcp,
+		}},
+	}
+	if network.NAT {
+		def.Forward = &forwardXML{Mode: "nat"}
+	}
+
+	if network.SubnetV6 != "" {
+		subnetV6, err := netip.ParsePrefix(network.SubnetV6)
+		if err != nil {
+			return errors.New(errors.ErrCodeValidation, "invalid network IPv6 subnet", err).
+				WithContext("network_id", network.ID)
+		}
+		def.IPs = append(def.IPs, netIPXML{
+			Family:  "ipv6",
+			Address: network.GatewayV6,
+			Prefix:  subnetV6.Bits(),
+		})
+		if network.NAT && IsULA(subnetV6) {
+			def.Forward.NAT = &natXML{IPv6: "yes"}
+		}
+	}
+
+	if err := p.define(def); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to define network", err).
+			WithContext("network_id", network.ID)
+	}
+
+	p.logger.Debug("Private network defined",
+		zap.String("network_id", network.ID),
+		zap.String("bridge", network.Bridge),
+		zap.String("subnet", network.Subnet),
+		zap.String("subnet_v6", network.SubnetV6),
+	)
+
+	return nil
+}
+
+// Undefine stops and removes a network no VM is attached to
+func (p *PrivateNetworks) Undefine(ctx context.Context, networkID string) error {
+	lvNet, err := p.conn.LookupNetworkByName(p.NetworkName(networkID))
+	if err != nil {
+		if lerr, ok := err.(libvirt.Error); ok && lerr.Code == libvirt.ERR_NO_NETWORK {
+			return nil
+		}
+		return errors.New(errors.ErrCodeNetwork, "failed to look up network", err).
+			WithContext("network_id", networkID)
+	}
+	defer lvNet.Free()
+
+	if active, err := lvNet.IsActive(); err == nil && active {
+		if err := lvNet.Destroy(); err != nil {
+			return errors.New(errors.ErrCodeNetwork, "failed to stop network", err).
+				WithContext("network_id", networkID)
+		}
+	}
+	if err := lvNet.Undefine(); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to remove network", err).
+			WithContext("network_id", networkID)
+	}
+
+	return nil
+}
+
+// NetworkName returns the name of the libvirt network VM interfaces reference
+func (p *PrivateNetworks) NetworkName(networkID string) string {
+	return "ghost-net-" + networkID
+}
+
+// define creates or replaces a network definition, keeping the UUID of an
+// existing one, then starts it and marks it to start with libvirtd
+func (p *PrivateNetworks) define(def *networkXML) error {
+	if existing, err := p.conn.LookupNetworkByName(def.Name); err == nil {
+		uuid, err := existing.GetUUIDString()
+		existing.Free()
+		if err != nil {
+			return err
+		}
+		def.UUID = uuid
+	}
+
+	data, err := xml.Marshal(def)
+	if err != nil {
+		return err
+	}
+
+	lvNet, err := p.conn.NetworkDefineXML(string(data))
+	if err != nil {
+		return err
+	}
+	defer lvNet.Free()
+
+	active, err := lvNet.IsActive()
+	if err != nil {
+		return err
+	}
+	if !active {
+		if err := lvNet.Create(); err != nil {
+			return fmt.Errorf("failed to start network: %w", err)
+		}
+	}
+
+	return lvNet.SetAutostart(true)
+}
+
+// bridgeName derives a bridge name from a network ID
+// Linux limits interface names to 15 characters
+func bridgeName(networkID string) string {
+	if len(networkID) > 8 {
+		networkID = networkID[:8]
+	}
+	return "gn-" + networkID
+}
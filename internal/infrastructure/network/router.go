@@ -0,0 +1,118 @@
+package network
+
+import (
+	"context"
+	"encoding/xml"
+
+	"go.uber.org/zap"
+	"libvirt.org/go/libvirt"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// Router implements NetworkService by dispatching each call to the adapter
+// matching the VM's interface type, so NAT and bridged VMs can share an agent
+type Router struct {
+	conn   *libvirt.Connect
+	nat    service.NetworkService
+	bridge service.NetworkService // nil when bridging is disabled
+	logger *zap.Logger
+}
+
+// NewRouter creates a new network router
+// bridge may be nil when the agent has no bridge configured
+func NewRouter(conn *libvirt.Connect, nat, bridge service.NetworkService, logger *zap.Logger) *Router {
+	return &Router{
+		conn:   conn,
+		nat:    nat,
+		bridge: bridge,
+		logger: logger,
+	}
+}
+
+// AssignIP assigns an IP address to a VM
+func (r *Router) AssignIP(ctx context.Context, vmID string) (string, error) {
+	adapter, err := r.adapterFor(vmID)
+	if err != nil {
+		return "", err
+	}
+	return adapter.AssignIP(ctx, vmID)
+}
+
+// ReleaseIP releases an IP address from a VM
+func (r *Router) ReleaseIP(ctx context.Context, vmID string) error {
+	adapter, err := r.adapterFor(vmID)
+	if err != nil {
+		return err
+	}
+	return adapter.ReleaseIP(ctx, vmID)
+}
+
+// GetVMIP retrieves the current addresses of a VM
+func (r *Router) GetVMIP(ctx context.Context, vmID string) ([]entity.VMInterface, error) {
+	adapter, err := r.adapterFor(vmID)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.GetVMIP(ctx, vmID)
+}
+
+// adapterFor picks the adapter from the first interface in the domain definition
+func (r *Router) adapterFor(vmID string) (service.NetworkService, error) {
+	domain, err := r.conn.LookupDomainByName(vmID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", err).
+			WithContext("vm_id", vmID)
+	}
+	defer domain.Free()
+
+	desc, err := domain.GetXMLDesc(0)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNetwork, "failed to get domain XML", err).
+			WithContext("vm_id", vmID)
+	}
+
+	var def struct {
+		Interfaces []struct {
+			Type string `xml:"type,attr"`
+		} `xml:"devices>interface"`
+	}
+	if err := xml.Unmarshal([]byte(desc), &def); err != nil {
+		return nil, errors.New(errors.ErrCodeNetwork, "failed to parse domain XML", err).
+			WithContext("vm_id", vmID)
+	}
+
+	if len(def.Interfaces) > 0 && def.Interfaces[0].Type == "bridge" {
+		if r.bridge == nil {
+			r.logger.Warn("VM is bridged but bridging is disabled", zap.String("vm_id", vmID))
+			return nil, errors.New(errors.ErrCodeInvalidState, "bridge networking is not configured", nil).
+				WithContext("vm_id", vmID)
+		}
+		return r.bridge, nil
+	}
+
+	return r.nat, nil
+}
+
+// primaryMAC returns the MAC address of a domain's first interface, the one
+// the VM's address belongs to; empty when it cannot be determined
+func primaryMAC(domain *libvirt.Domain) string {
+	desc, err := domain.GetXMLDesc(0)
+	if err != nil {
+		return ""
+	}
+
+	var def struct {
+		Interfaces []struct {
+			MAC struct {
+				Address string `xml:"address,attr"`
+			} `xml:"mac"`
+		} `xml:"devices>interface"`
+	}
+	if err := xml.Unmarshal([]byte(desc), &def); err != nil || len(def.Interfaces) == 0 {
+		return ""
+	}
+	return def.Interfaces[0].MAC.Address
+}
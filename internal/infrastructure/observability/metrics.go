@@ -13,6 +13,7 @@ type Metrics struct {
 	VMOperations  *prometheus.CounterVec
 	APICallLatency *prometheus.HistogramVec
 	HeartbeatSuccess prometheus.Gauge
+	RequestsRejected *prometheus.CounterVec
 }
 
 // NewMetrics creates and registers all Prometheus metrics
@@ -49,5 +50,12 @@ func NewMetrics() *Metrics {
 			Name: "ghost_agent_heartbeat_success",
 			Help: "1 if last heartbeat was successful, 0 otherwise",
 		}),
+		RequestsRejected: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ghost_agent_requests_rejected_total",
+				Help: "API requests rejected by rate or concurrency limits",
+			},
+			[]string{"method", "reason"},
+		),
 	}
 }
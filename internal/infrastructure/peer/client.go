@@ -0,0 +1,149 @@
+package peer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// Client implements PeerAgentService by calling other agents' AgentService
+type Client struct {
+	defaultPort string
+	tlsConfig   *tls.Config
+	logger      *zap.Logger
+}
+
+// NewClient creates a new peer agent client
+// defaultPort is used for addresses given without a port; a nil tlsConfig
+// calls agents without TLS
+func NewClient(defaultPort string, tlsConfig *tls.Config, logger *zap.Logger) *Client {
+	return &Client{
+		defaultPort: defaultPort,
+		tlsConfig:   tlsConfig,
+		logger:      logger,
+	}
+}
+
+// PrepareMigration reserves resources and a disk for vm on the agent at address
+func (c *Client) PrepareMigration(ctx context.Context, address string, vm *entity.VM) (*service.MigrationReservation, error) {
+	client, conn, err := c.dial(address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	resp, err := client.PrepareMigration(ctx, &agentpb.PrepareMigrationRequest{
+		Vm: toMigratingVM(vm),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("target agent rejected migration: %w", err)
+	}
+
+	return &service.MigrationReservation{
+		DiskPath:   resp.DiskPath,
+		LibvirtURI: resp.LibvirtUri,
+	}, nil
+}
+
+// FinishMigration tells the agent at address whether the migration of vm succeeded
+func (c *Client) FinishMigration(ctx context.Context, address string, vm *entity.VM, succeeded bool) (*entity.VM, error) {
+	client, conn, err := c.dial(address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	resp, err := client.FinishMigration(ctx, &agentpb.FinishMigrationRequest{
+		Vm:        toMigratingVM(vm),
+		Succeeded: succeeded,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish migration on target agent: %w", err)
+	}
+
+	migrated := *vm
+	migrated.ID = resp.VmId
+	migrated.IP = resp.IpAddress
+	migrated.Status = entity.VMStatus(resp.Status)
+
+	return &migrated, nil
+}
+
+func (c *Client) dial(address string) (agentpb.AgentServiceClient, *grpc.ClientConn, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, c.defaultPort)
+	}
+
+	creds := insecure.NewCredentials()
+	if c.tlsConfig != nil {
+		creds = credentials.NewTLS(c.tlsConfig)
+	}
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to agent %s: %w", address, err)
+	}
+
+	return agentpb.NewAgentServiceClient(conn), conn, nil
+}
+
+func toMigratingVM(vm *entity.VM) *agentpb.MigratingVM {
+	migrating := &agentpb.MigratingVM{
+		VmId:           vm.ID,
+		Name:           vm.Name,
+		Vcpu:           int32(vm.VCPU),
+		RamGb:          int32(vm.RAMGB),
+		DiskGb:         int32(vm.DiskGB),
+		Template:       vm.Template,
+		NetworkMode:    string(vm.EffectiveNetworkMode()),
+		Limits:         toVMLimits(vm.Limits),
+		Metadata:       vm.Metadata,
+		SecurityGroups: vm.SecurityGroups,
+		NetworkIds:     vm.Networks,
+		Flavor:         vm.Flavor,
+		CpuMode:        string(vm.CPUMode),
+	}
+	if vm.Expiry != nil {
+		migrating.ExpiresAt = vm.Expiry.At.Unix()
+		migrating.ExpiryAction = string(vm.Expiry.Action)
+	}
+	if vm.Restart != nil {
+		migrating.RestartPolicy = string(vm.Restart.Policy)
+		migrating.RestartMaxRetries = int32(vm.Restart.MaxRetries)
+	}
+	if vm.Backup != nil {
+		migrating.BackupSchedule = vm.Backup.Schedule
+		migrating.BackupKeepDaily = int32(vm.Backup.KeepDaily)
+		migrating.BackupKeepWeekly = int32(vm.Backup.KeepWeekly)
+	}
+	return migrating
+}
+
+func toVMLimits(l *entity.VMLimits) *agentpb.VMLimits {
+	if l == nil {
+		return nil
+	}
+	return &agentpb.VMLimits{
+		Inbound: &agentpb.BandwidthLimit{
+			AverageKbytesSec: int32(l.Inbound.Average),
+			PeakKbytesSec:    int32(l.Inbound.Peak),
+			BurstKbytes:      int32(l.Inbound.Burst),
+		},
+		Outbound: &agentpb.BandwidthLimit{
+			AverageKbytesSec: int32(l.Outbound.Average),
+			PeakKbytesSec:    int32(l.Outbound.Peak),
+			BurstKbytes:      int32(l.Outbound.Burst),
+		},
+		DiskIops:     l.DiskIOPS,
+		DiskBytesSec: l.DiskBytesPerSec,
+	}
+}
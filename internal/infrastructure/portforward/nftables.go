@@ -0,0 +1,106 @@
+package portforward
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// nftTable is the nftables table owned by the agent; it is rebuilt on every sync
+const nftTable = "ghost_portforward"
+
+// NFTablesForwarder implements PortForwarder with nftables DNAT rules
+// One inet table serves both families: IPv4 clients reach the VM's IPv4
+// address and IPv6 clients its IPv6 address
+type NFTablesForwarder struct {
+	mu     sync.Mutex
+	logger *zap.Logger
+}
+
+// NewNFTablesForwarder creates a new nftables forwarder
+// It fails when the nft binary is not available
+func NewNFTablesForwarder(logger *zap.Logger) (*NFTablesForwarder, error) {
+	if _, err := exec.LookPath("nft"); err != nil {
+		return nil, fmt.Errorf("nft not found: %w", err)
+	}
+
+	return &NFTablesForwarder{
+		logger: logger,
+	}, nil
+}
+
+// Name returns the backend type
+func (f *NFTablesForwarder) Name() string {
+	return "nftables"
+}
+
+// Sync atomically replaces the agent's table with rules for forwards
+func (f *NFTablesForwarder) Sync(ctx context.Context, forwards []*entity.PortForward) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := runNFT(ctx, buildRuleset(forwards)); err != nil {
+		return err
+	}
+
+	f.logger.Debug("nftables port forwards synced", zap.Int("count", len(forwards)))
+	return nil
+}
+
+// Close removes the agent's table
+func (f *NFTablesForwarder) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return runNFT(context.Background(), fmt.Sprintf("table inet %s\ndelete table inet %s\n", nftTable, nftTable))
+}
+
+// buildRuleset renders the table; declaring it before deleting makes the delete safe
+// when the table does not exist yet, and nft applies the whole script in one transaction
+// The ip table of IPv4-only releases is dropped the same way
+func buildRuleset(forwards []*entity.PortForward) string {
+	var prerouting, output strings.Builder
+	for _, fwd := range forwards {
+		// Forwards without an address of a family are skipped for it until the VM has one
+		if fwd.GuestIP != "" {
+			match := fmt.Sprintf("meta nfproto ipv4 %s dport %d dnat ip to %s:%d", fwd.Protocol, fwd.HostPort, fwd.GuestIP, fwd.GuestPort)
+			fmt.Fprintf(&prerouting, "\t\t%s comment \"%s\"\n", match, fwd.ID)
+			fmt.Fprintf(&output, "\t\tfib daddr type local %s comment \"%s\"\n", match, fwd.ID)
+		}
+		if fwd.GuestIPv6 != "" {
+			match := fmt.Sprintf("meta nfproto ipv6 %s dport %d dnat ip6 to [%s]:%d", fwd.Protocol, fwd.HostPort, fwd.GuestIPv6, fwd.GuestPort)
+			fmt.Fprintf(&prerouting, "\t\t%s comment \"%s\"\n", match, fwd.ID)
+			fmt.Fprintf(&output, "\t\tfib daddr type local %s comment \"%s\"\n", match, fwd.ID)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "table ip %s\n", nftTable)
+	fmt.Fprintf(&b, "delete table ip %s\n", nftTable)
+	fmt.Fprintf(&b, "table inet %s\n", nftTable)
+	fmt.Fprintf(&b, "delete table inet %s\n", nftTable)
+	fmt.Fprintf(&b, "table inet %s {\n", nftTable)
+	b.WriteString("\tchain prerouting {\n\t\ttype nat hook prerouting priority dstnat; policy accept;\n")
+	b.WriteString(prerouting.String())
+	b.WriteString("\t}\n")
+	b.WriteString("\tchain output {\n\t\ttype nat hook output priority -100; policy accept;\n")
+	b.WriteString(output.String())
+	b.WriteString("\t}\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func runNFT(ctx context.Context, script string) error {
+	cmd := exec.CommandContext(ctx, "nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nft failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package portforward
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+const (
+	// dialTimeout bounds connecting to the VM for a new TCP connection
+	dialTimeout = 10 * time.Second
+
+	// udpSessionIdle is how long a UDP client mapping lives without traffic
+	udpSessionIdle = 2 * time.Minute
+
+	// udpBufferSize fits any UDP datagram
+	udpBufferSize = 64 * 1024
+)
+
+// ProxyForwarder implements PortForwarder with userspace TCP/UDP proxies
+// It works without firewall access, at the cost of hiding client addresses from VMs
+// Listening on "::" accepts IPv4 and IPv6 clients; VMs are reached over IPv4 when they have it
+type ProxyForwarder struct {
+	listenAddr string
+	active     map[string]*proxy
+	mu         sync.Mutex
+	logger     *zap.Logger
+}
+
+// proxy is one listening forward
+type proxy struct {
+	target string
+	closer io.Closer
+}
+
+// NewProxyForwarder creates a new userspace forwarder listening on listenAddr
+func NewProxyForwarder(listenAddr string, logger *zap.Logger) *ProxyForwarder {
+	return &ProxyForwarder{
+		listenAddr: listenAddr,
+		active:     make(map[string]*proxy),
+		logger:     logger,
+	}
+}
+
+// Name returns the backend type
+func (f *ProxyForwarder) Name() string {
+	return "proxy"
+}
+
+// Sync starts listeners for new forwards, restarts those whose target changed
+// and stops those no longer wanted
+func (f *ProxyForwarder) Sync(ctx context.Context, forwards []*entity.PortForward) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	wanted := make(map[string]*entity.PortForward, len(forwards))
+	for _, fwd := range forwards {
+		if proxyGuestIP(fwd) != "" {
+			wanted[proxyKey(fwd)] = fwd
+		}
+	}
+
+	for key, p := range f.active {
+		fwd, ok := wanted[key]
+		if ok && p.target == proxyTarget(fwd) {
+			continue
+		}
+		p.closer.Close()
+		delete(f.active, key)
+	}
+
+	var firstErr error
+	for key, fwd := range wanted {
+		if _, ok := f.active[key]; ok {
+			continue
+		}
+
+		p, err := f.start(fwd)
+		if err != nil {
+			f.logger.Error("Failed to start port forward proxy",
+				zap.String("id", fwd.ID),
+				zap.String("protocol", string(fwd.Protocol)),
+				zap.Int("host_port", fwd.HostPort),
+				zap.Error(err),
+			)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		f.active[key] = p
+	}
+
+	return firstErr
+}
+
+// Close stops all proxies
+func (f *ProxyForwarder) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, p := range f.active {
+		p.closer.Close()
+		delete(f.active, key)
+	}
+	return nil
+}
+
+func (f *ProxyForwarder) start(fwd *entity.PortForward) (*proxy, error) {
+	addr := net.JoinHostPort(f.listenAddr, strconv.Itoa(fwd.HostPort))
+	target := proxyTarget(fwd)
+
+	switch fwd.Protocol {
+	case entity.PortProtocolTCP:
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		go f.serveTCP(ln, target)
+		return &proxy{target: target, closer: ln}, nil
+	case entity.PortProtocolUDP:
+		pc, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		go f.serveUDP(pc, target)
+		return &proxy{target: target, closer: pc}, nil
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", fwd.Protocol)
+	}
+}
+
+func (f *ProxyForwarder) serveTCP(ln net.Listener, target string) {
+	for {
+		client, err := ln.Accept()
+		if err != nil {
+			return // Listener closed
+		}
+
+		go func() {
+			defer client.Close()
+
+			upstream, err := net.DialTimeout("tcp", target, dialTimeout)
+			if err != nil {
+				f.logger.Debug("Port forward dial failed", zap.String("target", target), zap.Error(err))
+				return
+			}
+			defer upstream.Close()
+
+			done := make(chan struct{}, 2)
+			go func() { io.Copy(upstream, client); done <- struct{}{} }()
+			go func() { io.Copy(client, upstream); done <- struct{}{} }()
+			<-done
+		}()
+	}
+}
+
+func (f *ProxyForwarder) serveUDP(pc net.PacketConn, target string) {
+	targetAddr, err := net.ResolveUDPAddr("udp", target)
+	if err != nil {
+		f.logger.Error("Invalid port forward target", zap.String("target", target), zap.Error(err))
+		return
+	}
+
+	var mu sync.Mutex
+	sessions := make(map[string]*net.UDPConn)
+
+	buf := make([]byte, udpBufferSize)
+	for {
+		n, clientAddr, err := pc.ReadFrom(buf)
+		if err != nil {
+			mu.Lock()
+			for _, upstream := range sessions {
+				upstream.Close()
+			}
+			mu.Unlock()
+			return // Listener closed
+		}
+
+		mu.Lock()
+		upstream, ok := sessions[clientAddr.String()]
+		if !ok {
+			upstream, err = net.DialUDP("udp", nil, targetAddr)
+			if err != nil {
+				mu.Unlock()
+				continue
+			}
+			sessions[clientAddr.String()] = upstream
+
+			// Relay replies until the session goes idle
+			go func(key string, upstream *net.UDPConn, clientAddr net.Addr) {
+				defer func() {
+					mu.Lock()
+					delete(sessions, key)
+					mu.Unlock()
+					upstream.Close()
+				}()
+
+				reply := make([]byte, udpBufferSize)
+				for {
+					upstream.SetReadDeadline(time.Now().Add(udpSessionIdle))
+					n, err := upstream.Read(reply)
+					if err != nil {
+						return
+					}
+					if _, err := pc.WriteTo(reply[:n], clientAddr); err != nil {
+						return
+					}
+				}
+			}(clientAddr.String(), upstream, clientAddr)
+		}
+		mu.Unlock()
+
+		upstream.Write(buf[:n])
+	}
+}
+
+func proxyKey(fwd *entity.PortForward) string {
+	return fmt.Sprintf("%s/%d", fwd.Protocol, fwd.HostPort)
+}
+
+func proxyTarget(fwd *entity.PortForward) string {
+	return net.JoinHostPort(proxyGuestIP(fwd), strconv.Itoa(fwd.GuestPort))
+}
+
+// proxyGuestIP returns the VM address to relay to, preferring IPv4
+func proxyGuestIP(fwd *entity.PortForward) string {
+	if fwd.GuestIP != "" {
+		return fwd.GuestIP
+	}
+	return fwd.GuestIPv6
+}
@@ -9,12 +9,15 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
 	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
 )
 
 // Adapter implements StorageService
@@ -23,6 +26,7 @@ type Adapter struct {
 	imageRepo      repository.ImageRepository
 	logger         *zap.Logger
 	imageTemplates map[string]string // template name -> download URL
+	imageUploads   nameReservations  // Names of uploads from their existence check until they are registered
 }
 
 // NewAdapter creates a new storage adapter
@@ -68,6 +72,11 @@ func (a *Adapter) GetImage(ctx context.Context, template string) (string, error)
 			return image.Path, nil
 		}
 		
+		if image.IsUploaded() {
+			return "", errors.New(errors.ErrCodeStorage, "uploaded image is corrupted", nil).
+				WithContext("template", template)
+		}
+		
 		a.logger.Warn("Cached image checksum mismatch, re-downloading", zap.String("template", template))
 	}
 
@@ -98,6 +107,9 @@ func (a *Adapter) GetImage(ctx context.Context, template string) (string, error)
 		Path:     imagePath,
 		URL:      url,
 		Checksum: checksum,
+		Format:   entity.ImageFormatQCOW2,
+		Source:   entity.ImageSourceBuiltin,
+		CachedAt: time.Now(),
 	}
 	
 	if err := a.imageRepo.Save(ctx, image); err != nil {
@@ -107,6 +119,132 @@ func (a *Adapter) GetImage(ctx context.Context, template string) (string, error)
 	return imagePath, nil
 }
 
+// ImportImage validates uploaded image data and registers it as a template
+func (a *Adapter) ImportImage(ctx context.Context, spec *service.ImageImportSpec, data io.Reader) (*entity.Image, error) {
+	a.logger.Info("Importing image",
+		zap.String("name", spec.Name),
+		zap.String("format", string(spec.Format)),
+		zap.Int64("size_bytes", spec.SizeBytes),
+	)
+
+	// Uploaded images must not shadow built-in templates or existing uploads;
+	// an upload of a name still being received fails right away
+	release, ok := a.imageUploads.reserve(spec.Name)
+	if !ok {
+		return nil, errors.New(errors.ErrCodeConflict, "image is already being uploaded", nil).
+			WithContext("image_name", spec.Name)
+	}
+	defer release()
+
+	if _, ok := a.imageTemplates[spec.Name]; ok {
+		return nil, errors.New(errors.ErrCodeConflict, "image name is reserved by a built-in template", nil).
+			WithContext("image_name", spec.Name)
+	}
+	exists, err := a.imageRepo.Exists(ctx, spec.Name)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to check image cache", err).
+			WithContext("image_name", spec.Name)
+	}
+	if exists {
+		return nil, errors.New(errors.ErrCodeConflict, "image already exists", nil).
+			WithContext("image_name", spec.Name)
+	}
+
+	if err := os.MkdirAll(a.imageCache, 0755); err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to create cache directory", err)
+	}
+
+	// Receive into a temporary file, hashing while writing
+	upload, err := os.CreateTemp(a.imageCache, fmt.Sprintf(".%s.*.upload", spec.Name))
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to create upload file", err).
+			WithContext("image_name", spec.Name)
+	}
+	upload.Close()
+	uploadPath := upload.Name()
+	defer os.Remove(uploadPath)
+
+	received, checksum, err := a.receiveUpload(uploadPath, data)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to receive image", err).
+			WithContext("image_name", spec.Name)
+	}
+
+	if spec.SizeBytes > 0 && received != spec.SizeBytes {
+		return nil, errors.New(errors.ErrCodeValidation, "image size mismatch", nil).
+			WithContext("declared_size_bytes", spec.SizeBytes).
+			WithContext("received_size_bytes", received)
+	}
+	if checksum != spec.SHA256 {
+		return nil, errors.New(errors.ErrCodeValidation, "image checksum mismatch", nil).
+			WithContext("declared_sha256", spec.SHA256).
+			WithContext("actual_sha256", checksum)
+	}
+
+	// Validate the header and make sure it matches the declared format
+	format, err := detectImageFormat(uploadPath)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid image", err).
+			WithContext("image_name", spec.Name)
+	}
+	if format != spec.Format {
+		return nil, errors.New(errors.ErrCodeValidation, "image format does not match declared format", nil).
+			WithContext("declared_format", string(spec.Format)).
+			WithContext("detected_format", string(format))
+	}
+	if err := checkStandalone(uploadPath); err != nil {
+		return nil, errors.New(errors.ErrCodeValidation, "invalid image", err).
+			WithContext("image_name", spec.Name)
+	}
+
+	imagePath := filepath.Join(a.imageCache, fmt.Sprintf("%s.qcow2", spec.Name))
+	if format == entity.ImageFormatRaw {
+		if err := a.convertToQCOW2(ctx, uploadPath, imagePath); err != nil {
+			return nil, errors.New(errors.ErrCodeStorage, "failed to convert raw image", err).
+				WithContext("image_name", spec.Name)
+		}
+		// Cached checksum must describe the converted file
+		if checksum, err = a.calculateChecksum(imagePath); err != nil {
+			_ = os.Remove(imagePath)
+			return nil, errors.New(errors.ErrCodeStorage, "failed to calculate checksum", err).
+				WithContext("path", imagePath)
+		}
+	} else if err := os.Rename(uploadPath, imagePath); err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to store image", err).
+			WithContext("path", imagePath)
+	}
+
+	info, err := os.Stat(imagePath)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to stat image", err).
+			WithContext("path", imagePath)
+	}
+
+	image := &entity.Image{
+		Name:      spec.Name,
+		Path:      imagePath,
+		Checksum:  checksum,
+		SizeBytes: info.Size(),
+		Format:    entity.ImageFormatQCOW2,
+		Source:    entity.ImageSourceUpload,
+		CachedAt:  time.Now(),
+	}
+
+	if err := a.imageRepo.Save(ctx, image); err != nil {
+		_ = os.Remove(imagePath)
+		return nil, errors.New(errors.ErrCodeStorage, "failed to register image", err).
+			WithContext("image_name", spec.Name)
+	}
+
+	a.logger.Info("Image imported successfully",
+		zap.String("name", image.Name),
+		zap.String("path", image.Path),
+		zap.Bool("converted", format == entity.ImageFormatRaw),
+	)
+
+	return image, nil
+}
+
 // CreateDisk creates a new disk for a VM from a base image
 func (a *Adapter) CreateDisk(ctx context.Context, vmID string, baseImage string, sizeGB int) (string, error) {
 	a.logger.Info("Creating disk",
@@ -139,6 +277,37 @@ func (a *Adapter) CreateDisk(ctx context.Context, vmID string, baseImage string,
 	return diskPath, nil
 }
 
+// CreateBlankDisk creates an empty standalone disk for a VM
+func (a *Adapter) CreateBlankDisk(ctx context.Context, vmID string, sizeGB int) (string, error) {
+	a.logger.Info("Creating blank disk",
+		zap.String("vm_id", vmID),
+		zap.Int("size_gb", sizeGB),
+	)
+
+	diskPath := filepath.Join(a.imageCache, "disks", fmt.Sprintf("%s.qcow2", vmID))
+
+	if err := os.MkdirAll(filepath.Dir(diskPath), 0755); err != nil {
+		return "", errors.New(errors.ErrCodeStorage, "failed to create disks directory", err)
+	}
+	if _, err := os.Stat(diskPath); err == nil {
+		return "", errors.New(errors.ErrCodeConflict, "disk already exists", nil).
+			WithContext("vm_id", vmID)
+	}
+
+	cmd := exec.CommandContext(ctx, "qemu-img", "create",
+		"-f", "qcow2",
+		diskPath,
+		fmt.Sprintf("%dG", sizeGB),
+	)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", errors.New(errors.ErrCodeStorage, "failed to create disk", err).
+			WithContext("output", string(output))
+	}
+
+	return diskPath, nil
+}
+
 // DeleteDisk deletes a VM's disk
 func (a *Adapter) DeleteDisk(ctx context.Context, vmID string) error {
 	a.logger.Info("Deleting disk", zap.String("vm_id", vmID))
@@ -165,8 +334,140 @@ func (a *Adapter) GetDiskPath(ctx context.Context, vmID string) (string, error)
 	return diskPath, nil
 }
 
+// Ping checks that the disk directory exists and accepts new files
+func (a *Adapter) Ping(ctx context.Context) error {
+	diskDir := filepath.Join(a.imageCache, "disks")
+	if err := os.MkdirAll(diskDir, 0755); err != nil {
+		return fmt.Errorf("disk directory unavailable: %w", err)
+	}
+
+	probe, err := os.CreateTemp(diskDir, ".ping-*")
+	if err != nil {
+		return fmt.Errorf("disk directory not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// DiskUsage returns the bytes VM disks take on the host
+// Disks are sparse qcow2 files, so this is what their guests wrote rather than their size
+func (a *Adapter) DiskUsage(ctx context.Context) (int64, error) {
+	diskDir := filepath.Join(a.imageCache, "disks")
+	entries, err := os.ReadDir(diskDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.New(errors.ErrCodeStorage, "failed to list disks", err)
+	}
+
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".qcow2" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Deleted meanwhile
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			total += stat.Blocks * 512
+		} else {
+			total += info.Size()
+		}
+	}
+	return total, nil
+}
+
+// ExportDisk creates a flattened copy of a VM's disk without its backing image
+func (a *Adapter) ExportDisk(ctx context.Context, vmID string) (*service.DiskExport, error) {
+	a.logger.Info("Exporting disk", zap.String("vm_id", vmID))
+
+	diskPath, err := a.GetDiskPath(ctx, vmID)
+	if err != nil {
+		return nil, err
+	}
+
+	exportDir := filepath.Join(a.imageCache, "exports")
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		return nil, errors.New(errors.ErrCodeStorage, "failed to create exports directory", err)
+	}
+
+	exportPath := filepath.Join(exportDir, fmt.Sprintf("%s-%d.qcow2", vmID, time.Now().Unix()))
+
+	// Convert merges the backing chain into a standalone image
+	// -U allows reading a disk that a running VM holds open read-only
+	cmd := exec.CommandContext(ctx, "qemu-img", "convert",
+		"-U",
+		"-O", "qcow2",
+		diskPath,
+		exportPath,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(exportPath)
+		return nil, errors.New(errors.ErrCodeStorage, "failed to flatten disk", err).
+			WithContext("vm_id", vmID).
+			WithContext("output", string(output))
+	}
+
+	checksum, err := a.calculateChecksum(exportPath)
+	if err != nil {
+		_ = os.Remove(exportPath)
+		return nil, errors.New(errors.ErrCodeStorage, "failed to calculate checksum", err).
+			WithContext("path", exportPath)
+	}
+
+	file, err := os.Open(exportPath)
+	if err != nil {
+		_ = os.Remove(exportPath)
+		return nil, errors.New(errors.ErrCodeStorage, "failed to open exported disk", err).
+			WithContext("path", exportPath)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		_ = os.Remove(exportPath)
+		return nil, errors.New(errors.ErrCodeStorage, "failed to stat exported disk", err).
+			WithContext("path", exportPath)
+	}
+
+	return &service.DiskExport{
+		ReadCloser: &tempFile{File: file},
+		SizeBytes:  info.Size(),
+		SHA256:     checksum,
+	}, nil
+}
+
+// ImportDisk stores a standalone qcow2 disk for a VM
+func (a *Adapter) ImportDisk(ctx context.Context, vmID string, data io.Reader, expectedChecksum string) (string, error) {
+	a.logger.Info("Importing disk", zap.String("vm_id", vmID))
+	return a.storeDisk(vmID, data, expectedChecksum, false)
+}
+
+// RestoreDisk replaces a VM's disk with a standalone qcow2 disk
+// Backups come from outside the host, so they get the checks of imported disks;
+// the current disk is only replaced once they pass
+func (a *Adapter) RestoreDisk(ctx context.Context, vmID string, data io.Reader, expectedChecksum string) (string, error) {
+	a.logger.Info("Restoring disk", zap.String("vm_id", vmID))
+	return a.storeDisk(vmID, data, expectedChecksum, true)
+}
+
 // Helper methods
 
+// tempFile removes the underlying file once it is closed
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	if rmErr := os.Remove(f.Name()); rmErr != nil && err == nil {
+		err = rmErr
+	}
+	return err
+}
+
 func (a *Adapter) downloadImage(url, destPath string) error {
 	a.logger.Info("Downloading image", zap.String("url", url))
 
@@ -202,6 +503,88 @@ func (a *Adapter) downloadImage(url, destPath string) error {
 	return nil
 }
 
+// receiveUpload writes uploaded data to path and returns its size and SHA256
+func (a *Adapter) receiveUpload(path string, data io.Reader) (int64, string, error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(out, hash), data)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := out.Sync(); err != nil {
+		return 0, "", fmt.Errorf("failed to sync file: %w", err)
+	}
+
+	return written, fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+func (a *Adapter) convertToQCOW2(ctx context.Context, src, dest string) error {
+	cmd := exec.CommandContext(ctx, "qemu-img", "convert",
+		"-f", "raw",
+		"-O", "qcow2",
+		src,
+		dest,
+	)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(dest)
+		return fmt.Errorf("qemu-img convert failed: %w: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// storeDisk verifies a standalone qcow2 disk and moves it into place
+func (a *Adapter) storeDisk(vmID string, data io.Reader, expectedChecksum string, overwrite bool) (string, error) {
+	diskPath := filepath.Join(a.imageCache, "disks", fmt.Sprintf("%s.qcow2", vmID))
+
+	if err := os.MkdirAll(filepath.Dir(diskPath), 0755); err != nil {
+		return "", errors.New(errors.ErrCodeStorage, "failed to create disks directory", err)
+	}
+	if _, err := os.Stat(diskPath); err == nil && !overwrite {
+		return "", errors.New(errors.ErrCodeConflict, "disk already exists", nil).
+			WithContext("vm_id", vmID)
+	}
+
+	importPath := filepath.Join(filepath.Dir(diskPath), fmt.Sprintf(".%s.import", vmID))
+	defer os.Remove(importPath)
+
+	_, checksum, err := a.receiveUpload(importPath, data)
+	if err != nil {
+		return "", errors.New(errors.ErrCodeStorage, "failed to receive disk", err).
+			WithContext("vm_id", vmID)
+	}
+	if checksum != expectedChecksum {
+		return "", errors.New(errors.ErrCodeValidation, "disk checksum mismatch", nil).
+			WithContext("expected_sha256", expectedChecksum).
+			WithContext("actual_sha256", checksum)
+	}
+
+	format, err := detectImageFormat(importPath)
+	if err != nil || format != entity.ImageFormatQCOW2 {
+		return "", errors.New(errors.ErrCodeValidation, "disk is not a valid qcow2 image", err).
+			WithContext("vm_id", vmID)
+	}
+	if err := checkStandalone(importPath); err != nil {
+		return "", errors.New(errors.ErrCodeValidation, "disk is not a standalone qcow2 image", err).
+			WithContext("vm_id", vmID)
+	}
+
+	// Rename atomically replaces an existing disk
+	if err := os.Rename(importPath, diskPath); err != nil {
+		return "", errors.New(errors.ErrCodeStorage, "failed to store disk", err).
+			WithContext("path", diskPath)
+	}
+
+	return diskPath, nil
+}
+
 func (a *Adapter) calculateChecksum(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+func TestImportImageRejectsConcurrentUploadOfName(t *testing.T) {
+	ctx := context.Background()
+	adapter := NewAdapter(t.TempDir(), NewInMemoryImageRepository(), zap.NewNop())
+	spec := &service.ImageImportSpec{Name: "custom", Format: entity.ImageFormatRaw, SHA256: "unused"}
+
+	// The first upload blocks while receiving its data
+	data, writer := io.Pipe()
+	first := make(chan error, 1)
+	go func() {
+		_, err := adapter.ImportImage(ctx, spec, data)
+		first <- err
+	}()
+	_, err := writer.Write([]byte("first"))
+	require.NoError(t, err)
+
+	_, err = adapter.ImportImage(ctx, spec, strings.NewReader("second"))
+	var appErr *errors.AppError
+	require.True(t, stderrors.As(err, &appErr))
+	assert.Equal(t, errors.ErrCodeConflict, appErr.Code)
+
+	// The name is free again once the first upload is done
+	require.NoError(t, writer.Close())
+	require.Error(t, <-first, "the checksum does not match")
+	_, err = adapter.ImportImage(ctx, spec, strings.NewReader("third"))
+	require.True(t, stderrors.As(err, &appErr))
+	assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+}
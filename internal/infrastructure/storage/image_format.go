@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+const (
+	// qcow2HeaderSize is the size of the fixed part of a qcow2 v2 header
+	qcow2HeaderSize = 72
+	// rawSectorSize is the sector size raw disk images must be aligned to
+	rawSectorSize = 512
+	// qcow2V3FeaturesEnd is where the incompatible feature bits of a v3 header end
+	qcow2V3FeaturesEnd = 80
+	// qcow2IncompatDataFile marks a v3 image whose data lives in an external file
+	qcow2IncompatDataFile = 1 << 2
+)
+
+var qcow2Magic = []byte{'Q', 'F', 'I', 0xfb}
+
+// foreignImageMagics lists headers of image formats we do not accept.
+// A file starting with one of these is never treated as a raw disk.
+var foreignImageMagics = map[string][]byte{
+	"vmdk": []byte("KDMV"),
+	"vhdx": []byte("vhdxfile"),
+	"vpc":  []byte("conectix"),
+	"vdi":  []byte("<<< "),
+}
+
+// detectImageFormat inspects the image header to determine its format
+func detectImageFormat(path string) (entity.ImageFormat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Size() == 0 {
+		return "", fmt.Errorf("image is empty")
+	}
+
+	header := make([]byte, qcow2HeaderSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read image header: %w", err)
+	}
+	header = header[:n]
+
+	if bytes.HasPrefix(header, qcow2Magic) {
+		if n < qcow2HeaderSize {
+			return "", fmt.Errorf("truncated qcow2 header")
+		}
+		version := binary.BigEndian.Uint32(header[4:8])
+		if version != 2 && version != 3 {
+			return "", fmt.Errorf("unsupported qcow2 version %d", version)
+		}
+		return entity.ImageFormatQCOW2, nil
+	}
+
+	for name, magic := range foreignImageMagics {
+		if bytes.HasPrefix(header, magic) {
+			return "", fmt.Errorf("unsupported image format %s", name)
+		}
+	}
+
+	if info.Size()%rawSectorSize != 0 {
+		return "", fmt.Errorf("raw image size %d is not a multiple of %d bytes", info.Size(), rawSectorSize)
+	}
+
+	return entity.ImageFormatRaw, nil
+}
+
+// checkStandalone rejects qcow2 images that read another file through a
+// backing file or an external data file. The path of that file comes from the
+// image, so it could hand any host file to the VMs using the image.
+func checkStandalone(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header := make([]byte, qcow2V3FeaturesEnd)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("failed to read image header: %w", err)
+	}
+	header = header[:n]
+	if !bytes.HasPrefix(header, qcow2Magic) {
+		return nil
+	}
+	if n < qcow2HeaderSize {
+		return fmt.Errorf("truncated qcow2 header")
+	}
+
+	if binary.BigEndian.Uint64(header[8:16]) != 0 {
+		return fmt.Errorf("qcow2 image has a backing file")
+	}
+	if binary.BigEndian.Uint32(header[4:8]) >= 3 {
+		if n < qcow2V3FeaturesEnd {
+			return fmt.Errorf("truncated qcow2 v3 header")
+		}
+		if binary.BigEndian.Uint64(header[72:80])&qcow2IncompatDataFile != 0 {
+			return fmt.Errorf("qcow2 image has an external data file")
+		}
+	}
+	return nil
+}
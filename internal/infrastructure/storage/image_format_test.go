@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// qcow2Header returns a qcow2 header of the given version, padded to size
+func qcow2Header(version uint32, backingOffset uint64, incompat uint64, size int) []byte {
+	header := make([]byte, size)
+	copy(header, qcow2Magic)
+	binary.BigEndian.PutUint32(header[4:8], version)
+	binary.BigEndian.PutUint64(header[8:16], backingOffset)
+	if size >= qcow2V3FeaturesEnd {
+		binary.BigEndian.PutUint64(header[72:80], incompat)
+	}
+	return header
+}
+
+func writeImage(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "image")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func TestDetectImageFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    entity.ImageFormat
+		wantErr string
+	}{
+		{name: "qcow2 v2", data: qcow2Header(2, 0, 0, 512), want: entity.ImageFormatQCOW2},
+		{name: "qcow2 v3", data: qcow2Header(3, 0, 0, 512), want: entity.ImageFormatQCOW2},
+		{name: "qcow2 v4", data: qcow2Header(4, 0, 0, 512), wantErr: "unsupported qcow2 version 4"},
+		{name: "truncated qcow2", data: qcow2Header(3, 0, 0, 40), wantErr: "truncated qcow2 header"},
+		{name: "raw", data: make([]byte, 4096), want: entity.ImageFormatRaw},
+		{name: "unaligned raw", data: make([]byte, 1000), wantErr: "raw image size 1000 is not a multiple of 512 bytes"},
+		{name: "vmdk", data: append([]byte("KDMV"), make([]byte, 508)...), wantErr: "unsupported image format vmdk"},
+		{name: "empty", data: nil, wantErr: "image is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectImageFormat(writeImage(t, tt.data))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckStandalone(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "standalone v2", data: qcow2Header(2, 0, 0, 512)},
+		{name: "standalone v3", data: qcow2Header(3, 0, 0, 512)},
+		{name: "backing file", data: qcow2Header(3, 512, 0, 1024), wantErr: "qcow2 image has a backing file"},
+		{name: "data file", data: qcow2Header(3, 0, qcow2IncompatDataFile, 512), wantErr: "qcow2 image has an external data file"},
+		{name: "other incompatible features", data: qcow2Header(3, 0, 1<<1, 512)},
+		{name: "v2 ignores feature bits", data: qcow2Header(2, 0, qcow2IncompatDataFile, 512)},
+		{name: "truncated v3", data: qcow2Header(3, 0, 0, 76), wantErr: "truncated qcow2 v3 header"},
+		{name: "raw", data: make([]byte, 4096)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkStandalone(writeImage(t, tt.data))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
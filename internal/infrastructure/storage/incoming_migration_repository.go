@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// InMemoryIncomingMigrationRepository implements IncomingMigrationRepository using in-memory storage
+// Reservations of migrations in flight when the agent stops are not kept
+type InMemoryIncomingMigrationRepository struct {
+	migrations map[string]*entity.IncomingMigration
+	mu         sync.Mutex
+}
+
+// NewInMemoryIncomingMigrationRepository creates a new in-memory incoming migration repository
+func NewInMemoryIncomingMigrationRepository() *InMemoryIncomingMigrationRepository {
+	return &InMemoryIncomingMigrationRepository{
+		migrations: make(map[string]*entity.IncomingMigration),
+	}
+}
+
+// Save records a reservation; it fails when the VM already has one
+func (r *InMemoryIncomingMigrationRepository) Save(ctx context.Context, migration *entity.IncomingMigration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.migrations[migration.VMID]; ok {
+		return errors.New(errors.ErrCodeConflict, "migration already prepared", nil).
+			WithContext("vm_id", migration.VMID)
+	}
+	r.migrations[migration.VMID] = migration
+	return nil
+}
+
+// Take removes and returns the reservation of a VM
+func (r *InMemoryIncomingMigrationRepository) Take(ctx context.Context, vmID string) (*entity.IncomingMigration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	migration, ok := r.migrations[vmID]
+	if !ok {
+		return nil, errors.New(errors.ErrCodeNotFound, "no migration prepared for VM", nil).
+			WithContext("vm_id", vmID)
+	}
+	delete(r.migrations, vmID)
+	return migration, nil
+}
@@ -0,0 +1,30 @@
+package storage
+
+import "sync"
+
+// nameReservations tracks the names taken by work still in progress
+type nameReservations struct {
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+// reserve takes name and returns the function that frees it
+// ok is false when name is taken already; the caller must not wait for it
+func (r *nameReservations) reserve(name string) (release func(), ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		return nil, false
+	}
+	if r.names == nil {
+		r.names = make(map[string]bool)
+	}
+	r.names[name] = true
+
+	return func() {
+		r.mu.Lock()
+		delete(r.names, name)
+		r.mu.Unlock()
+	}, true
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// PersistentBackupRepository implements BackupRepository with file-based persistence
+type PersistentBackupRepository struct {
+	backups  map[string]*entity.Backup
+	mu       sync.RWMutex
+	filePath string
+}
+
+// NewPersistentBackupRepository creates a new persistent backup repository
+func NewPersistentBackupRepository(dataDir string) (*PersistentBackupRepository, error) {
+	// Ensure data directory exists
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	filePath := filepath.Join(dataDir, "backups.json")
+	repo := &PersistentBackupRepository{
+		backups:  make(map[string]*entity.Backup),
+		filePath: filePath,
+	}
+
+	// Load existing records from disk
+	if err := repo.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Save persists a backup record
+func (r *PersistentBackupRepository) Save(ctx context.Context, backup *entity.Backup) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.backups[backup.ID] = backup
+	return r.persist()
+}
+
+// FindByID retrieves a backup by ID
+func (r *PersistentBackupRepository) FindByID(ctx context.Context, id string) (*entity.Backup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	backup, ok := r.backups[id]
+	if !ok {
+		return nil, errors.New(errors.ErrCodeNotFound, "backup not found", nil).
+			WithContext("backup_id", id)
+	}
+
+	return backup, nil
+}
+
+// FindByVM retrieves all backups of a VM, newest first
+func (r *PersistentBackupRepository) FindByVM(ctx context.Context, vmID string) ([]*entity.Backup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	backups := make([]*entity.Backup, 0)
+	for _, backup := range r.backups {
+		if backup.VMID == vmID {
+			backups = append(backups, backup)
+		}
+	}
+
+	sortNewestFirst(backups)
+	return backups, nil
+}
+
+// FindAll retrieves all backups, newest first
+func (r *PersistentBackupRepository) FindAll(ctx context.Context) ([]*entity.Backup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	backups := make([]*entity.Backup, 0, len(r.backups))
+	for _, backup := range r.backups {
+		backups = append(backups, backup)
+	}
+
+	sortNewestFirst(backups)
+	return backups, nil
+}
+
+// Delete removes a backup record
+func (r *PersistentBackupRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.backups, id)
+	return r.persist()
+}
+
+// persist saves the current records to disk
+func (r *PersistentBackupRepository) persist() error {
+	data, err := json.MarshalIndent(r.backups, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to temp file first, then rename (atomic operation)
+	tempFile := r.filePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile, r.filePath)
+}
+
+// load reads the records from disk
+func (r *PersistentBackupRepository) load() error {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &r.backups)
+}
+
+func sortNewestFirst(backups []*entity.Backup) {
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+}
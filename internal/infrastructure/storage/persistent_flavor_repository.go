@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// PersistentFlavorRepository implements FlavorRepository
+// Configured flavors are kept in memory, those pushed by Ghost Core on disk
+type PersistentFlavorRepository struct {
+	configured map[string]*entity.Flavor
+	pushed     map[string]*entity.Flavor
+	mu         sync.RWMutex
+	filePath   string
+}
+
+// NewPersistentFlavorRepository creates a new persistent flavor repository
+func NewPersistentFlavorRepository(dataDir string, configured []*entity.Flavor) (*PersistentFlavorRepository, error) {
+	// Ensure data directory exists
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	repo := &PersistentFlavorRepository{
+		configured: make(map[string]*entity.Flavor, len(configured)),
+		pushed:     make(map[string]*entity.Flavor),
+		filePath:   filepath.Join(dataDir, "flavors.json"),
+	}
+	for _, f := range configured {
+		repo.configured[f.Name] = f
+	}
+
+	// Load flavors pushed before a restart
+	if err := repo.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// FindByName retrieves a flavor by name
+func (r *PersistentFlavorRepository) FindByName(ctx context.Context, name string) (*entity.Flavor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if f, ok := r.pushed[name]; ok {
+		return f, nil
+	}
+	if f, ok := r.configured[name]; ok {
+		return f, nil
+	}
+
+	return nil, errors.New(errors.ErrCodeNotFound, "flavor not found", nil).
+		WithContext("flavor", name)
+}
+
+// FindAll retrieves all flavors, ordered by name
+func (r *PersistentFlavorRepository) FindAll(ctx context.Context) ([]*entity.Flavor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	flavors := make([]*entity.Flavor, 0, len(r.configured)+len(r.pushed))
+	for _, f := range r.pushed {
+		flavors = append(flavors, f)
+	}
+	for name, f := range r.configured {
+		if _, ok := r.pushed[name]; !ok {
+			flavors = append(flavors, f)
+		}
+	}
+
+	sort.Slice(flavors, func(i, j int) bool {
+		return flavors[i].Name < flavors[j].Name
+	})
+	return flavors, nil
+}
+
+// ReplacePushed replaces the flavors pushed by Ghost Core
+func (r *PersistentFlavorRepository) ReplacePushed(ctx context.Context, flavors []*entity.Flavor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pushed := make(map[string]*entity.Flavor, len(flavors))
+	for _, f := range flavors {
+		pushed[f.Name] = f
+	}
+
+	previous := r.pushed
+	r.pushed = pushed
+	if err := r.persist(); err != nil {
+		r.pushed = previous
+		return err
+	}
+	return nil
+}
+
+// persist saves the pushed flavors to disk
+func (r *PersistentFlavorRepository) persist() error {
+	data, err := json.MarshalIndent(r.pushed, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to temp file first, then rename (atomic operation)
+	tempFile := r.filePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile, r.filePath)
+}
+
+// load reads the pushed flavors from disk
+func (r *PersistentFlavorRepository) load() error {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &r.pushed)
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// PersistentImageRepository implements ImageRepository with file-based persistence
+// Uploaded images are only known through this catalog, so it must survive restarts
+type PersistentImageRepository struct {
+	images   map[string]*entity.Image
+	mu       sync.RWMutex
+	filePath string
+}
+
+// NewPersistentImageRepository creates a new persistent image repository
+func NewPersistentImageRepository(dataDir string) (*PersistentImageRepository, error) {
+	// Ensure data directory exists
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	filePath := filepath.Join(dataDir, "images.json")
+	repo := &PersistentImageRepository{
+		images:   make(map[string]*entity.Image),
+		filePath: filePath,
+	}
+
+	// Load existing catalog from disk
+	if err := repo.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Get retrieves an image by name
+func (r *PersistentImageRepository) Get(ctx context.Context, name string) (*entity.Image, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	image, ok := r.images[name]
+	if !ok {
+		return nil, errors.New(errors.ErrCodeNotFound, "image not found", nil).
+			WithContext("image_name", name)
+	}
+
+	return image, nil
+}
+
+// Save persists an image
+func (r *PersistentImageRepository) Save(ctx context.Context, image *entity.Image) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.images[image.Name] = image
+	return r.persist()
+}
+
+// Exists checks if an image exists
+func (r *PersistentImageRepository) Exists(ctx context.Context, name string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.images[name]
+	return ok, nil
+}
+
+// Delete removes an image
+func (r *PersistentImageRepository) Delete(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.images, name)
+	return r.persist()
+}
+
+// persist saves the current catalog to disk
+func (r *PersistentImageRepository) persist() error {
+	data, err := json.MarshalIndent(r.images, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to temp file first, then rename (atomic operation)
+	tempFile := r.filePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile, r.filePath)
+}
+
+// load reads the catalog from disk
+func (r *PersistentImageRepository) load() error {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &r.images)
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// PersistentIPAllocationRepository implements IPAllocationRepository with file-based persistence
+type PersistentIPAllocationRepository struct {
+	allocations map[string]*entity.IPAllocation
+	mu          sync.RWMutex
+	filePath    string
+}
+
+// NewPersistentIPAllocationRepository creates a new persistent IP allocation repository
+func NewPersistentIPAllocationRepository(dataDir string) (*PersistentIPAllocationRepository, error) {
+	// Ensure data directory exists
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	filePath := filepath.Join(dataDir, "ip_allocations.json")
+	repo := &PersistentIPAllocationRepository{
+		allocations: make(map[string]*entity.IPAllocation),
+		filePath:    filePath,
+	}
+
+	// Load existing records from disk
+	if err := repo.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Save persists an allocation
+func (r *PersistentIPAllocationRepository) Save(ctx context.Context, alloc *entity.IPAllocation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.allocations[alloc.VMID] = alloc
+	return r.persist()
+}
+
+// FindByVM retrieves the allocation of a VM
+func (r *PersistentIPAllocationRepository) FindByVM(ctx context.Context, vmID string) (*entity.IPAllocation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alloc, ok := r.allocations[vmID]
+	if !ok {
+		return nil, errors.New(errors.ErrCodeNotFound, "IP allocation not found", nil).
+			WithContext("vm_id", vmID)
+	}
+
+	return alloc, nil
+}
+
+// FindAll retrieves all allocations
+func (r *PersistentIPAllocationRepository) FindAll(ctx context.Context) ([]*entity.IPAllocation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	allocations := make([]*entity.IPAllocation, 0, len(r.allocations))
+	for _, alloc := range r.allocations {
+		allocations = append(allocations, alloc)
+	}
+
+	return allocations, nil
+}
+
+// Delete removes the allocation of a VM
+func (r *PersistentIPAllocationRepository) Delete(ctx context.Context, vmID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.allocations, vmID)
+	return r.persist()
+}
+
+// persist saves the current records to disk
+func (r *PersistentIPAllocationRepository) persist() error {
+	data, err := json.MarshalIndent(r.allocations, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to temp file first, then rename (atomic operation)
+	tempFile := r.filePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile, r.filePath)
+}
+
+// load reads the records from disk
+func (r *PersistentIPAllocationRepository) load() error {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &r.allocations)
+}
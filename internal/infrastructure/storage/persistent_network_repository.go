@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// PersistentNetworkRepository implements NetworkRepository with file-based persistence
+type PersistentNetworkRepository struct {
+	networks map[string]*entity.Network
+	mu       sync.RWMutex
+	filePath string
+}
+
+// NewPersistentNetworkRepository creates a new persistent network repository
+func NewPersistentNetworkRepository(dataDir string) (*PersistentNetworkRepository, error) {
+	// Ensure data directory exists
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	filePath := filepath.Join(dataDir, "networks.json")
+	repo := &PersistentNetworkRepository{
+		networks: make(map[string]*entity.Network),
+		filePath: filePath,
+	}
+
+	// Load existing records from disk
+	if err := repo.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Save persists a network
+func (r *PersistentNetworkRepository) Save(ctx context.Context, network *entity.Network) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.networks[network.ID] = network
+	return r.persist()
+}
+
+// FindByID retrieves a network by ID
+func (r *PersistentNetworkRepository) FindByID(ctx context.Context, id string) (*entity.Network, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	network, ok := r.networks[id]
+	if !ok {
+		return nil, errors.New(errors.ErrCodeNotFound, "network not found", nil).
+			WithContext("network_id", id)
+	}
+
+	return network, nil
+}
+
+// FindByName retrieves a network by name
+func (r *PersistentNetworkRepository) FindByName(ctx context.Context, name string) (*entity.Network, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, network := range r.networks {
+		if network.Name == name {
+			return network, nil
+		}
+	}
+
+	return nil, errors.New(errors.ErrCodeNotFound, "network not found", nil).
+		WithContext("network_name", name)
+}
+
+// FindAll retrieves all networks, ordered by name
+func (r *PersistentNetworkRepository) FindAll(ctx context.Context) ([]*entity.Network, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	networks := make([]*entity.Network, 0, len(r.networks))
+	for _, network := range r.networks {
+		networks = append(networks, network)
+	}
+
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].Name < networks[j].Name
+	})
+	return networks, nil
+}
+
+// Delete removes a network
+func (r *PersistentNetworkRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.networks, id)
+	return r.persist()
+}
+
+// persist saves the current records to disk
+func (r *PersistentNetworkRepository) persist() error {
+	data, err := json.MarshalIndent(r.networks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to temp file first, then rename (atomic operation)
+	tempFile := r.filePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile, r.filePath)
+}
+
+// load reads the records from disk
+func (r *PersistentNetworkRepository) load() error {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &r.networks)
+}
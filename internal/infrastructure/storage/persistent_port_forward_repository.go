@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// PersistentPortForwardRepository implements PortForwardRepository with file-based persistence
+type PersistentPortForwardRepository struct {
+	forwards map[string]*entity.PortForward
+	mu       sync.RWMutex
+	filePath string
+}
+
+// NewPersistentPortForwardRepository creates a new persistent port forward repository
+func NewPersistentPortForwardRepository(dataDir string) (*PersistentPortForwardRepository, error) {
+	// Ensure data directory exists
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	filePath := filepath.Join(dataDir, "port_forwards.json")
+	repo := &PersistentPortForwardRepository{
+		forwards: make(map[string]*entity.PortForward),
+		filePath: filePath,
+	}
+
+	// Load existing records from disk
+	if err := repo.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Save persists a port forward
+func (r *PersistentPortForwardRepository) Save(ctx context.Context, fwd *entity.PortForward) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.forwards[fwd.ID] = fwd
+	return r.persist()
+}
+
+// FindByID retrieves a port forward by ID
+func (r *PersistentPortForwardRepository) FindByID(ctx context.Context, id string) (*entity.PortForward, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fwd, ok := r.forwards[id]
+	if !ok {
+		return nil, errors.New(errors.ErrCodeNotFound, "port forward not found", nil).
+			WithContext("port_forward_id", id)
+	}
+
+	return fwd, nil
+}
+
+// FindByVM retrieves all port forwards of a VM
+func (r *PersistentPortForwardRepository) FindByVM(ctx context.Context, vmID string) ([]*entity.PortForward, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	forwards := make([]*entity.PortForward, 0)
+	for _, fwd := range r.forwards {
+		if fwd.VMID == vmID {
+			forwards = append(forwards, fwd)
+		}
+	}
+
+	sortByHostPort(forwards)
+	return forwards, nil
+}
+
+// FindAll retrieves all port forwards, ordered by host port
+func (r *PersistentPortForwardRepository) FindAll(ctx context.Context) ([]*entity.PortForward, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	forwards := make([]*entity.PortForward, 0, len(r.forwards))
+	for _, fwd := range r.forwards {
+		forwards = append(forwards, fwd)
+	}
+
+	sortByHostPort(forwards)
+	return forwards, nil
+}
+
+// Delete removes a port forward
+func (r *PersistentPortForwardRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.forwards, id)
+	return r.persist()
+}
+
+// persist saves the current records to disk
+func (r *PersistentPortForwardRepository) persist() error {
+	data, err := json.MarshalIndent(r.forwards, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to temp file first, then rename (atomic operation)
+	tempFile := r.filePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile, r.filePath)
+}
+
+// load reads the records from disk
+func (r *PersistentPortForwardRepository) load() error {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &r.forwards)
+}
+
+func sortByHostPort(forwards []*entity.PortForward) {
+	sort.Slice(forwards, func(i, j int) bool {
+		if forwards[i].HostPort != forwards[j].HostPort {
+			return forwards[i].HostPort < forwards[j].HostPort
+		}
+		return forwards[i].Protocol < forwards[j].Protocol
+	})
+}
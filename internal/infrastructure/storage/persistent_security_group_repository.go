@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// PersistentSecurityGroupRepository implements SecurityGroupRepository with file-based persistence
+type PersistentSecurityGroupRepository struct {
+	groups   map[string]*entity.SecurityGroup
+	mu       sync.RWMutex
+	filePath string
+}
+
+// NewPersistentSecurityGroupRepository creates a new persistent security group repository
+func NewPersistentSecurityGroupRepository(dataDir string) (*PersistentSecurityGroupRepository, error) {
+	// Ensure data directory exists
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	filePath := filepath.Join(dataDir, "security_groups.json")
+	repo := &PersistentSecurityGroupRepository{
+		groups:   make(map[string]*entity.SecurityGroup),
+		filePath: filePath,
+	}
+
+	// Load existing records from disk
+	if err := repo.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Save persists a security group
+func (r *PersistentSecurityGroupRepository) Save(ctx context.Context, sg *entity.SecurityGroup) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.groups[sg.ID] = sg
+	return r.persist()
+}
+
+// FindByID retrieves a security group by ID
+func (r *PersistentSecurityGroupRepository) FindByID(ctx context.Context, id string) (*entity.SecurityGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sg, ok := r.groups[id]
+	if !ok {
+		return nil, errors.New(errors.ErrCodeNotFound, "security group not found", nil).
+			WithContext("security_group_id", id)
+	}
+
+	return sg, nil
+}
+
+// FindByName retrieves a security group by name
+func (r *PersistentSecurityGroupRepository) FindByName(ctx context.Context, name string) (*entity.SecurityGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, sg := range r.groups {
+		if sg.Name == name {
+			return sg, nil
+		}
+	}
+
+	return nil, errors.New(errors.ErrCodeNotFound, "security group not found", nil).
+		WithContext("security_group_name", name)
+}
+
+// FindAll retrieves all security groups, ordered by name
+func (r *PersistentSecurityGroupRepository) FindAll(ctx context.Context) ([]*entity.SecurityGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groups := make([]*entity.SecurityGroup, 0, len(r.groups))
+	for _, sg := range r.groups {
+		groups = append(groups, sg)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
+
+// Delete removes a security group
+func (r *PersistentSecurityGroupRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.groups, id)
+	return r.persist()
+}
+
+// persist saves the current records to disk
+func (r *PersistentSecurityGroupRepository) persist() error {
+	data, err := json.MarshalIndent(r.groups, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to temp file first, then rename (atomic operation)
+	tempFile := r.filePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile, r.filePath)
+}
+
+// load reads the records from disk
+func (r *PersistentSecurityGroupRepository) load() error {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &r.groups)
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+func TestPersistentVMRepositoryUpdate(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo, err := NewPersistentVMRepository(dir)
+	require.NoError(t, err)
+
+	vm := &entity.VM{ID: "vm-1", Name: "vm-1", Expiry: &entity.Expiry{At: time.Now()}}
+	require.NoError(t, repo.Save(ctx, vm))
+
+	updated, err := repo.Update(ctx, "vm-1", func(vm *entity.VM) {
+		vm.Expiry.Warned = true
+	})
+	require.NoError(t, err)
+	assert.True(t, updated.Expiry.Warned)
+	assert.False(t, vm.Expiry.Warned, "the VM handed out before must not change")
+
+	reopened, err := NewPersistentVMRepository(dir)
+	require.NoError(t, err)
+	stored, err := reopened.FindByID(ctx, "vm-1")
+	require.NoError(t, err)
+	assert.True(t, stored.Expiry.Warned)
+
+	// A VM deleted in the meantime stays deleted
+	require.NoError(t, repo.Delete(ctx, "vm-1"))
+	_, err = repo.Update(ctx, "vm-1", func(vm *entity.VM) {
+		vm.Expiry.Warned = false
+	})
+	var appErr *errors.AppError
+	require.True(t, stderrors.As(err, &appErr))
+	assert.Equal(t, errors.ErrCodeNotFound, appErr.Code)
+	exists, err := repo.Exists(ctx, "vm-1")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
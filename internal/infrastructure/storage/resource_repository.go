@@ -8,14 +8,19 @@ import (
 	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
 )
 
+const bytesPerGB = 1 << 30
+
 // InMemoryResourceRepository implements ResourceRepository using in-memory storage
 type InMemoryResourceRepository struct {
 	resource *entity.Resource
+	disks    *Adapter // Measures disk usage under thin provisioning, nil otherwise
 	mu       sync.RWMutex
 }
 
 // NewInMemoryResourceRepository creates a new in-memory resource repository
-func NewInMemoryResourceRepository(reservedCPU, reservedRAMGB, reservedDiskGB int) *InMemoryResourceRepository {
+// Available resources are what is left after the reservations, scaled by the
+// overcommit ratios; with disks set, the space VM disks use is also measured
+func NewInMemoryResourceRepository(reservedCPU, reservedRAMGB, reservedDiskGB int, overcommit entity.Overcommit, disks *Adapter) *InMemoryResourceRepository {
 	totalCPU := runtime.NumCPU()
 	// TODO: Get actual total RAM and disk from system
 	totalRAMGB := 32   // Placeholder
@@ -23,16 +28,19 @@ func NewInMemoryResourceRepository(reservedCPU, reservedRAMGB, reservedDiskGB in
 
 	return &InMemoryResourceRepository{
 		resource: &entity.Resource{
-			TotalCPU:       totalCPU,
-			AvailableCPU:   totalCPU - reservedCPU,
-			ReservedCPU:    reservedCPU,
-			TotalRAMGB:     totalRAMGB,
-			AvailableRAMGB: totalRAMGB - reservedRAMGB,
-			ReservedRAMGB:  reservedRAMGB,
-			TotalDiskGB:    totalDiskGB,
-			AvailableDiskGB: totalDiskGB - reservedDiskGB,
-			ReservedDiskGB: reservedDiskGB,
+			TotalCPU:        totalCPU,
+			AvailableCPU:    entity.Allocatable(totalCPU-reservedCPU, overcommit.CPU),
+			ReservedCPU:     reservedCPU,
+			TotalRAMGB:      totalRAMGB,
+			AvailableRAMGB:  entity.Allocatable(totalRAMGB-reservedRAMGB, overcommit.RAM),
+			ReservedRAMGB:   reservedRAMGB,
+			TotalDiskGB:     totalDiskGB,
+			AvailableDiskGB: entity.Allocatable(totalDiskGB-reservedDiskGB, overcommit.Disk),
+			ReservedDiskGB:  reservedDiskGB,
+			Overcommit:      overcommit,
+			ThinProvisioned: disks != nil,
 		},
+		disks: disks,
 	}
 }
 
@@ -43,6 +51,16 @@ func (r *InMemoryResourceRepository) GetAvailable(ctx context.Context) (*entity.
 
 	// Return a copy to prevent external modification
 	resourceCopy := *r.resource
+
+	// Disk sizes are allocated against the overcommitted capacity; thin disks
+	// grow as guests write, so the physical space left is measured every time
+	if r.disks != nil {
+		used, err := r.disks.DiskUsage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resourceCopy.FreeDiskGB = resourceCopy.TotalDiskGB - resourceCopy.ReservedDiskGB - int((used+bytesPerGB-1)/bytesPerGB)
+	}
 	return &resourceCopy, nil
 }
 
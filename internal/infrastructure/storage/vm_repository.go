@@ -30,6 +30,23 @@ func (r *InMemoryVMRepository) Save(ctx context.Context, vm *entity.VM) error {
 	return nil
 }
 
+// Update changes a copy of a stored VM and saves it in one step
+func (r *InMemoryVMRepository) Update(ctx context.Context, id string, update func(vm *entity.VM)) (*entity.VM, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vm, ok := r.vms[id]
+	if !ok {
+		return nil, errors.New(errors.ErrCodeNotFound, "VM not found", nil).
+			WithContext("vm_id", id)
+	}
+
+	updated := vm.Clone()
+	update(updated)
+	r.vms[id] = updated
+	return updated, nil
+}
+
 // FindByID retrieves a VM by ID
 func (r *InMemoryVMRepository) FindByID(ctx context.Context, id string) (*entity.VM, error) {
 	r.mu.RLock()
@@ -0,0 +1,151 @@
+package tailscale
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IPN bus options: send the current state first, leave private keys out of
+// network maps and coalesce bursts of notifications
+const watchMask = 2 | 16 | 256
+
+// localClient talks to tailscaled's local API over its unix socket
+type localClient struct {
+	socket string
+	http   *http.Client
+}
+
+type statusJSON struct {
+	BackendState string
+	TailscaleIPs []string
+	Self         *struct {
+		HostName      string
+		DNSName       string
+		PrimaryRoutes []string
+	}
+	Health         []string
+	CurrentTailnet *struct {
+		Name string
+	}
+}
+
+type prefsJSON struct {
+	AdvertiseRoutes []string
+}
+
+// newLocalClient creates a client for the local API listening on socket
+func newLocalClient(socket string) *localClient {
+	var dialer net.Dialer
+	return &localClient{
+		socket: socket,
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+// Status returns tailscaled's view of this host, without peers
+func (c *localClient) Status(ctx context.Context) (*statusJSON, error) {
+	var status statusJSON
+	if err := c.do(ctx, http.MethodGet, "/localapi/v0/status?peers=false", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// AdvertisedRoutes returns the subnet routes in the node's preferences
+func (c *localClient) AdvertisedRoutes(ctx context.Context) ([]string, error) {
+	var prefs prefsJSON
+	if err := c.do(ctx, http.MethodGet, "/localapi/v0/prefs", nil, &prefs); err != nil {
+		return nil, err
+	}
+	return prefs.AdvertiseRoutes, nil
+}
+
+// SetAdvertisedRoutes replaces the subnet routes in the node's preferences
+func (c *localClient) SetAdvertisedRoutes(ctx context.Context, routes []string) error {
+	body := map[string]any{
+		"AdvertiseRoutes":    routes,
+		"AdvertiseRoutesSet": true,
+	}
+	return c.do(ctx, http.MethodPatch, "/localapi/v0/prefs", body, nil)
+}
+
+// WatchIPNBus calls notify for every notification tailscaled publishes until
+// ctx is done or the stream breaks
+func (c *localClient) WatchIPNBus(ctx context.Context, notify func()) error {
+	resp, err := c.request(ctx, http.MethodGet, fmt.Sprintf("/localapi/v0/watch-ipn-bus?mask=%d", watchMask), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(resp.Body))
+	for {
+		var msg json.RawMessage
+		if err := dec.Decode(&msg); err != nil {
+			return fmt.Errorf("IPN bus closed: %w", err)
+		}
+		notify()
+	}
+}
+
+func (c *localClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	resp, err := c.request(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response from tailscaled: %w", err)
+	}
+	return nil
+}
+
+// request sends a local API request and checks its status
+func (c *localClient) request(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	// The host is ignored by tailscaled, but must be set
+	req, err := http.NewRequestWithContext(ctx, method, "http://local-tailscaled.sock"+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Sec-Tailscale", "localapi")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("tailscaled unreachable at %s: %w", c.socket, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("tailscaled %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
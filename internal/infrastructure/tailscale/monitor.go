@@ -0,0 +1,251 @@
+package tailscale
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Monitor implements TailnetService on top of tailscaled's local API
+// The status is refreshed on every IPN bus notification and every pollInterval,
+// so changes are picked up even when the bus is unavailable
+type Monitor struct {
+	client       *localClient
+	pollInterval time.Duration
+	refresh      chan struct{}
+	logger       *zap.Logger
+
+	mu       sync.Mutex
+	status   *entity.TailnetStatus
+	err      error
+	watchers map[chan *entity.TailnetStatus]struct{}
+}
+
+// NewMonitor creates a monitor for the tailscaled listening on socket
+func NewMonitor(socket string, pollInterval time.Duration, logger *zap.Logger) *Monitor {
+	return &Monitor{
+		client:       newLocalClient(socket),
+		pollInterval: pollInterval,
+		refresh:      make(chan struct{}, 1),
+		logger:       logger,
+		watchers:     make(map[chan *entity.TailnetStatus]struct{}),
+	}
+}
+
+// Run keeps the status up to date until ctx is done
+func (m *Monitor) Run(ctx context.Context) {
+	go m.watchBus(ctx)
+
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		m.update(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.refresh:
+		}
+	}
+}
+
+// Status returns the last known status
+func (m *Monitor) Status(ctx context.Context) (*entity.TailnetStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return nil, errors.New(errors.ErrCodeNetwork, "tailnet status unavailable", m.err)
+	}
+	if m.status == nil {
+		return nil, errors.New(errors.ErrCodeInvalidState, "tailnet status not known yet", nil)
+	}
+	return m.status, nil
+}
+
+// Watch returns the current status, then every change of state or addresses,
+// until ctx is done
+// Slow receivers only get the latest status
+func (m *Monitor) Watch(ctx context.Context) <-chan *entity.TailnetStatus {
+	ch := make(chan *entity.TailnetStatus, 1)
+
+	m.mu.Lock()
+	if m.status != nil {
+		ch <- m.status
+	}
+	m.watchers[ch] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		delete(m.watchers, ch)
+		m.mu.Unlock()
+	}()
+
+	return ch
+}
+
+// WaitUp blocks until the tailnet is up or ctx is done
+func (m *Monitor) WaitUp(ctx context.Context) (*entity.TailnetStatus, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changes := m.Watch(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, errors.New(errors.ErrCodeTimeout, "tailnet is not up", ctx.Err())
+		case status := <-changes:
+			if status.Up() {
+				return status, nil
+			}
+		}
+	}
+}
+
+// AdvertiseRoutes offers subnets to the tailnet through this host,
+// keeping routes advertised by others
+// Headscale and Tailscale only route them once they are approved
+func (m *Monitor) AdvertiseRoutes(ctx context.Context, routes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	current, err := m.client.AdvertisedRoutes(ctx)
+	if err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to read advertised routes", err)
+	}
+
+	merged := slices.Clone(current)
+	var added []string
+	for _, route := range routes {
+		prefix, err := netip.ParsePrefix(route)
+		if err != nil {
+			return errors.New(errors.ErrCodeValidation, "invalid route", err).
+				WithContext("route", route)
+		}
+		route = prefix.Masked().String()
+		if !slices.Contains(merged, route) {
+			merged = append(merged, route)
+			added = append(added, route)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	if err := m.client.SetAdvertisedRoutes(ctx, merged); err != nil {
+		return errors.New(errors.ErrCodeNetwork, "failed to advertise routes", err).
+			WithContext("routes", strings.Join(added, ","))
+	}
+
+	m.logger.Info("Advertising routes on the tailnet", zap.Strings("routes", added))
+	return nil
+}
+
+// watchBus triggers a refresh for every IPN bus notification, reconnecting
+// after pollInterval when the stream breaks
+func (m *Monitor) watchBus(ctx context.Context) {
+	for {
+		err := m.client.WatchIPNBus(ctx, func() {
+			select {
+			case m.refresh <- struct{}{}:
+			default:
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		m.logger.Debug("IPN bus watch ended, relying on polling", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.pollInterval):
+		}
+	}
+}
+
+// update fetches the status and tells watchers when it changed
+// An unreachable tailscaled counts as a tailnet that is down
+func (m *Monitor) update(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	status := &entity.TailnetStatus{State: entity.TailnetStateNoState, UpdatedAt: time.Now()}
+	raw, err := m.client.Status(ctx)
+	if err == nil {
+		status = toTailnetStatus(raw)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prev, prevErr := m.status, m.err
+	m.status, m.err = status, err
+
+	if err != nil && prevErr == nil {
+		m.logger.Warn("Failed to query tailscaled", zap.Error(err))
+	}
+	if !changed(prev, status) {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("state", string(status.State)),
+		zap.Strings("ips", status.IPs),
+		zap.Strings("health", status.Health),
+	}
+	if status.Up() {
+		m.logger.Info("Tailnet up", fields...)
+	} else {
+		m.logger.Warn("Tailnet down", fields...)
+	}
+
+	for ch := range m.watchers {
+		// Replace a status the receiver has not picked up yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- status
+	}
+}
+
+// changed reports whether the state, addresses or routes of the host changed
+func changed(prev, cur *entity.TailnetStatus) bool {
+	return prev == nil ||
+		prev.State != cur.State ||
+		!slices.Equal(prev.IPs, cur.IPs) ||
+		!slices.Equal(prev.Routes, cur.Routes)
+}
+
+func toTailnetStatus(raw *statusJSON) *entity.TailnetStatus {
+	status := &entity.TailnetStatus{
+		State:     entity.TailnetState(raw.BackendState),
+		IPs:       raw.TailscaleIPs,
+		Health:    raw.Health,
+		UpdatedAt: time.Now(),
+	}
+	if raw.Self != nil {
+		status.HostName = raw.Self.HostName
+		status.DNSName = strings.TrimSuffix(raw.Self.DNSName, ".")
+		status.Routes = raw.Self.PrimaryRoutes
+	}
+	if raw.CurrentTailnet != nil {
+		status.Tailnet = raw.CurrentTailnet.Name
+	}
+	return status
+}
@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Services reported besides the agent as a whole, which is "" and the
+// AgentService name
+const (
+	ServiceLibvirt = "libvirt"
+	ServiceStorage = "storage"
+	ServiceCore    = "core"
+)
+
+// agentService is the full name of AgentService
+const agentService = "agentpb.AgentService"
+
+// How long one probe may take
+const checkTimeout = 10 * time.Second
+
+// Check probes one subsystem, nil means it serves
+type Check func(ctx context.Context) error
+
+type subsystem struct {
+	name     string
+	check    Check
+	critical bool
+}
+
+// Reporter keeps the gRPC health service up to date with the agent's subsystems
+// The agent as a whole serves while its critical subsystems do
+type Reporter struct {
+	server     *grpchealth.Server
+	interval   time.Duration
+	subsystems []subsystem
+	logger     *zap.Logger
+	failed     map[string]string // Error of each failing subsystem, owned by Run
+}
+
+// NewReporter creates a reporter probing every interval
+func NewReporter(interval time.Duration, logger *zap.Logger) *Reporter {
+	server := grpchealth.NewServer()
+	server.SetServingStatus("", healthpb.HealthCheckResponse_UNKNOWN)
+	server.SetServingStatus(agentService, healthpb.HealthCheckResponse_UNKNOWN)
+
+	return &Reporter{
+		server:   server,
+		interval: interval,
+		logger:   logger,
+		failed:   make(map[string]string),
+	}
+}
+
+// AddCheck reports the subsystem name as the result of check
+// A failing critical subsystem makes the whole agent NOT_SERVING
+// Must be called before Run
+func (r *Reporter) AddCheck(name string, check Check, critical bool) {
+	r.subsystems = append(r.subsystems, subsystem{name: name, check: check, critical: critical})
+	r.server.SetServingStatus(name, healthpb.HealthCheckResponse_UNKNOWN)
+}
+
+// Server returns the health service to register with the gRPC server
+func (r *Reporter) Server() healthpb.HealthServer {
+	return r.server
+}
+
+// Run probes the subsystems until ctx is done
+func (r *Reporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown reports every service as NOT_SERVING from now on, so clients
+// watching the agent move away before it stops
+func (r *Reporter) Shutdown() {
+	r.server.Shutdown()
+}
+
+// probe runs all checks at once and updates the statuses
+func (r *Reporter) probe(ctx context.Context) {
+	errs := make([]error, len(r.subsystems))
+
+	var wg sync.WaitGroup
+	for i, sub := range r.subsystems {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			errs[i] = sub.check(checkCtx)
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	overall := healthpb.HealthCheckResponse_SERVING
+	for i, sub := range r.subsystems {
+		status := healthpb.HealthCheckResponse_SERVING
+		if errs[i] != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			if sub.critical {
+				overall = healthpb.HealthCheckResponse_NOT_SERVING
+			}
+		}
+		r.server.SetServingStatus(sub.name, status)
+		r.logChange(sub.name, errs[i])
+	}
+	r.server.SetServingStatus("", overall)
+	r.server.SetServingStatus(agentService, overall)
+}
+
+// logChange logs when a subsystem starts or stops failing
+func (r *Reporter) logChange(name string, err error) {
+	prev, failing := r.failed[name]
+	switch {
+	case err != nil && (!failing || prev != err.Error()):
+		r.failed[name] = err.Error()
+		r.logger.Warn("Subsystem unhealthy", zap.String("subsystem", name), zap.Error(err))
+	case err == nil && failing:
+		delete(r.failed, name)
+		r.logger.Info("Subsystem healthy again", zap.String("subsystem", name))
+	}
+}
@@ -0,0 +1,166 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// Longest request summary kept in the audit log
+const maxSummaryLen = 1024
+
+// Request fields left out of summaries besides payloads, as they may hold secrets
+var redactedFields = map[protoreflect.Name]bool{
+	"env": true,
+}
+
+// Auditor records every mutating AgentService call in the audit log
+// Chained after the Authorizer, it sees the caller's identity
+type Auditor struct {
+	auditLog service.AuditLog
+	logger   *zap.Logger
+}
+
+// NewAuditor creates an auditor
+func NewAuditor(auditLog service.AuditLog, logger *zap.Logger) *Auditor {
+	return &Auditor{
+		auditLog: auditLog,
+		logger:   logger,
+	}
+}
+
+// UnaryInterceptor audits unary RPCs
+func (a *Auditor) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !mutating(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		a.record(ctx, info.FullMethod, req, resp, start, err)
+		return resp, err
+	}
+}
+
+// StreamInterceptor audits streaming RPCs, summarizing their first message
+func (a *Auditor) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !mutating(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		start := time.Now()
+		stream := &recordingStream{ServerStream: ss}
+		err := handler(srv, stream)
+		a.record(ss.Context(), info.FullMethod, stream.first, nil, start, err)
+		return err
+	}
+}
+
+func (a *Auditor) record(ctx context.Context, method string, req, resp any, start time.Time, err error) {
+	id, _ := IdentityFromContext(ctx)
+	event := newAuditEvent(ctx, method, id, req, start)
+	event.Duration = time.Since(start)
+	event.Result = entity.AuditResultSuccess
+	if err != nil {
+		event.Result = entity.AuditResultFailure
+		event.Error = status.Convert(err).Message()
+	}
+	// Created VMs are only known from the response
+	if r, ok := resp.(vmRequest); ok && event.VMID == "" {
+		event.VMID = r.GetVmId()
+	}
+
+	if err := a.auditLog.Record(ctx, event); err != nil {
+		a.logger.Error("Failed to record audit event", zap.String("method", method), zap.Error(err))
+	}
+}
+
+// newAuditEvent describes a call of method by id, nil when unauthenticated
+func newAuditEvent(ctx context.Context, method string, id *entity.Identity, req any, at time.Time) *entity.AuditEvent {
+	event := &entity.AuditEvent{
+		Time:    at,
+		Method:  method,
+		Summary: summarize(req),
+	}
+	if id != nil {
+		event.Subject = id.Subject
+		event.Role = id.Role
+		event.Tenant = id.Tenant
+		event.AuthMethod = id.Method
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		event.Peer = p.Addr.String()
+	}
+	if r, ok := req.(vmRequest); ok {
+		event.VMID = r.GetVmId()
+	}
+	return event
+}
+
+// summarize renders a request as JSON without bytes and redacted fields
+func summarize(req any) string {
+	msg, ok := req.(proto.Message)
+	if !ok || msg == nil {
+		return ""
+	}
+	msg = proto.Clone(msg)
+	strip(msg.ProtoReflect())
+
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return ""
+	}
+	if len(data) > maxSummaryLen {
+		return string(data[:maxSummaryLen]) + "..."
+	}
+	return string(data)
+}
+
+// strip clears payloads and redacted fields of m and its nested messages
+func strip(m protoreflect.Message) {
+	var cleared []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Kind() == protoreflect.BytesKind || redactedFields[fd.Name()]:
+			cleared = append(cleared, fd)
+		case fd.Kind() == protoreflect.MessageKind && fd.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				strip(v.List().Get(i).Message())
+			}
+		case fd.Kind() == protoreflect.MessageKind && !fd.IsMap():
+			strip(v.Message())
+		}
+		return true
+	})
+	for _, fd := range cleared {
+		m.Clear(fd)
+	}
+}
+
+// recordingStream keeps the first message received for the audit log
+type recordingStream struct {
+	grpc.ServerStream
+	first any
+}
+
+func (s *recordingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.first == nil {
+		s.first = m
+	}
+	return nil
+}
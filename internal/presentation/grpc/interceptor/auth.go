@@ -0,0 +1,250 @@
+package interceptor
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/repository"
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/auth"
+)
+
+// RoleMap assigns roles to callers that authenticate without a token
+// Keys are lower case, as the configuration loader lowers them
+type RoleMap struct {
+	Certs        map[string]entity.Role // By client certificate common name
+	LocalUsers   map[string]entity.Role // By user name of unix socket peers
+	LocalDefault entity.Role            // Other unix socket peers, empty denies them
+}
+
+// Authorizer authenticates AgentService callers and checks each call against
+// the caller's role and tenant
+type Authorizer struct {
+	tokens      service.TokenVerifier
+	roles       RoleMap
+	vmRepo      repository.VMRepository
+	networkRepo repository.NetworkRepository
+	forwardRepo repository.PortForwardRepository
+	backupRepo  repository.BackupRepository
+	auditLog    service.AuditLog
+	logger      *zap.Logger
+}
+
+type identityKey struct{}
+
+// NewAuthorizer creates an authorizer
+// A nil tokens verifier rejects bearer tokens
+func NewAuthorizer(
+	tokens service.TokenVerifier,
+	roles RoleMap,
+	vmRepo repository.VMRepository,
+	networkRepo repository.NetworkRepository,
+	forwardRepo repository.PortForwardRepository,
+	backupRepo repository.BackupRepository,
+	auditLog service.AuditLog,
+	logger *zap.Logger,
+) *Authorizer {
+	return &Authorizer{
+		tokens:      tokens,
+		roles:       roles,
+		vmRepo:      vmRepo,
+		networkRepo: networkRepo,
+		forwardRepo: forwardRepo,
+		backupRepo:  backupRepo,
+		auditLog:    auditLog,
+		logger:      logger,
+	}
+}
+
+// IdentityFromContext returns the caller of the RPC handling ctx
+func IdentityFromContext(ctx context.Context) (*entity.Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*entity.Identity)
+	return id, ok
+}
+
+// UnaryInterceptor authorizes unary RPCs
+func (a *Authorizer) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if public(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		id, err := a.authorize(ctx, info.FullMethod, req)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := handler(context.WithValue(ctx, identityKey{}, id), req)
+		if err != nil || id.Tenant == "" {
+			return resp, err
+		}
+		return a.filterResponse(ctx, id, resp)
+	}
+}
+
+// StreamInterceptor authorizes streaming RPCs
+// The tenant of a caller is checked against the first message of the stream
+func (a *Authorizer) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if public(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx := ss.Context()
+		id, err := a.authorize(ctx, info.FullMethod, nil)
+		if err != nil {
+			return err
+		}
+
+		stream := &authorizedStream{ServerStream: ss, ctx: context.WithValue(ctx, identityKey{}, id)}
+		if id.Tenant != "" {
+			stream.authorizeFirst = func(msg any) error {
+				return a.authorizeMessage(ctx, info.FullMethod, id, msg)
+			}
+		}
+		return handler(srv, stream)
+	}
+}
+
+// authorize authenticates the caller and checks its role, and its tenant when
+// req is given
+func (a *Authorizer) authorize(ctx context.Context, method string, req any) (*entity.Identity, error) {
+	id, err := a.authenticate(ctx)
+	if err != nil {
+		a.deny(ctx, method, nil, req, err.Error())
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if !allowed(id.Role, method) {
+		a.deny(ctx, method, id, req, "role not permitted")
+		return nil, status.Errorf(codes.PermissionDenied, "role %s may not call %s", id.Role, method)
+	}
+
+	if req != nil && id.Tenant != "" {
+		if err := a.authorizeMessage(ctx, method, id, req); err != nil {
+			return nil, err
+		}
+	}
+	return id, nil
+}
+
+// authorizeMessage checks a request of a caller limited to a tenant
+func (a *Authorizer) authorizeMessage(ctx context.Context, method string, id *entity.Identity, req any) error {
+	if err := a.authorizeTenant(ctx, id, req); err != nil {
+		a.deny(ctx, method, id, req, err.Error())
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+// authenticate identifies the caller by bearer token, client certificate or
+// unix socket peer, in that order
+func (a *Authorizer) authenticate(ctx context.Context) (*entity.Identity, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token, ok := strings.CutPrefix(values[0], "Bearer ")
+			if !ok {
+				return nil, stderrors.New("unsupported authorization scheme")
+			}
+			if a.tokens == nil {
+				return nil, stderrors.New("tokens are not accepted by this agent")
+			}
+			id, err := a.tokens.Verify(ctx, token)
+			var appErr *errors.AppError
+			if stderrors.As(err, &appErr) {
+				return nil, fmt.Errorf("invalid token: %s", appErr.Message)
+			}
+			return id, err
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, stderrors.New("no credentials")
+	}
+
+	switch info := p.AuthInfo.(type) {
+	case credentials.TLSInfo:
+		// Only set for certificates that chain to the configured CA
+		if len(info.State.VerifiedChains) == 0 {
+			break
+		}
+		name := info.State.VerifiedChains[0][0].Subject.CommonName
+		role, ok := a.roles.Certs[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("certificate %q has no role", name)
+		}
+		return &entity.Identity{Subject: name, Role: role, Method: entity.AuthMethodTLS}, nil
+
+	case *auth.UnixPeerInfo:
+		name := strconv.FormatUint(uint64(info.UID), 10)
+		if u, err := user.LookupId(name); err == nil {
+			name = u.Username
+		}
+		role, ok := a.roles.LocalUsers[strings.ToLower(name)]
+		if !ok {
+			role = a.roles.LocalDefault
+		}
+		if role == "" {
+			return nil, fmt.Errorf("local user %q has no role", name)
+		}
+		return &entity.Identity{Subject: name, Role: role, Method: entity.AuthMethodUnix}, nil
+	}
+
+	return nil, stderrors.New("no credentials")
+}
+
+// deny logs and audits a rejected call
+func (a *Authorizer) deny(ctx context.Context, method string, id *entity.Identity, req any, reason string) {
+	event := newAuditEvent(ctx, method, id, req, time.Now())
+	event.Result = entity.AuditResultDenied
+	event.Error = reason
+
+	a.logger.Warn("Denied API call",
+		zap.String("method", method),
+		zap.String("subject", event.Subject),
+		zap.String("role", string(event.Role)),
+		zap.String("tenant", event.Tenant),
+		zap.String("peer", event.Peer),
+		zap.String("reason", reason),
+	)
+	if err := a.auditLog.Record(ctx, event); err != nil {
+		a.logger.Error("Failed to record audit event", zap.Error(err))
+	}
+}
+
+// authorizedStream checks the first message received when authorizeFirst is set
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx            context.Context
+	authorizeFirst func(msg any) error
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authorizedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.authorizeFirst != nil {
+		authorize := s.authorizeFirst
+		s.authorizeFirst = nil
+		return authorize(m)
+	}
+	return nil
+}
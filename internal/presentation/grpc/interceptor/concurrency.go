@@ -0,0 +1,132 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/observability"
+)
+
+// ConcurrencyLimiter caps how many calls of heavy RPCs run at once
+// Further calls wait in a queue, as long as their deadline and the queue
+// timeout allow; calls finding the queue full are rejected
+type ConcurrencyLimiter struct {
+	slots        map[string]chan struct{} // By lower case RPC name
+	queueSize    int
+	queueTimeout time.Duration
+	metrics      *observability.Metrics
+	logger       *zap.Logger
+
+	mu      sync.Mutex
+	waiting map[string]int
+}
+
+// NewConcurrencyLimiter creates a limiter allowing limits[name] concurrent
+// calls of each RPC, e.g. CreateVM; up to queueSize more calls wait for
+// queueTimeout at most
+func NewConcurrencyLimiter(
+	limits map[string]int,
+	queueSize int,
+	queueTimeout time.Duration,
+	metrics *observability.Metrics,
+	logger *zap.Logger,
+) (*ConcurrencyLimiter, error) {
+	l := &ConcurrencyLimiter{
+		slots:        make(map[string]chan struct{}),
+		queueSize:    queueSize,
+		queueTimeout: queueTimeout,
+		metrics:      metrics,
+		logger:       logger,
+		waiting:      make(map[string]int),
+	}
+	for name, limit := range limits {
+		if !knownMethod(name) {
+			return nil, fmt.Errorf("unknown RPC %q in concurrency limits", name)
+		}
+		if limit > 0 {
+			l.slots[strings.ToLower(name)] = make(chan struct{}, limit)
+		}
+	}
+	return l, nil
+}
+
+// UnaryInterceptor limits concurrent unary RPCs
+func (l *ConcurrencyLimiter) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		release, err := l.acquire(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor limits concurrent streams
+func (l *ConcurrencyLimiter) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := l.acquire(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return handler(srv, ss)
+	}
+}
+
+// acquire waits for a free slot of the RPC and returns the function that
+// frees it again
+func (l *ConcurrencyLimiter) acquire(ctx context.Context, method string) (func(), error) {
+	name := strings.ToLower(method[strings.LastIndex(method, "/")+1:])
+	slots, ok := l.slots[name]
+	if !ok {
+		return func() {}, nil
+	}
+	release := func() { <-slots }
+
+	// Fast path while slots are free
+	select {
+	case slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	l.mu.Lock()
+	if l.waiting[name] >= l.queueSize {
+		l.mu.Unlock()
+		l.metrics.RequestsRejected.WithLabelValues(method, "queue_full").Inc()
+		return nil, exhausted(ctx, l.queueTimeout, "too many %s calls in progress, queue is full", method)
+	}
+	l.waiting[name]++
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		l.waiting[name]--
+		l.mu.Unlock()
+	}()
+
+	l.logger.Debug("Queued API call", zap.String("method", method), zap.Int("running", cap(slots)))
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	start := time.Now()
+	select {
+	case slots <- struct{}{}:
+		l.logger.Debug("Dequeued API call", zap.String("method", method), zap.Duration("waited", time.Since(start)))
+		return release, nil
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	case <-timer.C:
+		l.metrics.RequestsRejected.WithLabelValues(method, "queue_timeout").Inc()
+		return nil, exhausted(ctx, l.queueTimeout, "%s waited %s for a free slot", method, l.queueTimeout)
+	}
+}
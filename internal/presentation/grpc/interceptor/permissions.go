@@ -0,0 +1,122 @@
+package interceptor
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+)
+
+// servicePrefix starts the full method names of AgentService
+const servicePrefix = "/agentpb.AgentService/"
+
+// healthPrefix starts the full method names of the gRPC health service
+const healthPrefix = "/grpc.health.v1.Health/"
+
+// reflectionPrefix starts the full method names of every version of the
+// server reflection service
+const reflectionPrefix = "/grpc.reflection."
+
+// permission is what an RPC does
+type permission string
+
+const (
+	permRead  permission = "read"  // Reads state
+	permWrite permission = "write" // Changes VMs, networks and images
+	permPeer  permission = "peer"  // Agent-to-agent migration calls
+	permAudit permission = "audit" // Reads the audit log
+	permCore  permission = "core"  // Replaces agent-wide settings Ghost Core manages
+)
+
+// rolePermissions is what each role may do
+var rolePermissions = map[entity.Role][]permission{
+	entity.RoleCore:     {permRead, permWrite, permPeer, permAudit, permCore},
+	entity.RoleOperator: {permRead, permWrite, permAudit},
+	entity.RoleReadOnly: {permRead},
+}
+
+// methodPermissions is the permission each AgentService RPC requires
+// RPCs missing here are denied to every caller
+var methodPermissions = map[string]permission{
+	"CreateVM":         permWrite,
+	"DeleteVM":         permWrite,
+	"StartVM":          permWrite,
+	"StopVM":           permWrite,
+	"GetVMStatus":      permRead,
+	"ListVMs":          permRead,
+	"ExportVM":         permWrite, // Hands out the VM's disk
+	"ImportVM":         permWrite,
+	"MigrateVM":        permWrite,
+	"UpdateVMLimits":   permWrite,
+	"RenewVM":          permWrite,
+	"SetRestartPolicy": permWrite,
+
+	"AttachConsole":  permWrite,
+	"CreateVNCToken": permWrite,
+	"GuestExec":      permWrite,
+
+	"AddPortForward":    permWrite,
+	"RemovePortForward": permWrite,
+	"ListPortForwards":  permRead,
+
+	"CreateSecurityGroup":     permWrite,
+	"DeleteSecurityGroup":     permWrite,
+	"ListSecurityGroups":      permRead,
+	"AddSecurityGroupRule":    permWrite,
+	"RemoveSecurityGroupRule": permWrite,
+	"AttachSecurityGroup":     permWrite,
+	"DetachSecurityGroup":     permWrite,
+
+	"CreateNetwork": permWrite,
+	"DeleteNetwork": permWrite,
+	"ListNetworks":  permRead,
+
+	"PrepareMigration": permPeer,
+	"FinishMigration":  permPeer,
+
+	"UploadImage": permWrite,
+
+	"SetBackupPolicy": permWrite,
+	"CreateBackup":    permWrite,
+	"ListBackups":     permRead,
+	"RestoreBackup":   permWrite,
+
+	"QueryAuditLog": permAudit,
+
+	"GetAgentInfo": permRead,
+
+	"GetQuotas": permRead,
+
+	"ListFlavors": permRead,
+	"SetFlavors":  permCore,
+}
+
+// mutating reports whether the RPC with the full method name changes state
+func mutating(fullMethod string) bool {
+	perm := methodPermissions[strings.TrimPrefix(fullMethod, servicePrefix)]
+	return perm == permWrite || perm == permPeer || perm == permCore
+}
+
+// public reports whether the RPC with the full method name may be called
+// without credentials
+// Health checks only tell whether the agent and its subsystems serve
+func public(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, healthPrefix)
+}
+
+// allowed reports whether role may call the RPC with the full method name
+// Reflection describes the API and is open to every role that may read
+func allowed(role entity.Role, fullMethod string) bool {
+	if strings.HasPrefix(fullMethod, reflectionPrefix) {
+		return slices.Contains(rolePermissions[role], permRead)
+	}
+	name, ok := strings.CutPrefix(fullMethod, servicePrefix)
+	if !ok {
+		return false
+	}
+	perm, ok := methodPermissions[name]
+	if !ok {
+		return false
+	}
+	return slices.Contains(rolePermissions[role], perm)
+}
@@ -0,0 +1,217 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/observability"
+)
+
+// RetryAfterKey is the response header telling rejected callers how many
+// seconds to wait before trying again
+const RetryAfterKey = "retry-after"
+
+// Rate is a token bucket: Limit requests per second on average, up to Burst at once
+// A zero Limit is unlimited
+type Rate struct {
+	Limit float64
+	Burst int
+}
+
+// RateLimiter rejects callers that send requests faster than allowed, per
+// caller and per RPC
+// Chained after the Authorizer, callers are told apart by identity, otherwise
+// by address
+type RateLimiter struct {
+	perClient   Rate
+	perMethod   map[string]*rate.Limiter // By lower case RPC name
+	idleTimeout time.Duration
+	metrics     *observability.Metrics
+	logger      *zap.Logger
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a rate limiter
+// perMethod is keyed by RPC name, e.g. CreateVM; clients idle for idleTimeout
+// are forgotten
+func NewRateLimiter(
+	perClient Rate,
+	perMethod map[string]Rate,
+	idleTimeout time.Duration,
+	metrics *observability.Metrics,
+	logger *zap.Logger,
+) (*RateLimiter, error) {
+	l := &RateLimiter{
+		perClient:   perClient,
+		perMethod:   make(map[string]*rate.Limiter),
+		idleTimeout: idleTimeout,
+		metrics:     metrics,
+		logger:      logger,
+		clients:     make(map[string]*clientLimiter),
+		lastSweep:   time.Now(),
+	}
+	for name, r := range perMethod {
+		if !knownMethod(name) {
+			return nil, fmt.Errorf("unknown RPC %q in rate limits", name)
+		}
+		if r.Limit > 0 {
+			l.perMethod[strings.ToLower(name)] = rate.NewLimiter(rate.Limit(r.Limit), r.Burst)
+		}
+	}
+	return l, nil
+}
+
+// UnaryInterceptor rate limits unary RPCs
+func (l *RateLimiter) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.allow(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor rate limits the opening of streams
+func (l *RateLimiter) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.allow(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// allow takes a token from the caller's and the RPC's bucket, or neither when
+// one of them is empty
+// Health checks are not limited, so probes keep working under load
+func (l *RateLimiter) allow(ctx context.Context, method string) error {
+	if public(method) {
+		return nil
+	}
+	now := time.Now()
+	var reservations []*rate.Reservation
+
+	if client := l.client(ctx, now); client != nil {
+		reservations = append(reservations, client.ReserveN(now, 1))
+	}
+	name := strings.ToLower(method[strings.LastIndex(method, "/")+1:])
+	if limiter, ok := l.perMethod[name]; ok {
+		reservations = append(reservations, limiter.ReserveN(now, 1))
+	}
+
+	var wait time.Duration
+	for _, r := range reservations {
+		wait = max(wait, r.DelayFrom(now)) // rate.InfDuration when never possible
+	}
+	if wait == 0 {
+		return nil
+	}
+	for _, r := range reservations {
+		r.CancelAt(now)
+	}
+
+	l.metrics.RequestsRejected.WithLabelValues(method, "rate_limit").Inc()
+	l.logger.Debug("Rate limited API call",
+		zap.String("method", method),
+		zap.String("client", clientKey(ctx)),
+		zap.Duration("retry_after", wait),
+	)
+	return exhausted(ctx, wait, "rate limit exceeded for %s", method)
+}
+
+// client returns the caller's bucket, nil when callers are not limited
+func (l *RateLimiter) client(ctx context.Context, now time.Time) *rate.Limiter {
+	if l.perClient.Limit <= 0 {
+		return nil
+	}
+	key := clientKey(ctx)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.idleTimeout > 0 && now.Sub(l.lastSweep) > l.idleTimeout {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > l.idleTimeout {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(l.perClient.Limit), l.perClient.Burst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+	return c.limiter
+}
+
+// clientKey identifies the caller by identity, or by host when unauthenticated
+func clientKey(ctx context.Context) string {
+	if id, ok := IdentityFromContext(ctx); ok {
+		return string(id.Method) + ":" + id.Subject
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	if p.Addr.Network() == "unix" {
+		return "unix"
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+// knownMethod reports whether name is an AgentService RPC, ignoring case
+func knownMethod(name string) bool {
+	for method := range methodPermissions {
+		if strings.EqualFold(method, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// exhausted returns a ResourceExhausted error and tells the caller when to
+// retry, in the response header and as RetryInfo
+func exhausted(ctx context.Context, retryAfter time.Duration, format string, args ...any) error {
+	st := status.Newf(codes.ResourceExhausted, format, args...)
+	if retryAfter < rate.InfDuration {
+		seconds := max(int64(math.Ceil(retryAfter.Seconds())), 1)
+		grpc.SetHeader(ctx, metadata.Pairs(RetryAfterKey, strconv.FormatInt(seconds, 10)))
+
+		if withRetry, err := st.WithDetails(&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(time.Duration(seconds) * time.Second),
+		}); err == nil {
+			st = withRetry
+		}
+	}
+	return st.Err()
+}
@@ -0,0 +1,138 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/entity"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// vmRequest is a request about one VM
+type vmRequest interface {
+	GetVmId() string
+}
+
+// authorizeTenant checks that a caller limited to a tenant only touches that
+// tenant's VMs and networks, stamping the tenant on what it creates
+// Requests that are not about an owned resource are denied
+func (a *Authorizer) authorizeTenant(ctx context.Context, id *entity.Identity, req any) error {
+	switch r := req.(type) {
+	case *agentpb.CreateVMRequest:
+		if owner := r.Metadata[entity.MetadataTenant]; owner != "" && owner != id.Tenant {
+			return fmt.Errorf("cannot create VMs of tenant %q", owner)
+		}
+		for _, ref := range r.Networks {
+			if err := a.checkNetwork(ctx, id, ref, true); err != nil {
+				return err
+			}
+		}
+		if r.Metadata == nil {
+			r.Metadata = make(map[string]string)
+		}
+		r.Metadata[entity.MetadataTenant] = id.Tenant
+		return nil
+
+	case *agentpb.ListVMsRequest:
+		// The response is filtered
+		return nil
+
+	case *agentpb.ListFlavorsRequest:
+		// Flavors are shared by all tenants
+		return nil
+
+	case *agentpb.CreateNetworkRequest:
+		if r.Tenant != "" && r.Tenant != id.Tenant {
+			return fmt.Errorf("cannot create networks of tenant %q", r.Tenant)
+		}
+		r.Tenant = id.Tenant
+		return nil
+
+	case *agentpb.ListNetworksRequest:
+		if r.Tenant != "" && r.Tenant != id.Tenant {
+			return fmt.Errorf("cannot list networks of tenant %q", r.Tenant)
+		}
+		r.Tenant = id.Tenant
+		return nil
+
+	case *agentpb.GetQuotasRequest:
+		if r.Tenant != "" && r.Tenant != id.Tenant {
+			return fmt.Errorf("cannot view quotas of tenant %q", r.Tenant)
+		}
+		r.Tenant = id.Tenant
+		return nil
+
+	case *agentpb.DeleteNetworkRequest:
+		return a.checkNetwork(ctx, id, r.Network, false)
+
+	case *agentpb.RemovePortForwardRequest:
+		fwd, err := a.forwardRepo.FindByID(ctx, r.Id)
+		if err != nil {
+			return fmt.Errorf("port forward %s is not owned by tenant %q", r.Id, id.Tenant)
+		}
+		return a.checkVM(ctx, id, fwd.VMID)
+
+	case *agentpb.RestoreBackupRequest:
+		backup, err := a.backupRepo.FindByID(ctx, r.BackupId)
+		if err != nil {
+			return fmt.Errorf("backup %s is not owned by tenant %q", r.BackupId, id.Tenant)
+		}
+		return a.checkVM(ctx, id, backup.VMID)
+	}
+
+	if r, ok := req.(vmRequest); ok && r.GetVmId() != "" {
+		return a.checkVM(ctx, id, r.GetVmId())
+	}
+	return fmt.Errorf("not available to callers limited to a tenant")
+}
+
+// checkVM checks that the VM belongs to the caller's tenant
+// Unknown VMs are reported like foreign ones so their existence is not revealed
+func (a *Authorizer) checkVM(ctx context.Context, id *entity.Identity, vmID string) error {
+	vm, err := a.vmRepo.FindByID(ctx, vmID)
+	if err != nil || vm.Tenant() != id.Tenant {
+		return fmt.Errorf("VM %s is not owned by tenant %q", vmID, id.Tenant)
+	}
+	return nil
+}
+
+// checkNetwork checks that the network, given by ID or name, belongs to the
+// caller's tenant; shared networks pass when allowShared is set
+func (a *Authorizer) checkNetwork(ctx context.Context, id *entity.Identity, ref string, allowShared bool) error {
+	network, err := a.networkRepo.FindByID(ctx, ref)
+	if err != nil {
+		network, err = a.networkRepo.FindByName(ctx, ref)
+	}
+	if err != nil || !(network.Tenant == id.Tenant || allowShared && network.Tenant == "") {
+		return fmt.Errorf("network %s is not owned by tenant %q", ref, id.Tenant)
+	}
+	return nil
+}
+
+// filterResponse removes resources of other tenants from list responses
+func (a *Authorizer) filterResponse(ctx context.Context, id *entity.Identity, resp any) (any, error) {
+	list, ok := resp.(*agentpb.ListVMsResponse)
+	if !ok {
+		return resp, nil
+	}
+
+	vms, err := a.vmRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[string]bool)
+	for _, vm := range vms {
+		if vm.Tenant() == id.Tenant {
+			owned[vm.ID] = true
+		}
+	}
+
+	filtered := list.Vms[:0]
+	for _, info := range list.Vms {
+		if owned[info.VmId] {
+			filtered = append(filtered, info)
+		}
+	}
+	list.Vms = filtered
+	return list, nil
+}
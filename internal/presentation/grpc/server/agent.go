@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// GetAgentInfo returns the agent's name, version and available resources
+func (s *Server) GetAgentInfo(ctx context.Context, req *agentpb.GetAgentInfoRequest) (*agentpb.GetAgentInfoResponse, error) {
+	s.logger.Debug("gRPC GetAgentInfo request")
+
+	resp, err := s.getAgentInfoUC.Execute(ctx)
+	if err != nil {
+		s.logger.Error("GetAgentInfo failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.GetAgentInfoResponse{
+		Name:      resp.Name,
+		Version:   resp.Version,
+		StartedAt: resp.StartedAt.Unix(),
+		Resources: &agentpb.AgentResources{
+			TotalCpu:        int32(resp.Resources.TotalCPU),
+			AvailableCpu:    int32(resp.Resources.AvailableCPU),
+			TotalRamGb:      int32(resp.Resources.TotalRAMGB),
+			AvailableRamGb:  int32(resp.Resources.AvailableRAMGB),
+			TotalDiskGb:     int32(resp.Resources.TotalDiskGB),
+			AvailableDiskGb: int32(resp.Resources.AvailableDiskGB),
+		},
+	}, nil
+}
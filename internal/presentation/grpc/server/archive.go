@@ -0,0 +1,85 @@
+package server
+
+import (
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// ExportVM streams a tar archive of a stopped VM
+func (s *Server) ExportVM(req *agentpb.ExportVMRequest, stream agentpb.AgentService_ExportVMServer) error {
+	s.logger.Info("gRPC ExportVM request", zap.String("vm_id", req.VmId))
+
+	dtoReq := &dto.ExportVMRequest{
+		VMID: req.VmId,
+	}
+
+	w := newChunkWriter(func(chunk []byte) error {
+		return stream.Send(&agentpb.ExportVMResponse{Chunk: chunk})
+	})
+
+	err := s.exportVMUC.Execute(stream.Context(), dtoReq, w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		s.metrics.VMOperations.WithLabelValues("export", "error").Inc()
+		s.logger.Error("ExportVM failed", zap.Error(err))
+		return s.toGRPCError(err)
+	}
+
+	s.metrics.VMOperations.WithLabelValues("export", "success").Inc()
+	return nil
+}
+
+// ImportVM recreates a VM from a streamed archive
+func (s *Server) ImportVM(stream agentpb.AgentService_ImportVMServer) error {
+	// First message must carry the import options
+	first, err := stream.Recv()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "missing import options")
+	}
+	opts := first.GetOptions()
+	if opts == nil {
+		return status.Error(codes.InvalidArgument, "first message must contain import options")
+	}
+
+	s.logger.Info("gRPC ImportVM request", zap.String("name", opts.Name))
+
+	dtoReq := &dto.ImportVMRequest{
+		Name: opts.Name,
+	}
+
+	pr := pipeChunks(func() ([]byte, error) {
+		msg, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		chunk := msg.GetChunk()
+		if chunk == nil {
+			return nil, status.Error(codes.InvalidArgument, "expected archive chunk")
+		}
+		return chunk, nil
+	})
+
+	resp, err := s.importVMUC.Execute(stream.Context(), dtoReq, pr)
+	pr.Close()
+	if err != nil {
+		s.metrics.VMOperations.WithLabelValues("import", "error").Inc()
+		s.logger.Error("ImportVM failed", zap.Error(err))
+		return s.toGRPCError(err)
+	}
+
+	s.metrics.VMsCreated.Inc()
+	s.metrics.VMsRunning.Inc()
+	s.metrics.VMOperations.WithLabelValues("import", "success").Inc()
+
+	return stream.SendAndClose(&agentpb.ImportVMResponse{
+		VmId:      resp.VMID,
+		IpAddress: resp.IPAddress,
+		Status:    resp.Status,
+	})
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// QueryAuditLog returns recorded operations, optionally of one VM or period
+func (s *Server) QueryAuditLog(ctx context.Context, req *agentpb.QueryAuditLogRequest) (*agentpb.QueryAuditLogResponse, error) {
+	s.logger.Debug("gRPC QueryAuditLog request", zap.String("vm_id", req.VmId))
+
+	query := &dto.QueryAuditLogRequest{
+		VMID:  req.VmId,
+		Limit: int(req.Limit),
+	}
+	if req.Since > 0 {
+		query.Since = time.Unix(req.Since, 0)
+	}
+	if req.Until > 0 {
+		query.Until = time.Unix(req.Until, 0)
+	}
+
+	resp, err := s.queryAuditLogUC.Execute(ctx, query)
+	if err != nil {
+		s.logger.Error("QueryAuditLog failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	events := make([]*agentpb.AuditEvent, len(resp.Events))
+	for i, event := range resp.Events {
+		events[i] = &agentpb.AuditEvent{
+			Seq:        event.Seq,
+			Time:       event.Time.UnixMilli(),
+			Method:     event.Method,
+			Subject:    event.Subject,
+			Role:       event.Role,
+			Tenant:     event.Tenant,
+			AuthMethod: event.AuthMethod,
+			Peer:       event.Peer,
+			VmId:       event.VMID,
+			Summary:    event.Summary,
+			Result:     event.Result,
+			Error:      event.Error,
+			DurationMs: event.Duration.Milliseconds(),
+			Hash:       event.Hash,
+		}
+	}
+
+	return &agentpb.QueryAuditLogResponse{
+		Events:         events,
+		Intact:         resp.Intact,
+		IntegrityError: resp.IntegrityError,
+	}, nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// SetBackupPolicy configures scheduled backups of a VM
+func (s *Server) SetBackupPolicy(ctx context.Context, req *agentpb.SetBackupPolicyRequest) (*agentpb.SetBackupPolicyResponse, error) {
+	s.logger.Info("gRPC SetBackupPolicy request",
+		zap.String("vm_id", req.VmId),
+		zap.String("schedule", req.Schedule),
+	)
+
+	dtoReq := &dto.SetBackupPolicyRequest{
+		VMID:       req.VmId,
+		Schedule:   req.Schedule,
+		KeepDaily:  int(req.KeepDaily),
+		KeepWeekly: int(req.KeepWeekly),
+	}
+
+	resp, err := s.setBackupPolicyUC.Execute(ctx, dtoReq)
+	if err != nil {
+		s.logger.Error("SetBackupPolicy failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.SetBackupPolicyResponse{
+		VmId:       resp.VMID,
+		Schedule:   resp.Schedule,
+		KeepDaily:  int32(resp.KeepDaily),
+		KeepWeekly: int32(resp.KeepWeekly),
+	}, nil
+}
+
+// CreateBackup backs up a VM immediately
+func (s *Server) CreateBackup(ctx context.Context, req *agentpb.CreateBackupRequest) (*agentpb.CreateBackupResponse, error) {
+	s.logger.Info("gRPC CreateBackup request", zap.String("vm_id", req.VmId))
+
+	resp, err := s.createBackupUC.Execute(ctx, &dto.CreateBackupRequest{VMID: req.VmId})
+	if err != nil {
+		s.metrics.VMOperations.WithLabelValues("backup", "error").Inc()
+		s.logger.Error("CreateBackup failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	s.metrics.VMOperations.WithLabelValues("backup", "success").Inc()
+
+	return &agentpb.CreateBackupResponse{
+		Backup: toBackupInfoProto(resp.Backup),
+	}, nil
+}
+
+// ListBackups lists stored backups, newest first
+func (s *Server) ListBackups(ctx context.Context, req *agentpb.ListBackupsRequest) (*agentpb.ListBackupsResponse, error) {
+	s.logger.Debug("gRPC ListBackups request", zap.String("vm_id", req.VmId))
+
+	resp, err := s.listBackupsUC.Execute(ctx, &dto.ListBackupsRequest{VMID: req.VmId})
+	if err != nil {
+		s.logger.Error("ListBackups failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	backups := make([]*agentpb.BackupInfo, len(resp.Backups))
+	for i, backup := range resp.Backups {
+		backups[i] = toBackupInfoProto(backup)
+	}
+
+	return &agentpb.ListBackupsResponse{
+		Backups: backups,
+	}, nil
+}
+
+// RestoreBackup replaces a stopped VM's disk with a backup
+func (s *Server) RestoreBackup(ctx context.Context, req *agentpb.RestoreBackupRequest) (*agentpb.RestoreBackupResponse, error) {
+	s.logger.Info("gRPC RestoreBackup request", zap.String("backup_id", req.BackupId))
+
+	resp, err := s.restoreBackupUC.Execute(ctx, &dto.RestoreBackupRequest{BackupID: req.BackupId})
+	if err != nil {
+		s.metrics.VMOperations.WithLabelValues("restore_backup", "error").Inc()
+		s.logger.Error("RestoreBackup failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	s.metrics.VMOperations.WithLabelValues("restore_backup", "success").Inc()
+
+	return &agentpb.RestoreBackupResponse{
+		VmId:   resp.VMID,
+		Status: resp.Status,
+	}, nil
+}
+
+func toBackupInfoProto(backup dto.BackupInfo) *agentpb.BackupInfo {
+	return &agentpb.BackupInfo{
+		BackupId:  backup.BackupID,
+		VmId:      backup.VMID,
+		Target:    backup.Target,
+		Location:  backup.Location,
+		SizeBytes: backup.SizeBytes,
+		Sha256:    backup.SHA256,
+		CreatedAt: backup.CreatedAt.Unix(),
+	}
+}
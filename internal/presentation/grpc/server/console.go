@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"io"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// consoleBufferSize is the largest console output chunk sent in one message
+const consoleBufferSize = 4096
+
+// AttachConsole bridges a VM's serial console to the client
+func (s *Server) AttachConsole(stream agentpb.AgentService_AttachConsoleServer) error {
+	// First message must carry the VM ID
+	first, err := stream.Recv()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "missing VM ID")
+	}
+	vmID := first.GetVmId()
+	if vmID == "" {
+		return status.Error(codes.InvalidArgument, "first message must contain the VM ID")
+	}
+
+	s.logger.Info("gRPC AttachConsole request", zap.String("vm_id", vmID))
+
+	console, err := s.attachConsoleUC.Execute(stream.Context(), &dto.AttachConsoleRequest{VMID: vmID})
+	if err != nil {
+		s.logger.Error("AttachConsole failed", zap.Error(err))
+		return s.toGRPCError(err)
+	}
+	defer console.Close()
+
+	// Client input; closing the console ends the output loop below
+	go func() {
+		defer console.Close()
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			if input := msg.GetInput(); len(input) > 0 {
+				if _, err := console.Write(input); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	buf := make([]byte, consoleBufferSize)
+	for {
+		n, err := console.Read(buf)
+		if n > 0 {
+			output := make([]byte, n)
+			copy(output, buf[:n])
+			if err := stream.Send(&agentpb.AttachConsoleResponse{Output: output}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			s.logger.Info("Console detached", zap.String("vm_id", vmID))
+			return nil
+		}
+		if err != nil {
+			s.logger.Error("Console stream failed", zap.String("vm_id", vmID), zap.Error(err))
+			return status.Error(codes.Internal, "console stream failed")
+		}
+	}
+}
+
+// CreateVNCToken issues a one-time token for the VNC WebSocket proxy
+func (s *Server) CreateVNCToken(ctx context.Context, req *agentpb.CreateVNCTokenRequest) (*agentpb.CreateVNCTokenResponse, error) {
+	s.logger.Info("gRPC CreateVNCToken request", zap.String("vm_id", req.VmId))
+
+	resp, err := s.createVNCTokenUC.Execute(ctx, &dto.CreateVNCTokenRequest{VMID: req.VmId})
+	if err != nil {
+		s.logger.Error("CreateVNCToken failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.CreateVNCTokenResponse{
+		Token:     resp.Token,
+		Url:       resp.URL,
+		ExpiresAt: resp.ExpiresAt.Unix(),
+	}, nil
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
+)
+
+// ErrorDomain is the ErrorInfo domain of errors raised by the agent
+const ErrorDomain = "ghost-agent"
+
+// Context keys with a meaning beyond ErrorInfo metadata
+const (
+	retryAfterKey = "retry_after"
+	requestedKey  = "requested_"
+	availableKey  = "available_"
+)
+
+// safeContextKeys are the context keys sent to callers: the objects and
+// values of the request. Other context, such as host paths, command output or
+// other tenants' VMs, only goes to the server logs
+var safeContextKeys = map[string]bool{
+	"vm_id":               true,
+	"vm_name":             true,
+	"name":                true,
+	"image_name":          true,
+	"template":            true,
+	"flavor":              true,
+	"tenant":              true,
+	"status":              true,
+	"target":              true,
+	"network":             true,
+	"network_id":          true,
+	"network_name":        true,
+	"network_mode":        true,
+	"subnet":              true,
+	"gateway":             true,
+	"prefix":              true,
+	"security_group":      true,
+	"security_group_id":   true,
+	"security_group_name": true,
+	"rule_id":             true,
+	"protocol":            true,
+	"port_min":            true,
+	"port_max":            true,
+	"host_port":           true,
+	"port_forward_id":     true,
+	"backup_id":           true,
+	"schedule":            true,
+	"expires_at":          true,
+	"max_ttl":             true,
+	"vcpu":                true,
+	"ram_gb":              true,
+	"disk_gb":             true,
+	"declared_format":     true,
+	"detected_format":     true,
+	"format_version":      true,
+	"declared_size_bytes": true,
+	"received_size_bytes": true,
+	retryAfterKey:         true,
+}
+
+// safeContextKey reports whether a context key may be sent to callers
+func safeContextKey(key string) bool {
+	return safeContextKeys[key] || strings.HasPrefix(key, requestedKey) || strings.HasPrefix(key, availableKey)
+}
+
+// genericCodes only say which dependency failed, not why
+var genericCodes = map[errors.ErrorCode]bool{
+	errors.ErrCodeHypervisor: true,
+	errors.ErrCodeStorage:    true,
+	errors.ErrCodeNetwork:    true,
+	errors.ErrCodeInternal:   true,
+}
+
+// toGRPCError converts a domain error to a gRPC status, logging the context
+// that is not sent to the caller
+func (s *Server) toGRPCError(err error) error {
+	if withheld := withheldContext(appErrors(err)); len(withheld) > 0 {
+		s.logger.Info("Error context withheld from caller",
+			zap.String("error", err.Error()),
+			zap.Any("context", withheld),
+		)
+	}
+	return grpcError(err)
+}
+
+// grpcError converts a domain error to a gRPC status with details
+// Every status carries an ErrorInfo with the error code and safe context, plus
+// BadRequest, QuotaFailure or RetryInfo where the code calls for them
+func grpcError(err error) error {
+	chain := appErrors(err)
+	if len(chain) == 0 {
+		switch {
+		case stderrors.Is(err, context.DeadlineExceeded):
+			return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+		case stderrors.Is(err, context.Canceled):
+			return status.Error(codes.Canceled, "request canceled")
+		default:
+			return status.Error(codes.Internal, "internal server error")
+		}
+	}
+
+	appErr := cause(chain)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason:   string(appErr.Code),
+		Domain:   ErrorDomain,
+		Metadata: metadata(chain),
+	}}
+
+	var code codes.Code
+	switch appErr.Code {
+	case errors.ErrCodeValidation:
+		code = codes.InvalidArgument
+		if violations := fieldViolations(appErr); len(violations) > 0 {
+			details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+		}
+	case errors.ErrCodeNotFound:
+		code = codes.NotFound
+	case errors.ErrCodeConflict:
+		code = codes.AlreadyExists
+	case errors.ErrCodeInvalidState:
+		code = codes.FailedPrecondition
+	case errors.ErrCodeResourceLimit:
+		code = codes.ResourceExhausted
+		details = append(details, &errdetails.QuotaFailure{Violations: quotaViolations(appErr)})
+	case errors.ErrCodeTimeout:
+		code = codes.DeadlineExceeded
+	case errors.ErrCodeUnavailable:
+		code = codes.Unavailable
+	default:
+		code = codes.Internal
+	}
+
+	if retryAfter, ok := appErr.Context[retryAfterKey].(time.Duration); ok {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	}
+
+	st, detailErr := status.New(code, appErr.Message).WithDetails(details...)
+	if detailErr != nil {
+		return status.Error(code, appErr.Message)
+	}
+	return st.Err()
+}
+
+// appErrors returns the AppErrors in err's chain, outermost first
+func appErrors(err error) []*errors.AppError {
+	var chain []*errors.AppError
+	for err != nil {
+		var appErr *errors.AppError
+		if !stderrors.As(err, &appErr) {
+			break
+		}
+		chain = append(chain, appErr)
+		err = appErr.Err
+	}
+	return chain
+}
+
+// cause picks the error that explains the failure best: the outermost one,
+// unless it only names the failed dependency and an inner one says why
+func cause(chain []*errors.AppError) *errors.AppError {
+	if !genericCodes[chain[0].Code] {
+		return chain[0]
+	}
+	for _, appErr := range chain[1:] {
+		if !genericCodes[appErr.Code] {
+			return appErr
+		}
+	}
+	return chain[0]
+}
+
+// metadata flattens the safe context of the chain, outer errors taking precedence
+func metadata(chain []*errors.AppError) map[string]string {
+	md := make(map[string]string)
+	for i := len(chain) - 1; i >= 0; i-- {
+		for key, value := range chain[i].Context {
+			if safeContextKey(key) {
+				md[key] = fmt.Sprint(value)
+			}
+		}
+	}
+	if len(md) == 0 {
+		return nil
+	}
+	return md
+}
+
+// withheldContext collects the context of the chain that is not sent to callers
+func withheldContext(chain []*errors.AppError) map[string]interface{} {
+	withheld := make(map[string]interface{})
+	for i := len(chain) - 1; i >= 0; i-- {
+		for key, value := range chain[i].Context {
+			if !safeContextKey(key) {
+				withheld[key] = value
+			}
+		}
+	}
+	return withheld
+}
+
+// fieldViolations describes the fields that failed request validation
+func fieldViolations(appErr *errors.AppError) []*errdetails.BadRequest_FieldViolation {
+	var validationErrs validator.ValidationErrors
+	if !stderrors.As(appErr, &validationErrs) {
+		return nil
+	}
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(validationErrs))
+	for i, fe := range validationErrs {
+		field := fe.Namespace()
+		if _, rest, ok := strings.Cut(field, "."); ok {
+			field = rest // Drop the request type
+		}
+		description := "failed " + fe.Tag()
+		if fe.Param() != "" {
+			description += "=" + fe.Param()
+		}
+		violations[i] = &errdetails.BadRequest_FieldViolation{Field: field, Description: description}
+	}
+	return violations
+}
+
+// quotaViolations pairs requested_<x> with available_<x> context values to
+// name the resources that ran short
+func quotaViolations(appErr *errors.AppError) []*errdetails.QuotaFailure_Violation {
+	var violations []*errdetails.QuotaFailure_Violation
+	for key, value := range appErr.Context {
+		resource, ok := strings.CutPrefix(key, requestedKey)
+		if !ok {
+			continue
+		}
+		requested, ok := toInt(value)
+		if !ok {
+			continue
+		}
+		available, ok := toInt(appErr.Context[availableKey+resource])
+		if !ok || requested <= available {
+			continue
+		}
+		violations = append(violations, &errdetails.QuotaFailure_Violation{
+			Subject:     resource,
+			Description: fmt.Sprintf("requested %d, %d available", requested, available),
+		})
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		return violations[i].Subject < violations[j].Subject
+	})
+
+	if len(violations) == 0 {
+		violations = append(violations, &errdetails.QuotaFailure_Violation{Description: appErr.Message})
+	}
+	return violations
+}
+
+// toInt reads an integer context value
+func toInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
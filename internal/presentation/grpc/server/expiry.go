@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// RenewVM extends or removes the lease of a VM
+func (s *Server) RenewVM(ctx context.Context, req *agentpb.RenewVMRequest) (*agentpb.RenewVMResponse, error) {
+	s.logger.Info("gRPC RenewVM request", zap.String("vm_id", req.VmId))
+
+	resp, err := s.renewVMUC.Execute(ctx, &dto.RenewVMRequest{
+		VMID:       req.VmId,
+		TTLSeconds: req.TtlSeconds,
+		ExpiresAt:  req.ExpiresAt,
+		Action:     req.Action,
+		Clear:      req.Clear,
+	})
+	if err != nil {
+		s.logger.Error("RenewVM failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.RenewVMResponse{
+		VmId:         resp.VMID,
+		ExpiresAt:    resp.ExpiresAt,
+		ExpiryAction: resp.ExpiryAction,
+	}, nil
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// ListFlavors lists the flavors with whether this host can run them
+func (s *Server) ListFlavors(ctx context.Context, req *agentpb.ListFlavorsRequest) (*agentpb.ListFlavorsResponse, error) {
+	s.logger.Debug("gRPC ListFlavors request")
+
+	resp, err := s.listFlavorsUC.Execute(ctx, &dto.ListFlavorsRequest{})
+	if err != nil {
+		s.logger.Error("ListFlavors failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	flavors := make([]*agentpb.FlavorInfo, len(resp.Flavors))
+	for i, f := range resp.Flavors {
+		flavors[i] = &agentpb.FlavorInfo{
+			Flavor:    toFlavorProto(f.Flavor),
+			Fits:      f.Fits,
+			Available: int32(f.Available),
+		}
+	}
+
+	return &agentpb.ListFlavorsResponse{Flavors: flavors}, nil
+}
+
+// SetFlavors replaces the flavors pushed by Ghost Core
+func (s *Server) SetFlavors(ctx context.Context, req *agentpb.SetFlavorsRequest) (*agentpb.SetFlavorsResponse, error) {
+	s.logger.Info("gRPC SetFlavors request", zap.Int("count", len(req.Flavors)))
+
+	flavors := make([]dto.Flavor, len(req.Flavors))
+	for i, f := range req.Flavors {
+		flavors[i] = dto.Flavor{
+			Name:      f.Name,
+			VCPU:      int(f.Vcpu),
+			RAMGB:     int(f.RamGb),
+			DiskGB:    int(f.DiskGb),
+			CPUMode:   f.CpuMode,
+			Limits:    toVMLimitsDTO(f.Limits),
+			Templates: f.Templates,
+		}
+	}
+
+	resp, err := s.setFlavorsUC.Execute(ctx, &dto.SetFlavorsRequest{Flavors: flavors})
+	if err != nil {
+		s.logger.Error("SetFlavors failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.SetFlavorsResponse{Success: resp.Success}, nil
+}
+
+func toFlavorProto(f dto.Flavor) *agentpb.Flavor {
+	return &agentpb.Flavor{
+		Name:      f.Name,
+		Vcpu:      int32(f.VCPU),
+		RamGb:     int32(f.RAMGB),
+		DiskGb:    int32(f.DiskGB),
+		CpuMode:   f.CPUMode,
+		Limits:    toVMLimitsProto(f.Limits),
+		Templates: f.Templates,
+		Source:    f.Source,
+	}
+}
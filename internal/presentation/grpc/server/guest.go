@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// GuestExec runs a command inside a VM through the guest agent
+func (s *Server) GuestExec(ctx context.Context, req *agentpb.GuestExecRequest) (*agentpb.GuestExecResponse, error) {
+	s.logger.Info("gRPC GuestExec request",
+		zap.String("vm_id", req.VmId),
+		zap.String("command", req.Command),
+	)
+
+	dtoReq := &dto.GuestExecRequest{
+		VMID:           req.VmId,
+		Command:        req.Command,
+		Args:           req.Args,
+		Env:            req.Env,
+		Stdin:          req.Stdin,
+		TimeoutSeconds: int(req.TimeoutSeconds),
+	}
+
+	resp, err := s.guestExecUC.Execute(ctx, dtoReq)
+	if err != nil {
+		s.metrics.VMOperations.WithLabelValues("guest_exec", "error").Inc()
+		s.logger.Error("GuestExec failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	s.metrics.VMOperations.WithLabelValues("guest_exec", "success").Inc()
+
+	return &agentpb.GuestExecResponse{
+		ExitCode:  int32(resp.ExitCode),
+		Stdout:    resp.Stdout,
+		Stderr:    resp.Stderr,
+		Truncated: resp.Truncated,
+	}, nil
+}
+
+// toGuestInfoProto converts guest agent details to protobuf
+func toGuestInfoProto(info *dto.GuestInfo) *agentpb.GuestInfo {
+	if info == nil {
+		return nil
+	}
+
+	out := &agentpb.GuestInfo{
+		OsName:        info.OSName,
+		OsVersion:     info.OSVersion,
+		KernelRelease: info.KernelRelease,
+		Hostname:      info.Hostname,
+	}
+	for _, fs := range info.FileSystems {
+		out.Filesystems = append(out.Filesystems, &agentpb.GuestFileSystem{
+			MountPoint: fs.MountPoint,
+			FsType:     fs.FSType,
+			TotalBytes: fs.TotalBytes,
+			UsedBytes:  fs.UsedBytes,
+		})
+	}
+	for _, iface := range info.Interfaces {
+		out.Interfaces = append(out.Interfaces, &agentpb.GuestInterface{
+			Name:         iface.Name,
+			HardwareAddr: iface.HardwareAddr,
+			Addresses:    iface.Addresses,
+		})
+	}
+	return out
+}
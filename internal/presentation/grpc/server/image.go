@@ -0,0 +1,66 @@
+package server
+
+import (
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// UploadImage receives a chunked image and registers it as a template
+func (s *Server) UploadImage(stream agentpb.AgentService_UploadImageServer) error {
+	// First message must carry the image metadata
+	first, err := stream.Recv()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "missing image metadata")
+	}
+	meta := first.GetMetadata()
+	if meta == nil {
+		return status.Error(codes.InvalidArgument, "first message must contain image metadata")
+	}
+
+	s.logger.Info("gRPC UploadImage request",
+		zap.String("name", meta.Name),
+		zap.Int64("size_bytes", meta.SizeBytes),
+	)
+
+	dtoReq := &dto.UploadImageRequest{
+		Name:      meta.Name,
+		Format:    meta.Format,
+		SHA256:    meta.Sha256,
+		SizeBytes: meta.SizeBytes,
+	}
+
+	// Feed the remaining chunks to the use case as a plain reader
+	pr := pipeChunks(func() ([]byte, error) {
+		msg, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		chunk := msg.GetChunk()
+		if chunk == nil {
+			return nil, status.Error(codes.InvalidArgument, "expected image data chunk")
+		}
+		return chunk, nil
+	})
+
+	resp, err := s.uploadImageUC.Execute(stream.Context(), dtoReq, pr)
+	// Unblock the receiver if the use case stopped reading early
+	pr.Close()
+	if err != nil {
+		s.metrics.VMOperations.WithLabelValues("upload_image", "error").Inc()
+		s.logger.Error("UploadImage failed", zap.Error(err))
+		return s.toGRPCError(err)
+	}
+
+	s.metrics.VMOperations.WithLabelValues("upload_image", "success").Inc()
+
+	return stream.SendAndClose(&agentpb.UploadImageResponse{
+		Name:      resp.Name,
+		Format:    resp.Format,
+		Sha256:    resp.SHA256,
+		SizeBytes: resp.SizeBytes,
+	})
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// UpdateVMLimits replaces the network and disk limits of a VM
+func (s *Server) UpdateVMLimits(ctx context.Context, req *agentpb.UpdateVMLimitsRequest) (*agentpb.UpdateVMLimitsResponse, error) {
+	s.logger.Info("gRPC UpdateVMLimits request", zap.String("vm_id", req.VmId))
+
+	dtoReq := &dto.UpdateVMLimitsRequest{
+		VMID:   req.VmId,
+		Limits: toVMLimitsDTO(req.Limits),
+	}
+
+	resp, err := s.updateVMLimitsUC.Execute(ctx, dtoReq)
+	if err != nil {
+		s.metrics.VMOperations.WithLabelValues("update_limits", "error").Inc()
+		s.logger.Error("UpdateVMLimits failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	s.metrics.VMOperations.WithLabelValues("update_limits", "success").Inc()
+
+	return &agentpb.UpdateVMLimitsResponse{
+		VmId:   resp.VMID,
+		Limits: toVMLimitsProto(resp.Limits),
+	}, nil
+}
+
+func toVMLimitsDTO(l *agentpb.VMLimits) dto.VMLimits {
+	return dto.VMLimits{
+		Inbound:         toBandwidthLimitDTO(l.GetInbound()),
+		Outbound:        toBandwidthLimitDTO(l.GetOutbound()),
+		DiskIOPS:        l.GetDiskIops(),
+		DiskBytesPerSec: l.GetDiskBytesSec(),
+	}
+}
+
+func toBandwidthLimitDTO(b *agentpb.BandwidthLimit) dto.BandwidthLimit {
+	return dto.BandwidthLimit{
+		AverageKBps: int(b.GetAverageKbytesSec()),
+		PeakKBps:    int(b.GetPeakKbytesSec()),
+		BurstKB:     int(b.GetBurstKbytes()),
+	}
+}
+
+func toVMLimitsProto(l dto.VMLimits) *agentpb.VMLimits {
+	return &agentpb.VMLimits{
+		Inbound:      toBandwidthLimitProto(l.Inbound),
+		Outbound:     toBandwidthLimitProto(l.Outbound),
+		DiskIops:     l.DiskIOPS,
+		DiskBytesSec: l.DiskBytesPerSec,
+	}
+}
+
+func toBandwidthLimitProto(b dto.BandwidthLimit) *agentpb.BandwidthLimit {
+	return &agentpb.BandwidthLimit{
+		AverageKbytesSec: int32(b.AverageKBps),
+		PeakKbytesSec:    int32(b.PeakKBps),
+		BurstKbytes:      int32(b.BurstKB),
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// MigrateVM live-migrates a VM to another agent and streams progress
+func (s *Server) MigrateVM(req *agentpb.MigrateVMRequest, stream agentpb.AgentService_MigrateVMServer) error {
+	s.logger.Info("gRPC MigrateVM request",
+		zap.String("vm_id", req.VmId),
+		zap.String("target", req.TargetAddress),
+	)
+
+	dtoReq := &dto.MigrateVMRequest{
+		VMID:          req.VmId,
+		TargetAddress: req.TargetAddress,
+	}
+
+	// Progress is best effort; a client that went away must not abort the migration
+	send := func(p *dto.MigrateVMProgress) {
+		if err := stream.Send(toMigrateVMProgressProto(p)); err != nil {
+			s.logger.Debug("Failed to send migration progress", zap.Error(err))
+		}
+	}
+
+	resp, err := s.migrateVMUC.Execute(context.WithoutCancel(stream.Context()), dtoReq, send)
+	if err != nil {
+		s.metrics.VMOperations.WithLabelValues("migrate", "error").Inc()
+		s.logger.Error("MigrateVM failed", zap.Error(err))
+		return s.toGRPCError(err)
+	}
+
+	s.metrics.VMOperations.WithLabelValues("migrate", "success").Inc()
+
+	return stream.Send(toMigrateVMProgressProto(resp))
+}
+
+// PrepareMigration reserves room for a VM another agent is migrating here
+func (s *Server) PrepareMigration(ctx context.Context, req *agentpb.PrepareMigrationRequest) (*agentpb.PrepareMigrationResponse, error) {
+	s.logger.Info("gRPC PrepareMigration request", zap.String("vm_id", req.GetVm().GetVmId()))
+
+	resp, err := s.prepareMigrationUC.Execute(ctx, &dto.PrepareMigrationRequest{
+		VM: toMigratingVMDTO(req.GetVm()),
+	})
+	if err != nil {
+		s.logger.Error("PrepareMigration failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.PrepareMigrationResponse{
+		DiskPath:   resp.DiskPath,
+		LibvirtUri: resp.LibvirtURI,
+	}, nil
+}
+
+// FinishMigration registers or discards a VM another agent migrated here
+func (s *Server) FinishMigration(ctx context.Context, req *agentpb.FinishMigrationRequest) (*agentpb.FinishMigrationResponse, error) {
+	s.logger.Info("gRPC FinishMigration request",
+		zap.String("vm_id", req.GetVm().GetVmId()),
+		zap.Bool("succeeded", req.Succeeded),
+	)
+
+	resp, err := s.finishMigrationUC.Execute(ctx, &dto.FinishMigrationRequest{
+		VM:        toMigratingVMDTO(req.GetVm()),
+		Succeeded: req.Succeeded,
+	})
+	if err != nil {
+		s.logger.Error("FinishMigration failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.FinishMigrationResponse{
+		VmId:      resp.VMID,
+		IpAddress: resp.IPAddress,
+		Status:    resp.Status,
+	}, nil
+}
+
+func toMigrateVMProgressProto(p *dto.MigrateVMProgress) *agentpb.MigrateVMProgress {
+	progress := &agentpb.MigrateVMProgress{
+		Phase:         p.Phase,
+		Percent:       int32(p.Percent),
+		DataTotal:     p.DataTotal,
+		DataProcessed: p.DataProcessed,
+		TargetAddress: p.TargetAddress,
+		IpAddress:     p.IPAddress,
+	}
+	for _, fwd := range p.RemovedPortForwards {
+		progress.RemovedPortForwards = append(progress.RemovedPortForwards, toPortForwardInfoProto(fwd))
+	}
+	return progress
+}
+
+func toMigratingVMDTO(vm *agentpb.MigratingVM) dto.MigratingVM {
+	return dto.MigratingVM{
+		VMID:              vm.GetVmId(),
+		Name:              vm.GetName(),
+		VCPU:              int(vm.GetVcpu()),
+		RAMGB:             int(vm.GetRamGb()),
+		DiskGB:            int(vm.GetDiskGb()),
+		Template:          vm.GetTemplate(),
+		NetworkMode:       vm.GetNetworkMode(),
+		Limits:            toVMLimitsDTO(vm.GetLimits()),
+		Metadata:          vm.GetMetadata(),
+		ExpiresAt:         vm.GetExpiresAt(),
+		ExpiryAction:      vm.GetExpiryAction(),
+		RestartPolicy:     vm.GetRestartPolicy(),
+		RestartMaxRetries: int(vm.GetRestartMaxRetries()),
+		BackupSchedule:    vm.GetBackupSchedule(),
+		BackupKeepDaily:   int(vm.GetBackupKeepDaily()),
+		BackupKeepWeekly:  int(vm.GetBackupKeepWeekly()),
+		SecurityGroups:    vm.GetSecurityGroups(),
+		Networks:          vm.GetNetworkIds(),
+		Flavor:            vm.GetFlavor(),
+		CPUMode:           vm.GetCpuMode(),
+	}
+}
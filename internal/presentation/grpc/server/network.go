@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// CreateNetwork creates an isolated private network
+func (s *Server) CreateNetwork(ctx context.Context, req *agentpb.CreateNetworkRequest) (*agentpb.CreateNetworkResponse, error) {
+	s.logger.Info("gRPC CreateNetwork request",
+		zap.String("name", req.Name),
+		zap.String("subnet", req.Subnet),
+	)
+
+	dtoReq := &dto.CreateNetworkRequest{
+		Name:      req.Name,
+		Tenant:    req.Tenant,
+		Subnet:    req.Subnet,
+		DHCPStart: req.DhcpStart,
+		DHCPEnd:   req.DhcpEnd,
+		SubnetV6:  req.SubnetV6,
+		NAT:       req.Nat,
+	}
+
+	resp, err := s.createNetworkUC.Execute(ctx, dtoReq)
+	if err != nil {
+		s.logger.Error("CreateNetwork failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.CreateNetworkResponse{
+		Network: toNetworkInfoProto(resp.Network),
+	}, nil
+}
+
+// DeleteNetwork deletes a private network no VM is attached to
+func (s *Server) DeleteNetwork(ctx context.Context, req *agentpb.DeleteNetworkRequest) (*agentpb.DeleteNetworkResponse, error) {
+	s.logger.Info("gRPC DeleteNetwork request", zap.String("network", req.Network))
+
+	resp, err := s.deleteNetworkUC.Execute(ctx, &dto.DeleteNetworkRequest{Network: req.Network})
+	if err != nil {
+		s.logger.Error("DeleteNetwork failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.DeleteNetworkResponse{
+		Success: resp.Success,
+	}, nil
+}
+
+// ListNetworks lists private networks, ordered by name
+func (s *Server) ListNetworks(ctx context.Context, req *agentpb.ListNetworksRequest) (*agentpb.ListNetworksResponse, error) {
+	s.logger.Debug("gRPC ListNetworks request", zap.String("tenant", req.Tenant))
+
+	resp, err := s.listNetworksUC.Execute(ctx, &dto.ListNetworksRequest{Tenant: req.Tenant})
+	if err != nil {
+		s.logger.Error("ListNetworks failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	networks := make([]*agentpb.NetworkInfo, len(resp.Networks))
+	for i, network := range resp.Networks {
+		networks[i] = toNetworkInfoProto(network)
+	}
+
+	return &agentpb.ListNetworksResponse{
+		Networks: networks,
+	}, nil
+}
+
+func toNetworkInfoProto(n dto.NetworkInfo) *agentpb.NetworkInfo {
+	return &agentpb.NetworkInfo{
+		Id:        n.ID,
+		Name:      n.Name,
+		Tenant:    n.Tenant,
+		Subnet:    n.Subnet,
+		Gateway:   n.Gateway,
+		DhcpStart: n.DHCPStart,
+		DhcpEnd:   n.DHCPEnd,
+		SubnetV6:  n.SubnetV6,
+		GatewayV6: n.GatewayV6,
+		Nat:       n.NAT,
+		Bridge:    n.Bridge,
+		VmIds:     n.VMIDs,
+		CreatedAt: n.CreatedAt.Unix(),
+	}
+}
+
+func toVMInterfacesProto(ifaces []dto.VMInterface) []*agentpb.VMInterface {
+	out := make([]*agentpb.VMInterface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		pb := &agentpb.VMInterface{
+			Name:       iface.Name,
+			MacAddress: iface.MACAddress,
+		}
+		for _, addr := range iface.Addresses {
+			pb.Addresses = append(pb.Addresses, &agentpb.IPAddress{
+				Address: addr.Address,
+				Prefix:  int32(addr.Prefix),
+				Family:  addr.Family,
+			})
+		}
+		out = append(out, pb)
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// AddPortForward forwards a host port to a VM port
+func (s *Server) AddPortForward(ctx context.Context, req *agentpb.AddPortForwardRequest) (*agentpb.AddPortForwardResponse, error) {
+	s.logger.Info("gRPC AddPortForward request",
+		zap.String("vm_id", req.VmId),
+		zap.String("protocol", req.Protocol),
+		zap.Int32("host_port", req.HostPort),
+		zap.Int32("guest_port", req.GuestPort),
+	)
+
+	dtoReq := &dto.AddPortForwardRequest{
+		VMID:      req.VmId,
+		Protocol:  req.Protocol,
+		HostPort:  int(req.HostPort),
+		GuestPort: int(req.GuestPort),
+	}
+
+	resp, err := s.addPortForwardUC.Execute(ctx, dtoReq)
+	if err != nil {
+		s.metrics.VMOperations.WithLabelValues("add_port_forward", "error").Inc()
+		s.logger.Error("AddPortForward failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	s.metrics.VMOperations.WithLabelValues("add_port_forward", "success").Inc()
+
+	return &agentpb.AddPortForwardResponse{
+		PortForward: toPortForwardInfoProto(resp.PortForward),
+	}, nil
+}
+
+// RemovePortForward removes a port forward
+func (s *Server) RemovePortForward(ctx context.Context, req *agentpb.RemovePortForwardRequest) (*agentpb.RemovePortForwardResponse, error) {
+	s.logger.Info("gRPC RemovePortForward request", zap.String("id", req.Id))
+
+	resp, err := s.removePortForwardUC.Execute(ctx, &dto.RemovePortForwardRequest{ID: req.Id})
+	if err != nil {
+		s.metrics.VMOperations.WithLabelValues("remove_port_forward", "error").Inc()
+		s.logger.Error("RemovePortForward failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	s.metrics.VMOperations.WithLabelValues("remove_port_forward", "success").Inc()
+
+	return &agentpb.RemovePortForwardResponse{
+		Success: resp.Success,
+	}, nil
+}
+
+// ListPortForwards lists port forwards, ordered by host port
+func (s *Server) ListPortForwards(ctx context.Context, req *agentpb.ListPortForwardsRequest) (*agentpb.ListPortForwardsResponse, error) {
+	s.logger.Debug("gRPC ListPortForwards request", zap.String("vm_id", req.VmId))
+
+	resp, err := s.listPortForwardsUC.Execute(ctx, &dto.ListPortForwardsRequest{VMID: req.VmId})
+	if err != nil {
+		s.logger.Error("ListPortForwards failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	forwards := make([]*agentpb.PortForwardInfo, len(resp.PortForwards))
+	for i, fwd := range resp.PortForwards {
+		forwards[i] = toPortForwardInfoProto(fwd)
+	}
+
+	return &agentpb.ListPortForwardsResponse{
+		PortForwards: forwards,
+	}, nil
+}
+
+func toPortForwardInfoProto(fwd dto.PortForwardInfo) *agentpb.PortForwardInfo {
+	return &agentpb.PortForwardInfo{
+		Id:        fwd.ID,
+		VmId:      fwd.VMID,
+		Protocol:  fwd.Protocol,
+		HostPort:  int32(fwd.HostPort),
+		GuestPort: int32(fwd.GuestPort),
+		GuestIp:   fwd.GuestIP,
+		GuestIpv6: fwd.GuestIPv6,
+		CreatedAt: fwd.CreatedAt.Unix(),
+	}
+}
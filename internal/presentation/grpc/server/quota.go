@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// GetQuotas returns tenant quotas with their usage and the overcommit ratios
+func (s *Server) GetQuotas(ctx context.Context, req *agentpb.GetQuotasRequest) (*agentpb.GetQuotasResponse, error) {
+	s.logger.Debug("gRPC GetQuotas request", zap.String("tenant", req.Tenant))
+
+	resp, err := s.getQuotasUC.Execute(ctx, &dto.GetQuotasRequest{Tenant: req.Tenant})
+	if err != nil {
+		s.logger.Error("GetQuotas failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	quotas := make([]*agentpb.TenantQuota, len(resp.Quotas))
+	for i, q := range resp.Quotas {
+		quotas[i] = &agentpb.TenantQuota{
+			Tenant: q.Tenant,
+			Limit:  toQuotaAmountsProto(q.Limit),
+			Usage:  toQuotaAmountsProto(q.Usage),
+		}
+	}
+
+	return &agentpb.GetQuotasResponse{
+		Quotas: quotas,
+		Overcommit: &agentpb.Overcommit{
+			Cpu:              resp.Overcommit.CPU,
+			Ram:              resp.Overcommit.RAM,
+			Disk:             resp.Overcommit.Disk,
+			ThinProvisioning: resp.Overcommit.ThinProvisioning,
+		},
+	}, nil
+}
+
+func toQuotaAmountsProto(u dto.QuotaUsage) *agentpb.QuotaAmounts {
+	return &agentpb.QuotaAmounts{
+		Vms:    int32(u.VMs),
+		Vcpu:   int32(u.VCPU),
+		RamGb:  int32(u.RAMGB),
+		DiskGb: int32(u.DiskGB),
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// SetRestartPolicy changes when the agent brings a VM back up
+func (s *Server) SetRestartPolicy(ctx context.Context, req *agentpb.SetRestartPolicyRequest) (*agentpb.SetRestartPolicyResponse, error) {
+	s.logger.Info("gRPC SetRestartPolicy request",
+		zap.String("vm_id", req.VmId),
+		zap.String("policy", req.Policy),
+	)
+
+	resp, err := s.setRestartPolicyUC.Execute(ctx, &dto.SetRestartPolicyRequest{
+		VMID:       req.VmId,
+		Policy:     req.Policy,
+		MaxRetries: int(req.MaxRetries),
+	})
+	if err != nil {
+		s.logger.Error("SetRestartPolicy failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.SetRestartPolicyResponse{
+		VmId:       resp.VMID,
+		Policy:     resp.Policy,
+		MaxRetries: int32(resp.MaxRetries),
+		Autostart:  resp.Autostart,
+	}, nil
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// CreateSecurityGroup creates a security group
+func (s *Server) CreateSecurityGroup(ctx context.Context, req *agentpb.CreateSecurityGroupRequest) (*agentpb.SecurityGroupResponse, error) {
+	s.logger.Info("gRPC CreateSecurityGroup request", zap.String("name", req.Name))
+
+	dtoReq := &dto.CreateSecurityGroupRequest{
+		Name:        req.Name,
+		Description: req.Description,
+		Rules:       make([]dto.SecurityGroupRule, len(req.Rules)),
+	}
+	for i, rule := range req.Rules {
+		dtoReq.Rules[i] = toSecurityGroupRuleDTO(rule)
+	}
+
+	resp, err := s.createSecurityGroupUC.Execute(ctx, dtoReq)
+	if err != nil {
+		s.logger.Error("CreateSecurityGroup failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.SecurityGroupResponse{
+		SecurityGroup: toSecurityGroupInfoProto(resp.SecurityGroup),
+	}, nil
+}
+
+// DeleteSecurityGroup deletes a security group
+func (s *Server) DeleteSecurityGroup(ctx context.Context, req *agentpb.DeleteSecurityGroupRequest) (*agentpb.DeleteSecurityGroupResponse, error) {
+	s.logger.Info("gRPC DeleteSecurityGroup request", zap.String("group", req.Group))
+
+	resp, err := s.deleteSecurityGroupUC.Execute(ctx, &dto.DeleteSecurityGroupRequest{Group: req.Group})
+	if err != nil {
+		s.logger.Error("DeleteSecurityGroup failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.DeleteSecurityGroupResponse{
+		Success: resp.Success,
+	}, nil
+}
+
+// ListSecurityGroups lists security groups, ordered by name
+func (s *Server) ListSecurityGroups(ctx context.Context, req *agentpb.ListSecurityGroupsRequest) (*agentpb.ListSecurityGroupsResponse, error) {
+	s.logger.Debug("gRPC ListSecurityGroups request")
+
+	resp, err := s.listSecurityGroupsUC.Execute(ctx)
+	if err != nil {
+		s.logger.Error("ListSecurityGroups failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	groups := make([]*agentpb.SecurityGroupInfo, len(resp.SecurityGroups))
+	for i, sg := range resp.SecurityGroups {
+		groups[i] = toSecurityGroupInfoProto(sg)
+	}
+
+	return &agentpb.ListSecurityGroupsResponse{
+		SecurityGroups: groups,
+	}, nil
+}
+
+// AddSecurityGroupRule adds a rule to a security group
+func (s *Server) AddSecurityGroupRule(ctx context.Context, req *agentpb.AddSecurityGroupRuleRequest) (*agentpb.SecurityGroupResponse, error) {
+	s.logger.Info("gRPC AddSecurityGroupRule request", zap.String("group", req.Group))
+
+	dtoReq := &dto.AddSecurityGroupRuleRequest{
+		Group: req.Group,
+	}
+	if req.Rule != nil {
+		dtoReq.Rule = toSecurityGroupRuleDTO(req.Rule)
+	}
+
+	resp, err := s.updateSecurityGroupRulesUC.AddRule(ctx, dtoReq)
+	if err != nil {
+		s.logger.Error("AddSecurityGroupRule failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.SecurityGroupResponse{
+		SecurityGroup: toSecurityGroupInfoProto(resp.SecurityGroup),
+	}, nil
+}
+
+// RemoveSecurityGroupRule removes a rule from a security group
+func (s *Server) RemoveSecurityGroupRule(ctx context.Context, req *agentpb.RemoveSecurityGroupRuleRequest) (*agentpb.SecurityGroupResponse, error) {
+	s.logger.Info("gRPC RemoveSecurityGroupRule request",
+		zap.String("group", req.Group),
+		zap.String("rule_id", req.RuleId),
+	)
+
+	resp, err := s.updateSecurityGroupRulesUC.RemoveRule(ctx, &dto.RemoveSecurityGroupRuleRequest{
+		Group:  req.Group,
+		RuleID: req.RuleId,
+	})
+	if err != nil {
+		s.logger.Error("RemoveSecurityGroupRule failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.SecurityGroupResponse{
+		SecurityGroup: toSecurityGroupInfoProto(resp.SecurityGroup),
+	}, nil
+}
+
+// AttachSecurityGroup attaches a security group to a VM
+func (s *Server) AttachSecurityGroup(ctx context.Context, req *agentpb.AttachSecurityGroupRequest) (*agentpb.AttachSecurityGroupResponse, error) {
+	s.logger.Info("gRPC AttachSecurityGroup request",
+		zap.String("vm_id", req.VmId),
+		zap.String("group", req.Group),
+	)
+
+	resp, err := s.attachSecurityGroupUC.Attach(ctx, &dto.AttachSecurityGroupRequest{
+		VMID:  req.VmId,
+		Group: req.Group,
+	})
+	if err != nil {
+		s.logger.Error("AttachSecurityGroup failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.AttachSecurityGroupResponse{
+		VmId:           resp.VMID,
+		SecurityGroups: resp.SecurityGroups,
+	}, nil
+}
+
+// DetachSecurityGroup detaches a security group from a VM
+func (s *Server) DetachSecurityGroup(ctx context.Context, req *agentpb.AttachSecurityGroupRequest) (*agentpb.AttachSecurityGroupResponse, error) {
+	s.logger.Info("gRPC DetachSecurityGroup request",
+		zap.String("vm_id", req.VmId),
+		zap.String("group", req.Group),
+	)
+
+	resp, err := s.attachSecurityGroupUC.Detach(ctx, &dto.AttachSecurityGroupRequest{
+		VMID:  req.VmId,
+		Group: req.Group,
+	})
+	if err != nil {
+		s.logger.Error("DetachSecurityGroup failed", zap.Error(err))
+		return nil, s.toGRPCError(err)
+	}
+
+	return &agentpb.AttachSecurityGroupResponse{
+		VmId:           resp.VMID,
+		SecurityGroups: resp.SecurityGroups,
+	}, nil
+}
+
+func toSecurityGroupRuleDTO(rule *agentpb.SecurityGroupRule) dto.SecurityGroupRule {
+	return dto.SecurityGroupRule{
+		Direction: rule.Direction,
+		Protocol:  rule.Protocol,
+		PortMin:   int(rule.PortMin),
+		PortMax:   int(rule.PortMax),
+		CIDR:      rule.Cidr,
+	}
+}
+
+func toSecurityGroupInfoProto(sg dto.SecurityGroupInfo) *agentpb.SecurityGroupInfo {
+	rules := make([]*agentpb.SecurityGroupRule, len(sg.Rules))
+	for i, rule := range sg.Rules {
+		rules[i] = &agentpb.SecurityGroupRule{
+			Id:        rule.ID,
+			Direction: rule.Direction,
+			Protocol:  rule.Protocol,
+			PortMin:   int32(rule.PortMin),
+			PortMax:   int32(rule.PortMax),
+			Cidr:      rule.CIDR,
+		}
+	}
+
+	return &agentpb.SecurityGroupInfo{
+		Id:          sg.ID,
+		Name:        sg.Name,
+		Description: sg.Description,
+		Rules:       rules,
+		VmIds:       sg.VMIDs,
+		CreatedAt:   sg.CreatedAt.Unix(),
+	}
+}
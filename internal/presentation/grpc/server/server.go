@@ -2,16 +2,12 @@ package server
 
 import (
 	"context"
-	stderrors "errors"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 
 	"github.com/iammahbubalam/ghost-agent/internal/application/dto"
 	"github.com/iammahbubalam/ghost-agent/internal/application/usecase"
-	"github.com/iammahbubalam/ghost-agent/internal/domain/errors"
 	"github.com/iammahbubalam/ghost-agent/internal/infrastructure/observability"
 	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
 )
@@ -20,12 +16,52 @@ import (
 type Server struct {
 	agentpb.UnimplementedAgentServiceServer
 	
-	createVMUC    *usecase.CreateVMUseCase
-	deleteVMUC    *usecase.DeleteVMUseCase
-	startVMUC     *usecase.StartVMUseCase
-	stopVMUC      *usecase.StopVMUseCase
-	getVMStatusUC *usecase.GetVMStatusUseCase
-	listVMsUC     *usecase.ListVMsUseCase
+	createVMUC       *usecase.CreateVMUseCase
+	deleteVMUC       *usecase.DeleteVMUseCase
+	startVMUC        *usecase.StartVMUseCase
+	stopVMUC         *usecase.StopVMUseCase
+	getVMStatusUC    *usecase.GetVMStatusUseCase
+	listVMsUC        *usecase.ListVMsUseCase
+	exportVMUC       *usecase.ExportVMUseCase
+	importVMUC       *usecase.ImportVMUseCase
+	migrateVMUC      *usecase.MigrateVMUseCase
+	updateVMLimitsUC *usecase.UpdateVMLimitsUseCase
+	uploadImageUC    *usecase.UploadImageUseCase
+	
+	attachConsoleUC  *usecase.AttachConsoleUseCase
+	createVNCTokenUC *usecase.CreateVNCTokenUseCase
+	guestExecUC      *usecase.GuestExecUseCase
+	
+	addPortForwardUC    *usecase.AddPortForwardUseCase
+	removePortForwardUC *usecase.RemovePortForwardUseCase
+	listPortForwardsUC  *usecase.ListPortForwardsUseCase
+	
+	createSecurityGroupUC      *usecase.CreateSecurityGroupUseCase
+	deleteSecurityGroupUC      *usecase.DeleteSecurityGroupUseCase
+	listSecurityGroupsUC       *usecase.ListSecurityGroupsUseCase
+	updateSecurityGroupRulesUC *usecase.UpdateSecurityGroupRulesUseCase
+	attachSecurityGroupUC      *usecase.AttachSecurityGroupUseCase
+	
+	createNetworkUC *usecase.CreateNetworkUseCase
+	deleteNetworkUC *usecase.DeleteNetworkUseCase
+	listNetworksUC  *usecase.ListNetworksUseCase
+	
+	setBackupPolicyUC *usecase.SetBackupPolicyUseCase
+	createBackupUC    *usecase.CreateBackupUseCase
+	listBackupsUC     *usecase.ListBackupsUseCase
+	restoreBackupUC   *usecase.RestoreBackupUseCase
+	
+	prepareMigrationUC *usecase.PrepareMigrationUseCase
+	finishMigrationUC  *usecase.FinishMigrationUseCase
+	
+	queryAuditLogUC *usecase.QueryAuditLogUseCase
+	getAgentInfoUC  *usecase.GetAgentInfoUseCase
+	getQuotasUC     *usecase.GetQuotasUseCase
+	listFlavorsUC   *usecase.ListFlavorsUseCase
+	setFlavorsUC    *usecase.SetFlavorsUseCase
+	renewVMUC       *usecase.RenewVMUseCase
+	
+	setRestartPolicyUC *usecase.SetRestartPolicyUseCase
 	
 	metrics *observability.Metrics
 	logger  *zap.Logger
@@ -39,18 +75,82 @@ func NewServer(
 	stopVMUC *usecase.StopVMUseCase,
 	getVMStatusUC *usecase.GetVMStatusUseCase,
 	listVMsUC *usecase.ListVMsUseCase,
+	exportVMUC *usecase.ExportVMUseCase,
+	importVMUC *usecase.ImportVMUseCase,
+	migrateVMUC *usecase.MigrateVMUseCase,
+	updateVMLimitsUC *usecase.UpdateVMLimitsUseCase,
+	attachConsoleUC *usecase.AttachConsoleUseCase,
+	createVNCTokenUC *usecase.CreateVNCTokenUseCase,
+	guestExecUC *usecase.GuestExecUseCase,
+	addPortForwardUC *usecase.AddPortForwardUseCase,
+	removePortForwardUC *usecase.RemovePortForwardUseCase,
+	listPortForwardsUC *usecase.ListPortForwardsUseCase,
+	createSecurityGroupUC *usecase.CreateSecurityGroupUseCase,
+	deleteSecurityGroupUC *usecase.DeleteSecurityGroupUseCase,
+	listSecurityGroupsUC *usecase.ListSecurityGroupsUseCase,
+	updateSecurityGroupRulesUC *usecase.UpdateSecurityGroupRulesUseCase,
+	attachSecurityGroupUC *usecase.AttachSecurityGroupUseCase,
+	createNetworkUC *usecase.CreateNetworkUseCase,
+	deleteNetworkUC *usecase.DeleteNetworkUseCase,
+	listNetworksUC *usecase.ListNetworksUseCase,
+	uploadImageUC *usecase.UploadImageUseCase,
+	setBackupPolicyUC *usecase.SetBackupPolicyUseCase,
+	createBackupUC *usecase.CreateBackupUseCase,
+	listBackupsUC *usecase.ListBackupsUseCase,
+	restoreBackupUC *usecase.RestoreBackupUseCase,
+	prepareMigrationUC *usecase.PrepareMigrationUseCase,
+	finishMigrationUC *usecase.FinishMigrationUseCase,
+	queryAuditLogUC *usecase.QueryAuditLogUseCase,
+	getAgentInfoUC *usecase.GetAgentInfoUseCase,
+	getQuotasUC *usecase.GetQuotasUseCase,
+	listFlavorsUC *usecase.ListFlavorsUseCase,
+	setFlavorsUC *usecase.SetFlavorsUseCase,
+	renewVMUC *usecase.RenewVMUseCase,
+	setRestartPolicyUC *usecase.SetRestartPolicyUseCase,
 	metrics *observability.Metrics,
 	logger *zap.Logger,
 ) *Server {
 	return &Server{
-		createVMUC:    createVMUC,
-		deleteVMUC:    deleteVMUC,
-		startVMUC:     startVMUC,
-		stopVMUC:      stopVMUC,
-		getVMStatusUC: getVMStatusUC,
-		listVMsUC:     listVMsUC,
-		metrics:       metrics,
-		logger:        logger,
+		createVMUC:                 createVMUC,
+		deleteVMUC:                 deleteVMUC,
+		startVMUC:                  startVMUC,
+		stopVMUC:                   stopVMUC,
+		getVMStatusUC:              getVMStatusUC,
+		listVMsUC:                  listVMsUC,
+		exportVMUC:                 exportVMUC,
+		importVMUC:                 importVMUC,
+		migrateVMUC:                migrateVMUC,
+		updateVMLimitsUC:           updateVMLimitsUC,
+		attachConsoleUC:            attachConsoleUC,
+		createVNCTokenUC:           createVNCTokenUC,
+		guestExecUC:                guestExecUC,
+		addPortForwardUC:           addPortForwardUC,
+		removePortForwardUC:        removePortForwardUC,
+		listPortForwardsUC:         listPortForwardsUC,
+		createSecurityGroupUC:      createSecurityGroupUC,
+		deleteSecurityGroupUC:      deleteSecurityGroupUC,
+		listSecurityGroupsUC:       listSecurityGroupsUC,
+		updateSecurityGroupRulesUC: updateSecurityGroupRulesUC,
+		attachSecurityGroupUC:      attachSecurityGroupUC,
+		createNetworkUC:            createNetworkUC,
+		deleteNetworkUC:            deleteNetworkUC,
+		listNetworksUC:             listNetworksUC,
+		uploadImageUC:              uploadImageUC,
+		setBackupPolicyUC:          setBackupPolicyUC,
+		createBackupUC:             createBackupUC,
+		listBackupsUC:              listBackupsUC,
+		restoreBackupUC:            restoreBackupUC,
+		prepareMigrationUC:         prepareMigrationUC,
+		finishMigrationUC:          finishMigrationUC,
+		queryAuditLogUC:            queryAuditLogUC,
+		getAgentInfoUC:             getAgentInfoUC,
+		getQuotasUC:                getQuotasUC,
+		listFlavorsUC:              listFlavorsUC,
+		setFlavorsUC:               setFlavorsUC,
+		renewVMUC:                  renewVMUC,
+		setRestartPolicyUC:         setRestartPolicyUC,
+		metrics:                    metrics,
+		logger:                     logger,
 	}
 }
 
@@ -60,12 +160,24 @@ func (s *Server) CreateVM(ctx context.Context, req *agentpb.CreateVMRequest) (*a
 	
 	// Convert protobuf to DTO
 	dtoReq := &dto.CreateVMRequest{
-		Name:     req.Name,
-		VCPU:     int(req.Vcpu),
-		RAMGB:    int(req.RamGb),
-		DiskGB:   int(req.DiskGb),
-		Template: req.Template,
-		Metadata: req.Metadata,
+		Name:              req.Name,
+		Flavor:            req.Flavor,
+		VCPU:              int(req.Vcpu),
+		RAMGB:             int(req.RamGb),
+		DiskGB:            int(req.DiskGb),
+		Template:          req.Template,
+		NetworkMode:       req.NetworkMode,
+		Networks:          req.Networks,
+		Metadata:          req.Metadata,
+		TTLSeconds:        req.TtlSeconds,
+		ExpiresAt:         req.ExpiresAt,
+		ExpiryAction:      req.ExpiryAction,
+		RestartPolicy:     req.RestartPolicy,
+		RestartMaxRetries: int(req.RestartMaxRetries),
+	}
+	if req.Limits != nil {
+		limits := toVMLimitsDTO(req.Limits)
+		dtoReq.Limits = &limits
 	}
 	
 	// Execute use case
@@ -73,7 +185,7 @@ func (s *Server) CreateVM(ctx context.Context, req *agentpb.CreateVMRequest) (*a
 	if err != nil {
 		s.metrics.VMOperations.WithLabelValues("create", "error").Inc()
 		s.logger.Error("CreateVM failed", zap.Error(err))
-		return nil, toGRPCError(err)
+		return nil, s.toGRPCError(err)
 	}
 	
 	s.metrics.VMsCreated.Inc()
@@ -81,9 +193,10 @@ func (s *Server) CreateVM(ctx context.Context, req *agentpb.CreateVMRequest) (*a
 	s.metrics.VMOperations.WithLabelValues("create", "success").Inc()
 	
 	return &agentpb.CreateVMResponse{
-		VmId:      resp.VMID,
-		IpAddress: resp.IPAddress,
-		Status:    resp.Status,
+		VmId:       resp.VMID,
+		IpAddress:  resp.IPAddress,
+		Status:     resp.Status,
+		Interfaces: toVMInterfacesProto(resp.Interfaces),
 	}, nil
 }
 
@@ -99,7 +212,7 @@ func (s *Server) DeleteVM(ctx context.Context, req *agentpb.DeleteVMRequest) (*a
 	if err != nil {
 		s.metrics.VMOperations.WithLabelValues("delete", "error").Inc()
 		s.logger.Error("DeleteVM failed", zap.Error(err))
-		return nil, toGRPCError(err)
+		return nil, s.toGRPCError(err)
 	}
 	
 	s.metrics.VMsDeleted.Inc()
@@ -123,7 +236,7 @@ func (s *Server) StartVM(ctx context.Context, req *agentpb.StartVMRequest) (*age
 	if err != nil {
 		s.metrics.VMOperations.WithLabelValues("start", "error").Inc()
 		s.logger.Error("StartVM failed", zap.Error(err))
-		return nil, toGRPCError(err)
+		return nil, s.toGRPCError(err)
 	}
 	
 	s.metrics.VMsRunning.Inc()
@@ -147,7 +260,7 @@ func (s *Server) StopVM(ctx context.Context, req *agentpb.StopVMRequest) (*agent
 	if err != nil {
 		s.metrics.VMOperations.WithLabelValues("stop", "error").Inc()
 		s.logger.Error("StopVM failed", zap.Error(err))
-		return nil, toGRPCError(err)
+		return nil, s.toGRPCError(err)
 	}
 	
 	s.metrics.VMsRunning.Dec()
@@ -169,20 +282,32 @@ func (s *Server) GetVMStatus(ctx context.Context, req *agentpb.GetVMStatusReques
 	resp, err := s.getVMStatusUC.Execute(ctx, dtoReq)
 	if err != nil {
 		s.logger.Error("GetVMStatus failed", zap.Error(err))
-		return nil, toGRPCError(err)
+		return nil, s.toGRPCError(err)
 	}
 	
 	return &agentpb.GetVMStatusResponse{
 		VmId:            resp.VMID,
 		Name:            resp.Name,
 		Status:          resp.Status,
+		Flavor:          resp.Flavor,
 		Vcpu:            int32(resp.VCPU),
 		RamGb:           int32(resp.RAMGB),
 		DiskGb:          int32(resp.DiskGB),
 		IpAddress:       resp.IPAddress,
+		NetworkMode:     resp.NetworkMode,
 		UptimeSeconds:   resp.UptimeSeconds,
 		CpuUsagePercent: resp.CPUUsagePercent,
 		RamUsagePercent: resp.RAMUsagePercent,
+		Guest:           toGuestInfoProto(resp.Guest),
+		SecurityGroups:  resp.SecurityGroups,
+		Networks:        resp.Networks,
+		Limits:          toVMLimitsProto(resp.Limits),
+		Interfaces:      toVMInterfacesProto(resp.Interfaces),
+		Metadata:        resp.Metadata,
+		ExpiresAt:       resp.ExpiresAt,
+		ExpiryAction:    resp.ExpiryAction,
+		RestartPolicy:   resp.RestartPolicy,
+		Restarts:        int32(resp.Restarts),
 	}, nil
 }
 
@@ -195,16 +320,17 @@ func (s *Server) ListVMs(ctx context.Context, req *agentpb.ListVMsRequest) (*age
 	resp, err := s.listVMsUC.Execute(ctx, dtoReq)
 	if err != nil {
 		s.logger.Error("ListVMs failed", zap.Error(err))
-		return nil, toGRPCError(err)
+		return nil, s.toGRPCError(err)
 	}
 	
 	vms := make([]*agentpb.VMInfo, len(resp.VMs))
 	for i, vm := range resp.VMs {
 		vms[i] = &agentpb.VMInfo{
-			VmId:      vm.VMID,
-			Name:      vm.Name,
-			Status:    vm.Status,
-			IpAddress: vm.IPAddress,
+			VmId:       vm.VMID,
+			Name:       vm.Name,
+			Status:     vm.Status,
+			IpAddress:  vm.IPAddress,
+			Interfaces: toVMInterfacesProto(vm.Interfaces),
 		}
 	}
 	
@@ -213,27 +339,6 @@ func (s *Server) ListVMs(ctx context.Context, req *agentpb.ListVMsRequest) (*age
 	}, nil
 }
 
-// Helper function to convert domain errors to gRPC errors
-func toGRPCError(err error) error {
-	var appErr *errors.AppError
-	if !stderrors.As(err, &appErr) {
-		return status.Error(codes.Internal, "internal server error")
-	}
-	
-	switch appErr.Code {
-	case errors.ErrCodeValidation:
-		return status.Error(codes.InvalidArgument, appErr.Message)
-	case errors.ErrCodeNotFound:
-		return status.Error(codes.NotFound, appErr.Message)
-	case errors.ErrCodeConflict:
-		return status.Error(codes.AlreadyExists, appErr.Message)
-	case errors.ErrCodeResourceLimit:
-		return status.Error(codes.ResourceExhausted, appErr.Message)
-	default:
-		return status.Error(codes.Internal, appErr.Message)
-	}
-}
-
 // RegisterAgentService registers the agent service with gRPC server
 func RegisterAgentService(grpcServer *grpc.Server, server *Server) {
 	agentpb.RegisterAgentServiceServer(grpcServer, server)
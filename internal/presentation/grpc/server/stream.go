@@ -0,0 +1,50 @@
+package server
+
+import (
+	"bufio"
+	"io"
+)
+
+// streamChunkSize is the size of each data message sent on a server stream
+const streamChunkSize = 1 << 20
+
+// pipeChunks turns a sequence of received data chunks into a reader.
+// next returns io.EOF once the client has finished sending.
+// The caller must close the returned reader to release the receiver.
+func pipeChunks(next func() ([]byte, error)) *io.PipeReader {
+	pr, pw := io.Pipe()
+	go func() {
+		for {
+			chunk, err := next()
+			if err == io.EOF {
+				pw.Close()
+				return
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := pw.Write(chunk); err != nil {
+				return
+			}
+		}
+	}()
+	return pr
+}
+
+// chunkWriter sends everything written to it as fixed-size stream messages
+type chunkWriter struct {
+	send func([]byte) error
+}
+
+func (w chunkWriter) Write(p []byte) (int, error) {
+	if err := w.send(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// newChunkWriter returns a buffered writer that flushes in streamChunkSize messages
+func newChunkWriter(send func([]byte) error) *bufio.Writer {
+	return bufio.NewWriterSize(chunkWriter{send: send}, streamChunkSize)
+}
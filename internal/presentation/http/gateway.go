@@ -0,0 +1,523 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// servicePrefix starts the full method names of AgentService
+const servicePrefix = "/agentpb.AgentService/"
+
+// Largest request body of a unary RPC
+const maxRequestBytes = 4 << 20
+
+var (
+	marshalOptions   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	unmarshalOptions = protojson.UnmarshalOptions{}
+)
+
+// Gateway serves AgentService as JSON over HTTP
+// Calls go through the same interceptors as gRPC calls, so they are
+// authenticated, rate limited and audited alike; callers authenticate with a
+// bearer token. Streaming RPCs take and return newline-delimited JSON messages
+type Gateway struct {
+	srv    agentpb.AgentServiceServer
+	unary  grpc.UnaryServerInterceptor
+	stream grpc.StreamServerInterceptor
+	mux    *http.ServeMux
+	spec   []byte
+	logger *zap.Logger
+}
+
+// NewGateway creates a gateway calling srv through the interceptors, in order
+// Every RPC of AgentService must have a route
+func NewGateway(
+	srv agentpb.AgentServiceServer,
+	unary []grpc.UnaryServerInterceptor,
+	stream []grpc.StreamServerInterceptor,
+	logger *zap.Logger,
+) (*Gateway, error) {
+	g := &Gateway{
+		srv:    srv,
+		unary:  chainUnary(unary),
+		stream: chainStream(stream),
+		mux:    http.NewServeMux(),
+		logger: logger,
+	}
+
+	desc := agentpb.AgentService_ServiceDesc
+	methods := make(map[string]grpc.MethodDesc, len(desc.Methods))
+	for _, m := range desc.Methods {
+		methods[m.MethodName] = m
+	}
+	streams := make(map[string]grpc.StreamDesc, len(desc.Streams))
+	for _, s := range desc.Streams {
+		streams[s.StreamName] = s
+	}
+
+	routed := make(map[string]bool, len(routes))
+	for _, rt := range routes {
+		var handler http.HandlerFunc
+		if m, ok := methods[rt.rpc]; ok {
+			handler = g.unaryHandler(rt, m)
+		} else if s, ok := streams[rt.rpc]; ok {
+			handler = g.streamHandler(rt, s)
+		} else {
+			return nil, fmt.Errorf("route %s %s names unknown RPC %s", rt.method, rt.path, rt.rpc)
+		}
+		g.mux.HandleFunc(rt.method+" "+rt.path, handler)
+		routed[rt.rpc] = true
+	}
+	for name := range methods {
+		if !routed[name] {
+			return nil, fmt.Errorf("RPC %s has no route", name)
+		}
+	}
+	for name := range streams {
+		if !routed[name] {
+			return nil, fmt.Errorf("RPC %s has no route", name)
+		}
+	}
+
+	spec, err := openAPISpec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OpenAPI document: %w", err)
+	}
+	g.spec = spec
+	g.mux.HandleFunc("GET /v1/openapi.json", g.serveSpec)
+
+	return g, nil
+}
+
+// ServeHTTP handles /v1/
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// serveSpec serves the OpenAPI document, which needs no credentials
+func (g *Gateway) serveSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(g.spec)
+}
+
+// unaryHandler calls a unary RPC with the request built from the body, query
+// and path
+func (g *Gateway) unaryHandler(rt route, desc grpc.MethodDesc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, headers := g.callContext(r, rt.rpc)
+
+		dec := func(v any) error {
+			return decodeRequest(w, r, rt, v.(proto.Message))
+		}
+
+		resp, err := desc.Handler(g.srv, ctx, dec, g.unary)
+		headers.copyTo(w)
+		if err != nil {
+			g.writeError(w, rt, err)
+			return
+		}
+
+		data, err := marshal(resp.(proto.Message))
+		if err != nil {
+			g.writeError(w, rt, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
+
+// streamHandler calls a streaming RPC; request messages are read from the body
+// when the client streams, otherwise the request is built like a unary one
+func (g *Gateway) streamHandler(rt route, desc grpc.StreamDesc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, headers := g.callContext(r, rt.rpc)
+
+		if desc.ClientStreams && desc.ServerStreams {
+			// Console output flows while input is still being read
+			if err := http.NewResponseController(w).EnableFullDuplex(); err != nil {
+				g.writeError(w, rt, status.Errorf(codes.Unimplemented, "streaming both ways is not supported: %v", err))
+				return
+			}
+		}
+
+		ss := &jsonStream{
+			ctx:          ctx,
+			w:            w,
+			headers:      headers,
+			route:        rt,
+			req:          r,
+			clientStream: desc.ClientStreams,
+			serverStream: desc.ServerStreams,
+			dec:          json.NewDecoder(r.Body),
+		}
+		info := &grpc.StreamServerInfo{
+			FullMethod:     servicePrefix + desc.StreamName,
+			IsClientStream: desc.ClientStreams,
+			IsServerStream: desc.ServerStreams,
+		}
+
+		var err error
+		if g.stream != nil {
+			err = g.stream(g.srv, ss, info, desc.Handler)
+		} else {
+			err = desc.Handler(g.srv, ss)
+		}
+		if err == nil {
+			return
+		}
+
+		if !ss.started {
+			headers.copyTo(w)
+			g.writeError(w, rt, err)
+			return
+		}
+		// The status line is gone, the error ends the stream instead
+		data, _ := protojson.Marshal(status.Convert(err).Proto())
+		fmt.Fprintf(w, "{\"error\":%s}\n", data)
+	}
+}
+
+// callContext carries the caller's token and address to the interceptors and
+// collects the response headers they set
+func (g *Gateway) callContext(r *http.Request, rpc string) (context.Context, *headerStream) {
+	md := metadata.MD{}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		md.Set("authorization", auth)
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+	}
+
+	headers := &headerStream{method: servicePrefix + rpc, header: metadata.MD{}}
+	return grpc.NewContextWithServerTransportStream(ctx, headers), headers
+}
+
+// writeError responds with the status as JSON and the matching HTTP status
+func (g *Gateway) writeError(w http.ResponseWriter, rt route, err error) {
+	st := status.Convert(err)
+	if st.Code() == codes.Internal || st.Code() == codes.Unknown {
+		g.logger.Error("Gateway call failed", zap.String("rpc", rt.rpc), zap.Error(err))
+	}
+
+	data, mErr := protojson.Marshal(st.Proto())
+	if mErr != nil {
+		data = []byte(`{"code":13,"message":"internal server error"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(st.Code()))
+	w.Write(data)
+}
+
+// httpStatus maps a gRPC status code to the closest HTTP status
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client closed request
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decodeRequest builds a request from the body, except for GET, then the
+// query and path
+func decodeRequest(w http.ResponseWriter, r *http.Request, rt route, msg proto.Message) error {
+	if r.Method != http.MethodGet {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed to read request body: %v", err)
+		}
+		if len(body) > 0 {
+			if err := unmarshalOptions.Unmarshal(body, msg); err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid request body: %v", err)
+			}
+		}
+	}
+	return bindParams(r, rt, msg)
+}
+
+// marshal encodes a response message as compact JSON
+func marshal(msg proto.Message) ([]byte, error) {
+	data, err := marshalOptions.Marshal(msg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response: %v", err)
+	}
+	// protojson varies its whitespace on purpose
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// bindParams sets request fields from the query string, then the path
+// Fields are named as in the proto, e.g. vm_id
+func bindParams(r *http.Request, rt route, msg proto.Message) error {
+	m := msg.ProtoReflect()
+	for name, values := range r.URL.Query() {
+		if err := setField(m, name, values); err != nil {
+			return status.Errorf(codes.InvalidArgument, "query parameter %s: %v", name, err)
+		}
+	}
+	for _, name := range rt.pathParams() {
+		if err := setField(m, name, []string{r.PathValue(name)}); err != nil {
+			return status.Errorf(codes.InvalidArgument, "path parameter %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// setField sets a scalar or repeated scalar field from its text form
+func setField(m protoreflect.Message, name string, values []string) error {
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil {
+		fd = m.Descriptor().Fields().ByJSONName(name)
+	}
+	if fd == nil || fd.IsMap() || fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		return stderrors.New("unknown field")
+	}
+
+	if fd.IsList() {
+		list := m.Mutable(fd).List()
+		for _, value := range values {
+			v, err := parseScalar(fd, value)
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+		return nil
+	}
+
+	v, err := parseScalar(fd, values[len(values)-1])
+	if err != nil {
+		return err
+	}
+	m.Set(fd, v)
+	return nil
+}
+
+// parseScalar parses the text form of a value of the field's kind
+func parseScalar(fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(value, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(value, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(value, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(value, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(value, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.EnumKind:
+		ev := fd.Enum().Values().ByName(protoreflect.Name(value))
+		if ev == nil {
+			return protoreflect.Value{}, fmt.Errorf("unknown value %q", value)
+		}
+		return protoreflect.ValueOfEnum(ev.Number()), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field type %s", fd.Kind())
+}
+
+// headerStream collects the response headers interceptors and handlers set
+type headerStream struct {
+	method string
+
+	mu     sync.Mutex
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return s.method }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+// SetTrailer drops trailers, HTTP responses have none here
+func (s *headerStream) SetTrailer(md metadata.MD) error {
+	return nil
+}
+
+// copyTo sets the collected headers on the HTTP response, e.g. retry-after
+func (s *headerStream) copyTo(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, values := range s.header {
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+}
+
+// jsonStream is a server stream reading and writing JSON messages over HTTP
+type jsonStream struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	headers *headerStream
+	route   route
+	req     *http.Request
+
+	clientStream bool // Messages come from the body one by one
+	serverStream bool // Responses are JSON lines, otherwise a single JSON object
+	dec          *json.Decoder
+	received     int
+	started      bool
+}
+
+func (s *jsonStream) Context() context.Context { return s.ctx }
+
+func (s *jsonStream) SetHeader(md metadata.MD) error { return s.headers.SetHeader(md) }
+
+func (s *jsonStream) SendHeader(md metadata.MD) error { return s.headers.SetHeader(md) }
+
+func (s *jsonStream) SetTrailer(md metadata.MD) {}
+
+// RecvMsg reads the next request message
+func (s *jsonStream) RecvMsg(m any) error {
+	msg := m.(proto.Message)
+	defer func() { s.received++ }()
+
+	if !s.clientStream {
+		if s.received > 0 {
+			return io.EOF
+		}
+		return decodeRequest(s.w, s.req, s.route, msg)
+	}
+
+	var raw json.RawMessage
+	if err := s.dec.Decode(&raw); err != nil {
+		if stderrors.Is(err, io.EOF) {
+			return io.EOF
+		}
+		return status.Errorf(codes.InvalidArgument, "invalid request message: %v", err)
+	}
+	if err := unmarshalOptions.Unmarshal(raw, msg); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request message: %v", err)
+	}
+	return nil
+}
+
+// SendMsg writes a response message as one line
+func (s *jsonStream) SendMsg(m any) error {
+	data, err := marshal(m.(proto.Message))
+	if err != nil {
+		return err
+	}
+
+	if !s.started {
+		contentType := "application/json"
+		if s.serverStream {
+			contentType = "application/x-ndjson"
+		}
+		s.headers.copyTo(s.w)
+		s.w.Header().Set("Content-Type", contentType)
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		return status.Errorf(codes.Canceled, "client went away: %v", err)
+	}
+	http.NewResponseController(s.w).Flush()
+	return nil
+}
+
+// chainUnary combines interceptors into one calling them in order, nil when
+// there are none
+func chainUnary(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	if len(interceptors) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// chainStream combines interceptors into one calling them in order, nil when
+// there are none
+func chainStream(interceptors []grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	if len(interceptors) == 0 {
+		return nil
+	}
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(srv any, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, inner)
+			}
+		}
+		return next(srv, ss)
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+// route maps an HTTP method and path to an AgentService RPC
+// Path wildcards are request fields; clients of streaming uploads send every
+// message in the body, so their paths have none
+type route struct {
+	method  string
+	path    string
+	rpc     string
+	summary string
+}
+
+// pathParams returns the names of the path's wildcards
+func (rt route) pathParams() []string {
+	var names []string
+	for _, segment := range strings.Split(rt.path, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			names = append(names, strings.TrimSuffix(name, "}"))
+		}
+	}
+	return names
+}
+
+// routes are the gateway's endpoints
+var routes = []route{
+	{http.MethodPost, "/v1/vms", "CreateVM", "Create a VM"},
+	{http.MethodGet, "/v1/vms", "ListVMs", "List VMs"},
+	{http.MethodGet, "/v1/vms/{vm_id}", "GetVMStatus", "Get a VM's status"},
+	{http.MethodDelete, "/v1/vms/{vm_id}", "DeleteVM", "Delete a VM"},
+	{http.MethodPost, "/v1/vms/{vm_id}/start", "StartVM", "Start a VM"},
+	{http.MethodPost, "/v1/vms/{vm_id}/stop", "StopVM", "Stop a VM"},
+	{http.MethodPatch, "/v1/vms/{vm_id}/limits", "UpdateVMLimits", "Change a VM's resource limits"},
+	{http.MethodPost, "/v1/vms/{vm_id}/renew", "RenewVM", "Extend or remove a VM's lease"},
+	{http.MethodPut, "/v1/vms/{vm_id}/restart-policy", "SetRestartPolicy", "Set when the agent brings a VM back up"},
+	{http.MethodGet, "/v1/vms/{vm_id}/export", "ExportVM", "Export a stopped VM as archive chunks"},
+	{http.MethodPost, "/v1/vms/import", "ImportVM", "Import a VM from options and archive chunks"},
+	{http.MethodPost, "/v1/vms/{vm_id}/migrate", "MigrateVM", "Live migrate a VM to another agent"},
+	{http.MethodPost, "/v1/vms/console", "AttachConsole", "Attach to a VM's serial console"},
+	{http.MethodPost, "/v1/vms/{vm_id}/vnc-token", "CreateVNCToken", "Create a one-time VNC token"},
+	{http.MethodPost, "/v1/vms/{vm_id}/exec", "GuestExec", "Run a command in a VM"},
+
+	{http.MethodPost, "/v1/vms/{vm_id}/port-forwards", "AddPortForward", "Forward a host port to a VM"},
+	{http.MethodGet, "/v1/port-forwards", "ListPortForwards", "List port forwards"},
+	{http.MethodDelete, "/v1/port-forwards/{id}", "RemovePortForward", "Remove a port forward"},
+
+	{http.MethodPost, "/v1/security-groups", "CreateSecurityGroup", "Create a security group"},
+	{http.MethodGet, "/v1/security-groups", "ListSecurityGroups", "List security groups"},
+	{http.MethodDelete, "/v1/security-groups/{group}", "DeleteSecurityGroup", "Delete a security group"},
+	{http.MethodPost, "/v1/security-groups/{group}/rules", "AddSecurityGroupRule", "Add a rule to a security group"},
+	{http.MethodDelete, "/v1/security-groups/{group}/rules/{rule_id}", "RemoveSecurityGroupRule", "Remove a rule from a security group"},
+	{http.MethodPost, "/v1/vms/{vm_id}/security-groups", "AttachSecurityGroup", "Attach a security group to a VM"},
+	{http.MethodDelete, "/v1/vms/{vm_id}/security-groups/{group}", "DetachSecurityGroup", "Detach a security group from a VM"},
+
+	{http.MethodPost, "/v1/networks", "CreateNetwork", "Create a private network"},
+	{http.MethodGet, "/v1/networks", "ListNetworks", "List private networks"},
+	{http.MethodDelete, "/v1/networks/{network}", "DeleteNetwork", "Delete a private network"},
+
+	{http.MethodPost, "/v1/migrations/prepare", "PrepareMigration", "Reserve resources for an incoming migration"},
+	{http.MethodPost, "/v1/migrations/finish", "FinishMigration", "Complete or cancel an incoming migration"},
+
+	{http.MethodPost, "/v1/images", "UploadImage", "Upload an OS image from metadata and chunks"},
+
+	{http.MethodPut, "/v1/vms/{vm_id}/backup-policy", "SetBackupPolicy", "Set a VM's backup schedule and retention"},
+	{http.MethodPost, "/v1/vms/{vm_id}/backups", "CreateBackup", "Back up a VM now"},
+	{http.MethodGet, "/v1/backups", "ListBackups", "List backups"},
+	{http.MethodPost, "/v1/backups/{backup_id}/restore", "RestoreBackup", "Restore a stopped VM from a backup"},
+
+	{http.MethodGet, "/v1/audit", "QueryAuditLog", "Query the audit log"},
+	{http.MethodGet, "/v1/agent", "GetAgentInfo", "Get the agent's version and resources"},
+	{http.MethodGet, "/v1/quotas", "GetQuotas", "Get tenant quotas, their usage and the overcommit ratios"},
+	{http.MethodGet, "/v1/flavors", "ListFlavors", "List flavors and how many VMs of each fit"},
+	{http.MethodPut, "/v1/flavors", "SetFlavors", "Replace the flavors pushed by Ghost Core"},
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"runtime"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -14,15 +15,17 @@ import (
 // HealthServer handles health check endpoints
 type HealthServer struct {
 	hypervisor service.HypervisorService
+	tailnet    service.TailnetService
 	version    string
 	startTime  time.Time
 	logger     *zap.Logger
 }
 
 // NewHealthServer creates a new health server
-func NewHealthServer(hypervisor service.HypervisorService, version string, logger *zap.Logger) *HealthServer {
+func NewHealthServer(hypervisor service.HypervisorService, tailnet service.TailnetService, version string, logger *zap.Logger) *HealthServer {
 	return &HealthServer{
 		hypervisor: hypervisor,
+		tailnet:    tailnet,
 		version:    version,
 		startTime:  time.Now(),
 		logger:     logger,
@@ -65,13 +68,19 @@ func (h *HealthServer) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		resp.Checks["libvirt"] = CheckResult{Status: "up"}
 	}
 
+	// Check the tailnet; VMs keep running without it, but Ghost Core cannot reach the agent
+	resp.Checks["tailnet"] = h.tailnetCheck(r)
+	if resp.Checks["tailnet"].Status != "up" && resp.Status == "healthy" {
+		resp.Status = "degraded"
+	}
+
 	// Add metrics
 	resp.Metrics["uptime_seconds"] = time.Since(h.startTime).Seconds()
 	resp.Metrics["goroutines"] = runtime.NumGoroutine()
 
 	// Set HTTP status
 	statusCode := http.StatusOK
-	if resp.Status != "healthy" {
+	if resp.Status == "unhealthy" {
 		statusCode = http.StatusServiceUnavailable
 	}
 
@@ -80,6 +89,28 @@ func (h *HealthServer) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// tailnetCheck reports the tailnet state, the host's tailnet addresses and
+// problems tailscaled knows of
+func (h *HealthServer) tailnetCheck(r *http.Request) CheckResult {
+	status, err := h.tailnet.Status(r.Context())
+	if err != nil {
+		return CheckResult{Status: "down", Message: err.Error()}
+	}
+
+	msg := string(status.State)
+	if len(status.IPs) > 0 {
+		msg += " " + strings.Join(status.IPs, ", ")
+	}
+	if len(status.Health) > 0 {
+		msg += ": " + strings.Join(status.Health, "; ")
+	}
+
+	if !status.Up() {
+		return CheckResult{Status: "down", Message: msg}
+	}
+	return CheckResult{Status: "up", Message: msg}
+}
+
 // LivenessCheck handles /live endpoint
 func (h *HealthServer) LivenessCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
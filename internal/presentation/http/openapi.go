@@ -0,0 +1,206 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/iammahbubalam/ghost-agent/pkg/agentpb"
+)
+
+// openAPISpec describes the gateway's routes as an OpenAPI 3 document,
+// derived from the AgentService descriptors
+func openAPISpec() ([]byte, error) {
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(agentpb.AgentService_ServiceDesc.ServiceName))
+	if err != nil {
+		return nil, err
+	}
+	svc := desc.(protoreflect.ServiceDescriptor)
+
+	schemas := map[string]any{
+		"Status": map[string]any{
+			"type":        "object",
+			"description": "Error, as a google.rpc.Status",
+			"properties": map[string]any{
+				"code":    map[string]any{"type": "integer", "description": "gRPC status code"},
+				"message": map[string]any{"type": "string"},
+				"details": map[string]any{"type": "array", "items": map[string]any{"type": "object"}},
+			},
+		},
+	}
+	paths := map[string]map[string]any{}
+
+	for _, rt := range routes {
+		md := svc.Methods().ByName(protoreflect.Name(rt.rpc))
+		if md == nil {
+			return nil, fmt.Errorf("RPC %s not found", rt.rpc)
+		}
+		addSchema(schemas, md.Input())
+		addSchema(schemas, md.Output())
+
+		params := rt.pathParams()
+		var parameters []any
+		for _, name := range params {
+			fd := md.Input().Fields().ByName(protoreflect.Name(name))
+			if fd == nil {
+				return nil, fmt.Errorf("route %s: %s has no field %s", rt.path, md.Input().Name(), name)
+			}
+			parameters = append(parameters, map[string]any{
+				"name": name, "in": "path", "required": true, "schema": fieldSchema(fd),
+			})
+		}
+		if rt.method == http.MethodGet && !md.IsStreamingClient() {
+			fields := md.Input().Fields()
+			for i := 0; i < fields.Len(); i++ {
+				fd := fields.Get(i)
+				name := string(fd.Name())
+				if slices.Contains(params, name) || fd.Message() != nil {
+					continue
+				}
+				parameters = append(parameters, map[string]any{
+					"name": name, "in": "query", "schema": fieldSchema(fd),
+				})
+			}
+		}
+
+		op := map[string]any{
+			"operationId": rt.rpc,
+			"summary":     rt.summary,
+			"tags":        []string{strings.Split(strings.TrimPrefix(rt.path, "/v1/"), "/")[0]},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     content(md.Output(), md.IsStreamingServer()),
+				},
+				"default": map[string]any{
+					"description": "Error",
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": ref("Status")},
+					},
+				},
+			},
+		}
+		if len(parameters) > 0 {
+			op["parameters"] = parameters
+		}
+		if rt.method != http.MethodGet && rt.method != http.MethodDelete {
+			op["requestBody"] = map[string]any{
+				"content": content(md.Input(), md.IsStreamingClient()),
+			}
+		}
+
+		if paths[rt.path] == nil {
+			paths[rt.path] = map[string]any{}
+		}
+		paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+
+	spec := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Ghost Agent API",
+			"version":     "v1",
+			"description": "JSON gateway to AgentService. Streaming RPCs use newline-delimited JSON messages.",
+		},
+		"security": []any{map[string]any{"bearer": []string{}}},
+		"paths":    paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+	return json.MarshalIndent(spec, "", "  ")
+}
+
+// content describes a JSON body of md, or a stream of them
+func content(md protoreflect.MessageDescriptor, stream bool) map[string]any {
+	mediaType := "application/json"
+	if stream {
+		mediaType = "application/x-ndjson"
+	}
+	return map[string]any{mediaType: map[string]any{"schema": ref(schemaName(md))}}
+}
+
+// addSchema adds md and the messages it refers to
+func addSchema(schemas map[string]any, md protoreflect.MessageDescriptor) {
+	name := schemaName(md)
+	if _, ok := schemas[name]; ok {
+		return
+	}
+
+	properties := map[string]any{}
+	schemas[name] = map[string]any{"type": "object", "properties": properties}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		properties[string(fd.Name())] = fieldSchema(fd)
+
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		if fd.Message() != nil {
+			addSchema(schemas, fd.Message())
+		}
+	}
+}
+
+// fieldSchema describes a field as protojson encodes it
+func fieldSchema(fd protoreflect.FieldDescriptor) map[string]any {
+	if fd.IsMap() {
+		return map[string]any{"type": "object", "additionalProperties": valueSchema(fd.MapValue())}
+	}
+	if fd.IsList() {
+		return map[string]any{"type": "array", "items": valueSchema(fd)}
+	}
+	return valueSchema(fd)
+}
+
+// valueSchema describes a single value of the field's kind
+func valueSchema(fd protoreflect.FieldDescriptor) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "string", "format": "uint64"}
+	case protoreflect.FloatKind:
+		return map[string]any{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]any{"type": "number", "format": "double"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]string, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return ref(schemaName(fd.Message()))
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+// schemaName names the schema of a message, without the proto package
+func schemaName(md protoreflect.MessageDescriptor) string {
+	return strings.TrimPrefix(string(md.FullName()), string(md.ParentFile().Package())+".")
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
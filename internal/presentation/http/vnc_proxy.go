@@ -0,0 +1,101 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/iammahbubalam/ghost-agent/internal/domain/service"
+)
+
+// VNCProxy bridges WebSocket clients (e.g. noVNC) to a VM's VNC server
+type VNCProxy struct {
+	hypervisor service.HypervisorService
+	tokens     service.ConsoleTokenService
+	upgrader   websocket.Upgrader
+	logger     *zap.Logger
+}
+
+// NewVNCProxy creates a new VNC proxy
+func NewVNCProxy(hypervisor service.HypervisorService, tokens service.ConsoleTokenService, logger *zap.Logger) *VNCProxy {
+	return &VNCProxy{
+		hypervisor: hypervisor,
+		tokens:     tokens,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{"binary"},
+			// Access is granted by the one-time token, not the page origin
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		logger: logger,
+	}
+}
+
+// ServeHTTP handles /vnc?token=<token>
+func (p *VNCProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vmID, err := p.tokens.Redeem(r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	addr, err := p.hypervisor.GetVNCAddress(r.Context(), vmID)
+	if err != nil {
+		http.Error(w, "VNC display unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	vnc, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		p.logger.Error("Failed to connect to VNC server",
+			zap.String("vm_id", vmID),
+			zap.Error(err),
+		)
+		http.Error(w, "VNC display unavailable", http.StatusBadGateway)
+		return
+	}
+	defer vnc.Close()
+
+	ws, err := p.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already wrote the error response
+		return
+	}
+	defer ws.Close()
+
+	p.logger.Info("VNC session started", zap.String("vm_id", vmID))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := vnc.Read(buf)
+			if n > 0 {
+				if werr := ws.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			break
+		}
+		if _, err := vnc.Write(data); err != nil {
+			break
+		}
+	}
+
+	vnc.Close()
+	<-done
+
+	p.logger.Info("VNC session ended", zap.String("vm_id", vmID))
+}
@@ -193,12 +193,21 @@ resources:
   reserved_disk_gb: 50
 
 grpc:
-  listen_addr: "0.0.0.0:9090"
+  listen_addr: "tailnet:9090"
+  unix_socket: "/run/ghost/agent.sock"
+  unix_socket_mode: "0660"
   tls_enabled: false
   tls_cert: "/etc/ghost/certs/agent.crt"
   tls_key: "/etc/ghost/certs/agent.key"
   tls_ca: "/etc/ghost/certs/ca.crt"
 
+auth:
+  enabled: true
+  local_roles:
+    root: operator
+  jwt:
+    public_key: ""
+
 logging:
   level: "info"
   output: "both"
@@ -270,7 +279,7 @@ echo ""
 echo "🔥 Configuring firewall..."
 
 if command -v ufw &> /dev/null && ufw status | grep -q "Status: active"; then
-    ufw allow 9090/tcp comment "Ghost Agent gRPC"
+    ufw allow in on tailscale0 to any port 9090 proto tcp comment "Ghost Agent gRPC"
     ufw allow 9091/tcp comment "Ghost Agent Metrics"
     ufw allow 9092/tcp comment "Ghost Agent Health"
     echo "✅ Firewall rules added"